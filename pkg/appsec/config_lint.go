@@ -0,0 +1,412 @@
+package appsec
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+type (
+	// Linter runs a set of consistency rules over an exported security configuration.
+	//
+	// The linter works offline on a GetExportConfigurationResponse, so it can be used on a
+	// configuration that was exported earlier, for example in a CI pipeline.
+	Linter struct {
+		rules []LintRule
+	}
+
+	// LintRule is a single check run by the Linter.
+	LintRule interface {
+		// Name returns a unique, stable name of the rule.
+		Name() string
+
+		// Check inspects the configuration and returns the findings of the rule.
+		Check(config *GetExportConfigurationResponse) []LintFinding
+	}
+
+	// LintSeverity is the severity of a lint finding.
+	LintSeverity string
+
+	// LintFinding is a single issue reported by a LintRule.
+	LintFinding struct {
+		Rule     string       `json:"rule"`
+		Severity LintSeverity `json:"severity"`
+		PolicyID string       `json:"policyId,omitempty"`
+		Message  string       `json:"message"`
+	}
+
+	lintRule struct {
+		name  string
+		check func(config *GetExportConfigurationResponse) []LintFinding
+	}
+)
+
+const (
+	// LintSeverityError is used for findings that most likely leave traffic unprotected.
+	LintSeverityError LintSeverity = "ERROR"
+	// LintSeverityWarning is used for findings that indicate drift between security policies.
+	LintSeverityWarning LintSeverity = "WARNING"
+	// LintSeverityInfo is used for findings that do not affect protection, such as unused objects.
+	LintSeverityInfo LintSeverity = "INFO"
+
+	// LintRuleOverlappingWebsiteMatchTargets is the name of the rule reporting website match targets
+	// of different security policies that match the same hostname and path.
+	LintRuleOverlappingWebsiteMatchTargets = "overlapping-website-match-targets"
+	// LintRuleUncoveredHostnames is the name of the rule reporting selected hostnames not covered by any match target.
+	LintRuleUncoveredHostnames = "uncovered-hostnames"
+	// LintRuleDisabledProtections is the name of the rule reporting protections disabled in some security policies
+	// while enabled in others.
+	LintRuleDisabledProtections = "disabled-protections"
+	// LintRuleReputationProfileDrift is the name of the rule reporting reputation profile actions
+	// that differ between security policies.
+	LintRuleReputationProfileDrift = "reputation-profile-drift"
+	// LintRuleUnreferencedCustomRules is the name of the rule reporting custom rules not used by any security policy.
+	LintRuleUnreferencedCustomRules = "unreferenced-custom-rules"
+)
+
+var (
+	// ErrLint is returned when a configuration cannot be linted.
+	ErrLint = errors.New("configuration lint")
+
+	lintSeverityOrder = map[LintSeverity]int{
+		LintSeverityError:   0,
+		LintSeverityWarning: 1,
+		LintSeverityInfo:    2,
+	}
+)
+
+// NewLinter returns a new Linter running the given rules. When no rules are provided, DefaultLintRules are used.
+func NewLinter(rules ...LintRule) *Linter {
+	if len(rules) == 0 {
+		rules = DefaultLintRules()
+	}
+	return &Linter{rules: rules}
+}
+
+// NewLintRule returns a LintRule with the given name that runs the check function.
+func NewLintRule(name string, check func(config *GetExportConfigurationResponse) []LintFinding) LintRule {
+	return lintRule{name: name, check: check}
+}
+
+// DefaultLintRules returns all lint rules provided by this package.
+func DefaultLintRules() []LintRule {
+	return []LintRule{
+		NewLintRule(LintRuleOverlappingWebsiteMatchTargets, checkOverlappingWebsiteMatchTargets),
+		NewLintRule(LintRuleUncoveredHostnames, checkUncoveredHostnames),
+		NewLintRule(LintRuleDisabledProtections, checkDisabledProtections),
+		NewLintRule(LintRuleReputationProfileDrift, checkReputationProfileDrift),
+		NewLintRule(LintRuleUnreferencedCustomRules, checkUnreferencedCustomRules),
+	}
+}
+
+// Lint runs all rules over the configuration and returns their findings ordered by severity, rule and policy.
+func (l *Linter) Lint(config *GetExportConfigurationResponse) ([]LintFinding, error) {
+	if config == nil {
+		return nil, fmt.Errorf("%w: configuration is nil", ErrLint)
+	}
+
+	names := make(map[string]struct{}, len(l.rules))
+	var findings []LintFinding
+	for _, rule := range l.rules {
+		if _, ok := names[rule.Name()]; ok {
+			return nil, fmt.Errorf("%w: duplicate rule '%s'", ErrLint, rule.Name())
+		}
+		names[rule.Name()] = struct{}{}
+
+		for _, f := range rule.Check(config) {
+			if f.Rule == "" {
+				f.Rule = rule.Name()
+			}
+			findings = append(findings, f)
+		}
+	}
+
+	sort.SliceStable(findings, func(i, j int) bool {
+		a, b := findings[i], findings[j]
+		if lintSeverityOrder[a.Severity] != lintSeverityOrder[b.Severity] {
+			return lintSeverityOrder[a.Severity] < lintSeverityOrder[b.Severity]
+		}
+		if a.Rule != b.Rule {
+			return a.Rule < b.Rule
+		}
+		return a.PolicyID < b.PolicyID
+	})
+	return findings, nil
+}
+
+// Name returns the name of the rule.
+func (r lintRule) Name() string {
+	return r.name
+}
+
+// Check runs the rule over the configuration.
+func (r lintRule) Check(config *GetExportConfigurationResponse) []LintFinding {
+	return r.check(config)
+}
+
+func checkOverlappingWebsiteMatchTargets(config *GetExportConfigurationResponse) []LintFinding {
+	targets := config.MatchTargets.WebsiteTargets
+	var findings []LintFinding
+	for i := 0; i < len(targets); i++ {
+		for j := i + 1; j < len(targets); j++ {
+			a, b := targets[i], targets[j]
+			if a.SecurityPolicy.PolicyID == b.SecurityPolicy.PolicyID {
+				continue
+			}
+			if a.IsNegativePathMatch || b.IsNegativePathMatch {
+				continue
+			}
+			hosts := overlappingHostnames(a.Hostnames, b.Hostnames, config.SelectedHosts)
+			if len(hosts) == 0 || !pathsOverlap(a.FilePaths, b.FilePaths) {
+				continue
+			}
+			findings = append(findings, LintFinding{
+				Rule:     LintRuleOverlappingWebsiteMatchTargets,
+				Severity: LintSeverityWarning,
+				PolicyID: a.SecurityPolicy.PolicyID,
+				Message: fmt.Sprintf("website match target %d (policy %s) overlaps with match target %d (policy %s) on hostnames: %s",
+					a.ID, a.SecurityPolicy.PolicyID, b.ID, b.SecurityPolicy.PolicyID, strings.Join(hosts, ", ")),
+			})
+		}
+	}
+	return findings
+}
+
+func checkUncoveredHostnames(config *GetExportConfigurationResponse) []LintFinding {
+	var findings []LintFinding
+	for _, host := range config.SelectedHosts {
+		covered := false
+		for _, target := range config.MatchTargets.WebsiteTargets {
+			if len(target.Hostnames) == 0 {
+				covered = true
+				break
+			}
+			for _, pattern := range target.Hostnames {
+				if hostnameMatches(pattern, host) {
+					covered = true
+					break
+				}
+			}
+			if covered {
+				break
+			}
+		}
+		if !covered {
+			findings = append(findings, LintFinding{
+				Rule:     LintRuleUncoveredHostnames,
+				Severity: LintSeverityError,
+				Message:  fmt.Sprintf("hostname %s is selected for protection but is not covered by any website match target", host),
+			})
+		}
+	}
+	return findings
+}
+
+func checkDisabledProtections(config *GetExportConfigurationResponse) []LintFinding {
+	protections := []struct {
+		name    string
+		enabled func(i int) bool
+	}{
+		{"API constraints", func(i int) bool { return config.SecurityPolicies[i].SecurityControls.ApplyAPIConstraints }},
+		{"application layer controls", func(i int) bool { return config.SecurityPolicies[i].SecurityControls.ApplyApplicationLayerControls }},
+		{"bot management controls", func(i int) bool { return config.SecurityPolicies[i].SecurityControls.ApplyBotmanControls }},
+		{"network layer controls", func(i int) bool { return config.SecurityPolicies[i].SecurityControls.ApplyNetworkLayerControls }},
+		{"rate controls", func(i int) bool { return config.SecurityPolicies[i].SecurityControls.ApplyRateControls }},
+		{"reputation controls", func(i int) bool { return config.SecurityPolicies[i].SecurityControls.ApplyReputationControls }},
+		{"slow POST controls", func(i int) bool { return config.SecurityPolicies[i].SecurityControls.ApplySlowPostControls }},
+		{"malware controls", func(i int) bool { return config.SecurityPolicies[i].SecurityControls.ApplyMalwareControls }},
+	}
+
+	var findings []LintFinding
+	for _, protection := range protections {
+		var enabledIn []string
+		for i, policy := range config.SecurityPolicies {
+			if protection.enabled(i) {
+				enabledIn = append(enabledIn, policy.ID)
+			}
+		}
+		if len(enabledIn) == 0 {
+			continue
+		}
+		for i, policy := range config.SecurityPolicies {
+			if protection.enabled(i) {
+				continue
+			}
+			findings = append(findings, LintFinding{
+				Rule:     LintRuleDisabledProtections,
+				Severity: LintSeverityWarning,
+				PolicyID: policy.ID,
+				Message: fmt.Sprintf("%s are disabled in policy %s but enabled in: %s",
+					protection.name, policy.ID, strings.Join(enabledIn, ", ")),
+			})
+		}
+	}
+	return findings
+}
+
+func checkReputationProfileDrift(config *GetExportConfigurationResponse) []LintFinding {
+	profileNames := make(map[int]string, len(config.ReputationProfiles))
+	for _, profile := range config.ReputationProfiles {
+		profileNames[profile.ID] = profile.Name
+	}
+
+	// actions maps a reputation profile ID to the action set in each policy using reputation controls.
+	actions := make(map[int]map[string]string)
+	var policies []string
+	for _, policy := range config.SecurityPolicies {
+		if !policy.SecurityControls.ApplyReputationControls {
+			continue
+		}
+		policies = append(policies, policy.ID)
+		if policy.ClientReputation.ReputationProfileActions == nil {
+			continue
+		}
+		for _, action := range *policy.ClientReputation.ReputationProfileActions {
+			if actions[action.ID] == nil {
+				actions[action.ID] = make(map[string]string)
+			}
+			actions[action.ID][policy.ID] = action.Action
+		}
+	}
+
+	ids := make([]int, 0, len(actions))
+	for id := range actions {
+		ids = append(ids, id)
+	}
+	sort.Ints(ids)
+
+	var findings []LintFinding
+	for _, id := range ids {
+		byPolicy := make(map[string][]string)
+		for _, policyID := range policies {
+			action, ok := actions[id][policyID]
+			if !ok {
+				action = string(ActionTypeNone)
+			}
+			byPolicy[action] = append(byPolicy[action], policyID)
+		}
+		if len(byPolicy) < 2 {
+			continue
+		}
+
+		summary := make([]string, 0, len(byPolicy))
+		for action, policyIDs := range byPolicy {
+			summary = append(summary, fmt.Sprintf("%s in %s", action, strings.Join(policyIDs, ", ")))
+		}
+		sort.Strings(summary)
+		findings = append(findings, LintFinding{
+			Rule:     LintRuleReputationProfileDrift,
+			Severity: LintSeverityWarning,
+			Message: fmt.Sprintf("reputation profile %d (%s) has different actions across policies: %s",
+				id, profileNames[id], strings.Join(summary, "; ")),
+		})
+	}
+	return findings
+}
+
+func checkUnreferencedCustomRules(config *GetExportConfigurationResponse) []LintFinding {
+	referenced := make(map[int]struct{})
+	for _, policy := range config.SecurityPolicies {
+		for _, action := range policy.CustomRuleActions {
+			referenced[action.ID] = struct{}{}
+		}
+	}
+
+	var findings []LintFinding
+	for _, rule := range config.CustomRules {
+		if _, ok := referenced[rule.ID]; ok {
+			continue
+		}
+		findings = append(findings, LintFinding{
+			Rule:     LintRuleUnreferencedCustomRules,
+			Severity: LintSeverityInfo,
+			Message:  fmt.Sprintf("custom rule %d (%s) is not referenced by any security policy", rule.ID, rule.Name),
+		})
+	}
+	return findings
+}
+
+// overlappingHostnames returns the hostnames matched by both hostname lists. An empty list matches all selected hosts.
+func overlappingHostnames(a, b, selected []string) []string {
+	if len(a) == 0 && len(b) == 0 {
+		if len(selected) == 0 {
+			return []string{"*"}
+		}
+		return selected
+	}
+	if len(a) == 0 {
+		return b
+	}
+	if len(b) == 0 {
+		return a
+	}
+
+	var hosts []string
+	for _, ha := range a {
+		for _, hb := range b {
+			switch {
+			case hostnameMatches(ha, hb):
+				hosts = append(hosts, hb)
+			case hostnameMatches(hb, ha):
+				hosts = append(hosts, ha)
+			}
+		}
+	}
+	return hosts
+}
+
+// hostnameMatches reports whether the hostname matches the pattern, which may start with a "*." wildcard.
+func hostnameMatches(pattern, hostname string) bool {
+	pattern, hostname = strings.ToLower(pattern), strings.ToLower(hostname)
+	if pattern == hostname {
+		return true
+	}
+	if suffix, ok := strings.CutPrefix(pattern, "*"); ok {
+		return strings.HasSuffix(hostname, suffix) && len(hostname) > len(suffix)
+	}
+	return false
+}
+
+// pathsOverlap reports whether two match target path lists can match the same request path.
+// An empty list or "/*" matches all paths.
+func pathsOverlap(a, b []string) bool {
+	if matchesAllPaths(a) || matchesAllPaths(b) {
+		return true
+	}
+	for _, pa := range a {
+		for _, pb := range b {
+			if pathPatternsOverlap(pa, pb) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func matchesAllPaths(paths []string) bool {
+	if len(paths) == 0 {
+		return true
+	}
+	for _, p := range paths {
+		if p == "/*" || p == "*" {
+			return true
+		}
+	}
+	return false
+}
+
+func pathPatternsOverlap(a, b string) bool {
+	prefixA, wildA := strings.CutSuffix(a, "*")
+	prefixB, wildB := strings.CutSuffix(b, "*")
+	switch {
+	case wildA && wildB:
+		return strings.HasPrefix(prefixA, prefixB) || strings.HasPrefix(prefixB, prefixA)
+	case wildA:
+		return strings.HasPrefix(b, prefixA)
+	case wildB:
+		return strings.HasPrefix(a, prefixB)
+	default:
+		return a == b
+	}
+}
@@ -0,0 +1,118 @@
+package appsec
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLinter_Lint(t *testing.T) {
+	var config GetExportConfigurationResponse
+	require.NoError(t, json.Unmarshal(loadFixtureBytes("testdata/TestConfigLint/ConfigLint.json"), &config))
+
+	tests := map[string]struct {
+		config           *GetExportConfigurationResponse
+		rules            []LintRule
+		expectedFindings []LintFinding
+		withError        error
+	}{
+		"default rules": {
+			config: &config,
+			expectedFindings: []LintFinding{
+				{
+					Rule:     LintRuleUncoveredHostnames,
+					Severity: LintSeverityError,
+					Message:  "hostname legacy.example.org is selected for protection but is not covered by any website match target",
+				},
+				{
+					Rule:     LintRuleDisabledProtections,
+					Severity: LintSeverityWarning,
+					PolicyID: "POL2_1002",
+					Message:  "rate controls are disabled in policy POL2_1002 but enabled in: POL1_1001",
+				},
+				{
+					Rule:     LintRuleOverlappingWebsiteMatchTargets,
+					Severity: LintSeverityWarning,
+					PolicyID: "POL1_1001",
+					Message:  "website match target 3001 (policy POL1_1001) overlaps with match target 3002 (policy POL2_1002) on hostnames: www.example.com, api.example.com",
+				},
+				{
+					Rule:     LintRuleReputationProfileDrift,
+					Severity: LintSeverityWarning,
+					Message:  "reputation profile 1001 (Web Attackers (High Threat)) has different actions across policies: alert in POL2_1002; deny in POL1_1001",
+				},
+				{
+					Rule:     LintRuleUnreferencedCustomRules,
+					Severity: LintSeverityInfo,
+					Message:  "custom rule 60002 (Old header check) is not referenced by any security policy",
+				},
+			},
+		},
+		"custom rule": {
+			config: &config,
+			rules: []LintRule{
+				NewLintRule("siem-disabled", func(c *GetExportConfigurationResponse) []LintFinding {
+					if c.Siem == nil || !c.Siem.EnableSiem {
+						return []LintFinding{{Severity: LintSeverityInfo, Message: "SIEM is disabled"}}
+					}
+					return nil
+				}),
+			},
+			expectedFindings: []LintFinding{
+				{Rule: "siem-disabled", Severity: LintSeverityInfo, Message: "SIEM is disabled"},
+			},
+		},
+		"no findings": {
+			config:           &GetExportConfigurationResponse{},
+			expectedFindings: nil,
+		},
+		"duplicate rule": {
+			config: &config,
+			rules: []LintRule{
+				NewLintRule("dup", func(*GetExportConfigurationResponse) []LintFinding { return nil }),
+				NewLintRule("dup", func(*GetExportConfigurationResponse) []LintFinding { return nil }),
+			},
+			withError: ErrLint,
+		},
+		"nil configuration": {
+			withError: ErrLint,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			findings, err := NewLinter(test.rules...).Lint(test.config)
+			if test.withError != nil {
+				assert.True(t, errors.Is(err, test.withError), "want: %s; got: %s", test.withError, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, test.expectedFindings, findings)
+		})
+	}
+}
+
+func TestPathsOverlap(t *testing.T) {
+	tests := map[string]struct {
+		a, b     []string
+		expected bool
+	}{
+		"empty matches all":       {a: nil, b: []string{"/a"}, expected: true},
+		"wildcard matches all":    {a: []string{"/*"}, b: []string{"/a"}, expected: true},
+		"nested prefixes":         {a: []string{"/shop/*"}, b: []string{"/shop/cart/*"}, expected: true},
+		"prefix and exact path":   {a: []string{"/shop/*"}, b: []string{"/shop/index.html"}, expected: true},
+		"disjoint prefixes":       {a: []string{"/shop/*"}, b: []string{"/static/*"}, expected: false},
+		"different exact paths":   {a: []string{"/a"}, b: []string{"/b"}, expected: false},
+		"same exact paths":        {a: []string{"/a"}, b: []string{"/a"}, expected: true},
+		"exact path outside pref": {a: []string{"/shop/*"}, b: []string{"/blog"}, expected: false},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			assert.Equal(t, test.expected, pathsOverlap(test.a, test.b))
+		})
+	}
+}
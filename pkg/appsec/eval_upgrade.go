@@ -0,0 +1,441 @@
+package appsec
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+
+	validation "github.com/go-ozzo/ozzo-validation/v4"
+)
+
+type (
+	// EvalUpgradeManager drives a WAF rule upgrade through evaluation mode for a single security policy.
+	//
+	// The workflow starts evaluation, collects the evaluation rule and attack group actions, compares them
+	// with the currently active actions, promotes the evaluation actions into active actions, upgrades
+	// the ruleset, promotes the actions of rules and attack groups new in the ruleset and finally stops
+	// evaluation. Progress is kept in an EvalUpgradeState, so an interrupted upgrade can be resumed by
+	// creating a new manager from the last saved state.
+	EvalUpgradeManager struct {
+		client         APPSEC
+		state          EvalUpgradeState
+		promotedRules  map[string]struct{}
+		promotedGroups map[string]struct{}
+		onStateChange  func(EvalUpgradeState) error
+	}
+
+	// EvalUpgradeOption configures an EvalUpgradeManager.
+	EvalUpgradeOption func(*EvalUpgradeManager)
+
+	// EvalUpgradeStep is the last completed step of an evaluation upgrade.
+	EvalUpgradeStep string
+
+	// EvalUpgradeState holds the progress of an evaluation upgrade. It is safe to marshal to JSON and
+	// restore later to resume the workflow.
+	EvalUpgradeState struct {
+		ConfigID       int                `json:"configId"`
+		Version        int                `json:"version"`
+		PolicyID       string             `json:"policyId"`
+		Mode           string             `json:"mode,omitempty"`
+		Step           EvalUpgradeStep    `json:"step"`
+		RuleChanges    []EvalActionChange `json:"ruleChanges,omitempty"`
+		GroupChanges   []EvalActionChange `json:"groupChanges,omitempty"`
+		PromotedRules  []string           `json:"promotedRules,omitempty"`
+		PromotedGroups []string           `json:"promotedGroups,omitempty"`
+		// RulesetUpgraded is set once the ruleset is upgraded, before the actions of evaluation-only rules
+		// and attack groups are promoted.
+		RulesetUpgraded bool `json:"rulesetUpgraded,omitempty"`
+	}
+
+	// EvalActionChange describes a rule or attack group whose evaluation action differs from its current action.
+	EvalActionChange struct {
+		ID                 string          `json:"id"`
+		CurrentAction      string          `json:"currentAction,omitempty"`
+		EvalAction         string          `json:"evalAction"`
+		ConditionException json.RawMessage `json:"conditionException,omitempty"`
+		// EvalOnly is set for rules and attack groups which exist only in the evaluation ruleset. Their actions
+		// can be promoted only after the ruleset is upgraded.
+		EvalOnly bool `json:"evalOnly,omitempty"`
+	}
+)
+
+const (
+	// EvalUpgradeStepNotStarted means that no step of the upgrade was performed yet.
+	EvalUpgradeStepNotStarted EvalUpgradeStep = ""
+	// EvalUpgradeStepEvaluating means that evaluation was started on the policy.
+	EvalUpgradeStepEvaluating EvalUpgradeStep = "EVALUATING"
+	// EvalUpgradeStepCollected means that evaluation actions were collected and compared with current actions.
+	EvalUpgradeStepCollected EvalUpgradeStep = "COLLECTED"
+	// EvalUpgradeStepPromoted means that evaluation actions were promoted into active actions.
+	EvalUpgradeStepPromoted EvalUpgradeStep = "PROMOTED"
+	// EvalUpgradeStepUpgraded means that the ruleset was upgraded.
+	EvalUpgradeStepUpgraded EvalUpgradeStep = "UPGRADED"
+	// EvalUpgradeStepCompleted means that evaluation was stopped and the upgrade is finished.
+	EvalUpgradeStepCompleted EvalUpgradeStep = "COMPLETED"
+
+	evalStart   = "START"
+	evalStop    = "STOP"
+	evalEnabled = "enabled"
+)
+
+var (
+	// ErrEvalUpgrade is returned when an evaluation upgrade step fails.
+	ErrEvalUpgrade = errors.New("evaluation upgrade")
+
+	// ErrEvalUpgradeStep is returned when an evaluation upgrade step is called out of order.
+	ErrEvalUpgradeStep = errors.New("evaluation upgrade step out of order")
+
+	evalUpgradeStepOrder = map[EvalUpgradeStep]int{
+		EvalUpgradeStepNotStarted: 0,
+		EvalUpgradeStepEvaluating: 1,
+		EvalUpgradeStepCollected:  2,
+		EvalUpgradeStepPromoted:   3,
+		EvalUpgradeStepUpgraded:   4,
+		EvalUpgradeStepCompleted:  5,
+	}
+)
+
+// WithEvalUpgradeStateHandler registers a function called each time the upgrade state changes.
+// It can be used to persist the state so an interrupted upgrade can be resumed.
+func WithEvalUpgradeStateHandler(f func(EvalUpgradeState) error) EvalUpgradeOption {
+	return func(m *EvalUpgradeManager) {
+		m.onStateChange = f
+	}
+}
+
+// Validate validates an EvalUpgradeState.
+func (s EvalUpgradeState) Validate() error {
+	return validation.Errors{
+		"ConfigID": validation.Validate(s.ConfigID, validation.Required),
+		"Version":  validation.Validate(s.Version, validation.Required),
+		"PolicyID": validation.Validate(s.PolicyID, validation.Required),
+		"Step": validation.Validate(s.Step, validation.By(func(interface{}) error {
+			if _, ok := evalUpgradeStepOrder[s.Step]; !ok {
+				return fmt.Errorf("unknown step '%s'", s.Step)
+			}
+			return nil
+		})),
+	}.Filter()
+}
+
+// NewEvalUpgradeManager returns a new EvalUpgradeManager. Pass a zero Step to start a new upgrade
+// or a previously saved state to resume one.
+func NewEvalUpgradeManager(client APPSEC, state EvalUpgradeState, opts ...EvalUpgradeOption) (*EvalUpgradeManager, error) {
+	if err := state.Validate(); err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrStructValidation, err.Error())
+	}
+
+	toSet := func(values []string) map[string]struct{} {
+		set := make(map[string]struct{}, len(values))
+		for _, v := range values {
+			set[v] = struct{}{}
+		}
+		return set
+	}
+	m := &EvalUpgradeManager{
+		client:         client,
+		state:          state,
+		promotedRules:  toSet(state.PromotedRules),
+		promotedGroups: toSet(state.PromotedGroups),
+	}
+
+	for _, opt := range opts {
+		opt(m)
+	}
+	return m, nil
+}
+
+// State returns the current upgrade state.
+func (m *EvalUpgradeManager) State() EvalUpgradeState {
+	return m.state
+}
+
+// Run executes all remaining steps of the upgrade, starting after the last completed step.
+func (m *EvalUpgradeManager) Run(ctx context.Context) error {
+	steps := []struct {
+		done EvalUpgradeStep
+		run  func(context.Context) error
+	}{
+		{EvalUpgradeStepEvaluating, m.Start},
+		{EvalUpgradeStepCollected, m.Collect},
+		{EvalUpgradeStepPromoted, m.Promote},
+		{EvalUpgradeStepUpgraded, m.Upgrade},
+		{EvalUpgradeStepCompleted, m.Stop},
+	}
+
+	for _, step := range steps {
+		if m.reached(step.done) {
+			continue
+		}
+		if err := step.run(ctx); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Start starts evaluation on the policy. It is a no-op if evaluation is already enabled.
+func (m *EvalUpgradeManager) Start(ctx context.Context) error {
+	if err := m.expect(EvalUpgradeStepNotStarted); err != nil {
+		return err
+	}
+
+	eval, err := m.client.GetEval(ctx, GetEvalRequest{
+		ConfigID: m.state.ConfigID,
+		Version:  m.state.Version,
+		PolicyID: m.state.PolicyID,
+	})
+	if err != nil {
+		return fmt.Errorf("%w: get eval: %w", ErrEvalUpgrade, err)
+	}
+	m.state.Mode = eval.Mode
+
+	if eval.Eval != evalEnabled {
+		if _, err := m.client.UpdateEval(ctx, UpdateEvalRequest{
+			ConfigID: m.state.ConfigID,
+			Version:  m.state.Version,
+			PolicyID: m.state.PolicyID,
+			Eval:     evalStart,
+		}); err != nil {
+			return fmt.Errorf("%w: start eval: %w", ErrEvalUpgrade, err)
+		}
+	}
+
+	return m.setStep(EvalUpgradeStepEvaluating)
+}
+
+// Collect collects the evaluation rule and attack group actions and compares them with the current actions.
+// Only rules and attack groups whose evaluation action differs from the current action are recorded.
+//
+// The comparison is based on actions only. Evaluation hits are not reported by the APPSEC API and should be
+// reviewed in the Akamai Control Center before the actions are promoted.
+func (m *EvalUpgradeManager) Collect(ctx context.Context) error {
+	if err := m.expect(EvalUpgradeStepEvaluating); err != nil {
+		return err
+	}
+
+	evalRules, err := m.client.GetEvalRules(ctx, GetEvalRulesRequest{
+		ConfigID: m.state.ConfigID,
+		Version:  m.state.Version,
+		PolicyID: m.state.PolicyID,
+	})
+	if err != nil {
+		return fmt.Errorf("%w: get eval rules: %w", ErrEvalUpgrade, err)
+	}
+	rules, err := m.client.GetRules(ctx, GetRulesRequest{
+		ConfigID: m.state.ConfigID,
+		Version:  m.state.Version,
+		PolicyID: m.state.PolicyID,
+	})
+	if err != nil {
+		return fmt.Errorf("%w: get rules: %w", ErrEvalUpgrade, err)
+	}
+	evalGroups, err := m.client.GetEvalGroups(ctx, GetAttackGroupsRequest{
+		ConfigID: m.state.ConfigID,
+		Version:  m.state.Version,
+		PolicyID: m.state.PolicyID,
+	})
+	if err != nil {
+		return fmt.Errorf("%w: get eval groups: %w", ErrEvalUpgrade, err)
+	}
+	groups, err := m.client.GetAttackGroups(ctx, GetAttackGroupsRequest{
+		ConfigID: m.state.ConfigID,
+		Version:  m.state.Version,
+		PolicyID: m.state.PolicyID,
+	})
+	if err != nil {
+		return fmt.Errorf("%w: get attack groups: %w", ErrEvalUpgrade, err)
+	}
+
+	currentRules := make(map[string]string, len(rules.Rules))
+	for _, r := range rules.Rules {
+		currentRules[strconv.Itoa(r.ID)] = r.Action
+	}
+	var ruleChanges []EvalActionChange
+	for _, r := range evalRules.Rules {
+		id := strconv.Itoa(r.ID)
+		current, ok := currentRules[id]
+		if ok && current == r.Action {
+			continue
+		}
+		change := EvalActionChange{ID: id, CurrentAction: current, EvalAction: r.Action, EvalOnly: !ok}
+		if r.ConditionException != nil {
+			if change.ConditionException, err = json.Marshal(r.ConditionException); err != nil {
+				return fmt.Errorf("%w: marshal condition exception of rule %s: %w", ErrEvalUpgrade, id, err)
+			}
+		}
+		ruleChanges = append(ruleChanges, change)
+	}
+
+	currentGroups := make(map[string]string, len(groups.AttackGroups))
+	for _, g := range groups.AttackGroups {
+		currentGroups[g.Group] = g.Action
+	}
+	var groupChanges []EvalActionChange
+	for _, g := range evalGroups.AttackGroups {
+		current, ok := currentGroups[g.Group]
+		if ok && current == g.Action {
+			continue
+		}
+		change := EvalActionChange{ID: g.Group, CurrentAction: current, EvalAction: g.Action, EvalOnly: !ok}
+		if g.ConditionException != nil {
+			if change.ConditionException, err = json.Marshal(g.ConditionException); err != nil {
+				return fmt.Errorf("%w: marshal condition exception of attack group %s: %w", ErrEvalUpgrade, g.Group, err)
+			}
+		}
+		groupChanges = append(groupChanges, change)
+	}
+
+	sortEvalActionChanges(ruleChanges)
+	sortEvalActionChanges(groupChanges)
+	m.state.RuleChanges = ruleChanges
+	m.state.GroupChanges = groupChanges
+
+	return m.setStep(EvalUpgradeStepCollected)
+}
+
+// Promote applies the collected evaluation actions as active rule and attack group actions. Rules and attack
+// groups which exist only in the evaluation ruleset are promoted by Upgrade once the ruleset is upgraded.
+// Rules and attack groups promoted before an interruption are skipped on resume.
+func (m *EvalUpgradeManager) Promote(ctx context.Context) error {
+	if err := m.expect(EvalUpgradeStepCollected); err != nil {
+		return err
+	}
+	if err := m.promote(ctx, false); err != nil {
+		return err
+	}
+	return m.setStep(EvalUpgradeStepPromoted)
+}
+
+// Upgrade upgrades the policy to the latest ruleset, keeping the WAF mode recorded when evaluation was started,
+// and then promotes the evaluation actions of rules and attack groups which are new in the ruleset.
+func (m *EvalUpgradeManager) Upgrade(ctx context.Context) error {
+	if err := m.expect(EvalUpgradeStepPromoted); err != nil {
+		return err
+	}
+
+	if !m.state.RulesetUpgraded {
+		if _, err := m.client.UpdateRuleUpgrade(ctx, UpdateRuleUpgradeRequest{
+			ConfigID: m.state.ConfigID,
+			Version:  m.state.Version,
+			PolicyID: m.state.PolicyID,
+			Upgrade:  true,
+			Mode:     m.state.Mode,
+		}); err != nil {
+			return fmt.Errorf("%w: upgrade ruleset: %w", ErrEvalUpgrade, err)
+		}
+		m.state.RulesetUpgraded = true
+		if err := m.notify(); err != nil {
+			return err
+		}
+	}
+	if err := m.promote(ctx, true); err != nil {
+		return err
+	}
+
+	return m.setStep(EvalUpgradeStepUpgraded)
+}
+
+// Stop stops evaluation on the policy and completes the upgrade.
+func (m *EvalUpgradeManager) Stop(ctx context.Context) error {
+	if err := m.expect(EvalUpgradeStepUpgraded); err != nil {
+		return err
+	}
+
+	if _, err := m.client.RemoveEval(ctx, RemoveEvalRequest{
+		ConfigID: m.state.ConfigID,
+		Version:  m.state.Version,
+		PolicyID: m.state.PolicyID,
+		Eval:     evalStop,
+	}); err != nil {
+		return fmt.Errorf("%w: stop eval: %w", ErrEvalUpgrade, err)
+	}
+
+	return m.setStep(EvalUpgradeStepCompleted)
+}
+
+// promote promotes the not yet promoted rule and attack group changes which exist only in the evaluation
+// ruleset when evalOnly is set, or in the current ruleset otherwise.
+func (m *EvalUpgradeManager) promote(ctx context.Context, evalOnly bool) error {
+	for _, change := range m.state.RuleChanges {
+		if _, ok := m.promotedRules[change.ID]; ok || change.EvalOnly != evalOnly {
+			continue
+		}
+		ruleID, err := strconv.Atoi(change.ID)
+		if err != nil {
+			return fmt.Errorf("%w: invalid rule ID '%s': %w", ErrEvalUpgrade, change.ID, err)
+		}
+		if _, err := m.client.UpdateRule(ctx, UpdateRuleRequest{
+			ConfigID:       m.state.ConfigID,
+			Version:        m.state.Version,
+			PolicyID:       m.state.PolicyID,
+			RuleID:         ruleID,
+			Action:         change.EvalAction,
+			JsonPayloadRaw: change.ConditionException,
+		}); err != nil {
+			return fmt.Errorf("%w: promote rule %d: %w", ErrEvalUpgrade, ruleID, err)
+		}
+		m.promotedRules[change.ID] = struct{}{}
+		m.state.PromotedRules = append(m.state.PromotedRules, change.ID)
+		if err := m.notify(); err != nil {
+			return err
+		}
+	}
+
+	for _, change := range m.state.GroupChanges {
+		if _, ok := m.promotedGroups[change.ID]; ok || change.EvalOnly != evalOnly {
+			continue
+		}
+		if _, err := m.client.UpdateAttackGroup(ctx, UpdateAttackGroupRequest{
+			ConfigID:       m.state.ConfigID,
+			Version:        m.state.Version,
+			PolicyID:       m.state.PolicyID,
+			Group:          change.ID,
+			Action:         change.EvalAction,
+			JsonPayloadRaw: change.ConditionException,
+		}); err != nil {
+			return fmt.Errorf("%w: promote attack group %s: %w", ErrEvalUpgrade, change.ID, err)
+		}
+		m.promotedGroups[change.ID] = struct{}{}
+		m.state.PromotedGroups = append(m.state.PromotedGroups, change.ID)
+		if err := m.notify(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (m *EvalUpgradeManager) reached(step EvalUpgradeStep) bool {
+	return evalUpgradeStepOrder[m.state.Step] >= evalUpgradeStepOrder[step]
+}
+
+func (m *EvalUpgradeManager) expect(step EvalUpgradeStep) error {
+	if m.state.Step != step {
+		return fmt.Errorf("%w: expected step '%s', current step is '%s'", ErrEvalUpgradeStep, step, m.state.Step)
+	}
+	return nil
+}
+
+func (m *EvalUpgradeManager) setStep(step EvalUpgradeStep) error {
+	m.state.Step = step
+	return m.notify()
+}
+
+func (m *EvalUpgradeManager) notify() error {
+	if m.onStateChange == nil {
+		return nil
+	}
+	if err := m.onStateChange(m.state); err != nil {
+		return fmt.Errorf("%w: save state: %w", ErrEvalUpgrade, err)
+	}
+	return nil
+}
+
+func sortEvalActionChanges(changes []EvalActionChange) {
+	sort.Slice(changes, func(i, j int) bool {
+		return changes[i].ID < changes[j].ID
+	})
+}
@@ -0,0 +1,277 @@
+package appsec
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEvalUpgradeManager_Run(t *testing.T) {
+	var (
+		evalRules  GetEvalRulesResponse
+		rules      GetRulesResponse
+		evalGroups GetAttackGroupsResponse
+		groups     GetAttackGroupsResponse
+	)
+	require.NoError(t, json.Unmarshal([]byte(`{"evalRuleActions":[{"id":950002,"action":"deny"},{"id":950006,"action":"alert"},{"id":950007,"action":"deny"}]}`), &evalRules))
+	require.NoError(t, json.Unmarshal([]byte(`{"ruleActions":[{"id":950002,"action":"alert"},{"id":950006,"action":"alert"}]}`), &rules))
+	require.NoError(t, json.Unmarshal([]byte(`{"attackGroupActions":[{"group":"SQL","action":"deny"},{"group":"XSS","action":"alert"}]}`), &evalGroups))
+	require.NoError(t, json.Unmarshal([]byte(`{"attackGroupActions":[{"group":"SQL","action":"alert"},{"group":"XSS","action":"alert"}]}`), &groups))
+
+	base := EvalUpgradeState{ConfigID: 43253, Version: 15, PolicyID: "AAAA_81230"}
+
+	expectCollect := func(m *Mock) {
+		m.On("GetEvalRules", mock.Anything, GetEvalRulesRequest{ConfigID: 43253, Version: 15, PolicyID: "AAAA_81230"}).Return(&evalRules, nil).Once()
+		m.On("GetRules", mock.Anything, GetRulesRequest{ConfigID: 43253, Version: 15, PolicyID: "AAAA_81230"}).Return(&rules, nil).Once()
+		m.On("GetEvalGroups", mock.Anything, GetAttackGroupsRequest{ConfigID: 43253, Version: 15, PolicyID: "AAAA_81230"}).Return(&evalGroups, nil).Once()
+		m.On("GetAttackGroups", mock.Anything, GetAttackGroupsRequest{ConfigID: 43253, Version: 15, PolicyID: "AAAA_81230"}).Return(&groups, nil).Once()
+	}
+	expectRule := func(m *Mock, id int, action string) *mock.Call {
+		return m.On("UpdateRule", mock.Anything, UpdateRuleRequest{ConfigID: 43253, Version: 15, PolicyID: "AAAA_81230", RuleID: id, Action: action}).
+			Return(&UpdateRuleResponse{Action: action}, nil).Once()
+	}
+	expectUpgrade := func(m *Mock, mode string) *mock.Call {
+		return m.On("UpdateRuleUpgrade", mock.Anything, UpdateRuleUpgradeRequest{ConfigID: 43253, Version: 15, PolicyID: "AAAA_81230", Upgrade: true, Mode: mode}).
+			Return(&UpdateRuleUpgradeResponse{Mode: mode}, nil).Once()
+	}
+	expectStop := func(m *Mock) {
+		m.On("RemoveEval", mock.Anything, RemoveEvalRequest{ConfigID: 43253, Version: 15, PolicyID: "AAAA_81230", Eval: "STOP"}).
+			Return(&RemoveEvalResponse{Eval: "disabled"}, nil).Once()
+	}
+	expectFinish := func(m *Mock, mode string) {
+		expectUpgrade(m, mode)
+		expectStop(m)
+	}
+
+	tests := map[string]struct {
+		state          EvalUpgradeState
+		init           func(*Mock)
+		expectedState  EvalUpgradeState
+		expectedSteps  []EvalUpgradeStep
+		withError      error
+		withErrorCheck func(*testing.T, error)
+	}{
+		"full upgrade": {
+			state: base,
+			init: func(m *Mock) {
+				m.On("GetEval", mock.Anything, GetEvalRequest{ConfigID: 43253, Version: 15, PolicyID: "AAAA_81230"}).
+					Return(&GetEvalResponse{Mode: "KRS", Eval: "disabled"}, nil).Once()
+				m.On("UpdateEval", mock.Anything, UpdateEvalRequest{ConfigID: 43253, Version: 15, PolicyID: "AAAA_81230", Eval: "START"}).
+					Return(&UpdateEvalResponse{Eval: "enabled"}, nil).Once()
+				expectCollect(m)
+				// 950007 exists only in the evaluation ruleset, so it is promoted after the ruleset upgrade
+				mock.InOrder(
+					expectRule(m, 950002, "deny"),
+					m.On("UpdateAttackGroup", mock.Anything, UpdateAttackGroupRequest{ConfigID: 43253, Version: 15, PolicyID: "AAAA_81230", Group: "SQL", Action: "deny"}).
+						Return(&UpdateAttackGroupResponse{Action: "deny"}, nil).Once(),
+					expectUpgrade(m, "KRS"),
+					expectRule(m, 950007, "deny"),
+				)
+				expectStop(m)
+			},
+			expectedState: EvalUpgradeState{
+				ConfigID: 43253,
+				Version:  15,
+				PolicyID: "AAAA_81230",
+				Mode:     "KRS",
+				Step:     EvalUpgradeStepCompleted,
+				RuleChanges: []EvalActionChange{
+					{ID: "950002", CurrentAction: "alert", EvalAction: "deny"},
+					{ID: "950007", EvalAction: "deny", EvalOnly: true},
+				},
+				GroupChanges:    []EvalActionChange{{ID: "SQL", CurrentAction: "alert", EvalAction: "deny"}},
+				PromotedRules:   []string{"950002", "950007"},
+				PromotedGroups:  []string{"SQL"},
+				RulesetUpgraded: true,
+			},
+			expectedSteps: []EvalUpgradeStep{
+				EvalUpgradeStepEvaluating, EvalUpgradeStepCollected, EvalUpgradeStepCollected, EvalUpgradeStepCollected,
+				EvalUpgradeStepPromoted, EvalUpgradeStepPromoted, EvalUpgradeStepPromoted, EvalUpgradeStepUpgraded,
+				EvalUpgradeStepCompleted,
+			},
+		},
+		"evaluation already running": {
+			state: base,
+			init: func(m *Mock) {
+				m.On("GetEval", mock.Anything, GetEvalRequest{ConfigID: 43253, Version: 15, PolicyID: "AAAA_81230"}).
+					Return(&GetEvalResponse{Mode: "AAG", Eval: "enabled"}, nil).Once()
+				m.On("GetEvalRules", mock.Anything, mock.Anything).Return(&GetEvalRulesResponse{}, nil).Once()
+				m.On("GetRules", mock.Anything, mock.Anything).Return(&GetRulesResponse{}, nil).Once()
+				m.On("GetEvalGroups", mock.Anything, mock.Anything).Return(&GetAttackGroupsResponse{}, nil).Once()
+				m.On("GetAttackGroups", mock.Anything, mock.Anything).Return(&GetAttackGroupsResponse{}, nil).Once()
+				expectFinish(m, "AAG")
+			},
+			expectedState: EvalUpgradeState{
+				ConfigID:        43253,
+				Version:         15,
+				PolicyID:        "AAAA_81230",
+				Mode:            "AAG",
+				Step:            EvalUpgradeStepCompleted,
+				RulesetUpgraded: true,
+			},
+			expectedSteps: []EvalUpgradeStep{
+				EvalUpgradeStepEvaluating, EvalUpgradeStepCollected, EvalUpgradeStepPromoted, EvalUpgradeStepPromoted,
+				EvalUpgradeStepUpgraded, EvalUpgradeStepCompleted,
+			},
+		},
+		"resume partially promoted upgrade": {
+			state: EvalUpgradeState{
+				ConfigID: 43253,
+				Version:  15,
+				PolicyID: "AAAA_81230",
+				Mode:     "KRS",
+				Step:     EvalUpgradeStepCollected,
+				RuleChanges: []EvalActionChange{
+					{ID: "950002", CurrentAction: "alert", EvalAction: "deny"},
+					{ID: "950007", EvalAction: "deny"},
+				},
+				PromotedRules: []string{"950002"},
+			},
+			init: func(m *Mock) {
+				expectRule(m, 950007, "deny")
+				expectFinish(m, "KRS")
+			},
+			expectedState: EvalUpgradeState{
+				ConfigID: 43253,
+				Version:  15,
+				PolicyID: "AAAA_81230",
+				Mode:     "KRS",
+				Step:     EvalUpgradeStepCompleted,
+				RuleChanges: []EvalActionChange{
+					{ID: "950002", CurrentAction: "alert", EvalAction: "deny"},
+					{ID: "950007", EvalAction: "deny"},
+				},
+				PromotedRules:   []string{"950002", "950007"},
+				RulesetUpgraded: true,
+			},
+			expectedSteps: []EvalUpgradeStep{
+				EvalUpgradeStepCollected, EvalUpgradeStepPromoted, EvalUpgradeStepPromoted, EvalUpgradeStepUpgraded,
+				EvalUpgradeStepCompleted,
+			},
+		},
+		"resume after ruleset upgrade": {
+			state: EvalUpgradeState{
+				ConfigID:        43253,
+				Version:         15,
+				PolicyID:        "AAAA_81230",
+				Mode:            "KRS",
+				Step:            EvalUpgradeStepPromoted,
+				RuleChanges:     []EvalActionChange{{ID: "950007", EvalAction: "deny", EvalOnly: true}},
+				GroupChanges:    []EvalActionChange{{ID: "CMD", EvalAction: "deny", EvalOnly: true}},
+				RulesetUpgraded: true,
+			},
+			init: func(m *Mock) {
+				expectRule(m, 950007, "deny")
+				m.On("UpdateAttackGroup", mock.Anything, UpdateAttackGroupRequest{ConfigID: 43253, Version: 15, PolicyID: "AAAA_81230", Group: "CMD", Action: "deny"}).
+					Return(&UpdateAttackGroupResponse{Action: "deny"}, nil).Once()
+				expectStop(m)
+			},
+			expectedState: EvalUpgradeState{
+				ConfigID:        43253,
+				Version:         15,
+				PolicyID:        "AAAA_81230",
+				Mode:            "KRS",
+				Step:            EvalUpgradeStepCompleted,
+				RuleChanges:     []EvalActionChange{{ID: "950007", EvalAction: "deny", EvalOnly: true}},
+				GroupChanges:    []EvalActionChange{{ID: "CMD", EvalAction: "deny", EvalOnly: true}},
+				PromotedRules:   []string{"950007"},
+				PromotedGroups:  []string{"CMD"},
+				RulesetUpgraded: true,
+			},
+			expectedSteps: []EvalUpgradeStep{
+				EvalUpgradeStepPromoted, EvalUpgradeStepPromoted, EvalUpgradeStepUpgraded, EvalUpgradeStepCompleted,
+			},
+		},
+		"promotion failure keeps progress": {
+			state: EvalUpgradeState{
+				ConfigID:    43253,
+				Version:     15,
+				PolicyID:    "AAAA_81230",
+				Step:        EvalUpgradeStepCollected,
+				RuleChanges: []EvalActionChange{{ID: "950002", EvalAction: "deny"}, {ID: "950007", EvalAction: "deny"}},
+			},
+			init: func(m *Mock) {
+				expectRule(m, 950002, "deny")
+				m.On("UpdateRule", mock.Anything, UpdateRuleRequest{ConfigID: 43253, Version: 15, PolicyID: "AAAA_81230", RuleID: 950007, Action: "deny"}).
+					Return(nil, &Error{StatusCode: 500, Title: "Internal Server Error"}).Once()
+			},
+			expectedState: EvalUpgradeState{
+				ConfigID:      43253,
+				Version:       15,
+				PolicyID:      "AAAA_81230",
+				Step:          EvalUpgradeStepCollected,
+				RuleChanges:   []EvalActionChange{{ID: "950002", EvalAction: "deny"}, {ID: "950007", EvalAction: "deny"}},
+				PromotedRules: []string{"950002"},
+			},
+			expectedSteps: []EvalUpgradeStep{EvalUpgradeStepCollected},
+			withError:     ErrEvalUpgrade,
+		},
+		"already completed": {
+			state:         EvalUpgradeState{ConfigID: 43253, Version: 15, PolicyID: "AAAA_81230", Step: EvalUpgradeStepCompleted},
+			init:          func(*Mock) {},
+			expectedState: EvalUpgradeState{ConfigID: 43253, Version: 15, PolicyID: "AAAA_81230", Step: EvalUpgradeStepCompleted},
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			client := &Mock{}
+			test.init(client)
+
+			var steps []EvalUpgradeStep
+			manager, err := NewEvalUpgradeManager(client, test.state, WithEvalUpgradeStateHandler(func(s EvalUpgradeState) error {
+				steps = append(steps, s.Step)
+				return nil
+			}))
+			require.NoError(t, err)
+
+			err = manager.Run(context.Background())
+			if test.withError != nil {
+				assert.True(t, errors.Is(err, test.withError), "want: %s; got: %s", test.withError, err)
+			} else {
+				require.NoError(t, err)
+			}
+			assert.Equal(t, test.expectedState, manager.State())
+			assert.Equal(t, test.expectedSteps, steps)
+			client.AssertExpectations(t)
+		})
+	}
+}
+
+func TestEvalUpgradeManager_StepOrder(t *testing.T) {
+	manager, err := NewEvalUpgradeManager(&Mock{}, EvalUpgradeState{ConfigID: 43253, Version: 15, PolicyID: "AAAA_81230"})
+	require.NoError(t, err)
+
+	err = manager.Promote(context.Background())
+	assert.True(t, errors.Is(err, ErrEvalUpgradeStep), "want: %s; got: %s", ErrEvalUpgradeStep, err)
+}
+
+func TestNewEvalUpgradeManager_Validation(t *testing.T) {
+	tests := map[string]struct {
+		state     EvalUpgradeState
+		withError string
+	}{
+		"missing required fields": {
+			state:     EvalUpgradeState{},
+			withError: "ConfigID: cannot be blank; PolicyID: cannot be blank; Version: cannot be blank",
+		},
+		"unknown step": {
+			state:     EvalUpgradeState{ConfigID: 43253, Version: 15, PolicyID: "AAAA_81230", Step: "FOO"},
+			withError: "Step: unknown step 'FOO'",
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			_, err := NewEvalUpgradeManager(&Mock{}, test.state)
+			require.Error(t, err)
+			assert.True(t, errors.Is(err, ErrStructValidation))
+			assert.Contains(t, err.Error(), test.withError)
+		})
+	}
+}
@@ -0,0 +1,425 @@
+package botman
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+
+	validation "github.com/go-ozzo/ozzo-validation/v4"
+)
+
+type (
+	// Bundle holds the BotMan settings of a security configuration version in a single document.
+	// Objects with IDs are read through TypedClient, so their IDs and references can be rewritten on import,
+	// while members without a typed field are kept in AdditionalProperties. Custom code and challenge
+	// injection rules are free-form settings without IDs and are kept as returned by the API.
+	Bundle struct {
+		CustomBotCategories            []CustomBotCategoryDetails `json:"customBotCategories,omitempty"`
+		CustomBotCategorySequence      []string                   `json:"customBotCategorySequence,omitempty"`
+		CustomBotCategoryItemSequences map[string][]string        `json:"customBotCategoryItemSequences,omitempty"`
+		CustomDefinedBots              []CustomDefinedBotDetails  `json:"customDefinedBots,omitempty"`
+		CustomClients                  []CustomClientDetails      `json:"customClients,omitempty"`
+		CustomClientSequence           []string                   `json:"customClientSequence,omitempty"`
+		CustomCode                     map[string]interface{}     `json:"customCode,omitempty"`
+		ChallengeInjectionRules        map[string]interface{}     `json:"challengeInjectionRules,omitempty"`
+		SecurityPolicies               map[string]BundlePolicy    `json:"securityPolicies,omitempty"`
+	}
+
+	// BundlePolicy holds the BotMan settings of a single security policy.
+	// JavascriptInjection is a free-form setting and is kept as returned by the API.
+	BundlePolicy struct {
+		ContentProtectionRules        []ContentProtectionRuleDetails `json:"contentProtectionRules,omitempty"`
+		ContentProtectionRuleSequence []string                       `json:"contentProtectionRuleSequence,omitempty"`
+		JavascriptInjection           map[string]interface{}         `json:"javascriptInjection,omitempty"`
+	}
+
+	// ExportBundleRequest is used to export the BotMan settings of a configuration version.
+	ExportBundleRequest struct {
+		ConfigID int64
+		Version  int64
+		// SecurityPolicyIDs lists the security policies whose policy level settings are exported.
+		SecurityPolicyIDs []string
+	}
+
+	// ImportBundleRequest is used to import a Bundle into a configuration version.
+	ImportBundleRequest struct {
+		ConfigID int64
+		Version  int64
+		Bundle   Bundle
+		// SecurityPolicyIDs maps security policy IDs from the bundle to policy IDs in the target configuration.
+		// Policies missing from the map are imported under the same ID.
+		SecurityPolicyIDs map[string]string
+	}
+
+	// ImportBundleResult maps IDs of objects in the bundle to IDs of objects created by the import.
+	ImportBundleResult struct {
+		CategoryIDs              map[string]string `json:"categoryIds"`
+		BotIDs                   map[string]string `json:"botIds"`
+		CustomClientIDs          map[string]string `json:"customClientIds"`
+		ContentProtectionRuleIDs map[string]string `json:"contentProtectionRuleIds"`
+	}
+)
+
+var (
+	// ErrBundleExport is returned when exporting a Bundle fails.
+	ErrBundleExport = errors.New("bundle export")
+
+	// ErrBundleImport is returned when importing a Bundle fails.
+	ErrBundleImport = errors.New("bundle import")
+)
+
+// Validate validates an ExportBundleRequest.
+func (v ExportBundleRequest) Validate() error {
+	return validation.Errors{
+		"ConfigID": validation.Validate(v.ConfigID, validation.Required),
+		"Version":  validation.Validate(v.Version, validation.Required),
+	}.Filter()
+}
+
+// Validate validates an ImportBundleRequest.
+func (v ImportBundleRequest) Validate() error {
+	return validation.Errors{
+		"ConfigID": validation.Validate(v.ConfigID, validation.Required),
+		"Version":  validation.Validate(v.Version, validation.Required),
+	}.Filter()
+}
+
+// ExportBundle reads all BotMan settings of a configuration version into a Bundle.
+func ExportBundle(ctx context.Context, client BotMan, params ExportBundleRequest) (*Bundle, error) {
+	if err := params.Validate(); err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrStructValidation, err.Error())
+	}
+
+	var (
+		bundle Bundle
+		err    error
+		typed  = NewTypedClient(client)
+	)
+
+	if bundle.CustomBotCategories, err = typed.ListCustomBotCategoryDetails(ctx, GetCustomBotCategoryListRequest{ConfigID: params.ConfigID, Version: params.Version}); err != nil {
+		return nil, fmt.Errorf("%w: custom bot categories: %w", ErrBundleExport, err)
+	}
+
+	categorySequence, err := client.GetCustomBotCategorySequence(ctx, GetCustomBotCategorySequenceRequest{ConfigID: params.ConfigID, Version: params.Version})
+	if err != nil {
+		return nil, fmt.Errorf("%w: custom bot category sequence: %w", ErrBundleExport, err)
+	}
+	bundle.CustomBotCategorySequence = categorySequence.Sequence
+
+	for _, category := range bundle.CustomBotCategories {
+		categoryID := category.CategoryID
+		if categoryID == "" {
+			continue
+		}
+		itemSequence, err := client.GetCustomBotCategoryItemSequence(ctx, GetCustomBotCategoryItemSequenceRequest{
+			ConfigID:   params.ConfigID,
+			Version:    params.Version,
+			CategoryID: categoryID,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("%w: item sequence of custom bot category %s: %w", ErrBundleExport, categoryID, err)
+		}
+		if len(itemSequence.Sequence) == 0 {
+			continue
+		}
+		if bundle.CustomBotCategoryItemSequences == nil {
+			bundle.CustomBotCategoryItemSequences = make(map[string][]string)
+		}
+		bundle.CustomBotCategoryItemSequences[categoryID] = itemSequence.Sequence
+	}
+
+	if bundle.CustomDefinedBots, err = typed.ListCustomDefinedBotDetails(ctx, GetCustomDefinedBotListRequest{ConfigID: params.ConfigID, Version: params.Version}); err != nil {
+		return nil, fmt.Errorf("%w: custom defined bots: %w", ErrBundleExport, err)
+	}
+
+	if bundle.CustomClients, err = typed.ListCustomClientDetails(ctx, GetCustomClientListRequest{ConfigID: params.ConfigID, Version: params.Version}); err != nil {
+		return nil, fmt.Errorf("%w: custom clients: %w", ErrBundleExport, err)
+	}
+
+	clientSequence, err := client.GetCustomClientSequence(ctx, GetCustomClientSequenceRequest{ConfigID: params.ConfigID, Version: params.Version})
+	if err != nil {
+		return nil, fmt.Errorf("%w: custom client sequence: %w", ErrBundleExport, err)
+	}
+	bundle.CustomClientSequence = clientSequence.Sequence
+
+	if bundle.CustomCode, err = client.GetCustomCode(ctx, GetCustomCodeRequest{ConfigID: params.ConfigID, Version: params.Version}); err != nil {
+		return nil, fmt.Errorf("%w: custom code: %w", ErrBundleExport, err)
+	}
+
+	if bundle.ChallengeInjectionRules, err = client.GetChallengeInjectionRules(ctx, GetChallengeInjectionRulesRequest{ConfigID: params.ConfigID, Version: params.Version}); err != nil {
+		return nil, fmt.Errorf("%w: challenge injection rules: %w", ErrBundleExport, err)
+	}
+
+	for _, policyID := range params.SecurityPolicyIDs {
+		policy, err := exportBundlePolicy(ctx, typed, params.ConfigID, params.Version, policyID)
+		if err != nil {
+			return nil, err
+		}
+		if bundle.SecurityPolicies == nil {
+			bundle.SecurityPolicies = make(map[string]BundlePolicy)
+		}
+		bundle.SecurityPolicies[policyID] = *policy
+	}
+
+	return &bundle, nil
+}
+
+func exportBundlePolicy(ctx context.Context, client *TypedClient, configID, version int64, policyID string) (*BundlePolicy, error) {
+	var (
+		policy BundlePolicy
+		err    error
+	)
+
+	if policy.ContentProtectionRules, err = client.ListContentProtectionRuleDetails(ctx, GetContentProtectionRuleListRequest{
+		ConfigID:         configID,
+		Version:          version,
+		SecurityPolicyID: policyID,
+	}); err != nil {
+		return nil, fmt.Errorf("%w: content protection rules of policy %s: %w", ErrBundleExport, policyID, err)
+	}
+
+	sequence, err := client.GetContentProtectionRuleSequence(ctx, GetContentProtectionRuleSequenceRequest{
+		ConfigID:         configID,
+		Version:          version,
+		SecurityPolicyID: policyID,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("%w: content protection rule sequence of policy %s: %w", ErrBundleExport, policyID, err)
+	}
+	policy.ContentProtectionRuleSequence = sequence.ContentProtectionRuleSequence
+
+	if policy.JavascriptInjection, err = client.GetJavascriptInjection(ctx, GetJavascriptInjectionRequest{
+		ConfigID:         configID,
+		Version:          version,
+		SecurityPolicyID: policyID,
+	}); err != nil {
+		return nil, fmt.Errorf("%w: javascript injection of policy %s: %w", ErrBundleExport, policyID, err)
+	}
+
+	return &policy, nil
+}
+
+// ImportBundle recreates the objects of a Bundle in a configuration version.
+//
+// Custom bot categories, custom defined bots, custom clients and content protection rules are created
+// as new objects. References between them and the category, item, client and content protection rule
+// sequences are rewritten to the IDs assigned by the API. Custom code, challenge injection rules and
+// JavaScript injection settings overwrite the settings of the target configuration.
+func ImportBundle(ctx context.Context, client BotMan, params ImportBundleRequest) (*ImportBundleResult, error) {
+	if err := params.Validate(); err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrStructValidation, err.Error())
+	}
+
+	bundle := params.Bundle
+	typed := NewTypedClient(client)
+	result := ImportBundleResult{
+		CategoryIDs:              make(map[string]string),
+		BotIDs:                   make(map[string]string),
+		CustomClientIDs:          make(map[string]string),
+		ContentProtectionRuleIDs: make(map[string]string),
+	}
+
+	for _, category := range bundle.CustomBotCategories {
+		oldID := category.CategoryID
+		category.CategoryID = ""
+		created, err := typed.CreateCustomBotCategoryDetails(ctx, CreateCustomBotCategoryDetailsRequest{
+			ConfigID: params.ConfigID,
+			Version:  params.Version,
+			Category: category,
+		})
+		if err != nil {
+			return &result, fmt.Errorf("%w: custom bot category %s: %w", ErrBundleImport, oldID, err)
+		}
+		if err := recordID(result.CategoryIDs, oldID, created.CategoryID, "categoryId"); err != nil {
+			return &result, fmt.Errorf("%w: custom bot category %s: %w", ErrBundleImport, oldID, err)
+		}
+	}
+
+	for _, bot := range bundle.CustomDefinedBots {
+		oldID := bot.BotID
+		bot.BotID = ""
+		bot.CategoryID = remapID(result.CategoryIDs, bot.CategoryID)
+		created, err := typed.CreateCustomDefinedBotDetails(ctx, CreateCustomDefinedBotDetailsRequest{
+			ConfigID: params.ConfigID,
+			Version:  params.Version,
+			Bot:      bot,
+		})
+		if err != nil {
+			return &result, fmt.Errorf("%w: custom defined bot %s: %w", ErrBundleImport, oldID, err)
+		}
+		if err := recordID(result.BotIDs, oldID, created.BotID, "botId"); err != nil {
+			return &result, fmt.Errorf("%w: custom defined bot %s: %w", ErrBundleImport, oldID, err)
+		}
+	}
+
+	for _, oldCategoryID := range sortedKeys(bundle.CustomBotCategoryItemSequences) {
+		categoryID := remapID(result.CategoryIDs, oldCategoryID)
+		if _, err := client.UpdateCustomBotCategoryItemSequence(ctx, UpdateCustomBotCategoryItemSequenceRequest{
+			ConfigID:   params.ConfigID,
+			Version:    params.Version,
+			CategoryID: categoryID,
+			Sequence:   UUIDSequence{Sequence: remapIDs(result.BotIDs, bundle.CustomBotCategoryItemSequences[oldCategoryID])},
+		}); err != nil {
+			return &result, fmt.Errorf("%w: item sequence of custom bot category %s: %w", ErrBundleImport, categoryID, err)
+		}
+	}
+
+	if len(bundle.CustomBotCategorySequence) > 0 {
+		if _, err := client.UpdateCustomBotCategorySequence(ctx, UpdateCustomBotCategorySequenceRequest{
+			ConfigID: params.ConfigID,
+			Version:  params.Version,
+			Sequence: remapIDs(result.CategoryIDs, bundle.CustomBotCategorySequence),
+		}); err != nil {
+			return &result, fmt.Errorf("%w: custom bot category sequence: %w", ErrBundleImport, err)
+		}
+	}
+
+	for _, customClient := range bundle.CustomClients {
+		oldID := customClient.CustomClientID
+		customClient.CustomClientID = ""
+		created, err := typed.CreateCustomClientDetails(ctx, CreateCustomClientDetailsRequest{
+			ConfigID:     params.ConfigID,
+			Version:      params.Version,
+			CustomClient: customClient,
+		})
+		if err != nil {
+			return &result, fmt.Errorf("%w: custom client %s: %w", ErrBundleImport, oldID, err)
+		}
+		if err := recordID(result.CustomClientIDs, oldID, created.CustomClientID, "customClientId"); err != nil {
+			return &result, fmt.Errorf("%w: custom client %s: %w", ErrBundleImport, oldID, err)
+		}
+	}
+
+	if len(bundle.CustomClientSequence) > 0 {
+		if _, err := client.UpdateCustomClientSequence(ctx, UpdateCustomClientSequenceRequest{
+			ConfigID: params.ConfigID,
+			Version:  params.Version,
+			Sequence: remapIDs(result.CustomClientIDs, bundle.CustomClientSequence),
+		}); err != nil {
+			return &result, fmt.Errorf("%w: custom client sequence: %w", ErrBundleImport, err)
+		}
+	}
+
+	if bundle.CustomCode != nil {
+		payload, err := json.Marshal(bundle.CustomCode)
+		if err != nil {
+			return &result, fmt.Errorf("%w: custom code: %w", ErrBundleImport, err)
+		}
+		if _, err := client.UpdateCustomCode(ctx, UpdateCustomCodeRequest{
+			ConfigID:    params.ConfigID,
+			Version:     params.Version,
+			JsonPayload: payload,
+		}); err != nil {
+			return &result, fmt.Errorf("%w: custom code: %w", ErrBundleImport, err)
+		}
+	}
+
+	if bundle.ChallengeInjectionRules != nil {
+		payload, err := json.Marshal(bundle.ChallengeInjectionRules)
+		if err != nil {
+			return &result, fmt.Errorf("%w: challenge injection rules: %w", ErrBundleImport, err)
+		}
+		if _, err := client.UpdateChallengeInjectionRules(ctx, UpdateChallengeInjectionRulesRequest{
+			ConfigID:    params.ConfigID,
+			Version:     params.Version,
+			JsonPayload: payload,
+		}); err != nil {
+			return &result, fmt.Errorf("%w: challenge injection rules: %w", ErrBundleImport, err)
+		}
+	}
+
+	for _, oldPolicyID := range sortedKeys(bundle.SecurityPolicies) {
+		policyID := remapID(params.SecurityPolicyIDs, oldPolicyID)
+		if err := importBundlePolicy(ctx, typed, params.ConfigID, params.Version, policyID, bundle.SecurityPolicies[oldPolicyID], &result); err != nil {
+			return &result, err
+		}
+	}
+
+	return &result, nil
+}
+
+func importBundlePolicy(ctx context.Context, client *TypedClient, configID, version int64, policyID string, policy BundlePolicy, result *ImportBundleResult) error {
+	for _, rule := range policy.ContentProtectionRules {
+		oldID := rule.ContentProtectionRuleID
+		rule.ContentProtectionRuleID = ""
+		created, err := client.CreateContentProtectionRuleDetails(ctx, CreateContentProtectionRuleDetailsRequest{
+			ConfigID:         configID,
+			Version:          version,
+			SecurityPolicyID: policyID,
+			Rule:             rule,
+		})
+		if err != nil {
+			return fmt.Errorf("%w: content protection rule %s of policy %s: %w", ErrBundleImport, oldID, policyID, err)
+		}
+		if err := recordID(result.ContentProtectionRuleIDs, oldID, created.ContentProtectionRuleID, "contentProtectionRuleId"); err != nil {
+			return fmt.Errorf("%w: content protection rule %s of policy %s: %w", ErrBundleImport, oldID, policyID, err)
+		}
+	}
+
+	if len(policy.ContentProtectionRuleSequence) > 0 {
+		if _, err := client.UpdateContentProtectionRuleSequence(ctx, UpdateContentProtectionRuleSequenceRequest{
+			ConfigID:         configID,
+			Version:          version,
+			SecurityPolicyID: policyID,
+			ContentProtectionRuleSequence: ContentProtectionRuleUUIDSequence{
+				ContentProtectionRuleSequence: remapIDs(result.ContentProtectionRuleIDs, policy.ContentProtectionRuleSequence),
+			},
+		}); err != nil {
+			return fmt.Errorf("%w: content protection rule sequence of policy %s: %w", ErrBundleImport, policyID, err)
+		}
+	}
+
+	if policy.JavascriptInjection != nil {
+		payload, err := json.Marshal(policy.JavascriptInjection)
+		if err != nil {
+			return fmt.Errorf("%w: javascript injection of policy %s: %w", ErrBundleImport, policyID, err)
+		}
+		if _, err := client.UpdateJavascriptInjection(ctx, UpdateJavascriptInjectionRequest{
+			ConfigID:         configID,
+			Version:          version,
+			SecurityPolicyID: policyID,
+			JsonPayload:      payload,
+		}); err != nil {
+			return fmt.Errorf("%w: javascript injection of policy %s: %w", ErrBundleImport, policyID, err)
+		}
+	}
+
+	return nil
+}
+
+// recordID stores the ID assigned by the API to the created object under the old ID.
+func recordID(ids map[string]string, oldID, newID, member string) error {
+	if newID == "" {
+		return fmt.Errorf("response does not contain '%s'", member)
+	}
+	if oldID != "" {
+		ids[oldID] = newID
+	}
+	return nil
+}
+
+func remapID(ids map[string]string, id string) string {
+	if newID, ok := ids[id]; ok {
+		return newID
+	}
+	return id
+}
+
+func remapIDs(ids map[string]string, values []string) []string {
+	result := make([]string, 0, len(values))
+	for _, id := range values {
+		result = append(result, remapID(ids, id))
+	}
+	return result
+}
+
+func sortedKeys[T any](m map[string]T) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
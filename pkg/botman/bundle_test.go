@@ -0,0 +1,190 @@
+package botman
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExportBundle(t *testing.T) {
+	client := &Mock{}
+	client.On("GetCustomBotCategoryList", mock.Anything, GetCustomBotCategoryListRequest{ConfigID: 43253, Version: 15}).
+		Return(&GetCustomBotCategoryListResponse{Categories: []map[string]interface{}{
+			{"categoryId": "cat-1", "categoryName": "Partners"},
+			{"categoryId": "cat-2", "categoryName": "Monitoring"},
+		}}, nil)
+	client.On("GetCustomBotCategorySequence", mock.Anything, GetCustomBotCategorySequenceRequest{ConfigID: 43253, Version: 15}).
+		Return(&CustomBotCategorySequenceResponse{Sequence: []string{"cat-2", "cat-1"}}, nil)
+	client.On("GetCustomBotCategoryItemSequence", mock.Anything, GetCustomBotCategoryItemSequenceRequest{ConfigID: 43253, Version: 15, CategoryID: "cat-1"}).
+		Return(&GetCustomBotCategoryItemSequenceResponse{Sequence: []string{"bot-1"}}, nil)
+	client.On("GetCustomBotCategoryItemSequence", mock.Anything, GetCustomBotCategoryItemSequenceRequest{ConfigID: 43253, Version: 15, CategoryID: "cat-2"}).
+		Return(&GetCustomBotCategoryItemSequenceResponse{}, nil)
+	client.On("GetCustomDefinedBotList", mock.Anything, GetCustomDefinedBotListRequest{ConfigID: 43253, Version: 15}).
+		Return(&GetCustomDefinedBotListResponse{Bots: []map[string]interface{}{{"botId": "bot-1", "botName": "Partner bot", "categoryId": "cat-1"}}}, nil)
+	client.On("GetCustomClientList", mock.Anything, GetCustomClientListRequest{ConfigID: 43253, Version: 15}).
+		Return(&GetCustomClientListResponse{CustomClients: []map[string]interface{}{{"customClientId": "cc-1", "customClientName": "App"}}}, nil)
+	client.On("GetCustomClientSequence", mock.Anything, GetCustomClientSequenceRequest{ConfigID: 43253, Version: 15}).
+		Return(&CustomClientSequenceResponse{Sequence: []string{"cc-1"}}, nil)
+	client.On("GetCustomCode", mock.Anything, GetCustomCodeRequest{ConfigID: 43253, Version: 15}).
+		Return(map[string]interface{}{"code": "x"}, nil)
+	client.On("GetChallengeInjectionRules", mock.Anything, GetChallengeInjectionRulesRequest{ConfigID: 43253, Version: 15}).
+		Return(map[string]interface{}{"injectJavaScript": true}, nil)
+	client.On("GetContentProtectionRuleList", mock.Anything, GetContentProtectionRuleListRequest{ConfigID: 43253, Version: 15, SecurityPolicyID: "AAAA_81230"}).
+		Return(&GetContentProtectionRuleListResponse{ContentProtectionRules: []map[string]interface{}{
+			{"contentProtectionRuleId": "cpr-1", "contentProtectionRuleName": "Checkout", "protectedOperations": []interface{}{"op-1"}},
+		}}, nil)
+	client.On("GetContentProtectionRuleSequence", mock.Anything, GetContentProtectionRuleSequenceRequest{ConfigID: 43253, Version: 15, SecurityPolicyID: "AAAA_81230"}).
+		Return(&GetContentProtectionRuleSequenceResponse{ContentProtectionRuleSequence: []string{"cpr-1"}}, nil)
+	client.On("GetJavascriptInjection", mock.Anything, GetJavascriptInjectionRequest{ConfigID: 43253, Version: 15, SecurityPolicyID: "AAAA_81230"}).
+		Return(map[string]interface{}{"injectJavaScript": "AROUND_PROTECTED_OPERATIONS"}, nil)
+
+	bundle, err := ExportBundle(context.Background(), client, ExportBundleRequest{
+		ConfigID:          43253,
+		Version:           15,
+		SecurityPolicyIDs: []string{"AAAA_81230"},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, &Bundle{
+		CustomBotCategories: []CustomBotCategoryDetails{
+			{CategoryID: "cat-1", CategoryName: "Partners"},
+			{CategoryID: "cat-2", CategoryName: "Monitoring"},
+		},
+		CustomBotCategorySequence:      []string{"cat-2", "cat-1"},
+		CustomBotCategoryItemSequences: map[string][]string{"cat-1": {"bot-1"}},
+		CustomDefinedBots:              []CustomDefinedBotDetails{{BotID: "bot-1", BotName: "Partner bot", CategoryID: "cat-1"}},
+		CustomClients:                  []CustomClientDetails{{CustomClientID: "cc-1", CustomClientName: "App"}},
+		CustomClientSequence:           []string{"cc-1"},
+		CustomCode:                     map[string]interface{}{"code": "x"},
+		ChallengeInjectionRules:        map[string]interface{}{"injectJavaScript": true},
+		SecurityPolicies: map[string]BundlePolicy{
+			"AAAA_81230": {
+				ContentProtectionRules: []ContentProtectionRuleDetails{{
+					ContentProtectionRuleID:   "cpr-1",
+					ContentProtectionRuleName: "Checkout",
+					AdditionalProperties:      AdditionalProperties{"protectedOperations": json.RawMessage(`["op-1"]`)},
+				}},
+				ContentProtectionRuleSequence: []string{"cpr-1"},
+				JavascriptInjection:           map[string]interface{}{"injectJavaScript": "AROUND_PROTECTED_OPERATIONS"},
+			},
+		},
+	}, bundle)
+	client.AssertExpectations(t)
+}
+
+func TestExportBundle_Error(t *testing.T) {
+	client := &Mock{}
+	client.On("GetCustomBotCategoryList", mock.Anything, mock.Anything).Return(nil, &Error{StatusCode: 500, Title: "Internal Server Error"})
+
+	_, err := ExportBundle(context.Background(), client, ExportBundleRequest{ConfigID: 43253, Version: 15})
+	assert.True(t, errors.Is(err, ErrBundleExport), "want: %s; got: %s", ErrBundleExport, err)
+	client.AssertExpectations(t)
+}
+
+func TestImportBundle(t *testing.T) {
+	bundle := Bundle{
+		CustomBotCategories: []CustomBotCategoryDetails{
+			{CategoryID: "cat-1", CategoryName: "Partners"},
+			{CategoryID: "cat-2", CategoryName: "Monitoring"},
+		},
+		CustomBotCategorySequence:      []string{"cat-2", "cat-1"},
+		CustomBotCategoryItemSequences: map[string][]string{"cat-1": {"bot-1"}},
+		CustomDefinedBots:              []CustomDefinedBotDetails{{BotID: "bot-1", BotName: "Partner bot", CategoryID: "cat-1"}},
+		CustomClients:                  []CustomClientDetails{{CustomClientID: "cc-1", CustomClientName: "App"}},
+		CustomClientSequence:           []string{"cc-1"},
+		SecurityPolicies: map[string]BundlePolicy{
+			"AAAA_81230": {
+				ContentProtectionRules: []ContentProtectionRuleDetails{{
+					ContentProtectionRuleID:   "cpr-1",
+					ContentProtectionRuleName: "Checkout",
+					AdditionalProperties:      AdditionalProperties{"protectedOperations": json.RawMessage(`["op-1"]`)},
+				}},
+				ContentProtectionRuleSequence: []string{"cpr-1"},
+				JavascriptInjection:           map[string]interface{}{"injectJavaScript": "NEVER"},
+			},
+		},
+	}
+
+	tests := map[string]struct {
+		init           func(*Mock)
+		expectedResult *ImportBundleResult
+		withError      error
+	}{
+		"import with ID remapping": {
+			init: func(m *Mock) {
+				m.On("CreateCustomBotCategory", mock.Anything, CreateCustomBotCategoryRequest{ConfigID: 1111, Version: 2, JsonPayload: json.RawMessage(`{"categoryName":"Partners"}`)}).
+					Return(map[string]interface{}{"categoryId": "new-cat-1", "categoryName": "Partners"}, nil).Once()
+				m.On("CreateCustomBotCategory", mock.Anything, CreateCustomBotCategoryRequest{ConfigID: 1111, Version: 2, JsonPayload: json.RawMessage(`{"categoryName":"Monitoring"}`)}).
+					Return(map[string]interface{}{"categoryId": "new-cat-2", "categoryName": "Monitoring"}, nil).Once()
+				m.On("CreateCustomDefinedBot", mock.Anything, CreateCustomDefinedBotRequest{ConfigID: 1111, Version: 2, JsonPayload: json.RawMessage(`{"botName":"Partner bot","categoryId":"new-cat-1"}`)}).
+					Return(map[string]interface{}{"botId": "new-bot-1"}, nil).Once()
+				m.On("UpdateCustomBotCategoryItemSequence", mock.Anything, UpdateCustomBotCategoryItemSequenceRequest{ConfigID: 1111, Version: 2, CategoryID: "new-cat-1", Sequence: UUIDSequence{Sequence: []string{"new-bot-1"}}}).
+					Return(&UpdateCustomBotCategoryItemSequenceResponse{}, nil).Once()
+				m.On("UpdateCustomBotCategorySequence", mock.Anything, UpdateCustomBotCategorySequenceRequest{ConfigID: 1111, Version: 2, Sequence: []string{"new-cat-2", "new-cat-1"}}).
+					Return(&CustomBotCategorySequenceResponse{}, nil).Once()
+				m.On("CreateCustomClient", mock.Anything, CreateCustomClientRequest{ConfigID: 1111, Version: 2, JsonPayload: json.RawMessage(`{"customClientName":"App"}`)}).
+					Return(map[string]interface{}{"customClientId": "new-cc-1"}, nil).Once()
+				m.On("UpdateCustomClientSequence", mock.Anything, UpdateCustomClientSequenceRequest{ConfigID: 1111, Version: 2, Sequence: []string{"new-cc-1"}}).
+					Return(&CustomClientSequenceResponse{}, nil).Once()
+				m.On("CreateContentProtectionRule", mock.Anything, CreateContentProtectionRuleRequest{ConfigID: 1111, Version: 2, SecurityPolicyID: "BBBB_1", JsonPayload: json.RawMessage(`{"contentProtectionRuleName":"Checkout","protectedOperations":["op-1"]}`)}).
+					Return(map[string]interface{}{"contentProtectionRuleId": "new-cpr-1"}, nil).Once()
+				m.On("UpdateContentProtectionRuleSequence", mock.Anything, UpdateContentProtectionRuleSequenceRequest{ConfigID: 1111, Version: 2, SecurityPolicyID: "BBBB_1",
+					ContentProtectionRuleSequence: ContentProtectionRuleUUIDSequence{ContentProtectionRuleSequence: []string{"new-cpr-1"}}}).
+					Return(&UpdateContentProtectionRuleSequenceResponse{}, nil).Once()
+				m.On("UpdateJavascriptInjection", mock.Anything, UpdateJavascriptInjectionRequest{ConfigID: 1111, Version: 2, SecurityPolicyID: "BBBB_1", JsonPayload: json.RawMessage(`{"injectJavaScript":"NEVER"}`)}).
+					Return(map[string]interface{}{}, nil).Once()
+			},
+			expectedResult: &ImportBundleResult{
+				CategoryIDs:              map[string]string{"cat-1": "new-cat-1", "cat-2": "new-cat-2"},
+				BotIDs:                   map[string]string{"bot-1": "new-bot-1"},
+				CustomClientIDs:          map[string]string{"cc-1": "new-cc-1"},
+				ContentProtectionRuleIDs: map[string]string{"cpr-1": "new-cpr-1"},
+			},
+		},
+		"create failure returns partial result": {
+			init: func(m *Mock) {
+				m.On("CreateCustomBotCategory", mock.Anything, CreateCustomBotCategoryRequest{ConfigID: 1111, Version: 2, JsonPayload: json.RawMessage(`{"categoryName":"Partners"}`)}).
+					Return(map[string]interface{}{"categoryId": "new-cat-1"}, nil).Once()
+				m.On("CreateCustomBotCategory", mock.Anything, CreateCustomBotCategoryRequest{ConfigID: 1111, Version: 2, JsonPayload: json.RawMessage(`{"categoryName":"Monitoring"}`)}).
+					Return(nil, &Error{StatusCode: 400, Title: "Bad Request"}).Once()
+			},
+			expectedResult: &ImportBundleResult{
+				CategoryIDs:              map[string]string{"cat-1": "new-cat-1"},
+				BotIDs:                   map[string]string{},
+				CustomClientIDs:          map[string]string{},
+				ContentProtectionRuleIDs: map[string]string{},
+			},
+			withError: ErrBundleImport,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			client := &Mock{}
+			test.init(client)
+
+			result, err := ImportBundle(context.Background(), client, ImportBundleRequest{
+				ConfigID:          1111,
+				Version:           2,
+				Bundle:            bundle,
+				SecurityPolicyIDs: map[string]string{"AAAA_81230": "BBBB_1"},
+			})
+			if test.withError != nil {
+				assert.True(t, errors.Is(err, test.withError), "want: %s; got: %s", test.withError, err)
+			} else {
+				require.NoError(t, err)
+			}
+			assert.Equal(t, test.expectedResult, result)
+			client.AssertExpectations(t)
+		})
+	}
+}
+
+func TestImportBundle_Validation(t *testing.T) {
+	_, err := ImportBundle(context.Background(), &Mock{}, ImportBundleRequest{})
+	assert.True(t, errors.Is(err, ErrStructValidation), "want: %s; got: %s", ErrStructValidation, err)
+}
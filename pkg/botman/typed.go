@@ -0,0 +1,1088 @@
+package botman
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+
+	validation "github.com/go-ozzo/ozzo-validation/v4"
+)
+
+type (
+	// TypedClient wraps a BotMan client and adds methods that exchange typed structures instead of
+	// map[string]interface{} and json.RawMessage. The raw methods of the wrapped client stay available.
+	TypedClient struct {
+		BotMan
+	}
+
+	// AdditionalProperties holds JSON members that are not modeled by a typed structure. They are kept
+	// verbatim, so a structure read from the API can be sent back without losing data.
+	AdditionalProperties map[string]json.RawMessage
+
+	// CustomClientDetails describes a custom client.
+	CustomClientDetails struct {
+		CustomClientID       string               `json:"customClientId,omitempty"`
+		CustomClientName     string               `json:"customClientName,omitempty"`
+		ClientType           string               `json:"clientType,omitempty"`
+		Platforms            []string             `json:"platforms,omitempty"`
+		Hostnames            []string             `json:"hostnames,omitempty"`
+		Notes                string               `json:"notes,omitempty"`
+		AdditionalProperties AdditionalProperties `json:"-"`
+	}
+
+	// CustomBotCategoryDetails describes a custom bot category.
+	CustomBotCategoryDetails struct {
+		CategoryID           string               `json:"categoryId,omitempty"`
+		CategoryName         string               `json:"categoryName,omitempty"`
+		Notes                string               `json:"notes,omitempty"`
+		AdditionalProperties AdditionalProperties `json:"-"`
+	}
+
+	// CustomDefinedBotDetails describes a custom defined bot.
+	CustomDefinedBotDetails struct {
+		BotID                string               `json:"botId,omitempty"`
+		BotName              string               `json:"botName,omitempty"`
+		CategoryID           string               `json:"categoryId,omitempty"`
+		AdditionalProperties AdditionalProperties `json:"-"`
+	}
+
+	// BotDetectionActionDetails describes the action taken for a bot detection in a security policy.
+	BotDetectionActionDetails struct {
+		DetectionID          string               `json:"detectionId,omitempty"`
+		Action               string               `json:"action,omitempty"`
+		AdditionalProperties AdditionalProperties `json:"-"`
+	}
+
+	// ChallengeActionDetails describes a challenge action.
+	ChallengeActionDetails struct {
+		ActionID             string               `json:"actionId,omitempty"`
+		ActionName           string               `json:"actionName,omitempty"`
+		AdditionalProperties AdditionalProperties `json:"-"`
+	}
+
+	// ConditionalActionDetails describes a conditional action.
+	ConditionalActionDetails struct {
+		ActionID               string                  `json:"actionId,omitempty"`
+		ActionName             string                  `json:"actionName,omitempty"`
+		Description            string                  `json:"description,omitempty"`
+		DefaultAction          string                  `json:"defaultAction,omitempty"`
+		ConditionalActionRules []ConditionalActionRule `json:"conditionalActionRules,omitempty"`
+		AdditionalProperties   AdditionalProperties    `json:"-"`
+	}
+
+	// ConditionalActionRule is a single rule of a conditional action.
+	ConditionalActionRule struct {
+		Action               string               `json:"action,omitempty"`
+		Conditions           []json.RawMessage    `json:"conditions,omitempty"`
+		AdditionalProperties AdditionalProperties `json:"-"`
+	}
+
+	// ServeAlternateActionDetails describes a serve alternate action.
+	ServeAlternateActionDetails struct {
+		ActionID             string               `json:"actionId,omitempty"`
+		ActionName           string               `json:"actionName,omitempty"`
+		AdditionalProperties AdditionalProperties `json:"-"`
+	}
+
+	// TransactionalEndpointDetails describes a transactional endpoint of a security policy.
+	TransactionalEndpointDetails struct {
+		OperationID          string               `json:"operationId,omitempty"`
+		APIEndpointID        int64                `json:"apiEndPointId,omitempty"`
+		AdditionalProperties AdditionalProperties `json:"-"`
+	}
+
+	// ContentProtectionRuleDetails describes a content protection rule of a security policy.
+	ContentProtectionRuleDetails struct {
+		ContentProtectionRuleID   string               `json:"contentProtectionRuleId,omitempty"`
+		ContentProtectionRuleName string               `json:"contentProtectionRuleName,omitempty"`
+		AdditionalProperties      AdditionalProperties `json:"-"`
+	}
+
+	// BotManagementSettingDetails describes the bot management settings of a security policy.
+	BotManagementSettingDetails struct {
+		EnableBotManagement                  *bool                `json:"enableBotManagement,omitempty"`
+		AddAkamaiBotHeader                   *bool                `json:"addAkamaiBotHeader,omitempty"`
+		ThirdPartyProxyServiceInUse          *bool                `json:"thirdPartyProxyServiceInUse,omitempty"`
+		RemoveBotManagementCookies           *bool                `json:"removeBotManagementCookies,omitempty"`
+		EnableActiveDetections               *bool                `json:"enableActiveDetections,omitempty"`
+		EnableBrowserValidation              *bool                `json:"enableBrowserValidation,omitempty"`
+		IncludeTransactionalEndpointRequests *bool                `json:"includeTransactionalEndpointRequests,omitempty"`
+		IncludeTransactionalEndpointStatus   *bool                `json:"includeTransactionalEndpointStatus,omitempty"`
+		AdditionalProperties                 AdditionalProperties `json:"-"`
+	}
+
+	// CreateCustomClientDetailsRequest is used to create a custom client from a typed structure.
+	CreateCustomClientDetailsRequest struct {
+		ConfigID     int64
+		Version      int64
+		CustomClient CustomClientDetails
+	}
+
+	// UpdateCustomClientDetailsRequest is used to update a custom client from a typed structure.
+	UpdateCustomClientDetailsRequest struct {
+		ConfigID       int64
+		Version        int64
+		CustomClientID string
+		CustomClient   CustomClientDetails
+	}
+
+	// CreateCustomBotCategoryDetailsRequest is used to create a custom bot category from a typed structure.
+	CreateCustomBotCategoryDetailsRequest struct {
+		ConfigID int64
+		Version  int64
+		Category CustomBotCategoryDetails
+	}
+
+	// UpdateCustomBotCategoryDetailsRequest is used to update a custom bot category from a typed structure.
+	UpdateCustomBotCategoryDetailsRequest struct {
+		ConfigID   int64
+		Version    int64
+		CategoryID string
+		Category   CustomBotCategoryDetails
+	}
+
+	// CreateCustomDefinedBotDetailsRequest is used to create a custom defined bot from a typed structure.
+	CreateCustomDefinedBotDetailsRequest struct {
+		ConfigID int64
+		Version  int64
+		Bot      CustomDefinedBotDetails
+	}
+
+	// UpdateCustomDefinedBotDetailsRequest is used to update a custom defined bot from a typed structure.
+	UpdateCustomDefinedBotDetailsRequest struct {
+		ConfigID int64
+		Version  int64
+		BotID    string
+		Bot      CustomDefinedBotDetails
+	}
+
+	// UpdateBotDetectionActionDetailsRequest is used to update a bot detection action from a typed structure.
+	UpdateBotDetectionActionDetailsRequest struct {
+		ConfigID         int64
+		Version          int64
+		SecurityPolicyID string
+		DetectionID      string
+		Action           BotDetectionActionDetails
+	}
+
+	// CreateChallengeActionDetailsRequest is used to create a challenge action from a typed structure.
+	CreateChallengeActionDetailsRequest struct {
+		ConfigID int64
+		Version  int64
+		Action   ChallengeActionDetails
+	}
+
+	// UpdateChallengeActionDetailsRequest is used to update a challenge action from a typed structure.
+	UpdateChallengeActionDetailsRequest struct {
+		ConfigID int64
+		Version  int64
+		ActionID string
+		Action   ChallengeActionDetails
+	}
+
+	// CreateConditionalActionDetailsRequest is used to create a conditional action from a typed structure.
+	CreateConditionalActionDetailsRequest struct {
+		ConfigID int64
+		Version  int64
+		Action   ConditionalActionDetails
+	}
+
+	// UpdateConditionalActionDetailsRequest is used to update a conditional action from a typed structure.
+	UpdateConditionalActionDetailsRequest struct {
+		ConfigID int64
+		Version  int64
+		ActionID string
+		Action   ConditionalActionDetails
+	}
+
+	// CreateServeAlternateActionDetailsRequest is used to create a serve alternate action from a typed structure.
+	CreateServeAlternateActionDetailsRequest struct {
+		ConfigID int64
+		Version  int64
+		Action   ServeAlternateActionDetails
+	}
+
+	// UpdateServeAlternateActionDetailsRequest is used to update a serve alternate action from a typed structure.
+	UpdateServeAlternateActionDetailsRequest struct {
+		ConfigID int64
+		Version  int64
+		ActionID string
+		Action   ServeAlternateActionDetails
+	}
+
+	// CreateTransactionalEndpointDetailsRequest is used to create a transactional endpoint from a typed structure.
+	CreateTransactionalEndpointDetailsRequest struct {
+		ConfigID         int64
+		Version          int64
+		SecurityPolicyID string
+		Endpoint         TransactionalEndpointDetails
+	}
+
+	// UpdateTransactionalEndpointDetailsRequest is used to update a transactional endpoint from a typed structure.
+	UpdateTransactionalEndpointDetailsRequest struct {
+		ConfigID         int64
+		Version          int64
+		SecurityPolicyID string
+		OperationID      string
+		Endpoint         TransactionalEndpointDetails
+	}
+
+	// CreateContentProtectionRuleDetailsRequest is used to create a content protection rule from a typed structure.
+	CreateContentProtectionRuleDetailsRequest struct {
+		ConfigID         int64
+		Version          int64
+		SecurityPolicyID string
+		Rule             ContentProtectionRuleDetails
+	}
+
+	// UpdateContentProtectionRuleDetailsRequest is used to update a content protection rule from a typed structure.
+	UpdateContentProtectionRuleDetailsRequest struct {
+		ConfigID                int64
+		Version                 int64
+		SecurityPolicyID        string
+		ContentProtectionRuleID string
+		Rule                    ContentProtectionRuleDetails
+	}
+
+	// UpdateBotManagementSettingDetailsRequest is used to update bot management settings from a typed structure.
+	UpdateBotManagementSettingDetailsRequest struct {
+		ConfigID         int64
+		Version          int64
+		SecurityPolicyID string
+		Setting          BotManagementSettingDetails
+	}
+)
+
+// NewTypedClient returns a TypedClient wrapping the given BotMan client.
+func NewTypedClient(client BotMan) *TypedClient {
+	return &TypedClient{BotMan: client}
+}
+
+// UnmarshalJSON decodes a CustomClientDetails, keeping unknown members in AdditionalProperties.
+func (d *CustomClientDetails) UnmarshalJSON(data []byte) error {
+	type alias CustomClientDetails
+	return unmarshalTyped(data, (*alias)(d), &d.AdditionalProperties)
+}
+
+// MarshalJSON encodes a CustomClientDetails together with its AdditionalProperties.
+func (d CustomClientDetails) MarshalJSON() ([]byte, error) {
+	type alias CustomClientDetails
+	return marshalTyped(alias(d), d.AdditionalProperties)
+}
+
+// UnmarshalJSON decodes a CustomBotCategoryDetails, keeping unknown members in AdditionalProperties.
+func (d *CustomBotCategoryDetails) UnmarshalJSON(data []byte) error {
+	type alias CustomBotCategoryDetails
+	return unmarshalTyped(data, (*alias)(d), &d.AdditionalProperties)
+}
+
+// MarshalJSON encodes a CustomBotCategoryDetails together with its AdditionalProperties.
+func (d CustomBotCategoryDetails) MarshalJSON() ([]byte, error) {
+	type alias CustomBotCategoryDetails
+	return marshalTyped(alias(d), d.AdditionalProperties)
+}
+
+// UnmarshalJSON decodes a CustomDefinedBotDetails, keeping unknown members in AdditionalProperties.
+func (d *CustomDefinedBotDetails) UnmarshalJSON(data []byte) error {
+	type alias CustomDefinedBotDetails
+	return unmarshalTyped(data, (*alias)(d), &d.AdditionalProperties)
+}
+
+// MarshalJSON encodes a CustomDefinedBotDetails together with its AdditionalProperties.
+func (d CustomDefinedBotDetails) MarshalJSON() ([]byte, error) {
+	type alias CustomDefinedBotDetails
+	return marshalTyped(alias(d), d.AdditionalProperties)
+}
+
+// UnmarshalJSON decodes a BotDetectionActionDetails, keeping unknown members in AdditionalProperties.
+func (d *BotDetectionActionDetails) UnmarshalJSON(data []byte) error {
+	type alias BotDetectionActionDetails
+	return unmarshalTyped(data, (*alias)(d), &d.AdditionalProperties)
+}
+
+// MarshalJSON encodes a BotDetectionActionDetails together with its AdditionalProperties.
+func (d BotDetectionActionDetails) MarshalJSON() ([]byte, error) {
+	type alias BotDetectionActionDetails
+	return marshalTyped(alias(d), d.AdditionalProperties)
+}
+
+// UnmarshalJSON decodes a ChallengeActionDetails, keeping unknown members in AdditionalProperties.
+func (d *ChallengeActionDetails) UnmarshalJSON(data []byte) error {
+	type alias ChallengeActionDetails
+	return unmarshalTyped(data, (*alias)(d), &d.AdditionalProperties)
+}
+
+// MarshalJSON encodes a ChallengeActionDetails together with its AdditionalProperties.
+func (d ChallengeActionDetails) MarshalJSON() ([]byte, error) {
+	type alias ChallengeActionDetails
+	return marshalTyped(alias(d), d.AdditionalProperties)
+}
+
+// UnmarshalJSON decodes a ConditionalActionDetails, keeping unknown members in AdditionalProperties.
+func (d *ConditionalActionDetails) UnmarshalJSON(data []byte) error {
+	type alias ConditionalActionDetails
+	return unmarshalTyped(data, (*alias)(d), &d.AdditionalProperties)
+}
+
+// MarshalJSON encodes a ConditionalActionDetails together with its AdditionalProperties.
+func (d ConditionalActionDetails) MarshalJSON() ([]byte, error) {
+	type alias ConditionalActionDetails
+	return marshalTyped(alias(d), d.AdditionalProperties)
+}
+
+// UnmarshalJSON decodes a ConditionalActionRule, keeping unknown members in AdditionalProperties.
+func (d *ConditionalActionRule) UnmarshalJSON(data []byte) error {
+	type alias ConditionalActionRule
+	return unmarshalTyped(data, (*alias)(d), &d.AdditionalProperties)
+}
+
+// MarshalJSON encodes a ConditionalActionRule together with its AdditionalProperties.
+func (d ConditionalActionRule) MarshalJSON() ([]byte, error) {
+	type alias ConditionalActionRule
+	return marshalTyped(alias(d), d.AdditionalProperties)
+}
+
+// UnmarshalJSON decodes a ServeAlternateActionDetails, keeping unknown members in AdditionalProperties.
+func (d *ServeAlternateActionDetails) UnmarshalJSON(data []byte) error {
+	type alias ServeAlternateActionDetails
+	return unmarshalTyped(data, (*alias)(d), &d.AdditionalProperties)
+}
+
+// MarshalJSON encodes a ServeAlternateActionDetails together with its AdditionalProperties.
+func (d ServeAlternateActionDetails) MarshalJSON() ([]byte, error) {
+	type alias ServeAlternateActionDetails
+	return marshalTyped(alias(d), d.AdditionalProperties)
+}
+
+// UnmarshalJSON decodes a TransactionalEndpointDetails, keeping unknown members in AdditionalProperties.
+func (d *TransactionalEndpointDetails) UnmarshalJSON(data []byte) error {
+	type alias TransactionalEndpointDetails
+	return unmarshalTyped(data, (*alias)(d), &d.AdditionalProperties)
+}
+
+// MarshalJSON encodes a TransactionalEndpointDetails together with its AdditionalProperties.
+func (d TransactionalEndpointDetails) MarshalJSON() ([]byte, error) {
+	type alias TransactionalEndpointDetails
+	return marshalTyped(alias(d), d.AdditionalProperties)
+}
+
+// UnmarshalJSON decodes a ContentProtectionRuleDetails, keeping unknown members in AdditionalProperties.
+func (d *ContentProtectionRuleDetails) UnmarshalJSON(data []byte) error {
+	type alias ContentProtectionRuleDetails
+	return unmarshalTyped(data, (*alias)(d), &d.AdditionalProperties)
+}
+
+// MarshalJSON encodes a ContentProtectionRuleDetails together with its AdditionalProperties.
+func (d ContentProtectionRuleDetails) MarshalJSON() ([]byte, error) {
+	type alias ContentProtectionRuleDetails
+	return marshalTyped(alias(d), d.AdditionalProperties)
+}
+
+// UnmarshalJSON decodes a BotManagementSettingDetails, keeping unknown members in AdditionalProperties.
+func (d *BotManagementSettingDetails) UnmarshalJSON(data []byte) error {
+	type alias BotManagementSettingDetails
+	return unmarshalTyped(data, (*alias)(d), &d.AdditionalProperties)
+}
+
+// MarshalJSON encodes a BotManagementSettingDetails together with its AdditionalProperties.
+func (d BotManagementSettingDetails) MarshalJSON() ([]byte, error) {
+	type alias BotManagementSettingDetails
+	return marshalTyped(alias(d), d.AdditionalProperties)
+}
+
+// Validate validates a CreateCustomClientDetailsRequest.
+func (v CreateCustomClientDetailsRequest) Validate() error {
+	return validation.Errors{
+		"ConfigID":                      validation.Validate(v.ConfigID, validation.Required),
+		"Version":                       validation.Validate(v.Version, validation.Required),
+		"CustomClient.CustomClientName": validation.Validate(v.CustomClient.CustomClientName, validation.Required),
+	}.Filter()
+}
+
+// Validate validates an UpdateCustomClientDetailsRequest.
+func (v UpdateCustomClientDetailsRequest) Validate() error {
+	return validation.Errors{
+		"ConfigID":                      validation.Validate(v.ConfigID, validation.Required),
+		"Version":                       validation.Validate(v.Version, validation.Required),
+		"CustomClientID":                validation.Validate(v.CustomClientID, validation.Required),
+		"CustomClient.CustomClientName": validation.Validate(v.CustomClient.CustomClientName, validation.Required),
+	}.Filter()
+}
+
+// Validate validates a CreateCustomBotCategoryDetailsRequest.
+func (v CreateCustomBotCategoryDetailsRequest) Validate() error {
+	return validation.Errors{
+		"ConfigID":              validation.Validate(v.ConfigID, validation.Required),
+		"Version":               validation.Validate(v.Version, validation.Required),
+		"Category.CategoryName": validation.Validate(v.Category.CategoryName, validation.Required),
+	}.Filter()
+}
+
+// Validate validates an UpdateCustomBotCategoryDetailsRequest.
+func (v UpdateCustomBotCategoryDetailsRequest) Validate() error {
+	return validation.Errors{
+		"ConfigID":              validation.Validate(v.ConfigID, validation.Required),
+		"Version":               validation.Validate(v.Version, validation.Required),
+		"CategoryID":            validation.Validate(v.CategoryID, validation.Required),
+		"Category.CategoryName": validation.Validate(v.Category.CategoryName, validation.Required),
+	}.Filter()
+}
+
+// Validate validates a CreateCustomDefinedBotDetailsRequest.
+func (v CreateCustomDefinedBotDetailsRequest) Validate() error {
+	return validation.Errors{
+		"ConfigID":    validation.Validate(v.ConfigID, validation.Required),
+		"Version":     validation.Validate(v.Version, validation.Required),
+		"Bot.BotName": validation.Validate(v.Bot.BotName, validation.Required),
+	}.Filter()
+}
+
+// Validate validates an UpdateCustomDefinedBotDetailsRequest.
+func (v UpdateCustomDefinedBotDetailsRequest) Validate() error {
+	return validation.Errors{
+		"ConfigID":    validation.Validate(v.ConfigID, validation.Required),
+		"Version":     validation.Validate(v.Version, validation.Required),
+		"BotID":       validation.Validate(v.BotID, validation.Required),
+		"Bot.BotName": validation.Validate(v.Bot.BotName, validation.Required),
+	}.Filter()
+}
+
+// Validate validates an UpdateBotDetectionActionDetailsRequest.
+func (v UpdateBotDetectionActionDetailsRequest) Validate() error {
+	return validation.Errors{
+		"ConfigID":         validation.Validate(v.ConfigID, validation.Required),
+		"Version":          validation.Validate(v.Version, validation.Required),
+		"SecurityPolicyID": validation.Validate(v.SecurityPolicyID, validation.Required),
+		"DetectionID":      validation.Validate(v.DetectionID, validation.Required),
+		"Action.Action":    validation.Validate(v.Action.Action, validation.Required),
+	}.Filter()
+}
+
+// Validate validates a CreateChallengeActionDetailsRequest.
+func (v CreateChallengeActionDetailsRequest) Validate() error {
+	return validation.Errors{
+		"ConfigID":          validation.Validate(v.ConfigID, validation.Required),
+		"Version":           validation.Validate(v.Version, validation.Required),
+		"Action.ActionName": validation.Validate(v.Action.ActionName, validation.Required),
+	}.Filter()
+}
+
+// Validate validates an UpdateChallengeActionDetailsRequest.
+func (v UpdateChallengeActionDetailsRequest) Validate() error {
+	return validation.Errors{
+		"ConfigID":          validation.Validate(v.ConfigID, validation.Required),
+		"Version":           validation.Validate(v.Version, validation.Required),
+		"ActionID":          validation.Validate(v.ActionID, validation.Required),
+		"Action.ActionName": validation.Validate(v.Action.ActionName, validation.Required),
+	}.Filter()
+}
+
+// Validate validates a CreateConditionalActionDetailsRequest.
+func (v CreateConditionalActionDetailsRequest) Validate() error {
+	return validation.Errors{
+		"ConfigID":          validation.Validate(v.ConfigID, validation.Required),
+		"Version":           validation.Validate(v.Version, validation.Required),
+		"Action.ActionName": validation.Validate(v.Action.ActionName, validation.Required),
+	}.Filter()
+}
+
+// Validate validates an UpdateConditionalActionDetailsRequest.
+func (v UpdateConditionalActionDetailsRequest) Validate() error {
+	return validation.Errors{
+		"ConfigID":          validation.Validate(v.ConfigID, validation.Required),
+		"Version":           validation.Validate(v.Version, validation.Required),
+		"ActionID":          validation.Validate(v.ActionID, validation.Required),
+		"Action.ActionName": validation.Validate(v.Action.ActionName, validation.Required),
+	}.Filter()
+}
+
+// Validate validates a CreateServeAlternateActionDetailsRequest.
+func (v CreateServeAlternateActionDetailsRequest) Validate() error {
+	return validation.Errors{
+		"ConfigID":          validation.Validate(v.ConfigID, validation.Required),
+		"Version":           validation.Validate(v.Version, validation.Required),
+		"Action.ActionName": validation.Validate(v.Action.ActionName, validation.Required),
+	}.Filter()
+}
+
+// Validate validates an UpdateServeAlternateActionDetailsRequest.
+func (v UpdateServeAlternateActionDetailsRequest) Validate() error {
+	return validation.Errors{
+		"ConfigID":          validation.Validate(v.ConfigID, validation.Required),
+		"Version":           validation.Validate(v.Version, validation.Required),
+		"ActionID":          validation.Validate(v.ActionID, validation.Required),
+		"Action.ActionName": validation.Validate(v.Action.ActionName, validation.Required),
+	}.Filter()
+}
+
+// Validate validates a CreateTransactionalEndpointDetailsRequest.
+func (v CreateTransactionalEndpointDetailsRequest) Validate() error {
+	return validation.Errors{
+		"ConfigID":         validation.Validate(v.ConfigID, validation.Required),
+		"Version":          validation.Validate(v.Version, validation.Required),
+		"SecurityPolicyID": validation.Validate(v.SecurityPolicyID, validation.Required),
+	}.Filter()
+}
+
+// Validate validates an UpdateTransactionalEndpointDetailsRequest.
+func (v UpdateTransactionalEndpointDetailsRequest) Validate() error {
+	return validation.Errors{
+		"ConfigID":         validation.Validate(v.ConfigID, validation.Required),
+		"Version":          validation.Validate(v.Version, validation.Required),
+		"SecurityPolicyID": validation.Validate(v.SecurityPolicyID, validation.Required),
+		"OperationID":      validation.Validate(v.OperationID, validation.Required),
+	}.Filter()
+}
+
+// Validate validates a CreateContentProtectionRuleDetailsRequest.
+func (v CreateContentProtectionRuleDetailsRequest) Validate() error {
+	return validation.Errors{
+		"ConfigID":                       validation.Validate(v.ConfigID, validation.Required),
+		"Version":                        validation.Validate(v.Version, validation.Required),
+		"SecurityPolicyID":               validation.Validate(v.SecurityPolicyID, validation.Required),
+		"Rule.ContentProtectionRuleName": validation.Validate(v.Rule.ContentProtectionRuleName, validation.Required),
+	}.Filter()
+}
+
+// Validate validates an UpdateContentProtectionRuleDetailsRequest.
+func (v UpdateContentProtectionRuleDetailsRequest) Validate() error {
+	return validation.Errors{
+		"ConfigID":                       validation.Validate(v.ConfigID, validation.Required),
+		"Version":                        validation.Validate(v.Version, validation.Required),
+		"SecurityPolicyID":               validation.Validate(v.SecurityPolicyID, validation.Required),
+		"ContentProtectionRuleID":        validation.Validate(v.ContentProtectionRuleID, validation.Required),
+		"Rule.ContentProtectionRuleName": validation.Validate(v.Rule.ContentProtectionRuleName, validation.Required),
+	}.Filter()
+}
+
+// Validate validates an UpdateBotManagementSettingDetailsRequest.
+func (v UpdateBotManagementSettingDetailsRequest) Validate() error {
+	return validation.Errors{
+		"ConfigID":         validation.Validate(v.ConfigID, validation.Required),
+		"Version":          validation.Validate(v.Version, validation.Required),
+		"SecurityPolicyID": validation.Validate(v.SecurityPolicyID, validation.Required),
+	}.Filter()
+}
+
+// GetCustomClientDetails returns a custom client as a CustomClientDetails.
+func (c *TypedClient) GetCustomClientDetails(ctx context.Context, params GetCustomClientRequest) (*CustomClientDetails, error) {
+	return fromMap[CustomClientDetails](c.GetCustomClient(ctx, params))
+}
+
+// ListCustomClientDetails returns the custom clients of a configuration as CustomClientDetails.
+func (c *TypedClient) ListCustomClientDetails(ctx context.Context, params GetCustomClientListRequest) ([]CustomClientDetails, error) {
+	resp, err := c.GetCustomClientList(ctx, params)
+	if err != nil {
+		return nil, err
+	}
+	return fromMaps[CustomClientDetails](resp.CustomClients)
+}
+
+// CreateCustomClientDetails creates a custom client from a CustomClientDetails.
+func (c *TypedClient) CreateCustomClientDetails(ctx context.Context, params CreateCustomClientDetailsRequest) (*CustomClientDetails, error) {
+	if err := params.Validate(); err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrStructValidation, err.Error())
+	}
+	payload, err := json.Marshal(params.CustomClient)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal custom client: %w", err)
+	}
+	return fromMap[CustomClientDetails](c.CreateCustomClient(ctx, CreateCustomClientRequest{
+		ConfigID:    params.ConfigID,
+		Version:     params.Version,
+		JsonPayload: payload,
+	}))
+}
+
+// UpdateCustomClientDetails updates a custom client from a CustomClientDetails.
+func (c *TypedClient) UpdateCustomClientDetails(ctx context.Context, params UpdateCustomClientDetailsRequest) (*CustomClientDetails, error) {
+	if err := params.Validate(); err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrStructValidation, err.Error())
+	}
+	payload, err := json.Marshal(params.CustomClient)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal custom client: %w", err)
+	}
+	return fromMap[CustomClientDetails](c.UpdateCustomClient(ctx, UpdateCustomClientRequest{
+		ConfigID:       params.ConfigID,
+		Version:        params.Version,
+		CustomClientID: params.CustomClientID,
+		JsonPayload:    payload,
+	}))
+}
+
+// GetCustomBotCategoryDetails returns a custom bot category as a CustomBotCategoryDetails.
+func (c *TypedClient) GetCustomBotCategoryDetails(ctx context.Context, params GetCustomBotCategoryRequest) (*CustomBotCategoryDetails, error) {
+	return fromMap[CustomBotCategoryDetails](c.GetCustomBotCategory(ctx, params))
+}
+
+// ListCustomBotCategoryDetails returns the custom bot categories of a configuration as CustomBotCategoryDetails.
+func (c *TypedClient) ListCustomBotCategoryDetails(ctx context.Context, params GetCustomBotCategoryListRequest) ([]CustomBotCategoryDetails, error) {
+	resp, err := c.GetCustomBotCategoryList(ctx, params)
+	if err != nil {
+		return nil, err
+	}
+	return fromMaps[CustomBotCategoryDetails](resp.Categories)
+}
+
+// CreateCustomBotCategoryDetails creates a custom bot category from a CustomBotCategoryDetails.
+func (c *TypedClient) CreateCustomBotCategoryDetails(ctx context.Context, params CreateCustomBotCategoryDetailsRequest) (*CustomBotCategoryDetails, error) {
+	if err := params.Validate(); err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrStructValidation, err.Error())
+	}
+	payload, err := json.Marshal(params.Category)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal custom bot category: %w", err)
+	}
+	return fromMap[CustomBotCategoryDetails](c.CreateCustomBotCategory(ctx, CreateCustomBotCategoryRequest{
+		ConfigID:    params.ConfigID,
+		Version:     params.Version,
+		JsonPayload: payload,
+	}))
+}
+
+// UpdateCustomBotCategoryDetails updates a custom bot category from a CustomBotCategoryDetails.
+func (c *TypedClient) UpdateCustomBotCategoryDetails(ctx context.Context, params UpdateCustomBotCategoryDetailsRequest) (*CustomBotCategoryDetails, error) {
+	if err := params.Validate(); err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrStructValidation, err.Error())
+	}
+	payload, err := json.Marshal(params.Category)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal custom bot category: %w", err)
+	}
+	return fromMap[CustomBotCategoryDetails](c.UpdateCustomBotCategory(ctx, UpdateCustomBotCategoryRequest{
+		ConfigID:    params.ConfigID,
+		Version:     params.Version,
+		CategoryID:  params.CategoryID,
+		JsonPayload: payload,
+	}))
+}
+
+// GetCustomDefinedBotDetails returns a custom defined bot as a CustomDefinedBotDetails.
+func (c *TypedClient) GetCustomDefinedBotDetails(ctx context.Context, params GetCustomDefinedBotRequest) (*CustomDefinedBotDetails, error) {
+	return fromMap[CustomDefinedBotDetails](c.GetCustomDefinedBot(ctx, params))
+}
+
+// ListCustomDefinedBotDetails returns the custom defined bots of a configuration as CustomDefinedBotDetails.
+func (c *TypedClient) ListCustomDefinedBotDetails(ctx context.Context, params GetCustomDefinedBotListRequest) ([]CustomDefinedBotDetails, error) {
+	resp, err := c.GetCustomDefinedBotList(ctx, params)
+	if err != nil {
+		return nil, err
+	}
+	return fromMaps[CustomDefinedBotDetails](resp.Bots)
+}
+
+// CreateCustomDefinedBotDetails creates a custom defined bot from a CustomDefinedBotDetails.
+func (c *TypedClient) CreateCustomDefinedBotDetails(ctx context.Context, params CreateCustomDefinedBotDetailsRequest) (*CustomDefinedBotDetails, error) {
+	if err := params.Validate(); err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrStructValidation, err.Error())
+	}
+	payload, err := json.Marshal(params.Bot)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal custom defined bot: %w", err)
+	}
+	return fromMap[CustomDefinedBotDetails](c.CreateCustomDefinedBot(ctx, CreateCustomDefinedBotRequest{
+		ConfigID:    params.ConfigID,
+		Version:     params.Version,
+		JsonPayload: payload,
+	}))
+}
+
+// UpdateCustomDefinedBotDetails updates a custom defined bot from a CustomDefinedBotDetails.
+func (c *TypedClient) UpdateCustomDefinedBotDetails(ctx context.Context, params UpdateCustomDefinedBotDetailsRequest) (*CustomDefinedBotDetails, error) {
+	if err := params.Validate(); err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrStructValidation, err.Error())
+	}
+	payload, err := json.Marshal(params.Bot)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal custom defined bot: %w", err)
+	}
+	return fromMap[CustomDefinedBotDetails](c.UpdateCustomDefinedBot(ctx, UpdateCustomDefinedBotRequest{
+		ConfigID:    params.ConfigID,
+		Version:     params.Version,
+		BotID:       params.BotID,
+		JsonPayload: payload,
+	}))
+}
+
+// GetBotDetectionActionDetails returns a bot detection action as a BotDetectionActionDetails.
+func (c *TypedClient) GetBotDetectionActionDetails(ctx context.Context, params GetBotDetectionActionRequest) (*BotDetectionActionDetails, error) {
+	return fromMap[BotDetectionActionDetails](c.GetBotDetectionAction(ctx, params))
+}
+
+// ListBotDetectionActionDetails returns the bot detection actions of a security policy as BotDetectionActionDetails.
+func (c *TypedClient) ListBotDetectionActionDetails(ctx context.Context, params GetBotDetectionActionListRequest) ([]BotDetectionActionDetails, error) {
+	resp, err := c.GetBotDetectionActionList(ctx, params)
+	if err != nil {
+		return nil, err
+	}
+	return fromMaps[BotDetectionActionDetails](resp.Actions)
+}
+
+// UpdateBotDetectionActionDetails updates a bot detection action from a BotDetectionActionDetails.
+func (c *TypedClient) UpdateBotDetectionActionDetails(ctx context.Context, params UpdateBotDetectionActionDetailsRequest) (*BotDetectionActionDetails, error) {
+	if err := params.Validate(); err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrStructValidation, err.Error())
+	}
+	payload, err := json.Marshal(params.Action)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal bot detection action: %w", err)
+	}
+	return fromMap[BotDetectionActionDetails](c.UpdateBotDetectionAction(ctx, UpdateBotDetectionActionRequest{
+		ConfigID:         params.ConfigID,
+		Version:          params.Version,
+		SecurityPolicyID: params.SecurityPolicyID,
+		DetectionID:      params.DetectionID,
+		JsonPayload:      payload,
+	}))
+}
+
+// GetChallengeActionDetails returns a challenge action as a ChallengeActionDetails.
+func (c *TypedClient) GetChallengeActionDetails(ctx context.Context, params GetChallengeActionRequest) (*ChallengeActionDetails, error) {
+	return fromMap[ChallengeActionDetails](c.GetChallengeAction(ctx, params))
+}
+
+// ListChallengeActionDetails returns the challenge actions of a configuration as ChallengeActionDetails.
+func (c *TypedClient) ListChallengeActionDetails(ctx context.Context, params GetChallengeActionListRequest) ([]ChallengeActionDetails, error) {
+	resp, err := c.GetChallengeActionList(ctx, params)
+	if err != nil {
+		return nil, err
+	}
+	return fromMaps[ChallengeActionDetails](resp.ChallengeActions)
+}
+
+// CreateChallengeActionDetails creates a challenge action from a ChallengeActionDetails.
+func (c *TypedClient) CreateChallengeActionDetails(ctx context.Context, params CreateChallengeActionDetailsRequest) (*ChallengeActionDetails, error) {
+	if err := params.Validate(); err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrStructValidation, err.Error())
+	}
+	payload, err := json.Marshal(params.Action)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal challenge action: %w", err)
+	}
+	return fromMap[ChallengeActionDetails](c.CreateChallengeAction(ctx, CreateChallengeActionRequest{
+		ConfigID:    params.ConfigID,
+		Version:     params.Version,
+		JsonPayload: payload,
+	}))
+}
+
+// UpdateChallengeActionDetails updates a challenge action from a ChallengeActionDetails.
+func (c *TypedClient) UpdateChallengeActionDetails(ctx context.Context, params UpdateChallengeActionDetailsRequest) (*ChallengeActionDetails, error) {
+	if err := params.Validate(); err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrStructValidation, err.Error())
+	}
+	payload, err := json.Marshal(params.Action)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal challenge action: %w", err)
+	}
+	return fromMap[ChallengeActionDetails](c.UpdateChallengeAction(ctx, UpdateChallengeActionRequest{
+		ConfigID:    params.ConfigID,
+		Version:     params.Version,
+		ActionID:    params.ActionID,
+		JsonPayload: payload,
+	}))
+}
+
+// GetConditionalActionDetails returns a conditional action as a ConditionalActionDetails.
+func (c *TypedClient) GetConditionalActionDetails(ctx context.Context, params GetConditionalActionRequest) (*ConditionalActionDetails, error) {
+	return fromMap[ConditionalActionDetails](c.GetConditionalAction(ctx, params))
+}
+
+// ListConditionalActionDetails returns the conditional actions of a configuration as ConditionalActionDetails.
+func (c *TypedClient) ListConditionalActionDetails(ctx context.Context, params GetConditionalActionListRequest) ([]ConditionalActionDetails, error) {
+	resp, err := c.GetConditionalActionList(ctx, params)
+	if err != nil {
+		return nil, err
+	}
+	return fromMaps[ConditionalActionDetails](resp.ConditionalActions)
+}
+
+// CreateConditionalActionDetails creates a conditional action from a ConditionalActionDetails.
+func (c *TypedClient) CreateConditionalActionDetails(ctx context.Context, params CreateConditionalActionDetailsRequest) (*ConditionalActionDetails, error) {
+	if err := params.Validate(); err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrStructValidation, err.Error())
+	}
+	payload, err := json.Marshal(params.Action)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal conditional action: %w", err)
+	}
+	return fromMap[ConditionalActionDetails](c.CreateConditionalAction(ctx, CreateConditionalActionRequest{
+		ConfigID:    params.ConfigID,
+		Version:     params.Version,
+		JsonPayload: payload,
+	}))
+}
+
+// UpdateConditionalActionDetails updates a conditional action from a ConditionalActionDetails.
+func (c *TypedClient) UpdateConditionalActionDetails(ctx context.Context, params UpdateConditionalActionDetailsRequest) (*ConditionalActionDetails, error) {
+	if err := params.Validate(); err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrStructValidation, err.Error())
+	}
+	payload, err := json.Marshal(params.Action)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal conditional action: %w", err)
+	}
+	return fromMap[ConditionalActionDetails](c.UpdateConditionalAction(ctx, UpdateConditionalActionRequest{
+		ConfigID:    params.ConfigID,
+		Version:     params.Version,
+		ActionID:    params.ActionID,
+		JsonPayload: payload,
+	}))
+}
+
+// GetServeAlternateActionDetails returns a serve alternate action as a ServeAlternateActionDetails.
+func (c *TypedClient) GetServeAlternateActionDetails(ctx context.Context, params GetServeAlternateActionRequest) (*ServeAlternateActionDetails, error) {
+	return fromMap[ServeAlternateActionDetails](c.GetServeAlternateAction(ctx, params))
+}
+
+// ListServeAlternateActionDetails returns the serve alternate actions of a configuration as ServeAlternateActionDetails.
+func (c *TypedClient) ListServeAlternateActionDetails(ctx context.Context, params GetServeAlternateActionListRequest) ([]ServeAlternateActionDetails, error) {
+	resp, err := c.GetServeAlternateActionList(ctx, params)
+	if err != nil {
+		return nil, err
+	}
+	return fromMaps[ServeAlternateActionDetails](resp.ServeAlternateActions)
+}
+
+// CreateServeAlternateActionDetails creates a serve alternate action from a ServeAlternateActionDetails.
+func (c *TypedClient) CreateServeAlternateActionDetails(ctx context.Context, params CreateServeAlternateActionDetailsRequest) (*ServeAlternateActionDetails, error) {
+	if err := params.Validate(); err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrStructValidation, err.Error())
+	}
+	payload, err := json.Marshal(params.Action)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal serve alternate action: %w", err)
+	}
+	return fromMap[ServeAlternateActionDetails](c.CreateServeAlternateAction(ctx, CreateServeAlternateActionRequest{
+		ConfigID:    params.ConfigID,
+		Version:     params.Version,
+		JsonPayload: payload,
+	}))
+}
+
+// UpdateServeAlternateActionDetails updates a serve alternate action from a ServeAlternateActionDetails.
+func (c *TypedClient) UpdateServeAlternateActionDetails(ctx context.Context, params UpdateServeAlternateActionDetailsRequest) (*ServeAlternateActionDetails, error) {
+	if err := params.Validate(); err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrStructValidation, err.Error())
+	}
+	payload, err := json.Marshal(params.Action)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal serve alternate action: %w", err)
+	}
+	return fromMap[ServeAlternateActionDetails](c.UpdateServeAlternateAction(ctx, UpdateServeAlternateActionRequest{
+		ConfigID:    params.ConfigID,
+		Version:     params.Version,
+		ActionID:    params.ActionID,
+		JsonPayload: payload,
+	}))
+}
+
+// GetTransactionalEndpointDetails returns a transactional endpoint as a TransactionalEndpointDetails.
+func (c *TypedClient) GetTransactionalEndpointDetails(ctx context.Context, params GetTransactionalEndpointRequest) (*TransactionalEndpointDetails, error) {
+	return fromMap[TransactionalEndpointDetails](c.GetTransactionalEndpoint(ctx, params))
+}
+
+// ListTransactionalEndpointDetails returns the transactional endpoints of a security policy as TransactionalEndpointDetails.
+func (c *TypedClient) ListTransactionalEndpointDetails(ctx context.Context, params GetTransactionalEndpointListRequest) ([]TransactionalEndpointDetails, error) {
+	resp, err := c.GetTransactionalEndpointList(ctx, params)
+	if err != nil {
+		return nil, err
+	}
+	return fromMaps[TransactionalEndpointDetails](resp.Operations)
+}
+
+// CreateTransactionalEndpointDetails creates a transactional endpoint from a TransactionalEndpointDetails.
+func (c *TypedClient) CreateTransactionalEndpointDetails(ctx context.Context, params CreateTransactionalEndpointDetailsRequest) (*TransactionalEndpointDetails, error) {
+	if err := params.Validate(); err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrStructValidation, err.Error())
+	}
+	payload, err := json.Marshal(params.Endpoint)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal transactional endpoint: %w", err)
+	}
+	return fromMap[TransactionalEndpointDetails](c.CreateTransactionalEndpoint(ctx, CreateTransactionalEndpointRequest{
+		ConfigID:         params.ConfigID,
+		Version:          params.Version,
+		SecurityPolicyID: params.SecurityPolicyID,
+		JsonPayload:      payload,
+	}))
+}
+
+// UpdateTransactionalEndpointDetails updates a transactional endpoint from a TransactionalEndpointDetails.
+func (c *TypedClient) UpdateTransactionalEndpointDetails(ctx context.Context, params UpdateTransactionalEndpointDetailsRequest) (*TransactionalEndpointDetails, error) {
+	if err := params.Validate(); err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrStructValidation, err.Error())
+	}
+	payload, err := json.Marshal(params.Endpoint)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal transactional endpoint: %w", err)
+	}
+	return fromMap[TransactionalEndpointDetails](c.UpdateTransactionalEndpoint(ctx, UpdateTransactionalEndpointRequest{
+		ConfigID:         params.ConfigID,
+		Version:          params.Version,
+		SecurityPolicyID: params.SecurityPolicyID,
+		OperationID:      params.OperationID,
+		JsonPayload:      payload,
+	}))
+}
+
+// GetContentProtectionRuleDetails returns a content protection rule as a ContentProtectionRuleDetails.
+func (c *TypedClient) GetContentProtectionRuleDetails(ctx context.Context, params GetContentProtectionRuleRequest) (*ContentProtectionRuleDetails, error) {
+	return fromMap[ContentProtectionRuleDetails](c.GetContentProtectionRule(ctx, params))
+}
+
+// ListContentProtectionRuleDetails returns the content protection rules of a security policy as ContentProtectionRuleDetails.
+func (c *TypedClient) ListContentProtectionRuleDetails(ctx context.Context, params GetContentProtectionRuleListRequest) ([]ContentProtectionRuleDetails, error) {
+	resp, err := c.GetContentProtectionRuleList(ctx, params)
+	if err != nil {
+		return nil, err
+	}
+	return fromMaps[ContentProtectionRuleDetails](resp.ContentProtectionRules)
+}
+
+// CreateContentProtectionRuleDetails creates a content protection rule from a ContentProtectionRuleDetails.
+func (c *TypedClient) CreateContentProtectionRuleDetails(ctx context.Context, params CreateContentProtectionRuleDetailsRequest) (*ContentProtectionRuleDetails, error) {
+	if err := params.Validate(); err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrStructValidation, err.Error())
+	}
+	payload, err := json.Marshal(params.Rule)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal content protection rule: %w", err)
+	}
+	return fromMap[ContentProtectionRuleDetails](c.CreateContentProtectionRule(ctx, CreateContentProtectionRuleRequest{
+		ConfigID:         params.ConfigID,
+		Version:          params.Version,
+		SecurityPolicyID: params.SecurityPolicyID,
+		JsonPayload:      payload,
+	}))
+}
+
+// UpdateContentProtectionRuleDetails updates a content protection rule from a ContentProtectionRuleDetails.
+func (c *TypedClient) UpdateContentProtectionRuleDetails(ctx context.Context, params UpdateContentProtectionRuleDetailsRequest) (*ContentProtectionRuleDetails, error) {
+	if err := params.Validate(); err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrStructValidation, err.Error())
+	}
+	payload, err := json.Marshal(params.Rule)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal content protection rule: %w", err)
+	}
+	return fromMap[ContentProtectionRuleDetails](c.UpdateContentProtectionRule(ctx, UpdateContentProtectionRuleRequest{
+		ConfigID:                params.ConfigID,
+		Version:                 params.Version,
+		SecurityPolicyID:        params.SecurityPolicyID,
+		ContentProtectionRuleID: params.ContentProtectionRuleID,
+		JsonPayload:             payload,
+	}))
+}
+
+// GetBotManagementSettingDetails returns the bot management settings of a security policy as a BotManagementSettingDetails.
+func (c *TypedClient) GetBotManagementSettingDetails(ctx context.Context, params GetBotManagementSettingRequest) (*BotManagementSettingDetails, error) {
+	return fromMap[BotManagementSettingDetails](c.GetBotManagementSetting(ctx, params))
+}
+
+// UpdateBotManagementSettingDetails updates the bot management settings of a security policy from a BotManagementSettingDetails.
+func (c *TypedClient) UpdateBotManagementSettingDetails(ctx context.Context, params UpdateBotManagementSettingDetailsRequest) (*BotManagementSettingDetails, error) {
+	if err := params.Validate(); err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrStructValidation, err.Error())
+	}
+	payload, err := json.Marshal(params.Setting)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal bot management setting: %w", err)
+	}
+	return fromMap[BotManagementSettingDetails](c.UpdateBotManagementSetting(ctx, UpdateBotManagementSettingRequest{
+		ConfigID:         params.ConfigID,
+		Version:          params.Version,
+		SecurityPolicyID: params.SecurityPolicyID,
+		JsonPayload:      payload,
+	}))
+}
+
+// fromMap converts a raw response into a typed structure, passing through the error of the raw call.
+func fromMap[T any](raw map[string]interface{}, err error) (*T, error) {
+	if err != nil {
+		return nil, err
+	}
+	data, err := json.Marshal(raw)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal response: %w", err)
+	}
+	var result T
+	if err := json.Unmarshal(data, &result); err != nil {
+		return nil, fmt.Errorf("failed to decode response into %T: %w", result, err)
+	}
+	return &result, nil
+}
+
+// fromMaps converts a list of raw objects into typed structures.
+func fromMaps[T any](raw []map[string]interface{}) ([]T, error) {
+	result := make([]T, 0, len(raw))
+	for _, item := range raw {
+		typed, err := fromMap[T](item, nil)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, *typed)
+	}
+	return result, nil
+}
+
+// unmarshalTyped decodes data into v, a pointer to a struct, and stores members without a matching
+// json tag in extra.
+func unmarshalTyped(data []byte, v interface{}, extra *AdditionalProperties) error {
+	if err := json.Unmarshal(data, v); err != nil {
+		return err
+	}
+	var members map[string]json.RawMessage
+	if err := json.Unmarshal(data, &members); err != nil {
+		return err
+	}
+
+	known := jsonFieldNames(reflect.TypeOf(v).Elem())
+	*extra = nil
+	for name, value := range members {
+		if _, ok := known[name]; ok {
+			continue
+		}
+		if *extra == nil {
+			*extra = make(AdditionalProperties)
+		}
+		(*extra)[name] = value
+	}
+	return nil
+}
+
+// marshalTyped encodes v and merges extra members into the result. Modeled fields take precedence.
+func marshalTyped(v interface{}, extra AdditionalProperties) ([]byte, error) {
+	data, err := json.Marshal(v)
+	if err != nil || len(extra) == 0 {
+		return data, err
+	}
+	var members map[string]json.RawMessage
+	if err := json.Unmarshal(data, &members); err != nil {
+		return nil, err
+	}
+	known := jsonFieldNames(reflect.TypeOf(v))
+	for name, value := range extra {
+		if _, ok := known[name]; ok {
+			continue
+		}
+		members[name] = value
+	}
+	return json.Marshal(members)
+}
+
+func jsonFieldNames(t reflect.Type) map[string]struct{} {
+	names := make(map[string]struct{}, t.NumField())
+	for i := 0; i < t.NumField(); i++ {
+		tag := t.Field(i).Tag.Get("json")
+		name, _, _ := strings.Cut(tag, ",")
+		if name == "" || name == "-" {
+			continue
+		}
+		names[name] = struct{}{}
+	}
+	return names
+}
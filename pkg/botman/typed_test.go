@@ -0,0 +1,256 @@
+package botman
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/akamai/AkamaiOPEN-edgegrid-golang/v11/pkg/ptr"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTypedClient_GetCustomClientDetails(t *testing.T) {
+	tests := map[string]struct {
+		params           GetCustomClientRequest
+		responseStatus   int
+		responseBody     string
+		expectedPath     string
+		expectedResponse *CustomClientDetails
+		withError        func(*testing.T, error)
+	}{
+		"200 OK": {
+			params:         GetCustomClientRequest{ConfigID: 43253, Version: 15, CustomClientID: "cc9c3f89"},
+			responseStatus: http.StatusOK,
+			responseBody: `{"customClientId":"cc9c3f89","customClientName":"Mobile app","clientType":"NATIVE_APP",
+"platforms":["IOS"],"hostnames":["www.example.com"],"validation":{"sdk":true}}`,
+			expectedPath: "/appsec/v1/configs/43253/versions/15/custom-clients/cc9c3f89",
+			expectedResponse: &CustomClientDetails{
+				CustomClientID:       "cc9c3f89",
+				CustomClientName:     "Mobile app",
+				ClientType:           "NATIVE_APP",
+				Platforms:            []string{"IOS"},
+				Hostnames:            []string{"www.example.com"},
+				AdditionalProperties: AdditionalProperties{"validation": json.RawMessage(`{"sdk":true}`)},
+			},
+		},
+		"500 internal server error": {
+			params:         GetCustomClientRequest{ConfigID: 43253, Version: 15, CustomClientID: "cc9c3f89"},
+			responseStatus: http.StatusInternalServerError,
+			responseBody:   `{"type": "internal_error", "title": "Internal Server Error", "detail": "Error fetching data"}`,
+			expectedPath:   "/appsec/v1/configs/43253/versions/15/custom-clients/cc9c3f89",
+			withError: func(t *testing.T, err error) {
+				want := &Error{
+					Type:       "internal_error",
+					Title:      "Internal Server Error",
+					Detail:     "Error fetching data",
+					StatusCode: http.StatusInternalServerError,
+				}
+				assert.True(t, errors.Is(err, want), "want: %s; got: %s", want, err)
+			},
+		},
+		"validation error": {
+			params: GetCustomClientRequest{ConfigID: 43253, Version: 15},
+			withError: func(t *testing.T, err error) {
+				assert.True(t, errors.Is(err, ErrStructValidation), "want: %s; got: %s", ErrStructValidation, err)
+			},
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			mockServer := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				assert.Equal(t, test.expectedPath, r.URL.String())
+				assert.Equal(t, http.MethodGet, r.Method)
+				w.WriteHeader(test.responseStatus)
+				_, err := w.Write([]byte(test.responseBody))
+				assert.NoError(t, err)
+			}))
+			client := NewTypedClient(mockAPIClient(t, mockServer))
+			result, err := client.GetCustomClientDetails(context.Background(), test.params)
+			if test.withError != nil {
+				test.withError(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, test.expectedResponse, result)
+		})
+	}
+}
+
+func TestTypedClient_ListChallengeActionDetails(t *testing.T) {
+	mockServer := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/appsec/v1/configs/43253/versions/15/response-actions/challenge-actions", r.URL.String())
+		w.WriteHeader(http.StatusOK)
+		_, err := w.Write([]byte(`{"challengeActions":[{"actionId":"a1","actionName":"Challenge 1"},{"actionId":"a2","actionName":"Challenge 2"}]}`))
+		assert.NoError(t, err)
+	}))
+	client := NewTypedClient(mockAPIClient(t, mockServer))
+
+	result, err := client.ListChallengeActionDetails(context.Background(), GetChallengeActionListRequest{ConfigID: 43253, Version: 15})
+	require.NoError(t, err)
+	assert.Equal(t, []ChallengeActionDetails{
+		{ActionID: "a1", ActionName: "Challenge 1"},
+		{ActionID: "a2", ActionName: "Challenge 2"},
+	}, result)
+}
+
+func TestTypedClient_UpdateConditionalActionDetails(t *testing.T) {
+	tests := map[string]struct {
+		params              UpdateConditionalActionDetailsRequest
+		responseBody        string
+		expectedRequestBody string
+		expectedResponse    *ConditionalActionDetails
+		withError           error
+	}{
+		"200 OK keeps unknown members": {
+			params: UpdateConditionalActionDetailsRequest{
+				ConfigID: 43253,
+				Version:  15,
+				ActionID: "ca1",
+				Action: ConditionalActionDetails{
+					ActionName:    "Conditional 1",
+					DefaultAction: "deny",
+					ConditionalActionRules: []ConditionalActionRule{
+						{Action: "monitor", Conditions: []json.RawMessage{json.RawMessage(`{"type":"requestHeaderCondition"}`)}},
+					},
+					AdditionalProperties: AdditionalProperties{"actionName": json.RawMessage(`"ignored"`), "extra": json.RawMessage(`[1,2]`)},
+				},
+			},
+			responseBody:        `{"actionId":"ca1","actionName":"Conditional 1","defaultAction":"deny","extra":[1,2]}`,
+			expectedRequestBody: `{"actionName":"Conditional 1","conditionalActionRules":[{"action":"monitor","conditions":[{"type":"requestHeaderCondition"}]}],"defaultAction":"deny","extra":[1,2]}`,
+			expectedResponse: &ConditionalActionDetails{
+				ActionID:             "ca1",
+				ActionName:           "Conditional 1",
+				DefaultAction:        "deny",
+				AdditionalProperties: AdditionalProperties{"extra": json.RawMessage(`[1,2]`)},
+			},
+		},
+		"validation error": {
+			params:    UpdateConditionalActionDetailsRequest{ConfigID: 43253, Version: 15, ActionID: "ca1"},
+			withError: ErrStructValidation,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			mockServer := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				assert.Equal(t, "/appsec/v1/configs/43253/versions/15/response-actions/conditional-actions/ca1", r.URL.String())
+				assert.Equal(t, http.MethodPut, r.Method)
+				body, err := io.ReadAll(r.Body)
+				assert.NoError(t, err)
+				assert.JSONEq(t, test.expectedRequestBody, string(body))
+				w.WriteHeader(http.StatusOK)
+				_, err = w.Write([]byte(test.responseBody))
+				assert.NoError(t, err)
+			}))
+			client := NewTypedClient(mockAPIClient(t, mockServer))
+			result, err := client.UpdateConditionalActionDetails(context.Background(), test.params)
+			if test.withError != nil {
+				assert.True(t, errors.Is(err, test.withError), "want: %s; got: %s", test.withError, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, test.expectedResponse, result)
+		})
+	}
+}
+
+func TestTypedClient_CreateContentProtectionRuleDetails(t *testing.T) {
+	tests := map[string]struct {
+		params           CreateContentProtectionRuleDetailsRequest
+		expectedResponse *ContentProtectionRuleDetails
+		withError        error
+	}{
+		"201 Created keeps unknown members": {
+			params: CreateContentProtectionRuleDetailsRequest{
+				ConfigID:         43253,
+				Version:          15,
+				SecurityPolicyID: "AAAA_81230",
+				Rule: ContentProtectionRuleDetails{
+					ContentProtectionRuleName: "Checkout",
+					AdditionalProperties:      AdditionalProperties{"protectedOperations": json.RawMessage(`["op-1"]`)},
+				},
+			},
+			expectedResponse: &ContentProtectionRuleDetails{
+				ContentProtectionRuleID:   "cpr-1",
+				ContentProtectionRuleName: "Checkout",
+				AdditionalProperties:      AdditionalProperties{"protectedOperations": json.RawMessage(`["op-1"]`)},
+			},
+		},
+		"validation error": {
+			params:    CreateContentProtectionRuleDetailsRequest{ConfigID: 43253, Version: 15, SecurityPolicyID: "AAAA_81230"},
+			withError: ErrStructValidation,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			mockServer := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				assert.Equal(t, "/appsec/v1/configs/43253/versions/15/security-policies/AAAA_81230/content-protection-rules", r.URL.String())
+				assert.Equal(t, http.MethodPost, r.Method)
+				body, err := io.ReadAll(r.Body)
+				assert.NoError(t, err)
+				assert.JSONEq(t, `{"contentProtectionRuleName":"Checkout","protectedOperations":["op-1"]}`, string(body))
+				w.WriteHeader(http.StatusCreated)
+				_, err = w.Write([]byte(`{"contentProtectionRuleId":"cpr-1","contentProtectionRuleName":"Checkout","protectedOperations":["op-1"]}`))
+				assert.NoError(t, err)
+			}))
+			client := NewTypedClient(mockAPIClient(t, mockServer))
+			result, err := client.CreateContentProtectionRuleDetails(context.Background(), test.params)
+			if test.withError != nil {
+				assert.True(t, errors.Is(err, test.withError), "want: %s; got: %s", test.withError, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, test.expectedResponse, result)
+		})
+	}
+}
+
+func TestTypedClient_UpdateBotManagementSettingDetails(t *testing.T) {
+	mockServer := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/appsec/v1/configs/43253/versions/15/security-policies/AAAA_81230/bot-management-settings", r.URL.String())
+		assert.Equal(t, http.MethodPut, r.Method)
+		body, err := io.ReadAll(r.Body)
+		assert.NoError(t, err)
+		assert.JSONEq(t, `{"enableBotManagement":true,"addAkamaiBotHeader":false}`, string(body))
+		w.WriteHeader(http.StatusOK)
+		_, err = w.Write(body)
+		assert.NoError(t, err)
+	}))
+	client := NewTypedClient(mockAPIClient(t, mockServer))
+
+	result, err := client.UpdateBotManagementSettingDetails(context.Background(), UpdateBotManagementSettingDetailsRequest{
+		ConfigID:         43253,
+		Version:          15,
+		SecurityPolicyID: "AAAA_81230",
+		Setting: BotManagementSettingDetails{
+			EnableBotManagement: ptr.To(true),
+			AddAkamaiBotHeader:  ptr.To(false),
+		},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, &BotManagementSettingDetails{
+		EnableBotManagement: ptr.To(true),
+		AddAkamaiBotHeader:  ptr.To(false),
+	}, result)
+}
+
+func TestCustomBotCategoryDetails_RoundTrip(t *testing.T) {
+	in := `{"categoryId":"c1","categoryName":"Partners","metadata":{"owner":"team-a"},"ruleId":1234567890123456789}`
+
+	var category CustomBotCategoryDetails
+	require.NoError(t, json.Unmarshal([]byte(in), &category))
+	assert.Equal(t, "c1", category.CategoryID)
+	assert.Equal(t, "Partners", category.CategoryName)
+	assert.Len(t, category.AdditionalProperties, 2)
+
+	out, err := json.Marshal(category)
+	require.NoError(t, err)
+	assert.JSONEq(t, in, string(out))
+}
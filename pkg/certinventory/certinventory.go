@@ -0,0 +1,460 @@
+// Package certinventory collects the certificates managed in CPS, mTLS Keystore and HAPI into a single
+// inventory, reporting their expiry, keys, names and trust chain issues.
+package certinventory
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/csv"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/akamai/AkamaiOPEN-edgegrid-golang/v11/pkg/cps"
+	"github.com/akamai/AkamaiOPEN-edgegrid-golang/v11/pkg/edgegriderr"
+	"github.com/akamai/AkamaiOPEN-edgegrid-golang/v11/pkg/hapi"
+	"github.com/akamai/AkamaiOPEN-edgegrid-golang/v11/pkg/mtlskeystore"
+	validation "github.com/go-ozzo/ozzo-validation/v4"
+)
+
+type (
+	// Clients groups the API clients the inventory is collected with. Sources with a nil client are skipped.
+	Clients struct {
+		CPS          cps.CPS
+		MTLSKeystore mtlskeystore.MTLSKeystore
+		HAPI         hapi.HAPI
+	}
+
+	// EdgeHostname identifies an edge hostname whose certificate is collected from HAPI.
+	EdgeHostname struct {
+		DNSZone    string
+		RecordName string
+	}
+
+	// CollectRequest is used to call Collect.
+	CollectRequest struct {
+		// ContractIDs are the contracts whose CPS enrollments are collected
+		ContractIDs []string
+		// EdgeHostnames are the edge hostnames whose certificates are collected from HAPI
+		EdgeHostnames []EdgeHostname
+		// Threshold is the period before expiry in which certificates are reported as expiring.
+		// It defaults to DefaultThreshold.
+		Threshold time.Duration
+		// Now is the time the inventory is collected at. It defaults to the current time.
+		Now time.Time
+	}
+
+	// Inventory is the result of Collect.
+	Inventory struct {
+		GeneratedAt time.Time     `json:"generatedAt"`
+		Threshold   time.Duration `json:"threshold"`
+		Entries     []Entry       `json:"entries"`
+		// Errors are the sources which could not be read
+		Errors []SourceError `json:"errors,omitempty"`
+	}
+
+	// Entry describes a single certificate.
+	Entry struct {
+		Source Source `json:"source"`
+		// ID identifies the certificate within its source: the enrollment ID for CPS, the certificate ID and version
+		// for mTLS Keystore and the edge hostname for HAPI
+		ID string `json:"id"`
+		// Name is the common name or, for mTLS Keystore client certificates, the certificate name
+		Name string `json:"name"`
+		// Detail is additional source specific information, such as the key algorithm of a CPS certificate or the
+		// status of an mTLS Keystore certificate
+		Detail       string    `json:"detail,omitempty"`
+		Subject      string    `json:"subject,omitempty"`
+		Issuer       string    `json:"issuer,omitempty"`
+		SerialNumber string    `json:"serialNumber,omitempty"`
+		SANs         []string  `json:"sans,omitempty"`
+		KeyType      string    `json:"keyType,omitempty"`
+		KeySize      int       `json:"keySize,omitempty"`
+		NotBefore    time.Time `json:"notBefore,omitempty"`
+		NotAfter     time.Time `json:"notAfter"`
+		DaysLeft     int       `json:"daysLeft"`
+		Status       Status    `json:"status"`
+		ChainIssues  []string  `json:"chainIssues,omitempty"`
+	}
+
+	// SourceError describes a source which could not be read.
+	SourceError struct {
+		Source Source `json:"source"`
+		ID     string `json:"id,omitempty"`
+		Error  string `json:"error"`
+	}
+
+	// Source is the API a certificate was collected from.
+	Source string
+
+	// Status is the expiry status of a certificate.
+	Status string
+)
+
+const (
+	// SourceCPS are certificates deployed to production by CPS enrollments
+	SourceCPS Source = "cps"
+	// SourceMTLSClient are mTLS Keystore client certificate versions
+	SourceMTLSClient Source = "mtls-client"
+	// SourceMTLSCA are mTLS Keystore account CA certificates
+	SourceMTLSCA Source = "mtls-ca"
+	// SourceHAPI are edge hostname certificates reported by HAPI
+	SourceHAPI Source = "hapi"
+
+	// StatusOK is the status of a certificate which does not expire within the threshold
+	StatusOK Status = "OK"
+	// StatusExpiring is the status of a certificate which expires within the threshold
+	StatusExpiring Status = "EXPIRING"
+	// StatusExpired is the status of an expired certificate
+	StatusExpired Status = "EXPIRED"
+
+	// DefaultThreshold is the default period before expiry in which certificates are reported as expiring
+	DefaultThreshold = 30 * 24 * time.Hour
+)
+
+var (
+	// ErrCollect is returned when Collect fails.
+	ErrCollect = errors.New("collect certificate inventory")
+	// ErrStructValidation is returned when given struct validation failed.
+	ErrStructValidation = errors.New("struct validation")
+
+	csvHeader = []string{"source", "id", "name", "detail", "subject", "issuer", "serial_number", "sans", "key_type",
+		"key_size", "not_before", "not_after", "days_left", "status", "chain_issues"}
+)
+
+// Validate validates CollectRequest.
+func (r CollectRequest) Validate() error {
+	return edgegriderr.ParseValidationErrors(validation.Errors{
+		"Threshold": validation.Validate(r.Threshold, validation.Min(time.Duration(0))),
+	})
+}
+
+// Collect collects the certificates of production deployments of the CPS enrollments in the given contracts,
+// mTLS Keystore client certificate versions and account CA certificates, and the certificates of the given edge
+// hostnames from HAPI. PEM encoded certificates are parsed with crypto/x509; HAPI only reports certificate
+// metadata, so its entries have no key or chain details.
+//
+// Sources which cannot be read are reported in Inventory.Errors and do not stop the collection. Entries are
+// sorted by expiry.
+func Collect(ctx context.Context, clients Clients, params CollectRequest) (*Inventory, error) {
+	if err := params.Validate(); err != nil {
+		return nil, fmt.Errorf("%s: %w: %s", ErrCollect, ErrStructValidation, err)
+	}
+	c := collector{
+		inventory: &Inventory{GeneratedAt: params.Now, Threshold: params.Threshold},
+	}
+	if c.inventory.GeneratedAt.IsZero() {
+		c.inventory.GeneratedAt = time.Now()
+	}
+	if c.inventory.Threshold == 0 {
+		c.inventory.Threshold = DefaultThreshold
+	}
+
+	if clients.CPS != nil {
+		c.collectCPS(ctx, clients.CPS, params.ContractIDs)
+	}
+	if clients.MTLSKeystore != nil {
+		c.collectMTLSClientCertificates(ctx, clients.MTLSKeystore)
+		c.collectMTLSCACertificates(ctx, clients.MTLSKeystore)
+	}
+	if clients.HAPI != nil {
+		c.collectHAPI(ctx, clients.HAPI, params.EdgeHostnames)
+	}
+
+	slices.SortStableFunc(c.inventory.Entries, func(a, b Entry) int {
+		return a.NotAfter.Compare(b.NotAfter)
+	})
+	return c.inventory, nil
+}
+
+// Expiring returns the expiring and expired entries.
+func (i *Inventory) Expiring() []Entry {
+	var entries []Entry
+	for _, e := range i.Entries {
+		if e.Status != StatusOK {
+			entries = append(entries, e)
+		}
+	}
+	return entries
+}
+
+// WriteJSON writes the inventory as indented JSON.
+func (i *Inventory) WriteJSON(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(i)
+}
+
+// WriteCSV writes the entries of the inventory as CSV with a header row. Lists are separated with semicolons.
+func (i *Inventory) WriteCSV(w io.Writer) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write(csvHeader); err != nil {
+		return err
+	}
+	for _, e := range i.Entries {
+		keySize := ""
+		if e.KeySize > 0 {
+			keySize = strconv.Itoa(e.KeySize)
+		}
+		notBefore := ""
+		if !e.NotBefore.IsZero() {
+			notBefore = e.NotBefore.UTC().Format(time.RFC3339)
+		}
+		if err := cw.Write([]string{
+			string(e.Source), e.ID, e.Name, e.Detail, e.Subject, e.Issuer, e.SerialNumber, strings.Join(e.SANs, ";"),
+			e.KeyType, keySize, notBefore, e.NotAfter.UTC().Format(time.RFC3339), strconv.Itoa(e.DaysLeft),
+			string(e.Status), strings.Join(e.ChainIssues, ";"),
+		}); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+type collector struct {
+	inventory *Inventory
+}
+
+func (c *collector) collectCPS(ctx context.Context, client cps.CPS, contractIDs []string) {
+	for _, contractID := range contractIDs {
+		enrollments, err := client.ListEnrollments(ctx, cps.ListEnrollmentsRequest{ContractID: contractID})
+		if err != nil {
+			c.fail(SourceCPS, contractID, err)
+			continue
+		}
+		for _, enrollment := range enrollments.Enrollments {
+			id := strconv.Itoa(enrollment.ID)
+			deployment, err := client.GetProductionDeployment(ctx, cps.GetDeploymentRequest{EnrollmentID: enrollment.ID})
+			var apiErr *cps.Error
+			if errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusNotFound {
+				continue
+			}
+			if err != nil {
+				c.fail(SourceCPS, id, err)
+				continue
+			}
+			for _, cert := range append([]cps.DeploymentCertificate{deployment.PrimaryCertificate}, deployment.MultiStackedCertificates...) {
+				if cert.Certificate == "" {
+					continue
+				}
+				c.addPEM(Entry{Source: SourceCPS, ID: id, Detail: cert.KeyAlgorithm}, cert.Certificate, cert.TrustChain, true)
+			}
+		}
+	}
+}
+
+func (c *collector) collectMTLSClientCertificates(ctx context.Context, client mtlskeystore.MTLSKeystore) {
+	certificates, err := client.ListClientCertificates(ctx)
+	if err != nil {
+		c.fail(SourceMTLSClient, "", err)
+		return
+	}
+	for _, certificate := range certificates.Certificates {
+		versions, err := client.ListClientCertificateVersions(ctx, mtlskeystore.ListClientCertificateVersionsRequest{
+			CertificateID: certificate.CertificateID,
+		})
+		if err != nil {
+			c.fail(SourceMTLSClient, strconv.FormatInt(certificate.CertificateID, 10), err)
+			continue
+		}
+		for _, version := range versions.Versions {
+			entry := Entry{
+				Source: SourceMTLSClient,
+				ID:     fmt.Sprintf("%d/%d", certificate.CertificateID, version.Version),
+				Name:   certificate.CertificateName,
+				Detail: version.Status,
+			}
+			if version.CertificateBlock != nil && version.CertificateBlock.Certificate != "" {
+				c.addPEM(entry, version.CertificateBlock.Certificate, version.CertificateBlock.TrustChain, true)
+				continue
+			}
+			if version.ExpiryDate == nil {
+				continue
+			}
+			entry.KeyType = version.KeyAlgorithm
+			if version.Subject != nil {
+				entry.Subject = *version.Subject
+			}
+			if version.Issuer != nil {
+				entry.Issuer = *version.Issuer
+			}
+			if version.IssuedDate != nil {
+				entry.NotBefore = *version.IssuedDate
+			}
+			entry.NotAfter = *version.ExpiryDate
+			c.add(entry)
+		}
+	}
+}
+
+func (c *collector) collectMTLSCACertificates(ctx context.Context, client mtlskeystore.MTLSKeystore) {
+	certificates, err := client.ListAccountCACertificates(ctx, mtlskeystore.ListAccountCACertificatesRequest{})
+	if err != nil {
+		c.fail(SourceMTLSCA, "", err)
+		return
+	}
+	for _, certificate := range certificates.Certificates {
+		entry := Entry{
+			Source: SourceMTLSCA,
+			ID:     fmt.Sprintf("%d/%d", certificate.ID, certificate.Version),
+			Detail: certificate.Status,
+		}
+		if certificate.Certificate != "" {
+			c.addPEM(entry, certificate.Certificate, "", false)
+			continue
+		}
+		entry.Name, entry.Subject, entry.KeyType = certificate.CommonName, certificate.Subject, certificate.KeyAlgorithm
+		entry.NotBefore, entry.NotAfter = certificate.IssuedDate, certificate.ExpiryDate
+		c.add(entry)
+	}
+}
+
+func (c *collector) collectHAPI(ctx context.Context, client hapi.HAPI, edgeHostnames []EdgeHostname) {
+	for _, ehn := range edgeHostnames {
+		id := ehn.RecordName + "." + ehn.DNSZone
+		certificate, err := client.GetCertificate(ctx, hapi.GetCertificateRequest{DNSZone: ehn.DNSZone, RecordName: ehn.RecordName})
+		if err != nil {
+			c.fail(SourceHAPI, id, err)
+			continue
+		}
+		c.add(Entry{
+			Source:       SourceHAPI,
+			ID:           id,
+			Name:         certificate.CommonName,
+			Detail:       certificate.CertificateType,
+			SerialNumber: certificate.SerialNumber,
+			SANs:         certificate.AvailableDomains,
+			NotAfter:     certificate.ExpirationDate,
+		})
+	}
+}
+
+// addPEM adds an entry for the first certificate in certPEM, named after its common name unless the entry already has
+// a name. When checkChain is set, the trust chain is checked as well.
+func (c *collector) addPEM(entry Entry, certPEM, chainPEM string, checkChain bool) {
+	certs, err := parseCertificates(certPEM)
+	if err == nil && len(certs) == 0 {
+		err = errors.New("no PEM encoded certificate found")
+	}
+	if err != nil {
+		c.fail(entry.Source, entry.ID, err)
+		return
+	}
+	chain, err := parseCertificates(chainPEM)
+	if err != nil {
+		c.fail(entry.Source, entry.ID, fmt.Errorf("trust chain: %w", err))
+		return
+	}
+	// certificates following the first one belong to the chain
+	chain = append(slices.Clone(certs[1:]), chain...)
+
+	cert := certs[0]
+	if entry.Name == "" {
+		entry.Name = cert.Subject.CommonName
+	}
+	entry.Subject = cert.Subject.String()
+	entry.Issuer = cert.Issuer.String()
+	entry.SerialNumber = cert.SerialNumber.Text(16)
+	entry.SANs = cert.DNSNames
+	for _, ip := range cert.IPAddresses {
+		entry.SANs = append(entry.SANs, ip.String())
+	}
+	entry.KeyType, entry.KeySize = keyTypeAndSize(cert)
+	entry.NotBefore = cert.NotBefore
+	entry.NotAfter = cert.NotAfter
+	if checkChain {
+		entry.ChainIssues = c.chainIssues(cert, chain)
+	}
+	c.add(entry)
+}
+
+// chainIssues reports certificates of the trust chain which did not issue the previous certificate, and expired
+// or expiring certificates of the chain
+func (c *collector) chainIssues(cert *x509.Certificate, chain []*x509.Certificate) []string {
+	var issues []string
+	if len(chain) == 0 && cert.CheckSignatureFrom(cert) != nil {
+		issues = append(issues, "trust chain is empty")
+	}
+	previous := cert
+	for i, ca := range chain {
+		if err := previous.CheckSignatureFrom(ca); err != nil {
+			issues = append(issues, fmt.Sprintf("trust chain certificate %d (%s) did not issue %s", i+1, ca.Subject, previous.Subject))
+		}
+		switch status, _ := c.status(ca.NotAfter); status {
+		case StatusExpired:
+			issues = append(issues, fmt.Sprintf("trust chain certificate %d (%s) expired on %s", i+1, ca.Subject, ca.NotAfter.UTC().Format(time.RFC3339)))
+		case StatusExpiring:
+			issues = append(issues, fmt.Sprintf("trust chain certificate %d (%s) expires on %s", i+1, ca.Subject, ca.NotAfter.UTC().Format(time.RFC3339)))
+		}
+		previous = ca
+	}
+	return issues
+}
+
+func (c *collector) add(entry Entry) {
+	entry.Status, entry.DaysLeft = c.status(entry.NotAfter)
+	c.inventory.Entries = append(c.inventory.Entries, entry)
+}
+
+func (c *collector) status(notAfter time.Time) (Status, int) {
+	left := notAfter.Sub(c.inventory.GeneratedAt)
+	days := int(math.Floor(left.Hours() / 24))
+	switch {
+	case left <= 0:
+		return StatusExpired, days
+	case left < c.inventory.Threshold:
+		return StatusExpiring, days
+	default:
+		return StatusOK, days
+	}
+}
+
+func (c *collector) fail(source Source, id string, err error) {
+	c.inventory.Errors = append(c.inventory.Errors, SourceError{Source: source, ID: id, Error: err.Error()})
+}
+
+// parseCertificates parses all PEM encoded certificates in s
+func parseCertificates(s string) ([]*x509.Certificate, error) {
+	var certs []*x509.Certificate
+	rest := []byte(s)
+	for {
+		var block *pem.Block
+		block, rest = pem.Decode(rest)
+		if block == nil {
+			return certs, nil
+		}
+		if block.Type != "CERTIFICATE" {
+			continue
+		}
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return nil, err
+		}
+		certs = append(certs, cert)
+	}
+}
+
+func keyTypeAndSize(cert *x509.Certificate) (string, int) {
+	switch key := cert.PublicKey.(type) {
+	case *rsa.PublicKey:
+		return "RSA", key.N.BitLen()
+	case *ecdsa.PublicKey:
+		return "ECDSA", key.Curve.Params().BitSize
+	case ed25519.PublicKey:
+		return "Ed25519", 256
+	default:
+		return cert.PublicKeyAlgorithm.String(), 0
+	}
+}
@@ -0,0 +1,315 @@
+package certinventory
+
+import (
+	"bytes"
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/csv"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"math/big"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/akamai/AkamaiOPEN-edgegrid-golang/v11/pkg/cps"
+	"github.com/akamai/AkamaiOPEN-edgegrid-golang/v11/pkg/hapi"
+	"github.com/akamai/AkamaiOPEN-edgegrid-golang/v11/pkg/mtlskeystore"
+	"github.com/akamai/AkamaiOPEN-edgegrid-golang/v11/pkg/ptr"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+type testCertificate struct {
+	cert *x509.Certificate
+	key  crypto.Signer
+}
+
+var testNow = time.Date(2025, 6, 1, 0, 0, 0, 0, time.UTC)
+
+func newTestKey(t *testing.T) *ecdsa.PrivateKey {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	return key
+}
+
+// issueTestCertificate issues a certificate for key, self-signed when issuer is nil
+func issueTestCertificate(t *testing.T, issuer *testCertificate, key crypto.Signer, cn string, notAfter time.Time, dnsNames ...string) *testCertificate {
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(int64(len(cn))),
+		Subject:               pkix.Name{CommonName: cn},
+		NotBefore:             testNow.Add(-24 * time.Hour),
+		NotAfter:              notAfter,
+		DNSNames:              dnsNames,
+		IsCA:                  issuer == nil || len(dnsNames) == 0,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+	}
+	parent, signer := template, key
+	if issuer != nil {
+		parent, signer = issuer.cert, issuer.key
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, parent, key.Public(), signer)
+	require.NoError(t, err)
+	cert, err := x509.ParseCertificate(der)
+	require.NoError(t, err)
+	return &testCertificate{cert: cert, key: key}
+}
+
+func pemCertificates(certs ...*testCertificate) string {
+	var s string
+	for _, c := range certs {
+		s += string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: c.cert.Raw}))
+	}
+	return s
+}
+
+func TestCollect(t *testing.T) {
+	year := testNow.Add(365 * 24 * time.Hour)
+	root := issueTestCertificate(t, nil, newTestKey(t), "Test Root", year.Add(time.Hour))
+	intermediate := issueTestCertificate(t, root, newTestKey(t), "Test Intermediate", year.Add(time.Hour))
+	otherRoot := issueTestCertificate(t, nil, newTestKey(t), "Other Root", testNow.Add(-time.Hour))
+
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	rsaLeaf := issueTestCertificate(t, intermediate, rsaKey, "www.example.com", year, "www.example.com", "example.com")
+	ecdsaLeaf := issueTestCertificate(t, intermediate, newTestKey(t), "www.example.com", testNow.Add(10*24*time.Hour), "www.example.com")
+	clientCert := issueTestCertificate(t, root, newTestKey(t), "client", testNow.Add(-48*time.Hour), "client.example.com")
+
+	mockCPS := func(m *cps.Mock) {
+		m.On("ListEnrollments", mock.Anything, cps.ListEnrollmentsRequest{ContractID: "C-1"}).
+			Return(&cps.ListEnrollmentsResponse{Enrollments: []cps.Enrollment{{ID: 1}, {ID: 2}}}, nil).Once()
+		m.On("GetProductionDeployment", mock.Anything, cps.GetDeploymentRequest{EnrollmentID: 1}).
+			Return(&cps.GetProductionDeploymentResponse{
+				PrimaryCertificate: cps.DeploymentCertificate{
+					Certificate:  pemCertificates(rsaLeaf),
+					TrustChain:   pemCertificates(intermediate),
+					KeyAlgorithm: "RSA",
+				},
+				MultiStackedCertificates: []cps.DeploymentCertificate{{
+					Certificate:  pemCertificates(ecdsaLeaf),
+					TrustChain:   pemCertificates(otherRoot),
+					KeyAlgorithm: "ECDSA",
+				}},
+			}, nil).Once()
+		m.On("GetProductionDeployment", mock.Anything, cps.GetDeploymentRequest{EnrollmentID: 2}).
+			Return(nil, &cps.Error{StatusCode: http.StatusNotFound}).Once()
+	}
+	mockMTLS := func(m *mtlskeystore.Mock) {
+		m.On("ListClientCertificates", mock.Anything).
+			Return(&mtlskeystore.ListClientCertificatesResponse{Certificates: []mtlskeystore.Certificate{
+				{CertificateID: 7, CertificateName: "my-client"},
+			}}, nil).Once()
+		m.On("ListClientCertificateVersions", mock.Anything, mtlskeystore.ListClientCertificateVersionsRequest{CertificateID: 7}).
+			Return(&mtlskeystore.ListClientCertificateVersionsResponse{Versions: []mtlskeystore.ClientCertificateVersion{
+				{Version: 1, Status: "DEPLOYED", CertificateBlock: &mtlskeystore.CertificateBlock{
+					Certificate: pemCertificates(clientCert),
+					TrustChain:  pemCertificates(root),
+				}},
+				{Version: 2, Status: "DEPLOYMENT_PENDING", KeyAlgorithm: "ECDSA", ExpiryDate: ptr.To(year.Add(24 * time.Hour)),
+					Subject: ptr.To("CN=my-client")},
+				{Version: 3, Status: "AWAITING_SIGNED_CERTIFICATE"},
+			}}, nil).Once()
+		m.On("ListAccountCACertificates", mock.Anything, mtlskeystore.ListAccountCACertificatesRequest{}).
+			Return(&mtlskeystore.ListAccountCACertificatesResponse{Certificates: []mtlskeystore.AccountCACertificate{
+				{ID: 3, Version: 1, Status: "CURRENT", Certificate: pemCertificates(root)},
+			}}, nil).Once()
+	}
+	mockHAPI := func(m *hapi.Mock) {
+		m.On("GetCertificate", mock.Anything, hapi.GetCertificateRequest{DNSZone: "edgekey.net", RecordName: "www.example.com"}).
+			Return(&hapi.GetCertificateResponse{
+				CommonName:       "www.example.com",
+				CertificateType:  "THIRD_PARTY",
+				SerialNumber:     "0a",
+				AvailableDomains: []string{"www.example.com"},
+				ExpirationDate:   testNow.Add(20 * 24 * time.Hour),
+			}, nil).Once()
+	}
+
+	tests := map[string]struct {
+		params          CollectRequest
+		mockCPS         func(*cps.Mock)
+		mockMTLS        func(*mtlskeystore.Mock)
+		mockHAPI        func(*hapi.Mock)
+		expectedEntries []Entry
+		expectedErrors  []SourceError
+		withError       error
+	}{
+		"all sources": {
+			params: CollectRequest{
+				ContractIDs:   []string{"C-1"},
+				EdgeHostnames: []EdgeHostname{{DNSZone: "edgekey.net", RecordName: "www.example.com"}},
+				Now:           testNow,
+			},
+			mockCPS:  mockCPS,
+			mockMTLS: mockMTLS,
+			mockHAPI: mockHAPI,
+			expectedEntries: []Entry{
+				{
+					Source: SourceMTLSClient, ID: "7/1", Name: "my-client", Detail: "DEPLOYED",
+					Subject: "CN=client", Issuer: "CN=Test Root", SerialNumber: "6", SANs: []string{"client.example.com"},
+					KeyType: "ECDSA", KeySize: 256, NotBefore: testNow.Add(-24 * time.Hour), NotAfter: testNow.Add(-48 * time.Hour),
+					DaysLeft: -2, Status: StatusExpired,
+				},
+				{
+					Source: SourceCPS, ID: "1", Name: "www.example.com", Detail: "ECDSA",
+					Subject: "CN=www.example.com", Issuer: "CN=Test Intermediate", SerialNumber: "f", SANs: []string{"www.example.com"},
+					KeyType: "ECDSA", KeySize: 256, NotBefore: testNow.Add(-24 * time.Hour), NotAfter: testNow.Add(10 * 24 * time.Hour),
+					DaysLeft: 10, Status: StatusExpiring,
+					ChainIssues: []string{
+						"trust chain certificate 1 (CN=Other Root) did not issue CN=www.example.com",
+						"trust chain certificate 1 (CN=Other Root) expired on 2025-05-31T23:00:00Z",
+					},
+				},
+				{
+					Source: SourceHAPI, ID: "www.example.com.edgekey.net", Name: "www.example.com", Detail: "THIRD_PARTY",
+					SerialNumber: "0a", SANs: []string{"www.example.com"}, NotAfter: testNow.Add(20 * 24 * time.Hour),
+					DaysLeft: 20, Status: StatusExpiring,
+				},
+				{
+					Source: SourceCPS, ID: "1", Name: "www.example.com", Detail: "RSA",
+					Subject: "CN=www.example.com", Issuer: "CN=Test Intermediate", SerialNumber: "f",
+					SANs: []string{"www.example.com", "example.com"}, KeyType: "RSA", KeySize: 2048,
+					NotBefore: testNow.Add(-24 * time.Hour), NotAfter: year, DaysLeft: 365, Status: StatusOK,
+				},
+				{
+					Source: SourceMTLSCA, ID: "3/1", Name: "Test Root", Detail: "CURRENT",
+					Subject: "CN=Test Root", Issuer: "CN=Test Root", SerialNumber: "9", KeyType: "ECDSA", KeySize: 256,
+					NotBefore: testNow.Add(-24 * time.Hour), NotAfter: year.Add(time.Hour), DaysLeft: 365, Status: StatusOK,
+				},
+				{
+					Source: SourceMTLSClient, ID: "7/2", Name: "my-client", Detail: "DEPLOYMENT_PENDING",
+					Subject: "CN=my-client", KeyType: "ECDSA", NotAfter: year.Add(24 * time.Hour), DaysLeft: 366, Status: StatusOK,
+				},
+			},
+		},
+		"custom threshold": {
+			params: CollectRequest{
+				EdgeHostnames: []EdgeHostname{{DNSZone: "edgekey.net", RecordName: "www.example.com"}},
+				Threshold:     7 * 24 * time.Hour,
+				Now:           testNow,
+			},
+			mockHAPI: mockHAPI,
+			expectedEntries: []Entry{
+				{
+					Source: SourceHAPI, ID: "www.example.com.edgekey.net", Name: "www.example.com", Detail: "THIRD_PARTY",
+					SerialNumber: "0a", SANs: []string{"www.example.com"}, NotAfter: testNow.Add(20 * 24 * time.Hour),
+					DaysLeft: 20, Status: StatusOK,
+				},
+			},
+		},
+		"failing sources are reported": {
+			params: CollectRequest{
+				ContractIDs:   []string{"C-1"},
+				EdgeHostnames: []EdgeHostname{{DNSZone: "edgekey.net", RecordName: "www.example.com"}},
+				Now:           testNow,
+			},
+			mockCPS: func(m *cps.Mock) {
+				m.On("ListEnrollments", mock.Anything, cps.ListEnrollmentsRequest{ContractID: "C-1"}).
+					Return(&cps.ListEnrollmentsResponse{Enrollments: []cps.Enrollment{{ID: 1}}}, nil).Once()
+				m.On("GetProductionDeployment", mock.Anything, cps.GetDeploymentRequest{EnrollmentID: 1}).
+					Return(&cps.GetProductionDeploymentResponse{
+						PrimaryCertificate: cps.DeploymentCertificate{Certificate: "invalid"},
+					}, nil).Once()
+			},
+			mockMTLS: func(m *mtlskeystore.Mock) {
+				m.On("ListClientCertificates", mock.Anything).Return(nil, errors.New("oops")).Once()
+				m.On("ListAccountCACertificates", mock.Anything, mtlskeystore.ListAccountCACertificatesRequest{}).
+					Return(nil, errors.New("oops")).Once()
+			},
+			mockHAPI: func(m *hapi.Mock) {
+				m.On("GetCertificate", mock.Anything, mock.Anything).Return(nil, errors.New("oops")).Once()
+			},
+			expectedErrors: []SourceError{
+				{Source: SourceCPS, ID: "1", Error: "no PEM encoded certificate found"},
+				{Source: SourceMTLSClient, Error: "oops"},
+				{Source: SourceMTLSCA, Error: "oops"},
+				{Source: SourceHAPI, ID: "www.example.com.edgekey.net", Error: "oops"},
+			},
+		},
+		"invalid threshold": {
+			params:    CollectRequest{Threshold: -time.Hour},
+			withError: ErrStructValidation,
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			var clients Clients
+			cpsMock, mtlsMock, hapiMock := &cps.Mock{}, &mtlskeystore.Mock{}, &hapi.Mock{}
+			if tc.mockCPS != nil {
+				tc.mockCPS(cpsMock)
+				clients.CPS = cpsMock
+			}
+			if tc.mockMTLS != nil {
+				tc.mockMTLS(mtlsMock)
+				clients.MTLSKeystore = mtlsMock
+			}
+			if tc.mockHAPI != nil {
+				tc.mockHAPI(hapiMock)
+				clients.HAPI = hapiMock
+			}
+
+			inventory, err := Collect(context.Background(), clients, tc.params)
+			if tc.withError != nil {
+				assert.True(t, errors.Is(err, tc.withError), "want: %s; got: %s", tc.withError, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, testNow, inventory.GeneratedAt)
+			assert.Equal(t, tc.expectedEntries, inventory.Entries)
+			assert.Equal(t, tc.expectedErrors, inventory.Errors)
+			cpsMock.AssertExpectations(t)
+			mtlsMock.AssertExpectations(t)
+			hapiMock.AssertExpectations(t)
+		})
+	}
+}
+
+func TestInventoryOutput(t *testing.T) {
+	inventory := &Inventory{
+		GeneratedAt: testNow,
+		Threshold:   DefaultThreshold,
+		Entries: []Entry{
+			{
+				Source: SourceCPS, ID: "1", Name: "www.example.com", SANs: []string{"www.example.com", "example.com"},
+				KeyType: "RSA", KeySize: 2048, NotBefore: testNow.Add(-24 * time.Hour), NotAfter: testNow.Add(10 * 24 * time.Hour),
+				DaysLeft: 10, Status: StatusExpiring, ChainIssues: []string{"a", "b"},
+			},
+			{
+				Source: SourceHAPI, ID: "www.example.com.edgekey.net", Name: "www.example.com",
+				NotAfter: testNow.Add(100 * 24 * time.Hour), DaysLeft: 100, Status: StatusOK,
+			},
+		},
+	}
+
+	assert.Equal(t, inventory.Entries[:1], inventory.Expiring())
+
+	var csvOut bytes.Buffer
+	require.NoError(t, inventory.WriteCSV(&csvOut))
+	records, err := csv.NewReader(&csvOut).ReadAll()
+	require.NoError(t, err)
+	assert.Equal(t, [][]string{
+		csvHeader,
+		{"cps", "1", "www.example.com", "", "", "", "", "www.example.com;example.com", "RSA", "2048",
+			"2025-05-31T00:00:00Z", "2025-06-11T00:00:00Z", "10", "EXPIRING", "a;b"},
+		{"hapi", "www.example.com.edgekey.net", "www.example.com", "", "", "", "", "", "", "",
+			"", "2025-09-09T00:00:00Z", "100", "OK", ""},
+	}, records)
+
+	var jsonOut bytes.Buffer
+	require.NoError(t, inventory.WriteJSON(&jsonOut))
+	var decoded Inventory
+	require.NoError(t, json.Unmarshal(jsonOut.Bytes(), &decoded))
+	assert.Equal(t, inventory.Entries[0].ChainIssues, decoded.Entries[0].ChainIssues)
+	assert.Equal(t, inventory.Entries[1].NotAfter, decoded.Entries[1].NotAfter.UTC())
+	assert.Equal(t, StatusExpiring, decoded.Entries[0].Status)
+}
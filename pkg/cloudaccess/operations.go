@@ -0,0 +1,58 @@
+package cloudaccess
+
+import (
+	"context"
+	"strconv"
+
+	"github.com/akamai/AkamaiOPEN-edgegrid-golang/v11/pkg/operation"
+)
+
+// AccessKeyOperation returns an Operation tracking the request to create an access key
+func AccessKeyOperation(client CloudAccess, requestID int64) *operation.Operation[*GetAccessKeyStatusResponse] {
+	return operation.New(strconv.FormatInt(requestID, 10), func(ctx context.Context) (operation.Status[*GetAccessKeyStatusResponse], error) {
+		resp, err := client.GetAccessKeyStatus(ctx, GetAccessKeyStatusRequest{RequestID: requestID})
+		if err != nil {
+			return operation.Status[*GetAccessKeyStatusResponse]{}, err
+		}
+		return operation.Status[*GetAccessKeyStatusResponse]{State: processingState(resp.ProcessingStatus), Value: resp}, nil
+	})
+}
+
+// AccessKeyVersionOperation returns an Operation tracking the request to create an access key version
+func AccessKeyVersionOperation(client CloudAccess, requestID int64) *operation.Operation[*GetAccessKeyVersionStatusResponse] {
+	return operation.New(strconv.FormatInt(requestID, 10), func(ctx context.Context) (operation.Status[*GetAccessKeyVersionStatusResponse], error) {
+		resp, err := client.GetAccessKeyVersionStatus(ctx, GetAccessKeyVersionStatusRequest{RequestID: requestID})
+		if err != nil {
+			return operation.Status[*GetAccessKeyVersionStatusResponse]{}, err
+		}
+		return operation.Status[*GetAccessKeyVersionStatusResponse]{State: processingState(resp.ProcessingStatus), Value: resp}, nil
+	})
+}
+
+// PropertiesLookupOperation returns an Operation tracking an asynchronous properties lookup
+func PropertiesLookupOperation(client CloudAccess, lookupID int64) *operation.Operation[*PerformAsyncPropertiesLookupResponse] {
+	return operation.New(strconv.FormatInt(lookupID, 10), func(ctx context.Context) (operation.Status[*PerformAsyncPropertiesLookupResponse], error) {
+		resp, err := client.PerformAsyncPropertiesLookup(ctx, PerformAsyncPropertiesLookupRequest{LookupID: lookupID})
+		if err != nil {
+			return operation.Status[*PerformAsyncPropertiesLookupResponse]{}, err
+		}
+		state := operation.StatePending
+		switch resp.LookupStatus {
+		case LookupComplete:
+			state = operation.StateSucceeded
+		case LookupError:
+			state = operation.StateFailed
+		}
+		return operation.Status[*PerformAsyncPropertiesLookupResponse]{State: state, Value: resp}, nil
+	})
+}
+
+func processingState(status ProcessingType) operation.State {
+	switch status {
+	case ProcessingDone:
+		return operation.StateSucceeded
+	case ProcessingFailed:
+		return operation.StateFailed
+	}
+	return operation.StatePending
+}
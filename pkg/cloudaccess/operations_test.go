@@ -0,0 +1,74 @@
+package cloudaccess
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/akamai/AkamaiOPEN-edgegrid-golang/v11/pkg/operation"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAccessKeyOperation(t *testing.T) {
+	tests := map[string]struct {
+		statuses  []ProcessingType
+		withError error
+	}{
+		"done": {
+			statuses: []ProcessingType{ProcessingInProgress, ProcessingDone},
+		},
+		"failed": {
+			statuses:  []ProcessingType{ProcessingInProgress, ProcessingFailed},
+			withError: operation.ErrOperationFailed,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			m := &Mock{}
+			for _, status := range test.statuses {
+				m.On("GetAccessKeyStatus", mock.Anything, GetAccessKeyStatusRequest{RequestID: 1}).
+					Return(&GetAccessKeyStatusResponse{ProcessingStatus: status, RequestID: 1}, nil).Once()
+			}
+			op := AccessKeyOperation(m, 1)
+			op.Backoff = operation.ConstantBackoff(time.Millisecond)
+			status, err := op.Wait(context.Background())
+			m.AssertExpectations(t)
+			assert.Equal(t, "1", op.ID)
+			assert.Equal(t, test.statuses[len(test.statuses)-1], status.Value.ProcessingStatus)
+			if test.withError != nil {
+				assert.True(t, errors.Is(err, test.withError), "want: %s; got: %s", test.withError, err)
+				return
+			}
+			require.NoError(t, err)
+		})
+	}
+}
+
+func TestPropertiesLookupOperation(t *testing.T) {
+	tests := map[string]struct {
+		status   LookupStatus
+		expected operation.State
+	}{
+		"submitted":   {status: LookupSubmitted, expected: operation.StatePending},
+		"pending":     {status: LookupPending, expected: operation.StatePending},
+		"in progress": {status: LookupInProgress, expected: operation.StatePending},
+		"complete":    {status: LookupComplete, expected: operation.StateSucceeded},
+		"error":       {status: LookupError, expected: operation.StateFailed},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			m := &Mock{}
+			m.On("PerformAsyncPropertiesLookup", mock.Anything, PerformAsyncPropertiesLookupRequest{LookupID: 2}).
+				Return(&PerformAsyncPropertiesLookupResponse{LookupID: 2, LookupStatus: test.status}, nil).Once()
+			status, err := PropertiesLookupOperation(m, 2).Poll(context.Background())
+			m.AssertExpectations(t)
+			require.NoError(t, err)
+			assert.Equal(t, test.expected, status.State)
+		})
+	}
+}
@@ -0,0 +1,243 @@
+package cloudaccess
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/akamai/AkamaiOPEN-edgegrid-golang/v11/pkg/edgegriderr"
+	"github.com/akamai/AkamaiOPEN-edgegrid-golang/v11/pkg/operation"
+	validation "github.com/go-ozzo/ozzo-validation/v4"
+)
+
+type (
+	// RotateAccessKeyRequest is used to rotate an access key with RotateAccessKey
+	RotateAccessKeyRequest struct {
+		// AccessKeyUID is the identifier of the rotated access key
+		AccessKeyUID int64
+
+		// Credentials are the new cloud credentials of the access key
+		Credentials Credentials
+
+		// DeleteOldVersion deletes the previously active version once the new version is created,
+		// unless an active property version still references it
+		DeleteOldVersion bool
+
+		// PollInterval is the interval between checks of asynchronous requests. It defaults to the retry
+		// interval returned by the API or DefaultRotationPollInterval when none is returned
+		PollInterval time.Duration
+
+		// OnStep is called with each step of the rotation once it is finished. Optional
+		OnStep func(RotationStep)
+	}
+
+	// RotateAccessKeyResult is the result of RotateAccessKey
+	RotateAccessKeyResult struct {
+		// NewVersion is the created version
+		NewVersion *AccessKeyVersion
+
+		// OldVersion is the version which was active before the rotation. It is nil when the access key had no active version
+		OldVersion *AccessKeyVersion
+
+		// OldVersionProperties are the properties which reference the old version
+		OldVersionProperties []Property
+
+		// OldVersionDeleted is set when the old version was deleted
+		OldVersionDeleted bool
+
+		// Steps is the report of the finished rotation steps
+		Steps []RotationStep
+	}
+
+	// RotationStep reports a finished step of RotateAccessKey
+	RotationStep struct {
+		Step    RotationStepType
+		Message string
+	}
+
+	// RotationStepType is a type of RotationStep
+	RotationStepType string
+)
+
+const (
+	// StepOldVersionFound represents the step in which the active version is found
+	StepOldVersionFound RotationStepType = "OLD_VERSION_FOUND"
+	// StepVersionRequested represents the step in which a new version is requested
+	StepVersionRequested RotationStepType = "VERSION_REQUESTED"
+	// StepVersionCreated represents the step in which the new version is created
+	StepVersionCreated RotationStepType = "VERSION_CREATED"
+	// StepPropertiesLookedUp represents the step in which the properties using the old version are looked up
+	StepPropertiesLookedUp RotationStepType = "PROPERTIES_LOOKED_UP"
+	// StepOldVersionKept represents the step in which the old version is kept
+	StepOldVersionKept RotationStepType = "OLD_VERSION_KEPT"
+	// StepOldVersionDeleted represents the step in which the old version is deleted
+	StepOldVersionDeleted RotationStepType = "OLD_VERSION_DELETED"
+
+	// DefaultRotationPollInterval is the default interval between checks of asynchronous requests
+	DefaultRotationPollInterval = 10 * time.Second
+)
+
+var (
+	// ErrRotateAccessKey is returned when RotateAccessKey fails
+	ErrRotateAccessKey = errors.New("rotate access key")
+	// ErrAccessKeyVersionCreationFailed is returned when the request to create an access key version fails
+	ErrAccessKeyVersionCreationFailed = errors.New("access key version creation failed")
+	// ErrPropertiesLookupFailed is returned when the asynchronous properties lookup fails
+	ErrPropertiesLookupFailed = errors.New("properties lookup failed")
+)
+
+// Validate validates RotateAccessKeyRequest
+func (r RotateAccessKeyRequest) Validate() error {
+	return edgegriderr.ParseValidationErrors(validation.Errors{
+		"AccessKeyUID":         validation.Validate(r.AccessKeyUID, validation.Required),
+		"CloudAccessKeyID":     validation.Validate(r.Credentials.CloudAccessKeyID, validation.Required),
+		"CloudSecretAccessKey": validation.Validate(r.Credentials.CloudSecretAccessKey, validation.Required),
+		"PollInterval":         validation.Validate(r.PollInterval, validation.Min(time.Duration(0))),
+	})
+}
+
+// RotateAccessKey creates a new version of an access key with the given credentials and waits until it is created.
+//
+// The properties using the previously active version are then looked up. With DeleteOldVersion set, the old
+// version is deleted only when no active property version references it. Each finished step is reported to OnStep
+// and recorded in the result, which is also returned alongside errors raised after the new version is requested.
+func RotateAccessKey(ctx context.Context, client CloudAccess, params RotateAccessKeyRequest) (*RotateAccessKeyResult, error) {
+	if err := params.Validate(); err != nil {
+		return nil, fmt.Errorf("%s: %w: %s", ErrRotateAccessKey, ErrStructValidation, err)
+	}
+
+	result := &RotateAccessKeyResult{}
+	report := func(step RotationStepType, format string, args ...any) {
+		s := RotationStep{Step: step, Message: fmt.Sprintf(format, args...)}
+		result.Steps = append(result.Steps, s)
+		if params.OnStep != nil {
+			params.OnStep(s)
+		}
+	}
+
+	versions, err := client.ListAccessKeyVersions(ctx, ListAccessKeyVersionsRequest{AccessKeyUID: params.AccessKeyUID})
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrRotateAccessKey, err)
+	}
+	result.OldVersion = activeVersion(versions.AccessKeyVersions)
+	if result.OldVersion != nil {
+		report(StepOldVersionFound, "version %d is active", result.OldVersion.Version)
+	}
+
+	created, err := client.CreateAccessKeyVersion(ctx, CreateAccessKeyVersionRequest{
+		AccessKeyUID: params.AccessKeyUID,
+		Body: CreateAccessKeyVersionRequestBody{
+			CloudAccessKeyID:     params.Credentials.CloudAccessKeyID,
+			CloudSecretAccessKey: params.Credentials.CloudSecretAccessKey,
+		},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrRotateAccessKey, err)
+	}
+	report(StepVersionRequested, "request %d to create a new version submitted", created.RequestID)
+
+	version, err := waitForAccessKeyVersion(ctx, client, created.RequestID, pollInterval(params.PollInterval, created.RetryAfter))
+	if err != nil {
+		return result, fmt.Errorf("%w: %w", ErrRotateAccessKey, err)
+	}
+	newVersion, err := client.GetAccessKeyVersion(ctx, GetAccessKeyVersionRequest{AccessKeyUID: params.AccessKeyUID, Version: version})
+	if err != nil {
+		return result, fmt.Errorf("%w: %w", ErrRotateAccessKey, err)
+	}
+	result.NewVersion = (*AccessKeyVersion)(newVersion)
+	report(StepVersionCreated, "version %d created", version)
+	if result.OldVersion == nil {
+		return result, nil
+	}
+
+	properties, err := lookupProperties(ctx, client, params.AccessKeyUID, result.OldVersion.Version, params.PollInterval)
+	if err != nil {
+		return result, fmt.Errorf("%w: %w", ErrRotateAccessKey, err)
+	}
+	result.OldVersionProperties = properties
+	var active []string
+	for _, property := range properties {
+		if property.StagingVersion != nil || property.ProductionVersion != nil {
+			active = append(active, property.PropertyName)
+		}
+	}
+	report(StepPropertiesLookedUp, "version %d is used by %d properties, %d of them active", result.OldVersion.Version, len(properties), len(active))
+
+	switch {
+	case !params.DeleteOldVersion:
+		report(StepOldVersionKept, "version %d kept as requested", result.OldVersion.Version)
+		return result, nil
+	case len(active) > 0:
+		report(StepOldVersionKept, "version %d kept as it is used by active properties: %v", result.OldVersion.Version, active)
+		return result, nil
+	}
+
+	if _, err := client.DeleteAccessKeyVersion(ctx, DeleteAccessKeyVersionRequest{
+		AccessKeyUID: params.AccessKeyUID,
+		Version:      result.OldVersion.Version,
+	}); err != nil {
+		return result, fmt.Errorf("%w: %w", ErrRotateAccessKey, err)
+	}
+	result.OldVersionDeleted = true
+	report(StepOldVersionDeleted, "version %d deleted", result.OldVersion.Version)
+	return result, nil
+}
+
+// activeVersion returns the latest active version
+func activeVersion(versions []AccessKeyVersion) *AccessKeyVersion {
+	var active *AccessKeyVersion
+	for i, version := range versions {
+		if version.DeploymentStatus == Active && (active == nil || version.Version > active.Version) {
+			active = &versions[i]
+		}
+	}
+	return active
+}
+
+func waitForAccessKeyVersion(ctx context.Context, client CloudAccess, requestID int64, interval time.Duration) (int64, error) {
+	op := AccessKeyVersionOperation(client, requestID)
+	op.Backoff = operation.ConstantBackoff(interval)
+	status, err := op.Wait(ctx)
+	if errors.Is(err, operation.ErrOperationFailed) {
+		return 0, fmt.Errorf("%w: %w", ErrAccessKeyVersionCreationFailed, err)
+	}
+	if err != nil {
+		return 0, err
+	}
+	if status.Value.AccessKeyVersion == nil {
+		return 0, fmt.Errorf("%w: request %d returned no version", ErrAccessKeyVersionCreationFailed, requestID)
+	}
+	return status.Value.AccessKeyVersion.Version, nil
+}
+
+func lookupProperties(ctx context.Context, client CloudAccess, accessKeyUID, version int64, interval time.Duration) ([]Property, error) {
+	lookup, err := client.GetAsyncPropertiesLookupID(ctx, GetAsyncPropertiesLookupIDRequest{AccessKeyUID: accessKeyUID, Version: version})
+	if err != nil {
+		return nil, err
+	}
+	op := PropertiesLookupOperation(client, lookup.LookupID)
+	// The lookup is not ready before the retry interval, so the first poll is delayed as well
+	interval = pollInterval(interval, lookup.RetryAfter)
+	op.InitialDelay = interval
+	op.Backoff = operation.ConstantBackoff(interval)
+	status, err := op.Wait(ctx)
+	if errors.Is(err, operation.ErrOperationFailed) {
+		return nil, fmt.Errorf("%w: %w", ErrPropertiesLookupFailed, err)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return status.Value.Properties, nil
+}
+
+// pollInterval returns the requested interval, the retry interval in seconds returned by the API or DefaultRotationPollInterval
+func pollInterval(requested time.Duration, retryAfter int64) time.Duration {
+	switch {
+	case requested > 0:
+		return requested
+	case retryAfter > 0:
+		return time.Duration(retryAfter) * time.Second
+	}
+	return DefaultRotationPollInterval
+}
@@ -0,0 +1,198 @@
+package cloudaccess
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/akamai/AkamaiOPEN-edgegrid-golang/v11/pkg/ptr"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRotateAccessKey(t *testing.T) {
+	credentials := Credentials{CloudAccessKeyID: "new-key-id", CloudSecretAccessKey: "new-secret"}
+	v1 := AccessKeyVersion{AccessKeyUID: 1, Version: 1, DeploymentStatus: Active}
+	v2 := AccessKeyVersion{AccessKeyUID: 1, Version: 2, DeploymentStatus: Active}
+	v3 := AccessKeyVersion{AccessKeyUID: 1, Version: 3, DeploymentStatus: PendingActivation}
+	inactive := Property{AccessKeyUID: 1, Version: 2, PropertyID: "prp_1", PropertyName: "inactive"}
+	active := Property{AccessKeyUID: 1, Version: 2, PropertyID: "prp_2", PropertyName: "active", StagingVersion: ptr.To(int64(4))}
+
+	create := func(m *Mock) {
+		m.On("ListAccessKeyVersions", mock.Anything, ListAccessKeyVersionsRequest{AccessKeyUID: 1}).
+			Return(&ListAccessKeyVersionsResponse{AccessKeyVersions: []AccessKeyVersion{v1, v2}}, nil).Once()
+		m.On("CreateAccessKeyVersion", mock.Anything, CreateAccessKeyVersionRequest{
+			AccessKeyUID: 1,
+			Body:         CreateAccessKeyVersionRequestBody{CloudAccessKeyID: "new-key-id", CloudSecretAccessKey: "new-secret"},
+		}).Return(&CreateAccessKeyVersionResponse{RequestID: 10, RetryAfter: 60}, nil).Once()
+		m.On("GetAccessKeyVersionStatus", mock.Anything, GetAccessKeyVersionStatusRequest{RequestID: 10}).
+			Return(&GetAccessKeyVersionStatusResponse{ProcessingStatus: ProcessingInProgress}, nil).Once()
+		m.On("GetAccessKeyVersionStatus", mock.Anything, GetAccessKeyVersionStatusRequest{RequestID: 10}).
+			Return(&GetAccessKeyVersionStatusResponse{ProcessingStatus: ProcessingDone, AccessKeyVersion: &KeyVersion{AccessKeyUID: 1, Version: 3}}, nil).Once()
+		m.On("GetAccessKeyVersion", mock.Anything, GetAccessKeyVersionRequest{AccessKeyUID: 1, Version: 3}).
+			Return(ptr.To(GetAccessKeyVersionResponse(v3)), nil).Once()
+	}
+	lookup := func(properties ...Property) func(*Mock) {
+		return func(m *Mock) {
+			m.On("GetAsyncPropertiesLookupID", mock.Anything, GetAsyncPropertiesLookupIDRequest{AccessKeyUID: 1, Version: 2}).
+				Return(&GetAsyncPropertiesLookupIDResponse{LookupID: 20, RetryAfter: 60}, nil).Once()
+			m.On("PerformAsyncPropertiesLookup", mock.Anything, PerformAsyncPropertiesLookupRequest{LookupID: 20}).
+				Return(&PerformAsyncPropertiesLookupResponse{LookupID: 20, LookupStatus: LookupInProgress}, nil).Once()
+			m.On("PerformAsyncPropertiesLookup", mock.Anything, PerformAsyncPropertiesLookupRequest{LookupID: 20}).
+				Return(&PerformAsyncPropertiesLookupResponse{LookupID: 20, LookupStatus: LookupComplete, Properties: properties}, nil).Once()
+		}
+	}
+
+	tests := map[string]struct {
+		params    RotateAccessKeyRequest
+		init      []func(*Mock)
+		expected  *RotateAccessKeyResult
+		withError []error
+	}{
+		"old version is deleted when no active property uses it": {
+			params: RotateAccessKeyRequest{AccessKeyUID: 1, Credentials: credentials, DeleteOldVersion: true},
+			init: []func(*Mock){create, lookup(inactive), func(m *Mock) {
+				m.On("DeleteAccessKeyVersion", mock.Anything, DeleteAccessKeyVersionRequest{AccessKeyUID: 1, Version: 2}).
+					Return(ptr.To(DeleteAccessKeyVersionResponse(v2)), nil).Once()
+			}},
+			expected: &RotateAccessKeyResult{
+				NewVersion:           &v3,
+				OldVersion:           &v2,
+				OldVersionProperties: []Property{inactive},
+				OldVersionDeleted:    true,
+				Steps: []RotationStep{
+					{Step: StepOldVersionFound, Message: "version 2 is active"},
+					{Step: StepVersionRequested, Message: "request 10 to create a new version submitted"},
+					{Step: StepVersionCreated, Message: "version 3 created"},
+					{Step: StepPropertiesLookedUp, Message: "version 2 is used by 1 properties, 0 of them active"},
+					{Step: StepOldVersionDeleted, Message: "version 2 deleted"},
+				},
+			},
+		},
+		"old version is kept when an active property uses it": {
+			params: RotateAccessKeyRequest{AccessKeyUID: 1, Credentials: credentials, DeleteOldVersion: true},
+			init:   []func(*Mock){create, lookup(inactive, active)},
+			expected: &RotateAccessKeyResult{
+				NewVersion:           &v3,
+				OldVersion:           &v2,
+				OldVersionProperties: []Property{inactive, active},
+				Steps: []RotationStep{
+					{Step: StepOldVersionFound, Message: "version 2 is active"},
+					{Step: StepVersionRequested, Message: "request 10 to create a new version submitted"},
+					{Step: StepVersionCreated, Message: "version 3 created"},
+					{Step: StepPropertiesLookedUp, Message: "version 2 is used by 2 properties, 1 of them active"},
+					{Step: StepOldVersionKept, Message: "version 2 kept as it is used by active properties: [active]"},
+				},
+			},
+		},
+		"old version is kept without DeleteOldVersion": {
+			params: RotateAccessKeyRequest{AccessKeyUID: 1, Credentials: credentials},
+			init:   []func(*Mock){create, lookup()},
+			expected: &RotateAccessKeyResult{
+				NewVersion: &v3,
+				OldVersion: &v2,
+				Steps: []RotationStep{
+					{Step: StepOldVersionFound, Message: "version 2 is active"},
+					{Step: StepVersionRequested, Message: "request 10 to create a new version submitted"},
+					{Step: StepVersionCreated, Message: "version 3 created"},
+					{Step: StepPropertiesLookedUp, Message: "version 2 is used by 0 properties, 0 of them active"},
+					{Step: StepOldVersionKept, Message: "version 2 kept as requested"},
+				},
+			},
+		},
+		"version creation failed": {
+			params: RotateAccessKeyRequest{AccessKeyUID: 1, Credentials: credentials, DeleteOldVersion: true},
+			init: []func(*Mock){func(m *Mock) {
+				m.On("ListAccessKeyVersions", mock.Anything, mock.Anything).Return(&ListAccessKeyVersionsResponse{}, nil).Once()
+				m.On("CreateAccessKeyVersion", mock.Anything, mock.Anything).Return(&CreateAccessKeyVersionResponse{RequestID: 10}, nil).Once()
+				m.On("GetAccessKeyVersionStatus", mock.Anything, mock.Anything).
+					Return(&GetAccessKeyVersionStatusResponse{ProcessingStatus: ProcessingFailed}, nil).Once()
+			}},
+			expected: &RotateAccessKeyResult{
+				Steps: []RotationStep{{Step: StepVersionRequested, Message: "request 10 to create a new version submitted"}},
+			},
+			withError: []error{ErrRotateAccessKey, ErrAccessKeyVersionCreationFailed},
+		},
+		"properties lookup failed": {
+			params: RotateAccessKeyRequest{AccessKeyUID: 1, Credentials: credentials, DeleteOldVersion: true},
+			init: []func(*Mock){create, func(m *Mock) {
+				m.On("GetAsyncPropertiesLookupID", mock.Anything, mock.Anything).Return(&GetAsyncPropertiesLookupIDResponse{LookupID: 20}, nil).Once()
+				m.On("PerformAsyncPropertiesLookup", mock.Anything, mock.Anything).
+					Return(&PerformAsyncPropertiesLookupResponse{LookupID: 20, LookupStatus: LookupError}, nil).Once()
+			}},
+			expected: &RotateAccessKeyResult{
+				NewVersion: &v3,
+				OldVersion: &v2,
+				Steps: []RotationStep{
+					{Step: StepOldVersionFound, Message: "version 2 is active"},
+					{Step: StepVersionRequested, Message: "request 10 to create a new version submitted"},
+					{Step: StepVersionCreated, Message: "version 3 created"},
+				},
+			},
+			withError: []error{ErrRotateAccessKey, ErrPropertiesLookupFailed},
+		},
+		"list versions error": {
+			params: RotateAccessKeyRequest{AccessKeyUID: 1, Credentials: credentials},
+			init: []func(*Mock){func(m *Mock) {
+				m.On("ListAccessKeyVersions", mock.Anything, mock.Anything).Return(nil, ErrListAccessKeyVersions).Once()
+			}},
+			withError: []error{ErrRotateAccessKey, ErrListAccessKeyVersions},
+		},
+		"validation error": {
+			params:    RotateAccessKeyRequest{AccessKeyUID: 1, Credentials: Credentials{CloudAccessKeyID: "new-key-id"}},
+			withError: []error{ErrStructValidation},
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			m := &Mock{}
+			for _, init := range test.init {
+				init(m)
+			}
+			var steps []RotationStep
+			test.params.PollInterval = time.Millisecond
+			test.params.OnStep = func(s RotationStep) { steps = append(steps, s) }
+			result, err := RotateAccessKey(context.Background(), m, test.params)
+			m.AssertExpectations(t)
+			if test.withError != nil {
+				for _, e := range test.withError {
+					assert.True(t, errors.Is(err, e), "want: %s; got: %s", e, err)
+				}
+			} else {
+				require.NoError(t, err)
+			}
+			if test.expected == nil {
+				assert.Nil(t, result)
+				return
+			}
+			assert.Equal(t, test.expected, result)
+			assert.Equal(t, test.expected.Steps, steps)
+		})
+	}
+}
+
+func TestLookupPropertiesInitialDelay(t *testing.T) {
+	var requested time.Time
+	m := &Mock{}
+	m.On("GetAsyncPropertiesLookupID", mock.Anything, GetAsyncPropertiesLookupIDRequest{AccessKeyUID: 1, Version: 2}).
+		Run(func(mock.Arguments) { requested = time.Now() }).
+		Return(&GetAsyncPropertiesLookupIDResponse{LookupID: 20, RetryAfter: 60}, nil).Once()
+	m.On("PerformAsyncPropertiesLookup", mock.Anything, PerformAsyncPropertiesLookupRequest{LookupID: 20}).
+		Run(func(mock.Arguments) {
+			assert.GreaterOrEqual(t, time.Since(requested), 20*time.Millisecond, "lookup polled before the poll interval")
+		}).
+		Return(&PerformAsyncPropertiesLookupResponse{LookupID: 20, LookupStatus: LookupComplete}, nil).Once()
+
+	_, err := lookupProperties(context.Background(), m, 1, 2, 20*time.Millisecond)
+	require.NoError(t, err)
+	m.AssertExpectations(t)
+}
+
+func TestPollInterval(t *testing.T) {
+	assert.Equal(t, time.Second, pollInterval(time.Second, 60))
+	assert.Equal(t, time.Minute, pollInterval(0, 60))
+	assert.Equal(t, DefaultRotationPollInterval, pollInterval(0, 0))
+}
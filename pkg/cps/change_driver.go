@@ -0,0 +1,338 @@
+package cps
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path"
+	"regexp"
+	"strings"
+	"time"
+
+	validation "github.com/go-ozzo/ozzo-validation/v4"
+)
+
+type (
+	// ChangeStepType identifies the input a change is waiting for
+	ChangeStepType string
+
+	// ChangeStep is an input a change is waiting for, decoded from AllowedInput. Only the field matching
+	// the step type is set.
+	ChangeStep struct {
+		Type              ChangeStepType
+		EnrollmentID      int
+		ChangeID          int
+		RequiredToProceed bool
+		Input             AllowedInput
+
+		DVChallenges             *DVArray
+		PreVerificationWarnings  []string
+		PostVerificationWarnings []string
+		ChangeManagementInfo     *ChangeManagementInfoResponse
+		ThirdPartyCSR            *ThirdPartyCSRResponse
+	}
+
+	// ChangeStepHandler handles a change step, usually by calling one of the Acknowledge* or Upload* methods of the client
+	ChangeStepHandler func(ctx context.Context, client CPS, step ChangeStep) error
+
+	// DriveChangeRequest contains parameters for DriveChange
+	DriveChangeRequest struct {
+		EnrollmentID int
+		ChangeID     int
+		// Handlers handle steps which are not acknowledged automatically
+		Handlers map[ChangeStepType]ChangeStepHandler
+		// AutoAcknowledgeWarnings are patterns of pre- and post-verification warnings which are acknowledged
+		// automatically. Warnings are acknowledged only when each of them matches one of the patterns.
+		AutoAcknowledgeWarnings []*regexp.Regexp
+		// AutoAcknowledgeChangeManagement acknowledges the change management step, allowing the certificate
+		// to be deployed to the production network
+		AutoAcknowledgeChangeManagement bool
+		// PollInterval is the initial interval between change status checks. It doubles while the change waits
+		// for CPS, up to MaxPollInterval, and is reset after a step is handled.
+		PollInterval    time.Duration
+		MaxPollInterval time.Duration
+		// OnStatus is called with every fetched change status
+		OnStatus func(*Change)
+	}
+
+	// DriveChangeResult is the result of DriveChange
+	DriveChangeResult struct {
+		StatusInfo *StatusInfo
+		Steps      []HandledChangeStep
+	}
+
+	// HandledChangeStep describes a step handled by DriveChange
+	HandledChangeStep struct {
+		Type ChangeStepType
+		// Status is the change status in which the step was handled
+		Status string
+		// AutoAcknowledged is set when the step was acknowledged by a policy and not by a handler
+		AutoAcknowledged bool
+	}
+)
+
+const (
+	// ChangeStepDVChallenges is a step waiting for domain validation challenges to be completed
+	ChangeStepDVChallenges ChangeStepType = "lets-encrypt-challenges"
+	// ChangeStepPreVerificationWarnings is a step waiting for pre-verification warnings to be acknowledged
+	ChangeStepPreVerificationWarnings ChangeStepType = "pre-verification-warnings"
+	// ChangeStepPostVerificationWarnings is a step waiting for post-verification warnings to be acknowledged
+	ChangeStepPostVerificationWarnings ChangeStepType = "post-verification-warnings"
+	// ChangeStepChangeManagement is a step waiting for the deployment to production to be acknowledged
+	ChangeStepChangeManagement ChangeStepType = "change-management-info"
+	// ChangeStepThirdPartyCSR is a step waiting for a third-party certificate and trust chain to be uploaded
+	ChangeStepThirdPartyCSR ChangeStepType = "third-party-csr"
+
+	// ChangeStateAwaitingInput is the change state in which the change waits for allowed input
+	ChangeStateAwaitingInput = "awaiting-input"
+	// ChangeStatusComplete is the status of a completed change
+	ChangeStatusComplete = "complete"
+
+	// DefaultChangePollInterval is the initial interval between change status checks
+	DefaultChangePollInterval = 30 * time.Second
+	// DefaultMaxChangePollInterval is the maximum interval between change status checks
+	DefaultMaxChangePollInterval = 5 * time.Minute
+)
+
+var (
+	// ErrDriveChange is returned when DriveChange fails
+	ErrDriveChange = errors.New("drive change")
+	// ErrUnhandledChangeStep is returned when a change waits for a step which has no handler
+	ErrUnhandledChangeStep = errors.New("no handler for change step")
+	// ErrChangeFailed is returned when a change reports an error
+	ErrChangeFailed = errors.New("change failed")
+
+	// changeStepTypes maps allowed input types to step types, for inputs whose type differs from the info endpoint
+	changeStepTypes = map[string]ChangeStepType{
+		"third-party-certificate": ChangeStepThirdPartyCSR,
+		"change-management":       ChangeStepChangeManagement,
+	}
+)
+
+// Validate validates DriveChangeRequest
+func (r DriveChangeRequest) Validate() error {
+	return validation.Errors{
+		"EnrollmentID":    validation.Validate(r.EnrollmentID, validation.Required),
+		"ChangeID":        validation.Validate(r.ChangeID, validation.Required),
+		"PollInterval":    validation.Validate(r.PollInterval, validation.Min(time.Duration(0))),
+		"MaxPollInterval": validation.Validate(r.MaxPollInterval, validation.Min(time.Duration(0))),
+	}.Filter()
+}
+
+// DecodeChangeSteps returns the steps a change is waiting for. The step type is taken from the info endpoint of
+// each allowed input, falling back to the input type.
+func DecodeChangeSteps(enrollmentID, changeID int, inputs []AllowedInput) []ChangeStep {
+	steps := make([]ChangeStep, 0, len(inputs))
+	for _, input := range inputs {
+		stepType := ChangeStepType(path.Base(input.Info))
+		if input.Info == "" {
+			stepType = ChangeStepType(input.Type)
+			if t, ok := changeStepTypes[input.Type]; ok {
+				stepType = t
+			}
+		}
+		steps = append(steps, ChangeStep{
+			Type:              stepType,
+			EnrollmentID:      enrollmentID,
+			ChangeID:          changeID,
+			RequiredToProceed: input.RequiredToProceed,
+			Input:             input,
+		})
+	}
+	return steps
+}
+
+// DriveChange watches a change until it completes, handling the steps it waits for. Pre- and post-verification
+// warnings matching AutoAcknowledgeWarnings and, when enabled, change management are acknowledged automatically;
+// other steps are passed to the handler registered for their type. The change is read from CPS on every
+// iteration, so DriveChange can be started, or resumed, in any state of the change.
+//
+// A step is handled at most once per change status. DriveChange returns an error matching ErrUnhandledChangeStep
+// when a required step has no handler, and ErrChangeFailed when the change reports an error.
+func DriveChange(ctx context.Context, client CPS, params DriveChangeRequest) (*DriveChangeResult, error) {
+	if err := params.Validate(); err != nil {
+		return nil, fmt.Errorf("%s: %w: %s", ErrDriveChange, ErrStructValidation, err)
+	}
+
+	initialInterval := params.PollInterval
+	if initialInterval == 0 {
+		initialInterval = DefaultChangePollInterval
+	}
+	maxInterval := params.MaxPollInterval
+	if maxInterval == 0 {
+		maxInterval = max(DefaultMaxChangePollInterval, initialInterval)
+	}
+
+	result := &DriveChangeResult{}
+	handled := make(map[string]bool)
+	interval := initialInterval
+	// This loop is not built on operation.Operation: it acts on the change between polls and
+	// restarts the backoff after each handled step. Use ChangeOperation to only wait for a change.
+	for {
+		change, err := client.GetChangeStatus(ctx, GetChangeStatusRequest{EnrollmentID: params.EnrollmentID, ChangeID: params.ChangeID})
+		if err != nil {
+			return result, fmt.Errorf("%w: %w", ErrDriveChange, err)
+		}
+		if params.OnStatus != nil {
+			params.OnStatus(change)
+		}
+		if change.StatusInfo == nil {
+			change.StatusInfo = &StatusInfo{}
+		}
+		result.StatusInfo = change.StatusInfo
+
+		if change.StatusInfo.Error != nil {
+			return result, fmt.Errorf("%w: %w: %s: %s", ErrDriveChange, ErrChangeFailed,
+				change.StatusInfo.Error.Code, change.StatusInfo.Error.Description)
+		}
+		if change.StatusInfo.Status == ChangeStatusComplete || change.StatusInfo.State == ChangeStatusComplete {
+			return result, nil
+		}
+
+		acted := false
+		for _, step := range DecodeChangeSteps(params.EnrollmentID, params.ChangeID, change.AllowedInput) {
+			key := change.StatusInfo.Status + "/" + string(step.Type)
+			if !step.RequiredToProceed || handled[key] {
+				continue
+			}
+			autoAcknowledged, err := handleChangeStep(ctx, client, params, step)
+			if err != nil {
+				return result, fmt.Errorf("%w: %s: %w", ErrDriveChange, step.Type, err)
+			}
+			handled[key] = true
+			acted = true
+			result.Steps = append(result.Steps, HandledChangeStep{
+				Type:             step.Type,
+				Status:           change.StatusInfo.Status,
+				AutoAcknowledged: autoAcknowledged,
+			})
+		}
+
+		if acted {
+			interval = initialInterval
+		}
+		select {
+		case <-ctx.Done():
+			return result, fmt.Errorf("%w: %w", ErrDriveChange, ctx.Err())
+		case <-time.After(interval):
+		}
+		if !acted {
+			interval = min(interval*2, maxInterval)
+		}
+	}
+}
+
+// AcknowledgeChangeStep acknowledges a DV challenges, pre-verification warnings, post-verification warnings or
+// change management step
+func AcknowledgeChangeStep(ctx context.Context, client CPS, step ChangeStep) error {
+	req := AcknowledgementRequest{
+		Acknowledgement: Acknowledgement{Acknowledgement: AcknowledgementAcknowledge},
+		EnrollmentID:    step.EnrollmentID,
+		ChangeID:        step.ChangeID,
+	}
+	switch step.Type {
+	case ChangeStepDVChallenges:
+		return client.AcknowledgeDVChallenges(ctx, req)
+	case ChangeStepPreVerificationWarnings:
+		return client.AcknowledgePreVerificationWarnings(ctx, req)
+	case ChangeStepPostVerificationWarnings:
+		return client.AcknowledgePostVerificationWarnings(ctx, req)
+	case ChangeStepChangeManagement:
+		return client.AcknowledgeChangeManagement(ctx, req)
+	default:
+		return fmt.Errorf("step %q cannot be acknowledged", step.Type)
+	}
+}
+
+// handleChangeStep fetches the information of a step and handles it, returning whether it was acknowledged automatically
+func handleChangeStep(ctx context.Context, client CPS, params DriveChangeRequest, step ChangeStep) (bool, error) {
+	if err := loadChangeStep(ctx, client, &step); err != nil {
+		return false, err
+	}
+
+	autoAcknowledge := false
+	switch step.Type {
+	case ChangeStepPreVerificationWarnings:
+		autoAcknowledge = warningsMatch(step.PreVerificationWarnings, params.AutoAcknowledgeWarnings)
+	case ChangeStepPostVerificationWarnings:
+		autoAcknowledge = warningsMatch(step.PostVerificationWarnings, params.AutoAcknowledgeWarnings)
+	case ChangeStepChangeManagement:
+		autoAcknowledge = params.AutoAcknowledgeChangeManagement
+	}
+	if autoAcknowledge {
+		return true, AcknowledgeChangeStep(ctx, client, step)
+	}
+
+	handler, ok := params.Handlers[step.Type]
+	if !ok {
+		return false, unhandledStepError(step)
+	}
+	return false, handler(ctx, client, step)
+}
+
+// loadChangeStep sets the typed information of a step
+func loadChangeStep(ctx context.Context, client CPS, step *ChangeStep) error {
+	req := GetChangeRequest{EnrollmentID: step.EnrollmentID, ChangeID: step.ChangeID}
+	var err error
+	switch step.Type {
+	case ChangeStepDVChallenges:
+		step.DVChallenges, err = client.GetChangeLetsEncryptChallenges(ctx, req)
+	case ChangeStepPreVerificationWarnings:
+		var warnings *PreVerificationWarnings
+		if warnings, err = client.GetChangePreVerificationWarnings(ctx, req); err == nil {
+			step.PreVerificationWarnings = splitWarnings(warnings.Warnings)
+		}
+	case ChangeStepPostVerificationWarnings:
+		var warnings *PostVerificationWarnings
+		if warnings, err = client.GetChangePostVerificationWarnings(ctx, req); err == nil {
+			step.PostVerificationWarnings = splitWarnings(warnings.Warnings)
+		}
+	case ChangeStepChangeManagement:
+		step.ChangeManagementInfo, err = client.GetChangeManagementInfo(ctx, req)
+	case ChangeStepThirdPartyCSR:
+		step.ThirdPartyCSR, err = client.GetChangeThirdPartyCSR(ctx, req)
+	}
+	return err
+}
+
+func unhandledStepError(step ChangeStep) error {
+	warnings := step.PreVerificationWarnings
+	if step.Type == ChangeStepPostVerificationWarnings {
+		warnings = step.PostVerificationWarnings
+	}
+	if len(warnings) > 0 {
+		return fmt.Errorf("%w: warnings: %s", ErrUnhandledChangeStep, strings.Join(warnings, "; "))
+	}
+	return ErrUnhandledChangeStep
+}
+
+// splitWarnings splits the newline separated warnings returned by CPS
+func splitWarnings(warnings string) []string {
+	var result []string
+	for _, w := range strings.Split(warnings, "\n") {
+		if w = strings.TrimSpace(w); w != "" {
+			result = append(result, w)
+		}
+	}
+	return result
+}
+
+// warningsMatch reports whether each warning matches one of the patterns
+func warningsMatch(warnings []string, patterns []*regexp.Regexp) bool {
+	if len(patterns) == 0 {
+		return false
+	}
+	for _, w := range warnings {
+		matched := false
+		for _, p := range patterns {
+			if p.MatchString(w) {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,276 @@
+package cps
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDecodeChangeSteps(t *testing.T) {
+	steps := DecodeChangeSteps(1, 2, []AllowedInput{
+		{
+			Type:              "third-party-certificate",
+			RequiredToProceed: true,
+			Info:              "/cps/v2/enrollments/1/changes/2/input/info/third-party-csr",
+			Update:            "/cps/v2/enrollments/1/changes/2/input/update/third-party-cert-and-trust-chain",
+		},
+		{Type: "pre-verification-warnings", Info: "/cps/v2/enrollments/1/changes/2/input/info/pre-verification-warnings"},
+		{Type: "change-management"},
+	})
+
+	assert.Equal(t, []ChangeStep{
+		{
+			Type:              ChangeStepThirdPartyCSR,
+			EnrollmentID:      1,
+			ChangeID:          2,
+			RequiredToProceed: true,
+			Input: AllowedInput{
+				Type:              "third-party-certificate",
+				RequiredToProceed: true,
+				Info:              "/cps/v2/enrollments/1/changes/2/input/info/third-party-csr",
+				Update:            "/cps/v2/enrollments/1/changes/2/input/update/third-party-cert-and-trust-chain",
+			},
+		},
+		{
+			Type:         ChangeStepPreVerificationWarnings,
+			EnrollmentID: 1,
+			ChangeID:     2,
+			Input:        AllowedInput{Type: "pre-verification-warnings", Info: "/cps/v2/enrollments/1/changes/2/input/info/pre-verification-warnings"},
+		},
+		{Type: ChangeStepChangeManagement, EnrollmentID: 1, ChangeID: 2, Input: AllowedInput{Type: "change-management"}},
+	}, steps)
+}
+
+func TestDriveChange(t *testing.T) {
+	statusReq := GetChangeStatusRequest{EnrollmentID: 1, ChangeID: 2}
+	changeReq := GetChangeRequest{EnrollmentID: 1, ChangeID: 2}
+	ack := AcknowledgementRequest{Acknowledgement: Acknowledgement{Acknowledgement: AcknowledgementAcknowledge}, EnrollmentID: 1, ChangeID: 2}
+	awaiting := func(status string, inputType string) *Change {
+		return &Change{
+			StatusInfo: &StatusInfo{State: ChangeStateAwaitingInput, Status: status},
+			AllowedInput: []AllowedInput{{
+				Type:              inputType,
+				RequiredToProceed: true,
+				Info:              fmt.Sprintf("/cps/v2/enrollments/1/changes/2/input/info/%s", inputType),
+			}},
+		}
+	}
+	running := &Change{StatusInfo: &StatusInfo{State: "running", Status: "wait-letsencrypt-cert-issuance"}}
+	complete := &Change{StatusInfo: &StatusInfo{State: "complete", Status: ChangeStatusComplete}}
+	ignoredWarnings := []*regexp.Regexp{regexp.MustCompile(`^CERTIFICATE_ADDED_TO_TRUST_CHAIN`), regexp.MustCompile(`will expire`)}
+
+	tests := map[string]struct {
+		params    DriveChangeRequest
+		init      func(*Mock)
+		expected  *DriveChangeResult
+		handled   map[ChangeStepType]int
+		withError []error
+	}{
+		"full lifecycle": {
+			params: DriveChangeRequest{AutoAcknowledgeWarnings: ignoredWarnings, AutoAcknowledgeChangeManagement: true},
+			init: func(m *Mock) {
+				dv := awaiting("coodinate-domain-validation", "lets-encrypt-challenges")
+				m.On("GetChangeStatus", mock.Anything, statusReq).Return(dv, nil).Twice()
+				m.On("GetChangeLetsEncryptChallenges", mock.Anything, changeReq).
+					Return(&DVArray{DV: []DV{{Domain: "www.example.com", Status: "pending"}}}, nil).Once()
+				m.On("GetChangeStatus", mock.Anything, statusReq).Return(running, nil).Once()
+				m.On("GetChangeStatus", mock.Anything, statusReq).
+					Return(awaiting("wait-review-pre-verification-safety-checks", "pre-verification-warnings"), nil).Once()
+				m.On("GetChangePreVerificationWarnings", mock.Anything, changeReq).Return(&PreVerificationWarnings{
+					Warnings: "CERTIFICATE_ADDED_TO_TRUST_CHAIN: added\nThe certificate will expire in 30 days\n",
+				}, nil).Once()
+				m.On("AcknowledgePreVerificationWarnings", mock.Anything, ack).Return(nil).Once()
+				m.On("GetChangeStatus", mock.Anything, statusReq).
+					Return(awaiting("wait-ack-change-management", "change-management-info"), nil).Once()
+				m.On("GetChangeManagementInfo", mock.Anything, changeReq).Return(&ChangeManagementInfoResponse{}, nil).Once()
+				m.On("AcknowledgeChangeManagement", mock.Anything, ack).Return(nil).Once()
+				m.On("GetChangeStatus", mock.Anything, statusReq).Return(complete, nil).Once()
+			},
+			expected: &DriveChangeResult{
+				StatusInfo: complete.StatusInfo,
+				Steps: []HandledChangeStep{
+					{Type: ChangeStepDVChallenges, Status: "coodinate-domain-validation"},
+					{Type: ChangeStepPreVerificationWarnings, Status: "wait-review-pre-verification-safety-checks", AutoAcknowledged: true},
+					{Type: ChangeStepChangeManagement, Status: "wait-ack-change-management", AutoAcknowledged: true},
+				},
+			},
+			handled: map[ChangeStepType]int{ChangeStepDVChallenges: 1},
+		},
+		"resume with third-party certificate": {
+			init: func(m *Mock) {
+				m.On("GetChangeStatus", mock.Anything, statusReq).Return(awaiting("wait-upload-third-party", "third-party-csr"), nil).Once()
+				m.On("GetChangeThirdPartyCSR", mock.Anything, changeReq).
+					Return(&ThirdPartyCSRResponse{CSRs: []CertSigningRequest{{CSR: "csr", KeyAlgorithm: "RSA"}}}, nil).Once()
+				m.On("GetChangeStatus", mock.Anything, statusReq).Return(complete, nil).Once()
+			},
+			expected: &DriveChangeResult{
+				StatusInfo: complete.StatusInfo,
+				Steps:      []HandledChangeStep{{Type: ChangeStepThirdPartyCSR, Status: "wait-upload-third-party"}},
+			},
+			handled: map[ChangeStepType]int{ChangeStepThirdPartyCSR: 1},
+		},
+		"warnings not matching policy go to handler": {
+			params: DriveChangeRequest{AutoAcknowledgeWarnings: ignoredWarnings},
+			init: func(m *Mock) {
+				m.On("GetChangeStatus", mock.Anything, statusReq).
+					Return(awaiting("wait-review-cert-warning", "post-verification-warnings"), nil).Once()
+				m.On("GetChangePostVerificationWarnings", mock.Anything, changeReq).Return(&PostVerificationWarnings{
+					Warnings: "The certificate will expire in 30 days\nSAN www.example.com is missing",
+				}, nil).Once()
+				m.On("GetChangeStatus", mock.Anything, statusReq).Return(complete, nil).Once()
+			},
+			expected: &DriveChangeResult{
+				StatusInfo: complete.StatusInfo,
+				Steps:      []HandledChangeStep{{Type: ChangeStepPostVerificationWarnings, Status: "wait-review-cert-warning"}},
+			},
+			handled: map[ChangeStepType]int{ChangeStepPostVerificationWarnings: 1},
+		},
+		"no handler": {
+			params: DriveChangeRequest{Handlers: map[ChangeStepType]ChangeStepHandler{}},
+			init: func(m *Mock) {
+				m.On("GetChangeStatus", mock.Anything, statusReq).
+					Return(awaiting("wait-review-pre-verification-safety-checks", "pre-verification-warnings"), nil).Once()
+				m.On("GetChangePreVerificationWarnings", mock.Anything, changeReq).
+					Return(&PreVerificationWarnings{Warnings: "SAN www.example.com is missing"}, nil).Once()
+			},
+			expected: &DriveChangeResult{
+				StatusInfo: &StatusInfo{State: ChangeStateAwaitingInput, Status: "wait-review-pre-verification-safety-checks"},
+			},
+			withError: []error{ErrDriveChange, ErrUnhandledChangeStep},
+		},
+		"change failed": {
+			init: func(m *Mock) {
+				m.On("GetChangeStatus", mock.Anything, statusReq).Return(&Change{StatusInfo: &StatusInfo{
+					State: "error", Error: &StatusInfoError{Code: "CA_REJECTED", Description: "rejected"},
+				}}, nil).Once()
+			},
+			expected: &DriveChangeResult{
+				StatusInfo: &StatusInfo{State: "error", Error: &StatusInfoError{Code: "CA_REJECTED", Description: "rejected"}},
+			},
+			withError: []error{ErrDriveChange, ErrChangeFailed},
+		},
+		"get change status fails": {
+			init: func(m *Mock) {
+				m.On("GetChangeStatus", mock.Anything, statusReq).Return(nil, &Error{StatusCode: http.StatusNotFound}).Once()
+			},
+			expected:  &DriveChangeResult{},
+			withError: []error{ErrDriveChange},
+		},
+		"acknowledge fails": {
+			params: DriveChangeRequest{AutoAcknowledgeChangeManagement: true},
+			init: func(m *Mock) {
+				m.On("GetChangeStatus", mock.Anything, statusReq).
+					Return(awaiting("wait-ack-change-management", "change-management-info"), nil).Once()
+				m.On("GetChangeManagementInfo", mock.Anything, changeReq).Return(&ChangeManagementInfoResponse{}, nil).Once()
+				m.On("AcknowledgeChangeManagement", mock.Anything, ack).Return(ErrAcknowledgeChangeManagement).Once()
+			},
+			expected: &DriveChangeResult{
+				StatusInfo: &StatusInfo{State: ChangeStateAwaitingInput, Status: "wait-ack-change-management"},
+			},
+			withError: []error{ErrDriveChange, ErrAcknowledgeChangeManagement},
+		},
+		"validation error": {
+			params:    DriveChangeRequest{PollInterval: -time.Second},
+			withError: []error{ErrStructValidation},
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			m := &Mock{}
+			calls := make(map[ChangeStepType][]ChangeStep)
+			if test.init != nil {
+				test.init(m)
+			}
+			params := test.params
+			if params.EnrollmentID == 0 && params.PollInterval == 0 {
+				params.EnrollmentID, params.ChangeID = 1, 2
+				params.PollInterval = time.Millisecond
+			}
+			if params.Handlers == nil {
+				params.Handlers = make(map[ChangeStepType]ChangeStepHandler)
+				for _, stepType := range []ChangeStepType{ChangeStepDVChallenges, ChangeStepThirdPartyCSR, ChangeStepPostVerificationWarnings} {
+					params.Handlers[stepType] = func(_ context.Context, _ CPS, step ChangeStep) error {
+						calls[step.Type] = append(calls[step.Type], step)
+						return nil
+					}
+				}
+			}
+
+			result, err := DriveChange(context.Background(), m, params)
+			m.AssertExpectations(t)
+			assert.Equal(t, test.expected, result)
+			if test.withError != nil {
+				for _, e := range test.withError {
+					assert.True(t, errors.Is(err, e), "want: %s; got: %s", e, err)
+				}
+				return
+			}
+			require.NoError(t, err)
+			for stepType, count := range test.handled {
+				require.Len(t, calls[stepType], count)
+			}
+			if steps := calls[ChangeStepDVChallenges]; len(steps) > 0 {
+				assert.Equal(t, "www.example.com", steps[0].DVChallenges.DV[0].Domain)
+			}
+			if steps := calls[ChangeStepThirdPartyCSR]; len(steps) > 0 {
+				assert.Equal(t, "csr", steps[0].ThirdPartyCSR.CSRs[0].CSR)
+			}
+			if steps := calls[ChangeStepPostVerificationWarnings]; len(steps) > 0 {
+				assert.Equal(t, []string{"The certificate will expire in 30 days", "SAN www.example.com is missing"},
+					steps[0].PostVerificationWarnings)
+			}
+		})
+	}
+}
+
+func TestDriveChangeHandlerError(t *testing.T) {
+	m := &Mock{}
+	m.On("GetChangeStatus", mock.Anything, mock.Anything).Return(&Change{
+		StatusInfo:   &StatusInfo{State: ChangeStateAwaitingInput, Status: "coodinate-domain-validation"},
+		AllowedInput: []AllowedInput{{Type: "lets-encrypt-challenges", RequiredToProceed: true}},
+	}, nil).Once()
+	m.On("GetChangeLetsEncryptChallenges", mock.Anything, mock.Anything).Return(&DVArray{}, nil).Once()
+	handlerErr := errors.New("publishing challenges")
+
+	_, err := DriveChange(context.Background(), m, DriveChangeRequest{
+		EnrollmentID: 1,
+		ChangeID:     2,
+		Handlers: map[ChangeStepType]ChangeStepHandler{
+			ChangeStepDVChallenges: func(context.Context, CPS, ChangeStep) error { return handlerErr },
+		},
+	})
+	m.AssertExpectations(t)
+	assert.True(t, errors.Is(err, ErrDriveChange))
+	assert.True(t, errors.Is(err, handlerErr))
+}
+
+func TestDriveChangeBackoff(t *testing.T) {
+	m := &Mock{}
+	m.On("GetChangeStatus", mock.Anything, mock.Anything).
+		Return(&Change{StatusInfo: &StatusInfo{State: "running", Status: "wait-letsencrypt-cert-issuance"}}, nil)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	var polls []time.Time
+	_, err := DriveChange(ctx, m, DriveChangeRequest{
+		EnrollmentID:    1,
+		ChangeID:        2,
+		PollInterval:    5 * time.Millisecond,
+		MaxPollInterval: 20 * time.Millisecond,
+		OnStatus:        func(*Change) { polls = append(polls, time.Now()) },
+	})
+	assert.True(t, errors.Is(err, context.DeadlineExceeded), "got: %s", err)
+
+	require.GreaterOrEqual(t, len(polls), 4)
+	assert.LessOrEqual(t, len(polls), 10)
+	assert.GreaterOrEqual(t, polls[3].Sub(polls[2]), polls[1].Sub(polls[0]))
+}
@@ -0,0 +1,36 @@
+package cps
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+
+	"github.com/akamai/AkamaiOPEN-edgegrid-golang/v11/pkg/operation"
+)
+
+// ChangeOperation returns an Operation tracking a change. A change waiting for allowed input reaches
+// operation.StateAwaitingInput; use DriveChange to act on its steps
+func ChangeOperation(client CPS, enrollmentID, changeID int) *operation.Operation[*Change] {
+	return operation.New(strconv.Itoa(changeID), func(ctx context.Context) (operation.Status[*Change], error) {
+		change, err := client.GetChangeStatus(ctx, GetChangeStatusRequest{EnrollmentID: enrollmentID, ChangeID: changeID})
+		if err != nil {
+			return operation.Status[*Change]{}, err
+		}
+		status := operation.Status[*Change]{State: operation.StatePending, Value: change}
+		info := change.StatusInfo
+		switch {
+		case info == nil:
+		case info.Error != nil:
+			status.State = operation.StateFailed
+			status.Message = fmt.Sprintf("%s: %s", info.Error.Code, info.Error.Description)
+		case info.Status == ChangeStatusComplete || info.State == ChangeStatusComplete:
+			status.State = operation.StateSucceeded
+		case info.State == ChangeStateAwaitingInput:
+			status.State = operation.StateAwaitingInput
+			status.Message = info.Status
+		default:
+			status.Message = info.Status
+		}
+		return status, nil
+	})
+}
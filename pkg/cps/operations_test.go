@@ -0,0 +1,57 @@
+package cps
+
+import (
+	"context"
+	"testing"
+
+	"github.com/akamai/AkamaiOPEN-edgegrid-golang/v11/pkg/operation"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestChangeOperation(t *testing.T) {
+	tests := map[string]struct {
+		statusInfo *StatusInfo
+		expected   operation.State
+		message    string
+	}{
+		"no status info": {
+			expected: operation.StatePending,
+		},
+		"running": {
+			statusInfo: &StatusInfo{State: "running", Status: "wait-letsencrypt-cert-issuance"},
+			expected:   operation.StatePending,
+			message:    "wait-letsencrypt-cert-issuance",
+		},
+		"awaiting input": {
+			statusInfo: &StatusInfo{State: ChangeStateAwaitingInput, Status: "wait-ack-change-management"},
+			expected:   operation.StateAwaitingInput,
+			message:    "wait-ack-change-management",
+		},
+		"complete": {
+			statusInfo: &StatusInfo{State: "complete", Status: ChangeStatusComplete},
+			expected:   operation.StateSucceeded,
+		},
+		"error": {
+			statusInfo: &StatusInfo{State: "error", Error: &StatusInfoError{Code: "CA_REJECTED", Description: "rejected"}},
+			expected:   operation.StateFailed,
+			message:    "CA_REJECTED: rejected",
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			m := &Mock{}
+			m.On("GetChangeStatus", mock.Anything, GetChangeStatusRequest{EnrollmentID: 1, ChangeID: 2}).
+				Return(&Change{StatusInfo: test.statusInfo}, nil).Once()
+			op := ChangeOperation(m, 1, 2)
+			status, err := op.Poll(context.Background())
+			m.AssertExpectations(t)
+			require.NoError(t, err)
+			assert.Equal(t, "2", op.ID)
+			assert.Equal(t, test.expected, status.State)
+			assert.Equal(t, test.message, status.Message)
+		})
+	}
+}
@@ -0,0 +1,253 @@
+package cps
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	validation "github.com/go-ozzo/ozzo-validation/v4"
+)
+
+type (
+	// UpdateSANsRequest contains parameters for AddSANs, RemoveSANs and their previews
+	UpdateSANsRequest struct {
+		EnrollmentID int
+		SANs         []string
+		// AllowCancelPendingChanges allows the update to cancel the pending changes of the enrollment. Without it,
+		// an update of an enrollment with pending changes fails with ErrPendingChanges.
+		AllowCancelPendingChanges bool
+		AllowStagingBypass        *bool
+		DeployNotAfter            string
+		DeployNotBefore           string
+	}
+
+	// SANUpdatePreview describes the effects of adding or removing SANs
+	SANUpdatePreview struct {
+		EnrollmentID   int
+		CN             string
+		ValidationType string
+		// CurrentSANs are the SANs of the enrollment before the update
+		CurrentSANs []string
+		// SANs are the SANs of the enrollment after the update
+		SANs []string
+		// Added and Removed are the SANs which change, ignoring SANs which are already present or missing
+		Added   []string
+		Removed []string
+		// NewCertificate is set when the update changes the SANs, which makes CPS issue a new certificate
+		NewCertificate bool
+		// CancelledPendingChanges are the pending changes of the enrollment which the update cancels
+		CancelledPendingChanges []PendingChange
+		// MaxSANs and MaxWildcardSANs are the SAN limits of the enrollment, taken from DefaultMaxSANs when the
+		// enrollment does not report them. Zero MaxWildcardSANs means wildcard SANs are only limited by MaxSANs.
+		// WildcardsAllowed is not set for validation types which do not allow wildcard SANs.
+		MaxSANs          int
+		MaxWildcardSANs  int
+		WildcardsAllowed bool
+		WildcardSANs     int
+	}
+
+	// UpdateSANsResponse is the result of AddSANs and RemoveSANs
+	UpdateSANsResponse struct {
+		SANUpdatePreview
+		// ChangeID is the change created by the update. It is zero when the SANs did not change.
+		ChangeID int
+	}
+)
+
+var (
+	// ErrUpdateSANs is returned when AddSANs, RemoveSANs or their previews fail
+	ErrUpdateSANs = errors.New("update enrollment SANs")
+	// ErrSANLimitExceeded is returned when an update exceeds the SAN limit of the enrollment
+	ErrSANLimitExceeded = errors.New("SAN limit exceeded")
+	// ErrRemoveCommonName is returned when the common name of the enrollment would be removed from its SANs
+	ErrRemoveCommonName = errors.New("common name cannot be removed from SANs")
+	// ErrPendingChanges is returned when an update would cancel pending changes without AllowCancelPendingChanges
+	ErrPendingChanges = errors.New("update would cancel pending changes")
+
+	// DefaultMaxSANs are the SAN limits per validation type, used when the enrollment does not report its limit
+	DefaultMaxSANs = map[string]int{
+		"dv":          100,
+		"ov":          100,
+		"ev":          25,
+		"third-party": 100,
+	}
+)
+
+// Validate validates UpdateSANsRequest
+func (r UpdateSANsRequest) Validate() error {
+	return validation.Errors{
+		"EnrollmentID": validation.Validate(r.EnrollmentID, validation.Required),
+		"SANs":         validation.Validate(r.SANs, validation.Required, validation.Each(validation.Required)),
+	}.Filter()
+}
+
+// Check returns an error when the update cannot be applied. Pending changes are accepted when allowCancelPendingChanges is set.
+func (p SANUpdatePreview) Check(allowCancelPendingChanges bool) error {
+	var errs []error
+	if p.MaxSANs > 0 && len(p.SANs) > p.MaxSANs {
+		errs = append(errs, fmt.Errorf("%w: %d SANs, %s enrollments allow %d", ErrSANLimitExceeded, len(p.SANs), p.ValidationType, p.MaxSANs))
+	}
+	if p.WildcardSANs > 0 && !p.WildcardsAllowed {
+		errs = append(errs, fmt.Errorf("%w: %s enrollments do not allow wildcard SANs", ErrSANLimitExceeded, p.ValidationType))
+	} else if p.MaxWildcardSANs > 0 && p.WildcardSANs > p.MaxWildcardSANs {
+		errs = append(errs, fmt.Errorf("%w: %d wildcard SANs, the enrollment allows %d", ErrSANLimitExceeded, p.WildcardSANs, p.MaxWildcardSANs))
+	}
+	if p.CN != "" && !slices.Contains(p.SANs, strings.ToLower(p.CN)) && slices.Contains(p.Removed, strings.ToLower(p.CN)) {
+		errs = append(errs, fmt.Errorf("%w: %s", ErrRemoveCommonName, p.CN))
+	}
+	if len(p.CancelledPendingChanges) > 0 && !allowCancelPendingChanges {
+		errs = append(errs, fmt.Errorf("%w: %d pending changes", ErrPendingChanges, len(p.CancelledPendingChanges)))
+	}
+	return errors.Join(errs...)
+}
+
+// PreviewAddSANs returns the effects of adding SANs to an enrollment without updating it
+func PreviewAddSANs(ctx context.Context, client CPS, params UpdateSANsRequest) (*SANUpdatePreview, error) {
+	_, preview, err := previewSANUpdate(ctx, client, params, true)
+	return preview, err
+}
+
+// PreviewRemoveSANs returns the effects of removing SANs from an enrollment without updating it
+func PreviewRemoveSANs(ctx context.Context, client CPS, params UpdateSANsRequest) (*SANUpdatePreview, error) {
+	_, preview, err := previewSANUpdate(ctx, client, params, false)
+	return preview, err
+}
+
+// AddSANs adds SANs to an enrollment and returns the change created by the update. SANs are compared case-insensitively
+// and SANs which are already present are ignored; when no SAN is added, the enrollment is not updated.
+func AddSANs(ctx context.Context, client CPS, params UpdateSANsRequest) (*UpdateSANsResponse, error) {
+	return updateSANs(ctx, client, params, true)
+}
+
+// RemoveSANs removes SANs from an enrollment and returns the change created by the update. SANs which are missing
+// are ignored; when no SAN is removed, the enrollment is not updated. The removed SANs are also removed from
+// the DNS names of the network configuration.
+func RemoveSANs(ctx context.Context, client CPS, params UpdateSANsRequest) (*UpdateSANsResponse, error) {
+	return updateSANs(ctx, client, params, false)
+}
+
+func updateSANs(ctx context.Context, client CPS, params UpdateSANsRequest, add bool) (*UpdateSANsResponse, error) {
+	enrollment, preview, err := previewSANUpdate(ctx, client, params, add)
+	if err != nil {
+		return nil, err
+	}
+	result := &UpdateSANsResponse{SANUpdatePreview: *preview}
+	if !preview.NewCertificate {
+		return result, nil
+	}
+	if err := preview.Check(params.AllowCancelPendingChanges); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrUpdateSANs, err)
+	}
+
+	body := enrollmentRequestBody(enrollment)
+	csr := *body.CSR
+	csr.SANS = preview.SANs
+	body.CSR = &csr
+	if body.NetworkConfiguration != nil && body.NetworkConfiguration.DNSNameSettings != nil && len(preview.Removed) > 0 {
+		networkConfiguration, dnsNameSettings := *body.NetworkConfiguration, *body.NetworkConfiguration.DNSNameSettings
+		dnsNameSettings.DNSNames = slices.DeleteFunc(slices.Clone(dnsNameSettings.DNSNames), func(name string) bool {
+			return slices.Contains(preview.Removed, strings.ToLower(name))
+		})
+		networkConfiguration.DNSNameSettings = &dnsNameSettings
+		body.NetworkConfiguration = &networkConfiguration
+	}
+
+	allowCancelPendingChanges := params.AllowCancelPendingChanges
+	resp, err := client.UpdateEnrollment(ctx, UpdateEnrollmentRequest{
+		EnrollmentRequestBody:     body,
+		EnrollmentID:              params.EnrollmentID,
+		AllowCancelPendingChanges: &allowCancelPendingChanges,
+		AllowStagingBypass:        params.AllowStagingBypass,
+		DeployNotAfter:            params.DeployNotAfter,
+		DeployNotBefore:           params.DeployNotBefore,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrUpdateSANs, err)
+	}
+	if len(resp.Changes) == 0 {
+		return nil, fmt.Errorf("%w: update did not create a change", ErrUpdateSANs)
+	}
+	result.ChangeID, err = GetIDFromLocation(resp.Changes[0])
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w: %s", ErrUpdateSANs, ErrInvalidLocation, err)
+	}
+	return result, nil
+}
+
+func previewSANUpdate(ctx context.Context, client CPS, params UpdateSANsRequest, add bool) (*Enrollment, *SANUpdatePreview, error) {
+	if err := params.Validate(); err != nil {
+		return nil, nil, fmt.Errorf("%s: %w: %s", ErrUpdateSANs, ErrStructValidation, err)
+	}
+	resp, err := client.GetEnrollment(ctx, GetEnrollmentRequest{EnrollmentID: params.EnrollmentID})
+	if err != nil {
+		return nil, nil, fmt.Errorf("%w: %w", ErrUpdateSANs, err)
+	}
+	enrollment := Enrollment(*resp)
+	if enrollment.CSR == nil {
+		return nil, nil, fmt.Errorf("%w: enrollment %d has no CSR", ErrUpdateSANs, params.EnrollmentID)
+	}
+
+	preview := &SANUpdatePreview{
+		EnrollmentID:     params.EnrollmentID,
+		CN:               enrollment.CSR.CN,
+		ValidationType:   enrollment.ValidationType,
+		CurrentSANs:      enrollment.CSR.SANS,
+		MaxSANs:          enrollment.MaxAllowedSanNames,
+		MaxWildcardSANs:  enrollment.MaxAllowedWildcardSanNames,
+		WildcardsAllowed: enrollment.ValidationType != "ev",
+	}
+	if preview.MaxSANs == 0 {
+		preview.MaxSANs = DefaultMaxSANs[enrollment.ValidationType]
+	}
+
+	for _, san := range enrollment.CSR.SANS {
+		if !slices.Contains(preview.SANs, strings.ToLower(san)) {
+			preview.SANs = append(preview.SANs, strings.ToLower(san))
+		}
+	}
+	current := slices.Clone(preview.SANs)
+	for _, san := range params.SANs {
+		san = strings.ToLower(strings.TrimSpace(san))
+		switch {
+		case add && !slices.Contains(preview.SANs, san):
+			preview.SANs = append(preview.SANs, san)
+			preview.Added = append(preview.Added, san)
+		case !add && slices.Contains(preview.SANs, san):
+			preview.SANs = slices.DeleteFunc(preview.SANs, func(s string) bool { return s == san })
+			preview.Removed = append(preview.Removed, san)
+		}
+	}
+	preview.NewCertificate = !slices.Equal(current, preview.SANs)
+	if preview.NewCertificate {
+		preview.CancelledPendingChanges = enrollment.PendingChanges
+	}
+	for _, san := range preview.SANs {
+		if strings.HasPrefix(san, "*.") {
+			preview.WildcardSANs++
+		}
+	}
+	return &enrollment, preview, nil
+}
+
+// enrollmentRequestBody returns the request body which updates an enrollment to its current settings
+func enrollmentRequestBody(e *Enrollment) EnrollmentRequestBody {
+	return EnrollmentRequestBody{
+		AdminContact:                   e.AdminContact,
+		AutoRenewalStartTime:           e.AutoRenewalStartTime,
+		CertificateChainType:           e.CertificateChainType,
+		CertificateType:                e.CertificateType,
+		ChangeManagement:               e.ChangeManagement,
+		CSR:                            e.CSR,
+		EnableMultiStackedCertificates: e.EnableMultiStackedCertificates,
+		NetworkConfiguration:           e.NetworkConfiguration,
+		Org:                            e.Org,
+		OrgID:                          e.OrgID,
+		RA:                             e.RA,
+		SignatureAlgorithm:             e.SignatureAlgorithm,
+		TechContact:                    e.TechContact,
+		ThirdParty:                     e.ThirdParty,
+		ValidationType:                 e.ValidationType,
+	}
+}
@@ -0,0 +1,212 @@
+package cps
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/akamai/AkamaiOPEN-edgegrid-golang/v11/pkg/ptr"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUpdateSANs(t *testing.T) {
+	enrollment := func(validationType string, sans []string, pending ...PendingChange) *GetEnrollmentResponse {
+		return &GetEnrollmentResponse{
+			ID:              1,
+			CertificateType: "san",
+			CSR:             &CSR{CN: "www.example.com", SANS: sans},
+			NetworkConfiguration: &NetworkConfiguration{
+				DNSNameSettings: &DNSNameSettings{DNSNames: []string{"www.example.com", "old.example.com"}},
+			},
+			PendingChanges: pending,
+			RA:             "lets-encrypt",
+			ValidationType: validationType,
+		}
+	}
+	pending := PendingChange{ChangeType: "renewal", Location: "/cps/v2/enrollments/1/changes/5"}
+	updateResponse := &UpdateEnrollmentResponse{
+		ID:         1,
+		Enrollment: "/cps/v2/enrollments/1",
+		Changes:    []string{"/cps/v2/enrollments/1/changes/10"},
+	}
+	manySANs := make([]string, 25)
+	for i := range manySANs {
+		manySANs[i] = string(rune('a'+i)) + ".example.com"
+	}
+
+	tests := map[string]struct {
+		params    UpdateSANsRequest
+		add       bool
+		init      func(*Mock)
+		expected  *UpdateSANsResponse
+		withError []error
+	}{
+		"add SANs": {
+			params: UpdateSANsRequest{EnrollmentID: 1, SANs: []string{"API.example.com", "www.example.com"}, AllowStagingBypass: ptr.To(true)},
+			add:    true,
+			init: func(m *Mock) {
+				m.On("GetEnrollment", mock.Anything, GetEnrollmentRequest{EnrollmentID: 1}).
+					Return(enrollment("dv", []string{"www.example.com"}), nil).Once()
+				m.On("UpdateEnrollment", mock.Anything, mock.MatchedBy(func(r UpdateEnrollmentRequest) bool {
+					return r.EnrollmentID == 1 && !*r.AllowCancelPendingChanges && *r.AllowStagingBypass &&
+						assert.ObjectsAreEqual([]string{"www.example.com", "api.example.com"}, r.CSR.SANS) &&
+						r.CSR.CN == "www.example.com" && r.RA == "lets-encrypt"
+				})).Return(updateResponse, nil).Once()
+			},
+			expected: &UpdateSANsResponse{
+				SANUpdatePreview: SANUpdatePreview{
+					EnrollmentID:     1,
+					CN:               "www.example.com",
+					ValidationType:   "dv",
+					CurrentSANs:      []string{"www.example.com"},
+					SANs:             []string{"www.example.com", "api.example.com"},
+					Added:            []string{"api.example.com"},
+					NewCertificate:   true,
+					MaxSANs:          100,
+					WildcardsAllowed: true,
+				},
+				ChangeID: 10,
+			},
+		},
+		"remove SANs cancelling pending changes": {
+			params: UpdateSANsRequest{EnrollmentID: 1, SANs: []string{"old.example.com", "missing.example.com"}, AllowCancelPendingChanges: true},
+			init: func(m *Mock) {
+				m.On("GetEnrollment", mock.Anything, GetEnrollmentRequest{EnrollmentID: 1}).
+					Return(enrollment("ov", []string{"www.example.com", "Old.example.com"}, pending), nil).Once()
+				m.On("UpdateEnrollment", mock.Anything, mock.MatchedBy(func(r UpdateEnrollmentRequest) bool {
+					return *r.AllowCancelPendingChanges &&
+						assert.ObjectsAreEqual([]string{"www.example.com"}, r.CSR.SANS) &&
+						assert.ObjectsAreEqual([]string{"www.example.com"}, r.NetworkConfiguration.DNSNameSettings.DNSNames)
+				})).Return(updateResponse, nil).Once()
+			},
+			expected: &UpdateSANsResponse{
+				SANUpdatePreview: SANUpdatePreview{
+					EnrollmentID:            1,
+					CN:                      "www.example.com",
+					ValidationType:          "ov",
+					CurrentSANs:             []string{"www.example.com", "Old.example.com"},
+					SANs:                    []string{"www.example.com"},
+					Removed:                 []string{"old.example.com"},
+					NewCertificate:          true,
+					CancelledPendingChanges: []PendingChange{pending},
+					MaxSANs:                 100,
+					WildcardsAllowed:        true,
+				},
+				ChangeID: 10,
+			},
+		},
+		"nothing to change": {
+			params: UpdateSANsRequest{EnrollmentID: 1, SANs: []string{"WWW.example.com"}},
+			add:    true,
+			init: func(m *Mock) {
+				m.On("GetEnrollment", mock.Anything, GetEnrollmentRequest{EnrollmentID: 1}).
+					Return(enrollment("dv", []string{"www.example.com"}, pending), nil).Once()
+			},
+			expected: &UpdateSANsResponse{
+				SANUpdatePreview: SANUpdatePreview{
+					EnrollmentID:     1,
+					CN:               "www.example.com",
+					ValidationType:   "dv",
+					CurrentSANs:      []string{"www.example.com"},
+					SANs:             []string{"www.example.com"},
+					MaxSANs:          100,
+					WildcardsAllowed: true,
+				},
+			},
+		},
+		"pending changes not allowed to be cancelled": {
+			params: UpdateSANsRequest{EnrollmentID: 1, SANs: []string{"api.example.com"}},
+			add:    true,
+			init: func(m *Mock) {
+				m.On("GetEnrollment", mock.Anything, GetEnrollmentRequest{EnrollmentID: 1}).
+					Return(enrollment("dv", []string{"www.example.com"}, pending), nil).Once()
+			},
+			withError: []error{ErrUpdateSANs, ErrPendingChanges},
+		},
+		"SAN limit exceeded": {
+			params: UpdateSANsRequest{EnrollmentID: 1, SANs: []string{"*.example.com"}},
+			add:    true,
+			init: func(m *Mock) {
+				m.On("GetEnrollment", mock.Anything, GetEnrollmentRequest{EnrollmentID: 1}).
+					Return(enrollment("ev", manySANs), nil).Once()
+			},
+			withError: []error{ErrUpdateSANs, ErrSANLimitExceeded},
+		},
+		"common name removed": {
+			params: UpdateSANsRequest{EnrollmentID: 1, SANs: []string{"www.example.com"}},
+			init: func(m *Mock) {
+				m.On("GetEnrollment", mock.Anything, GetEnrollmentRequest{EnrollmentID: 1}).
+					Return(enrollment("dv", []string{"www.example.com", "api.example.com"}), nil).Once()
+			},
+			withError: []error{ErrUpdateSANs, ErrRemoveCommonName},
+		},
+		"update fails": {
+			params: UpdateSANsRequest{EnrollmentID: 1, SANs: []string{"api.example.com"}},
+			add:    true,
+			init: func(m *Mock) {
+				m.On("GetEnrollment", mock.Anything, GetEnrollmentRequest{EnrollmentID: 1}).
+					Return(enrollment("dv", []string{"www.example.com"}), nil).Once()
+				m.On("UpdateEnrollment", mock.Anything, mock.Anything).Return(nil, ErrUpdateEnrollment).Once()
+			},
+			withError: []error{ErrUpdateSANs, ErrUpdateEnrollment},
+		},
+		"validation error": {
+			params:    UpdateSANsRequest{SANs: []string{""}},
+			add:       true,
+			init:      func(*Mock) {},
+			withError: []error{ErrStructValidation},
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			m := &Mock{}
+			test.init(m)
+			update := RemoveSANs
+			if test.add {
+				update = AddSANs
+			}
+			result, err := update(context.Background(), m, test.params)
+			m.AssertExpectations(t)
+			if test.withError != nil {
+				for _, e := range test.withError {
+					assert.True(t, errors.Is(err, e), "want: %s; got: %s", e, err)
+				}
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, test.expected, result)
+		})
+	}
+}
+
+func TestPreviewSANs(t *testing.T) {
+	m := &Mock{}
+	m.On("GetEnrollment", mock.Anything, GetEnrollmentRequest{EnrollmentID: 1}).Return(&GetEnrollmentResponse{
+		CSR:                        &CSR{CN: "www.example.com", SANS: []string{"www.example.com", "*.example.com"}},
+		MaxAllowedSanNames:         2,
+		MaxAllowedWildcardSanNames: 1,
+		PendingChanges:             []PendingChange{{ChangeType: "renewal"}},
+		ValidationType:             "ov",
+	}, nil).Twice()
+
+	preview, err := PreviewAddSANs(context.Background(), m, UpdateSANsRequest{EnrollmentID: 1, SANs: []string{"*.api.example.com"}})
+	require.NoError(t, err)
+	assert.True(t, preview.NewCertificate)
+	assert.Equal(t, []PendingChange{{ChangeType: "renewal"}}, preview.CancelledPendingChanges)
+	assert.Equal(t, 2, preview.MaxSANs)
+	assert.Equal(t, 2, preview.WildcardSANs)
+	err = preview.Check(true)
+	assert.True(t, errors.Is(err, ErrSANLimitExceeded))
+	assert.False(t, errors.Is(err, ErrPendingChanges))
+	assert.True(t, errors.Is(preview.Check(false), ErrPendingChanges))
+
+	preview, err = PreviewRemoveSANs(context.Background(), m, UpdateSANsRequest{EnrollmentID: 1, SANs: []string{"*.example.com"}})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"*.example.com"}, preview.Removed)
+	assert.Equal(t, []string{"www.example.com"}, preview.SANs)
+	assert.NoError(t, preview.Check(true))
+	m.AssertExpectations(t)
+}
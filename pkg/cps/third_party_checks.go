@@ -0,0 +1,309 @@
+package cps
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	validation "github.com/go-ozzo/ozzo-validation/v4"
+)
+
+type (
+	// CheckThirdPartyCertificateRequest contains parameters for CheckThirdPartyCertificate
+	CheckThirdPartyCertificateRequest struct {
+		// CSR is the certificate signing request returned by GetChangeThirdPartyCSR
+		CSR CertSigningRequest
+		// Expected are the names of the enrollment. When set, the CN and SANs of the CSR and of the certificate are checked against it.
+		Expected *CSR
+		// Certificate is the signed certificate and trust chain to be uploaded with UploadThirdPartyCertAndTrustChain
+		Certificate CertificateAndTrustChain
+		// Roots, when set, are used to verify that the trust chain is complete
+		Roots *x509.CertPool
+		// ExpiryWarning is the period before expiry of the certificate or the trust chain which is reported as a warning.
+		// It defaults to DefaultExpiryWarning.
+		ExpiryWarning time.Duration
+		// Now is the time to check validity at. It defaults to the current time.
+		Now time.Time
+	}
+
+	// ThirdPartyCertificateReport is the result of CheckThirdPartyCertificate. The certificate should not be uploaded
+	// when Errors is not empty.
+	ThirdPartyCertificateReport struct {
+		Subject  string
+		NotAfter time.Time
+		Errors   []string
+		Warnings []string
+	}
+)
+
+const (
+	// DefaultExpiryWarning is the default period before expiry reported by CheckThirdPartyCertificate
+	DefaultExpiryWarning = 30 * 24 * time.Hour
+)
+
+var (
+	// ErrCheckThirdPartyCertificate is returned when CheckThirdPartyCertificate fails
+	ErrCheckThirdPartyCertificate = errors.New("check third-party certificate")
+	// ErrInvalidCSR is returned when a certificate signing request cannot be parsed
+	ErrInvalidCSR = errors.New("invalid certificate signing request")
+	// ErrInvalidCertificate is returned when a certificate or trust chain cannot be parsed
+	ErrInvalidCertificate = errors.New("invalid certificate")
+)
+
+// Validate validates CheckThirdPartyCertificateRequest
+func (r CheckThirdPartyCertificateRequest) Validate() error {
+	return validation.Errors{
+		"CSR.CSR":                 validation.Validate(r.CSR.CSR, validation.Required),
+		"Certificate.Certificate": validation.Validate(r.Certificate.Certificate, validation.Required),
+		"ExpiryWarning":           validation.Validate(r.ExpiryWarning, validation.Min(time.Duration(0))),
+	}.Filter()
+}
+
+// Valid reports whether no errors were found
+func (r *ThirdPartyCertificateReport) Valid() bool {
+	return len(r.Errors) == 0
+}
+
+// ParseCertSigningRequest parses the PEM encoded CSR and verifies its signature
+func ParseCertSigningRequest(csr CertSigningRequest) (*x509.CertificateRequest, error) {
+	block, _ := pem.Decode([]byte(csr.CSR))
+	if block == nil || (block.Type != "CERTIFICATE REQUEST" && block.Type != "NEW CERTIFICATE REQUEST") {
+		return nil, fmt.Errorf("%w: no PEM encoded certificate request found", ErrInvalidCSR)
+	}
+	req, err := x509.ParseCertificateRequest(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidCSR, err)
+	}
+	if err := req.CheckSignature(); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidCSR, err)
+	}
+	return req, nil
+}
+
+// CheckCSRNames compares the CN and SANs of a parsed CSR with the names of an enrollment. CPS puts the CN
+// in the SANs, so the CN is expected among the SANs as well. It returns a description of each difference.
+func CheckCSRNames(req *x509.CertificateRequest, expected CSR) []string {
+	var problems []string
+	if !strings.EqualFold(req.Subject.CommonName, expected.CN) {
+		problems = append(problems, fmt.Sprintf("CSR CN %q does not match the enrollment CN %q", req.Subject.CommonName, expected.CN))
+	}
+	missing, unexpected := compareNames(req.DNSNames, expectedNames(expected))
+	for _, name := range missing {
+		problems = append(problems, fmt.Sprintf("CSR is missing SAN %q", name))
+	}
+	for _, name := range unexpected {
+		problems = append(problems, fmt.Sprintf("CSR contains SAN %q which is not in the enrollment", name))
+	}
+	return problems
+}
+
+// CheckThirdPartyCertificate checks locally a signed certificate before it is uploaded with
+// UploadThirdPartyCertAndTrustChain. It verifies that:
+//   - the CSR is valid and, when Expected is set, that its names match the enrollment,
+//   - the certificate was issued for the public key of the CSR and covers the names of the enrollment,
+//   - the key algorithm matches the certificate,
+//   - each certificate of the trust chain issued the previous one, starting with the issuer of the certificate,
+//   - when Roots is set, the trust chain leads to one of the roots,
+//   - the certificate and the trust chain are valid at Now, with a warning when they expire within ExpiryWarning.
+//
+// Problems are reported in ThirdPartyCertificateReport. An error is returned only when the input cannot be parsed.
+func CheckThirdPartyCertificate(params CheckThirdPartyCertificateRequest) (*ThirdPartyCertificateReport, error) {
+	if err := params.Validate(); err != nil {
+		return nil, fmt.Errorf("%s: %w: %s", ErrCheckThirdPartyCertificate, ErrStructValidation, err)
+	}
+	now := params.Now
+	if now.IsZero() {
+		now = time.Now()
+	}
+	expiryWarning := params.ExpiryWarning
+	if expiryWarning == 0 {
+		expiryWarning = DefaultExpiryWarning
+	}
+
+	req, err := ParseCertSigningRequest(params.CSR)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrCheckThirdPartyCertificate, err)
+	}
+	certs, err := parseCertificates(params.Certificate.Certificate)
+	if err != nil {
+		return nil, fmt.Errorf("%w: certificate: %w", ErrCheckThirdPartyCertificate, err)
+	}
+	if len(certs) == 0 {
+		return nil, fmt.Errorf("%w: certificate: %w: no PEM encoded certificate found", ErrCheckThirdPartyCertificate, ErrInvalidCertificate)
+	}
+	chain, err := parseCertificates(params.Certificate.TrustChain)
+	if err != nil {
+		return nil, fmt.Errorf("%w: trust chain: %w", ErrCheckThirdPartyCertificate, err)
+	}
+
+	report := &ThirdPartyCertificateReport{}
+	if len(certs) != 1 {
+		report.Errors = append(report.Errors,
+			fmt.Sprintf("certificate contains %d certificates, intermediate certificates belong in the trust chain", len(certs)))
+		chain = append(slices.Clone(certs[1:]), chain...)
+	}
+	cert := certs[0]
+	report.Subject = cert.Subject.String()
+	report.NotAfter = cert.NotAfter
+
+	if params.Expected != nil {
+		report.Errors = append(report.Errors, CheckCSRNames(req, *params.Expected)...)
+		missing, _ := compareNames(cert.DNSNames, expectedNames(*params.Expected))
+		for _, name := range missing {
+			report.Errors = append(report.Errors, fmt.Sprintf("certificate does not cover %q", name))
+		}
+	}
+
+	if key, ok := cert.PublicKey.(interface{ Equal(crypto.PublicKey) bool }); !ok || !key.Equal(req.PublicKey) {
+		report.Errors = append(report.Errors, "certificate public key does not match the CSR")
+	}
+	algorithm := keyAlgorithm(cert.PublicKey)
+	for _, expected := range []string{params.Certificate.KeyAlgorithm, params.CSR.KeyAlgorithm} {
+		if expected != "" && !strings.EqualFold(expected, algorithm) {
+			report.Errors = append(report.Errors, fmt.Sprintf("certificate key algorithm %s does not match %s", algorithm, expected))
+			break
+		}
+	}
+
+	report.checkChain(cert, chain, params.Roots, now)
+	for i, c := range append([]*x509.Certificate{cert}, chain...) {
+		name := "certificate"
+		if i > 0 {
+			name = fmt.Sprintf("trust chain certificate %d (%s)", i, c.Subject)
+		}
+		report.checkValidity(name, c, now, expiryWarning)
+	}
+	return report, nil
+}
+
+// checkChain checks that each certificate of the chain issued the previous one and, with roots, that the chain is complete
+func (r *ThirdPartyCertificateReport) checkChain(cert *x509.Certificate, chain []*x509.Certificate, roots *x509.CertPool, now time.Time) {
+	previous := cert
+	for i, c := range chain {
+		if err := previous.CheckSignatureFrom(c); err != nil {
+			issuer := slices.IndexFunc(chain, func(candidate *x509.Certificate) bool {
+				return previous.CheckSignatureFrom(candidate) == nil
+			})
+			if issuer >= 0 {
+				r.Errors = append(r.Errors, fmt.Sprintf("trust chain is out of order: the issuer of %s is certificate %d, expected %d",
+					previous.Subject, issuer+1, i+1))
+			} else {
+				r.Errors = append(r.Errors, fmt.Sprintf("trust chain certificate %d (%s) did not issue %s", i+1, c.Subject, previous.Subject))
+			}
+			return
+		}
+		previous = c
+	}
+
+	if roots != nil {
+		intermediates := x509.NewCertPool()
+		for _, c := range chain {
+			intermediates.AddCert(c)
+		}
+		_, err := cert.Verify(x509.VerifyOptions{
+			Roots:         roots,
+			Intermediates: intermediates,
+			CurrentTime:   now,
+			KeyUsages:     []x509.ExtKeyUsage{x509.ExtKeyUsageAny},
+		})
+		var unknownAuthority x509.UnknownAuthorityError
+		if errors.As(err, &unknownAuthority) {
+			r.Errors = append(r.Errors, fmt.Sprintf("trust chain is incomplete: %s is not issued by a trusted root", previous.Subject))
+		}
+		return
+	}
+	if len(chain) == 0 && previous.CheckSignatureFrom(previous) != nil {
+		r.Errors = append(r.Errors, "trust chain is empty")
+		return
+	}
+	if previous.CheckSignatureFrom(previous) != nil {
+		r.Warnings = append(r.Warnings,
+			fmt.Sprintf("trust chain ends with %s, issued by %s; no roots were given to verify it is complete", previous.Subject, previous.Issuer))
+	}
+}
+
+func (r *ThirdPartyCertificateReport) checkValidity(name string, c *x509.Certificate, now time.Time, expiryWarning time.Duration) {
+	switch {
+	case now.Before(c.NotBefore):
+		r.Errors = append(r.Errors, fmt.Sprintf("%s is not valid before %s", name, c.NotBefore.UTC().Format(time.RFC3339)))
+	case now.After(c.NotAfter):
+		r.Errors = append(r.Errors, fmt.Sprintf("%s expired on %s", name, c.NotAfter.UTC().Format(time.RFC3339)))
+	case c.NotAfter.Sub(now) < expiryWarning:
+		r.Warnings = append(r.Warnings, fmt.Sprintf("%s expires on %s", name, c.NotAfter.UTC().Format(time.RFC3339)))
+	}
+}
+
+// parseCertificates parses all PEM encoded certificates in s
+func parseCertificates(s string) ([]*x509.Certificate, error) {
+	var certs []*x509.Certificate
+	rest := []byte(s)
+	for {
+		var block *pem.Block
+		block, rest = pem.Decode(rest)
+		if block == nil {
+			break
+		}
+		if block.Type != "CERTIFICATE" {
+			return nil, fmt.Errorf("%w: unexpected PEM block %q", ErrInvalidCertificate, block.Type)
+		}
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %w", ErrInvalidCertificate, err)
+		}
+		certs = append(certs, cert)
+	}
+	if len(certs) == 0 && strings.TrimSpace(s) != "" {
+		return nil, fmt.Errorf("%w: no PEM encoded certificate found", ErrInvalidCertificate)
+	}
+	return certs, nil
+}
+
+// expectedNames returns the CN and SANs of an enrollment
+func expectedNames(csr CSR) []string {
+	return append([]string{csr.CN}, csr.SANS...)
+}
+
+// compareNames returns the expected names missing from names and the names which are not expected, case-insensitively
+func compareNames(names, expected []string) (missing, unexpected []string) {
+	set := func(values []string) map[string]bool {
+		m := make(map[string]bool, len(values))
+		for _, v := range values {
+			if v != "" {
+				m[strings.ToLower(strings.TrimSuffix(v, "."))] = true
+			}
+		}
+		return m
+	}
+	have, want := set(names), set(expected)
+	for name := range want {
+		if !have[name] {
+			missing = append(missing, name)
+		}
+	}
+	for name := range have {
+		if !want[name] {
+			unexpected = append(unexpected, name)
+		}
+	}
+	slices.Sort(missing)
+	slices.Sort(unexpected)
+	return missing, unexpected
+}
+
+func keyAlgorithm(key crypto.PublicKey) string {
+	switch key.(type) {
+	case *rsa.PublicKey:
+		return "RSA"
+	case *ecdsa.PublicKey:
+		return "ECDSA"
+	default:
+		return fmt.Sprintf("%T", key)
+	}
+}
@@ -0,0 +1,289 @@
+package cps
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"errors"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type testIssuer struct {
+	cert *x509.Certificate
+	key  crypto.Signer
+}
+
+var testNow = time.Date(2025, 6, 1, 0, 0, 0, 0, time.UTC)
+
+func newTestKey(t *testing.T) *ecdsa.PrivateKey {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	return key
+}
+
+// issueTestCertificate issues a certificate for key, self-signed when issuer is nil
+func issueTestCertificate(t *testing.T, issuer *testIssuer, key crypto.Signer, cn string, ca bool, notAfter time.Time, dnsNames ...string) *testIssuer {
+	serial, err := rand.Int(rand.Reader, big.NewInt(1<<62))
+	require.NoError(t, err)
+	template := &x509.Certificate{
+		SerialNumber:          serial,
+		Subject:               pkix.Name{CommonName: cn},
+		NotBefore:             testNow.Add(-24 * time.Hour),
+		NotAfter:              notAfter,
+		DNSNames:              dnsNames,
+		IsCA:                  ca,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+	}
+	parent, signer := template, key
+	if issuer != nil {
+		parent, signer = issuer.cert, issuer.key
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, parent, key.Public(), signer)
+	require.NoError(t, err)
+	cert, err := x509.ParseCertificate(der)
+	require.NoError(t, err)
+	return &testIssuer{cert: cert, key: key}
+}
+
+func pemCertificates(certs ...*testIssuer) string {
+	var s string
+	for _, c := range certs {
+		s += string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: c.cert.Raw}))
+	}
+	return s
+}
+
+func TestCheckThirdPartyCertificate(t *testing.T) {
+	expiry := testNow.Add(365 * 24 * time.Hour)
+	root := issueTestCertificate(t, nil, newTestKey(t), "Test Root", true, expiry.Add(time.Hour))
+	intermediate := issueTestCertificate(t, root, newTestKey(t), "Test Intermediate", true, expiry.Add(time.Hour))
+	otherRoot := issueTestCertificate(t, nil, newTestKey(t), "Other Root", true, expiry)
+
+	key := newTestKey(t)
+	csrDER, err := x509.CreateCertificateRequest(rand.Reader, &x509.CertificateRequest{
+		Subject:  pkix.Name{CommonName: "www.example.com"},
+		DNSNames: []string{"www.example.com", "api.example.com"},
+	}, key)
+	require.NoError(t, err)
+	csr := CertSigningRequest{CSR: string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE REQUEST", Bytes: csrDER})), KeyAlgorithm: "ECDSA"}
+	enrollment := &CSR{CN: "www.example.com", SANS: []string{"www.example.com", "API.example.com"}}
+
+	leaf := issueTestCertificate(t, intermediate, key, "www.example.com", false, expiry, "www.example.com", "api.example.com")
+	wrongKeyLeaf := issueTestCertificate(t, intermediate, newTestKey(t), "www.example.com", false, expiry, "www.example.com", "api.example.com")
+	partialLeaf := issueTestCertificate(t, intermediate, key, "www.example.com", false, expiry, "www.example.com")
+	expiringLeaf := issueTestCertificate(t, intermediate, key, "www.example.com", false, testNow.Add(10*24*time.Hour), "www.example.com", "api.example.com")
+	expiredLeaf := issueTestCertificate(t, intermediate, key, "www.example.com", false, testNow.Add(-time.Hour), "www.example.com", "api.example.com")
+
+	roots := x509.NewCertPool()
+	roots.AddCert(root.cert)
+	otherRoots := x509.NewCertPool()
+	otherRoots.AddCert(otherRoot.cert)
+
+	tests := map[string]struct {
+		params    CheckThirdPartyCertificateRequest
+		expected  *ThirdPartyCertificateReport
+		withError error
+	}{
+		"valid with roots": {
+			params: CheckThirdPartyCertificateRequest{
+				CSR:         csr,
+				Expected:    enrollment,
+				Certificate: CertificateAndTrustChain{Certificate: pemCertificates(leaf), TrustChain: pemCertificates(intermediate), KeyAlgorithm: "ECDSA"},
+				Roots:       roots,
+			},
+			expected: &ThirdPartyCertificateReport{},
+		},
+		"valid with root in trust chain": {
+			params: CheckThirdPartyCertificateRequest{
+				CSR:         csr,
+				Certificate: CertificateAndTrustChain{Certificate: pemCertificates(leaf), TrustChain: pemCertificates(intermediate, root)},
+			},
+			expected: &ThirdPartyCertificateReport{},
+		},
+		"chain not verified without roots": {
+			params: CheckThirdPartyCertificateRequest{
+				CSR:         csr,
+				Certificate: CertificateAndTrustChain{Certificate: pemCertificates(leaf), TrustChain: pemCertificates(intermediate)},
+			},
+			expected: &ThirdPartyCertificateReport{Warnings: []string{
+				"trust chain ends with CN=Test Intermediate, issued by CN=Test Root; no roots were given to verify it is complete",
+			}},
+		},
+		"names do not match": {
+			params: CheckThirdPartyCertificateRequest{
+				CSR:         csr,
+				Expected:    &CSR{CN: "example.com", SANS: []string{"www.example.com", "static.example.com"}},
+				Certificate: CertificateAndTrustChain{Certificate: pemCertificates(partialLeaf), TrustChain: pemCertificates(intermediate)},
+				Roots:       roots,
+			},
+			expected: &ThirdPartyCertificateReport{Errors: []string{
+				`CSR CN "www.example.com" does not match the enrollment CN "example.com"`,
+				`CSR is missing SAN "example.com"`,
+				`CSR is missing SAN "static.example.com"`,
+				`CSR contains SAN "api.example.com" which is not in the enrollment`,
+				`certificate does not cover "example.com"`,
+				`certificate does not cover "static.example.com"`,
+			}},
+		},
+		"public key and algorithm do not match": {
+			params: CheckThirdPartyCertificateRequest{
+				CSR:         csr,
+				Certificate: CertificateAndTrustChain{Certificate: pemCertificates(wrongKeyLeaf), TrustChain: pemCertificates(intermediate), KeyAlgorithm: "RSA"},
+				Roots:       roots,
+			},
+			expected: &ThirdPartyCertificateReport{Errors: []string{
+				"certificate public key does not match the CSR",
+				"certificate key algorithm ECDSA does not match RSA",
+			}},
+		},
+		"trust chain out of order": {
+			params: CheckThirdPartyCertificateRequest{
+				CSR:         csr,
+				Certificate: CertificateAndTrustChain{Certificate: pemCertificates(leaf), TrustChain: pemCertificates(root, intermediate)},
+			},
+			expected: &ThirdPartyCertificateReport{Errors: []string{
+				"trust chain is out of order: the issuer of CN=www.example.com is certificate 2, expected 1",
+			}},
+		},
+		"trust chain missing intermediate": {
+			params: CheckThirdPartyCertificateRequest{
+				CSR:         csr,
+				Certificate: CertificateAndTrustChain{Certificate: pemCertificates(leaf), TrustChain: pemCertificates(otherRoot)},
+			},
+			expected: &ThirdPartyCertificateReport{Errors: []string{
+				"trust chain certificate 1 (CN=Other Root) did not issue CN=www.example.com",
+			}},
+		},
+		"trust chain empty": {
+			params: CheckThirdPartyCertificateRequest{
+				CSR:         csr,
+				Certificate: CertificateAndTrustChain{Certificate: pemCertificates(leaf)},
+			},
+			expected: &ThirdPartyCertificateReport{Errors: []string{"trust chain is empty"}},
+		},
+		"trust chain incomplete": {
+			params: CheckThirdPartyCertificateRequest{
+				CSR:         csr,
+				Certificate: CertificateAndTrustChain{Certificate: pemCertificates(leaf), TrustChain: pemCertificates(intermediate)},
+				Roots:       otherRoots,
+			},
+			expected: &ThirdPartyCertificateReport{Errors: []string{
+				"trust chain is incomplete: CN=Test Intermediate is not issued by a trusted root",
+			}},
+		},
+		"intermediate in certificate": {
+			params: CheckThirdPartyCertificateRequest{
+				CSR:         csr,
+				Certificate: CertificateAndTrustChain{Certificate: pemCertificates(leaf, intermediate)},
+				Roots:       roots,
+			},
+			expected: &ThirdPartyCertificateReport{Errors: []string{
+				"certificate contains 2 certificates, intermediate certificates belong in the trust chain",
+			}},
+		},
+		"expiring": {
+			params: CheckThirdPartyCertificateRequest{
+				CSR:         csr,
+				Certificate: CertificateAndTrustChain{Certificate: pemCertificates(expiringLeaf), TrustChain: pemCertificates(intermediate)},
+				Roots:       roots,
+			},
+			expected: &ThirdPartyCertificateReport{Warnings: []string{"certificate expires on 2025-06-11T00:00:00Z"}},
+		},
+		"expired": {
+			params: CheckThirdPartyCertificateRequest{
+				CSR:         csr,
+				Certificate: CertificateAndTrustChain{Certificate: pemCertificates(expiredLeaf), TrustChain: pemCertificates(intermediate)},
+				Roots:       roots,
+			},
+			expected: &ThirdPartyCertificateReport{Errors: []string{"certificate expired on 2025-05-31T23:00:00Z"}},
+		},
+		"intermediate expiring": {
+			params: CheckThirdPartyCertificateRequest{
+				CSR:           csr,
+				Certificate:   CertificateAndTrustChain{Certificate: pemCertificates(leaf), TrustChain: pemCertificates(intermediate)},
+				Roots:         roots,
+				ExpiryWarning: 400 * 24 * time.Hour,
+			},
+			expected: &ThirdPartyCertificateReport{Warnings: []string{
+				"certificate expires on 2026-06-01T00:00:00Z",
+				"trust chain certificate 1 (CN=Test Intermediate) expires on 2026-06-01T01:00:00Z",
+			}},
+		},
+		"invalid CSR": {
+			params: CheckThirdPartyCertificateRequest{
+				CSR:         CertSigningRequest{CSR: "not a csr"},
+				Certificate: CertificateAndTrustChain{Certificate: pemCertificates(leaf)},
+			},
+			withError: ErrInvalidCSR,
+		},
+		"invalid certificate": {
+			params: CheckThirdPartyCertificateRequest{
+				CSR:         csr,
+				Certificate: CertificateAndTrustChain{Certificate: "not a certificate"},
+			},
+			withError: ErrInvalidCertificate,
+		},
+		"whitespace-only certificate": {
+			params: CheckThirdPartyCertificateRequest{
+				CSR:         csr,
+				Certificate: CertificateAndTrustChain{Certificate: " \n"},
+			},
+			withError: ErrInvalidCertificate,
+		},
+		"empty certificate": {
+			params: CheckThirdPartyCertificateRequest{
+				CSR:         csr,
+				Certificate: CertificateAndTrustChain{Certificate: ""},
+			},
+			withError: ErrStructValidation,
+		},
+		"validation error": {
+			params:    CheckThirdPartyCertificateRequest{CSR: csr},
+			withError: ErrStructValidation,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			test.params.Now = testNow
+			report, err := CheckThirdPartyCertificate(test.params)
+			if test.withError != nil {
+				assert.True(t, errors.Is(err, test.withError), "want: %s; got: %s", test.withError, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, "CN=www.example.com", report.Subject)
+			assert.Equal(t, test.expected.Errors, report.Errors)
+			assert.Equal(t, test.expected.Warnings, report.Warnings)
+			assert.Equal(t, len(test.expected.Errors) == 0, report.Valid())
+		})
+	}
+}
+
+func TestParseCertSigningRequest(t *testing.T) {
+	key := newTestKey(t)
+	der, err := x509.CreateCertificateRequest(rand.Reader, &x509.CertificateRequest{
+		Subject:  pkix.Name{CommonName: "www.example.com"},
+		DNSNames: []string{"www.example.com"},
+	}, key)
+	require.NoError(t, err)
+
+	req, err := ParseCertSigningRequest(CertSigningRequest{CSR: string(pem.EncodeToMemory(&pem.Block{Type: "NEW CERTIFICATE REQUEST", Bytes: der}))})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"www.example.com"}, req.DNSNames)
+	assert.Empty(t, CheckCSRNames(req, CSR{CN: "WWW.example.com", SANS: []string{"www.example.com."}}))
+
+	der[len(der)-1] ^= 0xff
+	_, err = ParseCertSigningRequest(CertSigningRequest{CSR: string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE REQUEST", Bytes: der}))})
+	assert.True(t, errors.Is(err, ErrInvalidCSR), "got: %s", err)
+}
@@ -150,6 +150,14 @@ type (
 		//
 		// See: https://techdocs.akamai.com/edge-dns/reference/post-changelists-zone-submit
 		SubmitChangeList(context.Context, SubmitChangeListRequest) error
+		// AddChangeListChange appends a record set change to the Change List of the Zone.
+		//
+		// See: https://techdocs.akamai.com/edge-dns/reference/post-changelists-zone-recordsets-add-change
+		AddChangeListChange(context.Context, AddChangeListChangeRequest) error
+		// DeleteChangeList discards the Change List of the Zone.
+		//
+		// See: https://techdocs.akamai.com/edge-dns/reference/delete-changelists-zone
+		DeleteChangeList(context.Context, DeleteChangeListRequest) error
 		// UpdateZone updates zone.
 		//
 		// See: https://techdocs.akamai.com/edge-dns/reference/put-zone
@@ -0,0 +1,439 @@
+package dns
+
+import (
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/akamai/AkamaiOPEN-edgegrid-golang/v11/pkg/edgegriderr"
+	validation "github.com/go-ozzo/ozzo-validation/v4"
+)
+
+type (
+	// CheckDNSSECRequest contains parameters for CheckDNSSEC
+	CheckDNSSECRequest struct {
+		Zone string
+		// DNSKEYs lists the DNSKEY records of the zone, for example from ParseSecRecords
+		DNSKEYs []DNSKEYRData
+		// RegistrarDS lists the DS records held by, or about to be sent to, the registrar
+		RegistrarDS []DSRData
+		// RecordSets are searched for RRSIG record sets, whose validity windows are checked
+		RecordSets []RecordSet
+		// Now is the time validity windows are checked against. It defaults to the current time.
+		Now time.Time
+		// ExpiryWarning marks signatures expiring within this duration. It defaults to DefaultRRSIGExpiryWarning.
+		ExpiryWarning time.Duration
+	}
+
+	// DNSSECReport is the result of CheckDNSSEC
+	DNSSECReport struct {
+		Zone string    `json:"zone"`
+		DS   []DSCheck `json:"ds"`
+		// KeysWithoutDS lists the key tags of secure entry point DNSKEYs which no registrar DS refers to
+		KeysWithoutDS []uint16     `json:"keysWithoutDs,omitempty"`
+		Signatures    []RRSIGCheck `json:"signatures,omitempty"`
+		CheckedAt     time.Time    `json:"checkedAt"`
+		Status        DNSSECStatus `json:"status"`
+		Problems      []string     `json:"problems,omitempty"`
+		Warnings      []string     `json:"warnings,omitempty"`
+	}
+
+	// DSCheck is the result of comparing a registrar DS with the DNSKEY records of the zone
+	DSCheck struct {
+		KeyTag     uint16 `json:"keyTag"`
+		Algorithm  uint8  `json:"algorithm"`
+		DigestType uint8  `json:"digestType"`
+		Digest     string `json:"digest"`
+		// ExpectedDigest is the digest computed from the DNSKEY with the same key tag and algorithm
+		ExpectedDigest string   `json:"expectedDigest,omitempty"`
+		Status         DSStatus `json:"status"`
+	}
+
+	// RRSIGCheck is the result of checking the validity window of an RRSIG record
+	RRSIGCheck struct {
+		Name        string      `json:"name"`
+		TypeCovered string      `json:"typeCovered"`
+		KeyTag      uint16      `json:"keyTag"`
+		Inception   time.Time   `json:"inception"`
+		Expiration  time.Time   `json:"expiration"`
+		Status      RRSIGStatus `json:"status"`
+	}
+
+	// DSStatus is the result of a DS check
+	DSStatus string
+
+	// RRSIGStatus is the result of an RRSIG check
+	RRSIGStatus string
+
+	// DNSSECStatus is the overall result of CheckDNSSEC
+	DNSSECStatus string
+)
+
+const (
+	// DSStatusMatch means the DS digest matches a DNSKEY of the zone
+	DSStatusMatch DSStatus = "MATCH"
+	// DSStatusDigestMismatch means a DNSKEY with the key tag and algorithm exists, but the digest differs
+	DSStatusDigestMismatch DSStatus = "DIGEST_MISMATCH"
+	// DSStatusNoKey means no DNSKEY of the zone has the key tag and algorithm of the DS
+	DSStatusNoKey DSStatus = "NO_MATCHING_KEY"
+	// DSStatusUnsupportedDigest means the digest type cannot be computed
+	DSStatusUnsupportedDigest DSStatus = "UNSUPPORTED_DIGEST_TYPE"
+
+	// RRSIGStatusValid means the signature is within its validity window
+	RRSIGStatusValid RRSIGStatus = "VALID"
+	// RRSIGStatusExpiring means the signature expires within the expiry warning
+	RRSIGStatusExpiring RRSIGStatus = "EXPIRING"
+	// RRSIGStatusExpired means the signature expiration is in the past
+	RRSIGStatusExpired RRSIGStatus = "EXPIRED"
+	// RRSIGStatusNotYetValid means the signature inception is in the future
+	RRSIGStatusNotYetValid RRSIGStatus = "NOT_YET_VALID"
+	// RRSIGStatusUnknownKey means no DNSKEY of the zone has the key tag of the signature
+	RRSIGStatusUnknownKey RRSIGStatus = "UNKNOWN_KEY"
+
+	// DNSSECStatusPass means every check passed
+	DNSSECStatusPass DNSSECStatus = "PASS"
+	// DNSSECStatusWarn means every check passed, but there are warnings
+	DNSSECStatusWarn DNSSECStatus = "WARN"
+	// DNSSECStatusFail means at least one check failed
+	DNSSECStatusFail DNSSECStatus = "FAIL"
+
+	// DigestTypeSHA1 is the SHA-1 DS digest type
+	DigestTypeSHA1 uint8 = 1
+	// DigestTypeSHA256 is the SHA-256 DS digest type
+	DigestTypeSHA256 uint8 = 2
+	// DigestTypeSHA384 is the SHA-384 DS digest type
+	DigestTypeSHA384 uint8 = 4
+
+	// DefaultRRSIGExpiryWarning is the default CheckDNSSECRequest.ExpiryWarning
+	DefaultRRSIGExpiryWarning = 72 * time.Hour
+
+	// dnskeyFlagSEP is the secure entry point flag of DNSKEY records
+	dnskeyFlagSEP = 1
+)
+
+var (
+	// ErrCheckDNSSEC is returned when CheckDNSSEC fails
+	ErrCheckDNSSEC = errors.New("check dnssec")
+	// ErrUnsupportedDigestType is returned when a DS digest type cannot be computed
+	ErrUnsupportedDigestType = errors.New("unsupported digest type")
+	// ErrParseSecRecords is returned when ParseSecRecords fails
+	ErrParseSecRecords = errors.New("parse dnssec records")
+)
+
+// Validate validates CheckDNSSECRequest
+func (r CheckDNSSECRequest) Validate() error {
+	return edgegriderr.ParseValidationErrors(validation.Errors{
+		"Zone":        validation.Validate(r.Zone, validation.Required),
+		"DNSKEYs":     validation.Validate(r.DNSKEYs),
+		"RegistrarDS": validation.Validate(r.RegistrarDS),
+	})
+}
+
+// OK reports whether the report has no failed checks. Warnings do not fail the report.
+func (r *DNSSECReport) OK() bool {
+	return r.Status != DNSSECStatusFail
+}
+
+// KeyTag computes the key tag of the DNSKEY record as defined in RFC 4034, Appendix B
+func (r DNSKEYRData) KeyTag() (uint16, error) {
+	rdata, err := r.wire()
+	if err != nil {
+		return 0, err
+	}
+	return keyTag(r.Algorithm, rdata), nil
+}
+
+// ComputeDS computes the DS record of the DNSKEY record owned by name. Supported digest types are
+// DigestTypeSHA1, DigestTypeSHA256 and DigestTypeSHA384.
+func ComputeDS(name string, key DNSKEYRData, digestType uint8) (*DSRData, error) {
+	var h hash.Hash
+	switch digestType {
+	case DigestTypeSHA1:
+		h = sha1.New()
+	case DigestTypeSHA256:
+		h = sha256.New()
+	case DigestTypeSHA384:
+		h = sha512.New384()
+	default:
+		return nil, fmt.Errorf("%w: %d", ErrUnsupportedDigestType, digestType)
+	}
+
+	owner, err := canonicalName(name)
+	if err != nil {
+		return nil, err
+	}
+	rdata, err := key.wire()
+	if err != nil {
+		return nil, err
+	}
+	h.Write(owner)
+	h.Write(rdata)
+
+	return &DSRData{
+		KeyTag:     keyTag(key.Algorithm, rdata),
+		Algorithm:  key.Algorithm,
+		DigestType: digestType,
+		Digest:     strings.ToUpper(hex.EncodeToString(h.Sum(nil))),
+	}, nil
+}
+
+// ParseSecRecords parses the DNSKEY and DS records of SecRecords returned by GetZonesDNSSecStatus.
+// The records are in master file format and may span several lines in parentheses.
+func ParseSecRecords(records SecRecords) ([]DNSKEYRData, []DSRData, error) {
+	var keys []DNSKEYRData
+	var ds []DSRData
+	for _, rdata := range secRecordRdata(records.DNSKeyRecord, "DNSKEY") {
+		var key DNSKEYRData
+		if err := key.UnmarshalText([]byte(rdata)); err != nil {
+			return nil, nil, fmt.Errorf("%w: DNSKEY: %w", ErrParseSecRecords, err)
+		}
+		keys = append(keys, key)
+	}
+	for _, rdata := range secRecordRdata(records.DSRecord, "DS") {
+		var record DSRData
+		if err := record.UnmarshalText([]byte(rdata)); err != nil {
+			return nil, nil, fmt.Errorf("%w: DS: %w", ErrParseSecRecords, err)
+		}
+		ds = append(ds, record)
+	}
+	return keys, ds, nil
+}
+
+// CheckDNSSEC compares the registrar DS records with digests computed from the DNSKEY records of the zone and checks
+// the validity windows of RRSIG records. It needs no resolver, so it can run in CI before a registrar DS change.
+//
+// The report fails when there is no registrar DS, when any registrar DS does not match a DNSKEY, or when any
+// signature is expired, not yet valid or made with an unknown key. Signatures close to expiry and secure entry
+// point keys without a DS are reported as warnings.
+func CheckDNSSEC(params CheckDNSSECRequest) (*DNSSECReport, error) {
+	if err := params.Validate(); err != nil {
+		return nil, fmt.Errorf("%s: %w: %s", ErrCheckDNSSEC, ErrStructValidation, err)
+	}
+	now := params.Now
+	if now.IsZero() {
+		now = time.Now()
+	}
+	expiryWarning := params.ExpiryWarning
+	if expiryWarning == 0 {
+		expiryWarning = DefaultRRSIGExpiryWarning
+	}
+
+	report := DNSSECReport{Zone: params.Zone, CheckedAt: now}
+
+	keyTags := make(map[uint16][]DNSKEYRData, len(params.DNSKEYs))
+	for _, key := range params.DNSKEYs {
+		tag, err := key.KeyTag()
+		if err != nil {
+			return nil, fmt.Errorf("%w: %w", ErrCheckDNSSEC, err)
+		}
+		keyTags[tag] = append(keyTags[tag], key)
+	}
+
+	referenced := make(map[uint16]bool)
+	for _, ds := range params.RegistrarDS {
+		check, err := checkDS(params.Zone, ds, keyTags[ds.KeyTag])
+		if err != nil {
+			return nil, fmt.Errorf("%w: %w", ErrCheckDNSSEC, err)
+		}
+		if check.Status == DSStatusMatch {
+			referenced[ds.KeyTag] = true
+		} else {
+			report.Problems = append(report.Problems, fmt.Sprintf("DS %d %d %d: %s", ds.KeyTag, ds.Algorithm, ds.DigestType, check.Status))
+		}
+		report.DS = append(report.DS, *check)
+	}
+	if len(params.RegistrarDS) == 0 {
+		report.Problems = append(report.Problems, "no registrar DS records")
+	}
+
+	for tag, keys := range keyTags {
+		for _, key := range keys {
+			if key.Flags&dnskeyFlagSEP != 0 && !referenced[tag] {
+				report.KeysWithoutDS = append(report.KeysWithoutDS, tag)
+				report.Warnings = append(report.Warnings, fmt.Sprintf("DNSKEY %d has no registrar DS", tag))
+				break
+			}
+		}
+	}
+	sort.Slice(report.KeysWithoutDS, func(i, j int) bool { return report.KeysWithoutDS[i] < report.KeysWithoutDS[j] })
+	sort.Strings(report.Warnings)
+
+	for _, set := range params.RecordSets {
+		if !strings.EqualFold(set.Type, "RRSIG") {
+			continue
+		}
+		for _, rdata := range set.Rdata {
+			var sig RRSIGRData
+			if err := sig.UnmarshalText([]byte(rdata)); err != nil {
+				return nil, fmt.Errorf("%w: %s RRSIG: %w", ErrCheckDNSSEC, set.Name, err)
+			}
+			check := checkRRSIG(set.Name, sig, now, expiryWarning, len(keyTags) > 0 && keyTags[sig.KeyTag] == nil)
+			switch check.Status {
+			case RRSIGStatusValid:
+			case RRSIGStatusExpiring:
+				report.Warnings = append(report.Warnings, fmt.Sprintf("RRSIG %s %s %d expires at %s", check.Name, check.TypeCovered, check.KeyTag, check.Expiration.Format(time.RFC3339)))
+			default:
+				report.Problems = append(report.Problems, fmt.Sprintf("RRSIG %s %s %d: %s", check.Name, check.TypeCovered, check.KeyTag, check.Status))
+			}
+			report.Signatures = append(report.Signatures, check)
+		}
+	}
+
+	switch {
+	case len(report.Problems) > 0:
+		report.Status = DNSSECStatusFail
+	case len(report.Warnings) > 0:
+		report.Status = DNSSECStatusWarn
+	default:
+		report.Status = DNSSECStatusPass
+	}
+	return &report, nil
+}
+
+func checkDS(zone string, ds DSRData, keys []DNSKEYRData) (*DSCheck, error) {
+	check := DSCheck{
+		KeyTag:     ds.KeyTag,
+		Algorithm:  ds.Algorithm,
+		DigestType: ds.DigestType,
+		Digest:     strings.ToUpper(ds.Digest),
+		Status:     DSStatusNoKey,
+	}
+	for _, key := range keys {
+		if key.Algorithm != ds.Algorithm {
+			continue
+		}
+		expected, err := ComputeDS(zone, key, ds.DigestType)
+		if errors.Is(err, ErrUnsupportedDigestType) {
+			check.Status = DSStatusUnsupportedDigest
+			return &check, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		check.ExpectedDigest = expected.Digest
+		if strings.EqualFold(expected.Digest, ds.Digest) {
+			check.Status = DSStatusMatch
+			return &check, nil
+		}
+		check.Status = DSStatusDigestMismatch
+	}
+	return &check, nil
+}
+
+func checkRRSIG(name string, sig RRSIGRData, now time.Time, expiryWarning time.Duration, unknownKey bool) RRSIGCheck {
+	check := RRSIGCheck{
+		Name:        name,
+		TypeCovered: sig.TypeCovered,
+		KeyTag:      sig.KeyTag,
+		Inception:   sig.Inception,
+		Expiration:  sig.Expiration,
+	}
+	switch {
+	case unknownKey:
+		check.Status = RRSIGStatusUnknownKey
+	case now.Before(sig.Inception):
+		check.Status = RRSIGStatusNotYetValid
+	case !now.Before(sig.Expiration):
+		check.Status = RRSIGStatusExpired
+	case sig.Expiration.Sub(now) <= expiryWarning:
+		check.Status = RRSIGStatusExpiring
+	default:
+		check.Status = RRSIGStatusValid
+	}
+	return check
+}
+
+// wire returns the DNSKEY RDATA in wire format
+func (r DNSKEYRData) wire() ([]byte, error) {
+	if err := validRData(r); err != nil {
+		return nil, err
+	}
+	key, err := base64.StdEncoding.DecodeString(r.PublicKey)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidRData, err)
+	}
+	rdata := binary.BigEndian.AppendUint16(make([]byte, 0, 4+len(key)), r.Flags)
+	rdata = append(rdata, r.Protocol, r.Algorithm)
+	return append(rdata, key...), nil
+}
+
+// keyTag implements the key tag algorithm of RFC 4034, Appendix B
+func keyTag(algorithm uint8, rdata []byte) uint16 {
+	if algorithm == 1 {
+		// RSA/MD5 uses the most significant 16 bits of the least significant 24 bits of the modulus
+		if len(rdata) < 3 {
+			return 0
+		}
+		return binary.BigEndian.Uint16(rdata[len(rdata)-3:])
+	}
+	var ac uint32
+	for i, b := range rdata {
+		if i&1 == 1 {
+			ac += uint32(b)
+		} else {
+			ac += uint32(b) << 8
+		}
+	}
+	ac += ac >> 16 & 0xFFFF
+	return uint16(ac & 0xFFFF)
+}
+
+// canonicalName returns the name in canonical wire format (RFC 4034, section 6.2)
+func canonicalName(name string) ([]byte, error) {
+	name = strings.ToLower(strings.TrimSuffix(name, "."))
+	var wire []byte
+	if name != "" {
+		for _, label := range strings.Split(name, ".") {
+			if label == "" || len(label) > 63 {
+				return nil, fmt.Errorf("%w: invalid owner name %q", ErrInvalidRData, name)
+			}
+			wire = append(wire, byte(len(label)))
+			wire = append(wire, label...)
+		}
+	}
+	return append(wire, 0), nil
+}
+
+// secRecordRdata returns the RDATA of every record of the type in master file text,
+// joining entries which span several lines in parentheses
+func secRecordRdata(text, recordType string) []string {
+	var entries []string
+	var current strings.Builder
+	depth := 0
+	for _, line := range strings.Split(text, "\n") {
+		if i := strings.IndexByte(line, ';'); i >= 0 {
+			line = line[:i]
+		}
+		depth += strings.Count(line, "(") - strings.Count(line, ")")
+		current.WriteString(line)
+		current.WriteByte(' ')
+		if depth <= 0 {
+			entries = append(entries, current.String())
+			current.Reset()
+			depth = 0
+		}
+	}
+	entries = append(entries, current.String())
+
+	var result []string
+	for _, entry := range entries {
+		fields := strings.Fields(strings.NewReplacer("(", " ", ")", " ").Replace(entry))
+		for i, field := range fields {
+			// the type follows the owner name and optional TTL and class
+			if i > 0 && i <= 3 && strings.EqualFold(field, recordType) && i+1 < len(fields) {
+				result = append(result, strings.Join(fields[i+1:], " "))
+				break
+			}
+		}
+	}
+	return result
+}
@@ -0,0 +1,356 @@
+package dns
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var (
+	// RFC 4034 and RFC 4509 example key
+	rsaKey = DNSKEYRData{
+		Flags:     256,
+		Protocol:  3,
+		Algorithm: 5,
+		PublicKey: "AQOeiiR0GOMYkDshWoSKz9XzfwJr1AYtsmx3TGkJaNXVbfi/2pHm822aJ5iI9BMzNXxeYCmZDRD99WYwYqUSdjMmmAphXdvxegXd/M5+X7OrzKBaMbCVdFLUUh6DhweJBjEVv5f2wwjM9XzcnOf+EPbtG9DMBmADjFDc2w/rljwvFw==",
+	}
+	// the same key with the secure entry point flag
+	rsaKSK = DNSKEYRData{
+		Flags:     257,
+		Protocol:  3,
+		Algorithm: 5,
+		PublicKey: rsaKey.PublicKey,
+	}
+	// RFC 6605 example key
+	p384Key = DNSKEYRData{
+		Flags:     257,
+		Protocol:  3,
+		Algorithm: 14,
+		PublicKey: "xKYaNhWdGOfJ+nPrL8/arkwf2EY3MDJ+SErKivBVSum1w/egsXvSADtNJhyem5RCOpgQ6K8X1DRSEkrbYQ+OB+v8/uX45NBwY8rp65F6Glur8I/mlVNgF6W/qTI37m40",
+	}
+)
+
+func TestComputeDS(t *testing.T) {
+	tests := map[string]struct {
+		name       string
+		key        DNSKEYRData
+		digestType uint8
+		expected   *DSRData
+		withError  error
+	}{
+		"SHA-1": {
+			name:       "dskey.example.com.",
+			key:        rsaKey,
+			digestType: DigestTypeSHA1,
+			expected:   &DSRData{KeyTag: 60485, Algorithm: 5, DigestType: 1, Digest: "2BB183AF5F22588179A53B0A98631FAD1A292118"},
+		},
+		"SHA-256": {
+			name:       "DSKEY.example.com",
+			key:        rsaKey,
+			digestType: DigestTypeSHA256,
+			expected:   &DSRData{KeyTag: 60485, Algorithm: 5, DigestType: 2, Digest: "D4B7D520E7BB5F0F67674A0CCEB1E3E0614B93C4F9E99B8383F6A1E4469DA50A"},
+		},
+		"SHA-256 of ECDSA P-384 key": {
+			name:       "example.net.",
+			key:        p384Key,
+			digestType: DigestTypeSHA256,
+			expected:   &DSRData{KeyTag: 10771, Algorithm: 14, DigestType: 2, Digest: "FDE87F87D3A32AD8781EB0D79AC02F80D1381CECDA3567C2352B4986645C2DD0"},
+		},
+		"SHA-384": {
+			name:       "example.net.",
+			key:        p384Key,
+			digestType: DigestTypeSHA384,
+			expected: &DSRData{
+				KeyTag:     10771,
+				Algorithm:  14,
+				DigestType: 4,
+				Digest:     "72D7B62976CE06438E9C0BF319013CF801F09ECC84B8D7E9495F27E305C6A9B0563A9B5F4D288405C3008A946DF983D6",
+			},
+		},
+		"unsupported digest type": {
+			name:       "example.net.",
+			key:        p384Key,
+			digestType: 3,
+			withError:  ErrUnsupportedDigestType,
+		},
+		"invalid key": {
+			name:       "example.net.",
+			key:        DNSKEYRData{Flags: 257, Protocol: 3, Algorithm: 13, PublicKey: "???"},
+			digestType: DigestTypeSHA256,
+			withError:  ErrInvalidRData,
+		},
+		"invalid owner name": {
+			name:       "a..example.net.",
+			key:        p384Key,
+			digestType: DigestTypeSHA256,
+			withError:  ErrInvalidRData,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			ds, err := ComputeDS(test.name, test.key, test.digestType)
+			if test.withError != nil {
+				assert.True(t, errors.Is(err, test.withError), "want: %s; got: %s", test.withError, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, test.expected, ds)
+			assert.NoError(t, ds.Validate())
+		})
+	}
+}
+
+func TestParseSecRecords(t *testing.T) {
+	tests := map[string]struct {
+		records      SecRecords
+		expectedKeys []DNSKEYRData
+		expectedDS   []DSRData
+		withError    error
+	}{
+		"records in parentheses": {
+			records: SecRecords{
+				DNSKeyRecord: "example.net. 3600 IN DNSKEY 257 3 14 (xKYaNhWdGOfJ+nPrL8/arkwf2EY3MDJ+SErKivBVSum1\n" +
+					"w/egsXvSADtNJhyem5RCOpgQ6K8X1DRSEkrbYQ+OB+v8/uX45NBwY8rp65F6Glur8I/mlVNgF6W/qTI37m40 ) ; KSK",
+				DSRecord: "example.net. 86400 IN DS 10771 14 4 ( 72d7b62976ce06438e9c0bf319013cf801f09ecc84b8\n" +
+					"d7e9495f27e305c6a9b0563a9b5f4d288405c3008a94 6df983d6 ) ",
+			},
+			expectedKeys: []DNSKEYRData{p384Key},
+			expectedDS: []DSRData{
+				{KeyTag: 10771, Algorithm: 14, DigestType: 4, Digest: "72D7B62976CE06438E9C0BF319013CF801F09ECC84B8D7E9495F27E305C6A9B0563A9B5F4D288405C3008A946DF983D6"},
+			},
+		},
+		"several records": {
+			records: SecRecords{
+				DNSKeyRecord: "dskey.example.com. 7200 IN DNSKEY 256 3 5 " + rsaKey.PublicKey + "\n" +
+					"example.net. IN DNSKEY 257 3 14 (\n" +
+					"  xKYaNhWdGOfJ+nPrL8/arkwf2EY3MDJ+SErKivBVSum1\n" +
+					"  w/egsXvSADtNJhyem5RCOpgQ6K8X1DRSEkrbYQ+OB+v8\n" +
+					"  /uX45NBwY8rp65F6Glur8I/mlVNgF6W/qTI37m40 )",
+			},
+			expectedKeys: []DNSKEYRData{rsaKey, p384Key},
+		},
+		"empty": {},
+		"invalid DS": {
+			records:   SecRecords{DSRecord: "foo.test.net. 86400 IN DS 42061 7 2 ( DUMMY_HASH_1 ) "},
+			withError: ErrParseSecRecords,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			keys, ds, err := ParseSecRecords(test.records)
+			if test.withError != nil {
+				assert.True(t, errors.Is(err, test.withError), "want: %s; got: %s", test.withError, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, test.expectedKeys, keys)
+			assert.Equal(t, test.expectedDS, ds)
+		})
+	}
+}
+
+func TestCheckDNSSEC(t *testing.T) {
+	now := time.Date(2024, 10, 15, 0, 0, 0, 0, time.UTC)
+	matchingDS := DSRData{KeyTag: 10771, Algorithm: 14, DigestType: 2, Digest: "fde87f87d3a32ad8781eb0d79ac02f80d1381cecda3567c2352b4986645c2dd0"}
+	signatures := []RecordSet{
+		{Name: "example.net", Type: "A", TTL: 300, Rdata: []string{"192.0.2.1"}},
+		{Name: "example.net", Type: "RRSIG", TTL: 300, Rdata: []string{
+			"A 13 2 300 20241101000000 20241001000000 10771 example.net. c2lnbmF0dXJl",
+			"DNSKEY 13 2 7200 20241016000000 20241001000000 10771 example.net. c2lnbmF0dXJl",
+		}},
+	}
+
+	tests := map[string]struct {
+		params    CheckDNSSECRequest
+		expected  *DNSSECReport
+		withError error
+	}{
+		"registrar DS matches": {
+			params: CheckDNSSECRequest{
+				Zone:        "example.net",
+				DNSKEYs:     []DNSKEYRData{p384Key},
+				RegistrarDS: []DSRData{matchingDS},
+				Now:         now,
+			},
+			expected: &DNSSECReport{
+				Zone: "example.net",
+				DS: []DSCheck{{
+					KeyTag:         10771,
+					Algorithm:      14,
+					DigestType:     2,
+					Digest:         "FDE87F87D3A32AD8781EB0D79AC02F80D1381CECDA3567C2352B4986645C2DD0",
+					ExpectedDigest: "FDE87F87D3A32AD8781EB0D79AC02F80D1381CECDA3567C2352B4986645C2DD0",
+					Status:         DSStatusMatch,
+				}},
+				CheckedAt: now,
+				Status:    DNSSECStatusPass,
+			},
+		},
+		"stale registrar DS and key without DS": {
+			params: CheckDNSSECRequest{
+				Zone:    "example.net",
+				DNSKEYs: []DNSKEYRData{p384Key, rsaKSK},
+				RegistrarDS: []DSRData{
+					matchingDS,
+					{KeyTag: 10771, Algorithm: 14, DigestType: 2, Digest: "00E87F87D3A32AD8781EB0D79AC02F80D1381CECDA3567C2352B4986645C2DD0"},
+					{KeyTag: 12345, Algorithm: 13, DigestType: 1, Digest: "2BB183AF5F22588179A53B0A98631FAD1A292118"},
+				},
+				Now: now,
+			},
+			expected: &DNSSECReport{
+				Zone: "example.net",
+				DS: []DSCheck{
+					{
+						KeyTag:         10771,
+						Algorithm:      14,
+						DigestType:     2,
+						Digest:         "FDE87F87D3A32AD8781EB0D79AC02F80D1381CECDA3567C2352B4986645C2DD0",
+						ExpectedDigest: "FDE87F87D3A32AD8781EB0D79AC02F80D1381CECDA3567C2352B4986645C2DD0",
+						Status:         DSStatusMatch,
+					},
+					{
+						KeyTag:         10771,
+						Algorithm:      14,
+						DigestType:     2,
+						Digest:         "00E87F87D3A32AD8781EB0D79AC02F80D1381CECDA3567C2352B4986645C2DD0",
+						ExpectedDigest: "FDE87F87D3A32AD8781EB0D79AC02F80D1381CECDA3567C2352B4986645C2DD0",
+						Status:         DSStatusDigestMismatch,
+					},
+					{
+						KeyTag:     12345,
+						Algorithm:  13,
+						DigestType: 1,
+						Digest:     "2BB183AF5F22588179A53B0A98631FAD1A292118",
+						Status:     DSStatusNoKey,
+					},
+				},
+				KeysWithoutDS: []uint16{60486},
+				CheckedAt:     now,
+				Status:        DNSSECStatusFail,
+				Problems:      []string{"DS 10771 14 2: DIGEST_MISMATCH", "DS 12345 13 1: NO_MATCHING_KEY"},
+				Warnings:      []string{"DNSKEY 60486 has no registrar DS"},
+			},
+		},
+		"signature windows": {
+			params: CheckDNSSECRequest{
+				Zone:        "example.net",
+				DNSKEYs:     []DNSKEYRData{p384Key},
+				RegistrarDS: []DSRData{matchingDS},
+				RecordSets: append(signatures, RecordSet{Name: "www.example.net", Type: "RRSIG", TTL: 300, Rdata: []string{
+					"A 13 3 300 20241014000000 20241001000000 10771 example.net. c2lnbmF0dXJl",
+					"AAAA 13 3 300 20241201000000 20241101000000 10771 example.net. c2lnbmF0dXJl",
+					"TXT 13 3 300 1730419200 1727740800 4242 example.net. c2lnbmF0dXJl",
+				}}),
+				Now: now,
+			},
+			expected: &DNSSECReport{
+				Zone: "example.net",
+				DS: []DSCheck{{
+					KeyTag:         10771,
+					Algorithm:      14,
+					DigestType:     2,
+					Digest:         "FDE87F87D3A32AD8781EB0D79AC02F80D1381CECDA3567C2352B4986645C2DD0",
+					ExpectedDigest: "FDE87F87D3A32AD8781EB0D79AC02F80D1381CECDA3567C2352B4986645C2DD0",
+					Status:         DSStatusMatch,
+				}},
+				Signatures: []RRSIGCheck{
+					{
+						Name:        "example.net",
+						TypeCovered: "A",
+						KeyTag:      10771,
+						Inception:   time.Date(2024, 10, 1, 0, 0, 0, 0, time.UTC),
+						Expiration:  time.Date(2024, 11, 1, 0, 0, 0, 0, time.UTC),
+						Status:      RRSIGStatusValid,
+					},
+					{
+						Name:        "example.net",
+						TypeCovered: "DNSKEY",
+						KeyTag:      10771,
+						Inception:   time.Date(2024, 10, 1, 0, 0, 0, 0, time.UTC),
+						Expiration:  time.Date(2024, 10, 16, 0, 0, 0, 0, time.UTC),
+						Status:      RRSIGStatusExpiring,
+					},
+					{
+						Name:        "www.example.net",
+						TypeCovered: "A",
+						KeyTag:      10771,
+						Inception:   time.Date(2024, 10, 1, 0, 0, 0, 0, time.UTC),
+						Expiration:  time.Date(2024, 10, 14, 0, 0, 0, 0, time.UTC),
+						Status:      RRSIGStatusExpired,
+					},
+					{
+						Name:        "www.example.net",
+						TypeCovered: "AAAA",
+						KeyTag:      10771,
+						Inception:   time.Date(2024, 11, 1, 0, 0, 0, 0, time.UTC),
+						Expiration:  time.Date(2024, 12, 1, 0, 0, 0, 0, time.UTC),
+						Status:      RRSIGStatusNotYetValid,
+					},
+					{
+						Name:        "www.example.net",
+						TypeCovered: "TXT",
+						KeyTag:      4242,
+						Inception:   time.Date(2024, 10, 1, 0, 0, 0, 0, time.UTC),
+						Expiration:  time.Date(2024, 11, 1, 0, 0, 0, 0, time.UTC),
+						Status:      RRSIGStatusUnknownKey,
+					},
+				},
+				CheckedAt: now,
+				Status:    DNSSECStatusFail,
+				Problems: []string{
+					"RRSIG www.example.net A 10771: EXPIRED",
+					"RRSIG www.example.net AAAA 10771: NOT_YET_VALID",
+					"RRSIG www.example.net TXT 4242: UNKNOWN_KEY",
+				},
+				Warnings: []string{"RRSIG example.net DNSKEY 10771 expires at 2024-10-16T00:00:00Z"},
+			},
+		},
+		"no registrar DS": {
+			params: CheckDNSSECRequest{
+				Zone:       "example.net",
+				DNSKEYs:    []DNSKEYRData{p384Key},
+				RecordSets: signatures[:1],
+				Now:        now,
+			},
+			expected: &DNSSECReport{
+				Zone:          "example.net",
+				KeysWithoutDS: []uint16{10771},
+				CheckedAt:     now,
+				Status:        DNSSECStatusFail,
+				Problems:      []string{"no registrar DS records"},
+				Warnings:      []string{"DNSKEY 10771 has no registrar DS"},
+			},
+		},
+		"invalid RRSIG": {
+			params: CheckDNSSECRequest{
+				Zone:       "example.net",
+				RecordSets: []RecordSet{{Name: "example.net", Type: "RRSIG", Rdata: []string{"A 13 2 300 never"}}},
+			},
+			withError: ErrInvalidRData,
+		},
+		"validation error": {
+			params: CheckDNSSECRequest{
+				DNSKEYs: []DNSKEYRData{{Protocol: 3}},
+			},
+			withError: ErrStructValidation,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			report, err := CheckDNSSEC(test.params)
+			if test.withError != nil {
+				assert.True(t, errors.Is(err, test.withError), "want: %s; got: %s", test.withError, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, test.expected, report)
+			assert.Equal(t, test.expected.Status != DNSSECStatusFail, report.OK())
+		})
+	}
+}
@@ -0,0 +1,450 @@
+package dns
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/akamai/AkamaiOPEN-edgegrid-golang/v11/pkg/edgegriderr"
+	"github.com/akamai/AkamaiOPEN-edgegrid-golang/v11/pkg/operation"
+	validation "github.com/go-ozzo/ozzo-validation/v4"
+)
+
+type (
+	// MigrateZonesRequest contains parameters for MigrateZones
+	MigrateZonesRequest struct {
+		ZoneQueryString ZoneQueryString
+		Zones           []MigrationZone
+		// ChunkSize is the number of zones in a single bulk request. It defaults to DefaultMigrationChunkSize.
+		ChunkSize int
+		// PollInterval is the first interval between bulk request status checks. It doubles after every check
+		// up to MaxPollInterval. It defaults to DefaultMigrationPollInterval.
+		PollInterval time.Duration
+		// MaxPollInterval defaults to DefaultMigrationMaxPollInterval
+		MaxPollInterval time.Duration
+		// MaxRetries is the number of times a zone with a transient failure, or an API call rejected with a 429 or
+		// 5xx response, is retried. It defaults to DefaultMigrationMaxRetries; a negative value disables retries.
+		MaxRetries int
+		// IsTransient decides which failure reasons of bulk requests are retried. It defaults to IsTransientBulkFailure.
+		IsTransient func(BulkFailedZone) bool
+		// Resume is the report of a previous run. Completed zones are skipped and created zones only get
+		// their zone file uploaded.
+		Resume *MigrationReport
+	}
+
+	// MigrationZone is a zone to create with MigrateZones
+	MigrationZone struct {
+		Zone ZoneCreate
+		// ZoneFile is master file content uploaded with PostMasterZoneFile once a PRIMARY zone is created
+		ZoneFile string
+	}
+
+	// MigrationReport is the result of MigrateZones. It can be passed to a later run as MigrateZonesRequest.Resume.
+	MigrationReport struct {
+		Zones      []MigrationZoneResult `json:"zones"`
+		RequestIDs []string              `json:"requestIds,omitempty"`
+	}
+
+	// MigrationZoneResult is the result of migrating a single zone
+	MigrationZoneResult struct {
+		Zone      string          `json:"zone"`
+		Status    MigrationStatus `json:"status"`
+		RequestID string          `json:"requestId,omitempty"`
+		Attempts  int             `json:"attempts"`
+		Error     string          `json:"error,omitempty"`
+	}
+
+	// MigrationStatus is the status of a migrated zone
+	MigrationStatus string
+)
+
+const (
+	// MigrationStatusPending means the zone was not created yet
+	MigrationStatusPending MigrationStatus = "PENDING"
+	// MigrationStatusCreated means the zone was created, but its zone file was not uploaded
+	MigrationStatusCreated MigrationStatus = "CREATED"
+	// MigrationStatusCompleted means the zone was created and its zone file, if any, was uploaded
+	MigrationStatusCompleted MigrationStatus = "COMPLETED"
+	// MigrationStatusFailed means the zone could not be created
+	MigrationStatusFailed MigrationStatus = "FAILED"
+
+	// DefaultMigrationChunkSize is the default number of zones in a single bulk request
+	DefaultMigrationChunkSize = 1000
+	// DefaultMigrationPollInterval is the default first interval between bulk request status checks
+	DefaultMigrationPollInterval = 5 * time.Second
+	// DefaultMigrationMaxPollInterval is the default longest interval between bulk request status checks
+	DefaultMigrationMaxPollInterval = time.Minute
+	// DefaultMigrationMaxRetries is the default number of retries of zones with transient failures
+	DefaultMigrationMaxRetries = 3
+)
+
+var (
+	// ErrMigrateZones is returned when MigrateZones fails
+	ErrMigrateZones = errors.New("migrate zones")
+
+	transientFailureReasons = []string{"timeout", "timed out", "temporar", "try again", "internal", "unavailable", "rate limit"}
+)
+
+// Validate validates MigrateZonesRequest
+func (r MigrateZonesRequest) Validate() error {
+	errs := validation.Errors{
+		"ZoneQueryString.Contract": validation.Validate(r.ZoneQueryString.Contract, validation.Required),
+		"Zones":                    validation.Validate(r.Zones, validation.Required),
+		"ChunkSize":                validation.Validate(r.ChunkSize, validation.Min(0)),
+	}
+	seen := make(map[string]bool, len(r.Zones))
+	for i, z := range r.Zones {
+		key := zoneKey(z.Zone.Zone)
+		zoneType := strings.ToUpper(z.Zone.Type)
+		errs[fmt.Sprintf("Zones[%d]", i)] = validation.Errors{
+			"Zone": validation.Validate(z.Zone.Zone, validation.Required, validation.By(func(interface{}) error {
+				if seen[key] {
+					return errors.New("is a duplicate")
+				}
+				return nil
+			})),
+			"Type":     validation.Validate(zoneType, validation.Required, validation.In("PRIMARY", "SECONDARY", "ALIAS")),
+			"Masters":  validation.Validate(z.Zone.Masters, validation.When(zoneType == "SECONDARY", validation.Required)),
+			"Target":   validation.Validate(z.Zone.Target, validation.When(zoneType == "ALIAS", validation.Required)),
+			"ZoneFile": validation.Validate(z.ZoneFile, validation.When(zoneType != "PRIMARY", validation.Empty.Error("is only allowed for PRIMARY zones"))),
+		}.Filter()
+		seen[key] = true
+	}
+	return edgegriderr.ParseValidationErrors(errs)
+}
+
+// Failed returns the results of zones which are not completed
+func (r *MigrationReport) Failed() []MigrationZoneResult {
+	var failed []MigrationZoneResult
+	for _, z := range r.Zones {
+		if z.Status != MigrationStatusCompleted {
+			failed = append(failed, z)
+		}
+	}
+	return failed
+}
+
+// IsTransientBulkFailure reports whether the failure reason of a zone in a bulk request looks temporary
+func IsTransientBulkFailure(failed BulkFailedZone) bool {
+	reason := strings.ToLower(failed.FailureReason)
+	for _, r := range transientFailureReasons {
+		if strings.Contains(reason, r) {
+			return true
+		}
+	}
+	return false
+}
+
+// MigrateZones creates many zones with bulk requests. Zones are split into chunks of ChunkSize, and every bulk request
+// is polled with GetBulkZoneCreateStatus, with growing intervals, until it completes. Failed zones are collected
+// from GetBulkZoneCreateResult. Zones failing for transient reasons, and API calls rejected with 429 or 5xx
+// responses, are retried up to MaxRetries times. When the status or the result of a bulk request cannot be read,
+// the zones of its chunk fail and the migration continues. Once a PRIMARY zone with a ZoneFile is created, the file
+// is uploaded with PostMasterZoneFile.
+//
+// The returned report lists every zone in the order of Zones. It is returned even with an error, for example when
+// the context is cancelled, and can be passed as Resume to continue the migration.
+func MigrateZones(ctx context.Context, client DNS, params MigrateZonesRequest) (*MigrationReport, error) {
+	if err := params.Validate(); err != nil {
+		return nil, fmt.Errorf("%s: %w: %s", ErrMigrateZones, ErrStructValidation, err)
+	}
+	m := newMigration(params)
+
+	if err := m.settleResumed(ctx, client); err != nil {
+		return m.report(), fmt.Errorf("%w: %w", ErrMigrateZones, err)
+	}
+
+	for attempt := 0; ; attempt++ {
+		pending := m.pending()
+		if len(pending) == 0 {
+			break
+		}
+		if attempt > 0 {
+			if err := operation.Sleep(ctx, m.pollInterval); err != nil {
+				return m.report(), fmt.Errorf("%w: %w", ErrMigrateZones, err)
+			}
+		}
+		for start := 0; start < len(pending); start += m.chunkSize {
+			chunk := pending[start:min(start+m.chunkSize, len(pending))]
+			if err := m.createChunk(ctx, client, chunk, attempt < m.maxRetries); err != nil {
+				return m.report(), fmt.Errorf("%w: %w", ErrMigrateZones, err)
+			}
+		}
+	}
+
+	for _, z := range m.zones {
+		result := m.results[zoneKey(z.Zone.Zone)]
+		if result.Status != MigrationStatusCreated {
+			continue
+		}
+		if err := m.uploadZoneFile(ctx, client, z, result); err != nil {
+			return m.report(), fmt.Errorf("%w: %w", ErrMigrateZones, err)
+		}
+	}
+
+	return m.report(), nil
+}
+
+type migration struct {
+	params       MigrateZonesRequest
+	zones        []MigrationZone
+	results      map[string]*MigrationZoneResult
+	requestIDs   []string
+	chunkSize    int
+	pollInterval time.Duration
+	backoff      operation.Backoff
+	maxRetries   int
+	isTransient  func(BulkFailedZone) bool
+}
+
+func newMigration(params MigrateZonesRequest) *migration {
+	m := migration{
+		params:       params,
+		zones:        params.Zones,
+		results:      make(map[string]*MigrationZoneResult, len(params.Zones)),
+		chunkSize:    params.ChunkSize,
+		pollInterval: params.PollInterval,
+		maxRetries:   params.MaxRetries,
+		isTransient:  params.IsTransient,
+	}
+	if m.chunkSize == 0 {
+		m.chunkSize = DefaultMigrationChunkSize
+	}
+	if m.pollInterval == 0 {
+		m.pollInterval = DefaultMigrationPollInterval
+	}
+	maxPollInterval := params.MaxPollInterval
+	if maxPollInterval == 0 {
+		maxPollInterval = DefaultMigrationMaxPollInterval
+	}
+	m.backoff = operation.ExponentialBackoff{Initial: m.pollInterval, Max: maxPollInterval}
+	if m.maxRetries == 0 {
+		m.maxRetries = DefaultMigrationMaxRetries
+	}
+	if m.isTransient == nil {
+		m.isTransient = IsTransientBulkFailure
+	}
+
+	previous := make(map[string]MigrationZoneResult)
+	if params.Resume != nil {
+		m.requestIDs = append(m.requestIDs, params.Resume.RequestIDs...)
+		for _, result := range params.Resume.Zones {
+			previous[zoneKey(result.Zone)] = result
+		}
+	}
+	for _, z := range params.Zones {
+		key := zoneKey(z.Zone.Zone)
+		result, ok := previous[key]
+		switch {
+		case !ok:
+			result = MigrationZoneResult{Zone: z.Zone.Zone, Status: MigrationStatusPending}
+		case result.Status == MigrationStatusFailed:
+			result = MigrationZoneResult{Zone: z.Zone.Zone, Status: MigrationStatusPending, Attempts: result.Attempts}
+		}
+		m.results[key] = &result
+	}
+	return &m
+}
+
+// pending returns the zones which still need to be created
+func (m *migration) pending() []MigrationZone {
+	var pending []MigrationZone
+	for _, z := range m.zones {
+		if m.results[zoneKey(z.Zone.Zone)].Status == MigrationStatusPending {
+			pending = append(pending, z)
+		}
+	}
+	return pending
+}
+
+// settleResumed collects the results of bulk requests which were still running when the resumed run stopped
+func (m *migration) settleResumed(ctx context.Context, client DNS) error {
+	byRequest := make(map[string][]MigrationZone)
+	var requestIDs []string
+	for _, z := range m.pending() {
+		requestID := m.results[zoneKey(z.Zone.Zone)].RequestID
+		if requestID == "" {
+			continue
+		}
+		if _, ok := byRequest[requestID]; !ok {
+			requestIDs = append(requestIDs, requestID)
+		}
+		byRequest[requestID] = append(byRequest[requestID], z)
+	}
+	for _, requestID := range requestIDs {
+		if err := m.collect(ctx, client, requestID, byRequest[requestID], true); err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			// the request may have expired, so its zones are submitted again
+			for _, z := range byRequest[requestID] {
+				m.results[zoneKey(z.Zone.Zone)].RequestID = ""
+			}
+		}
+	}
+	return nil
+}
+
+// createChunk creates a chunk of zones with a single bulk request. Zones which failed for transient reasons
+// stay pending when canRetry is set.
+func (m *migration) createChunk(ctx context.Context, client DNS, chunk []MigrationZone, canRetry bool) error {
+	bulk := BulkZonesCreate{Zones: make([]ZoneCreate, 0, len(chunk))}
+	for _, z := range chunk {
+		bulk.Zones = append(bulk.Zones, z.Zone)
+		m.results[zoneKey(z.Zone.Zone)].Attempts++
+	}
+
+	resp, err := client.CreateBulkZones(ctx, CreateBulkZonesRequest{BulkZones: &bulk, ZoneQueryString: m.params.ZoneQueryString})
+	if err != nil {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		for _, z := range chunk {
+			result := m.results[zoneKey(z.Zone.Zone)]
+			result.Error = err.Error()
+			if !canRetry || !isTransientError(err) {
+				result.Status = MigrationStatusFailed
+			}
+		}
+		return nil
+	}
+	m.requestIDs = append(m.requestIDs, resp.RequestID)
+	for _, z := range chunk {
+		m.results[zoneKey(z.Zone.Zone)].RequestID = resp.RequestID
+	}
+
+	if err := m.collect(ctx, client, resp.RequestID, chunk, canRetry); err != nil {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		for _, z := range chunk {
+			result := m.results[zoneKey(z.Zone.Zone)]
+			result.Error = fmt.Sprintf("bulk request %s: %s", resp.RequestID, err)
+			result.Status = MigrationStatusFailed
+		}
+	}
+	return nil
+}
+
+// collect waits for a bulk request to complete and records the result of every zone of the chunk
+func (m *migration) collect(ctx context.Context, client DNS, requestID string, chunk []MigrationZone, canRetry bool) error {
+	if err := m.waitForBulkRequest(ctx, client, requestID); err != nil {
+		return err
+	}
+	var result *GetBulkZoneCreateResultResponse
+	err := m.retry(ctx, requestID, func(ctx context.Context) (err error) {
+		result, err = client.GetBulkZoneCreateResult(ctx, GetBulkZoneCreateResultRequest{RequestID: requestID})
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	created := make(map[string]bool, len(result.SuccessfullyCreatedZones))
+	for _, name := range result.SuccessfullyCreatedZones {
+		created[zoneKey(name)] = true
+	}
+	failed := make(map[string]BulkFailedZone, len(result.FailedZones))
+	for _, f := range result.FailedZones {
+		failed[zoneKey(f.Zone)] = f
+	}
+
+	for _, z := range chunk {
+		key := zoneKey(z.Zone.Zone)
+		zoneResult := m.results[key]
+		if created[key] {
+			zoneResult.Status = MigrationStatusCreated
+			zoneResult.Error = ""
+			continue
+		}
+		f, ok := failed[key]
+		if !ok {
+			f = BulkFailedZone{Zone: z.Zone.Zone, FailureReason: "zone missing from bulk request result"}
+		}
+		zoneResult.Error = f.FailureReason
+		if !canRetry || !m.isTransient(f) {
+			zoneResult.Status = MigrationStatusFailed
+		}
+	}
+	return nil
+}
+
+// waitForBulkRequest polls the status of a bulk request until it completes
+func (m *migration) waitForBulkRequest(ctx context.Context, client DNS, requestID string) error {
+	op := BulkZoneCreateOperation(client, requestID)
+	op.Backoff = m.backoff
+	op.IsRetryable = m.isRetryable()
+	_, err := op.Wait(ctx)
+	return err
+}
+
+// uploadZoneFile uploads the zone file of a created zone, retrying transient errors
+func (m *migration) uploadZoneFile(ctx context.Context, client DNS, z MigrationZone, result *MigrationZoneResult) error {
+	if z.ZoneFile == "" {
+		result.Status = MigrationStatusCompleted
+		return nil
+	}
+	err := m.retry(ctx, z.Zone.Zone, func(ctx context.Context) error {
+		return client.PostMasterZoneFile(ctx, PostMasterZoneFileRequest{Zone: z.Zone.Zone, FileData: z.ZoneFile})
+	})
+	switch {
+	case err == nil:
+		result.Status = MigrationStatusCompleted
+		result.Error = ""
+	case ctx.Err() != nil:
+		return ctx.Err()
+	default:
+		result.Error = fmt.Sprintf("upload zone file: %s", err)
+	}
+	return nil
+}
+
+// retry calls f as an operation, so that transient errors are retried with the migration backoff
+func (m *migration) retry(ctx context.Context, id string, f func(context.Context) error) error {
+	op := operation.New(id, func(ctx context.Context) (operation.Status[struct{}], error) {
+		if err := f(ctx); err != nil {
+			return operation.Status[struct{}]{}, err
+		}
+		return operation.Status[struct{}]{State: operation.StateSucceeded}, nil
+	})
+	op.Backoff = m.backoff
+	op.IsRetryable = m.isRetryable()
+	_, err := op.Wait(ctx)
+	return err
+}
+
+// isRetryable returns an IsRetryable function which accepts transient errors up to MaxRetries times
+func (m *migration) isRetryable() func(error) bool {
+	failures := 0
+	return func(err error) bool {
+		failures++
+		return failures <= m.maxRetries && isTransientError(err)
+	}
+}
+
+func (m *migration) report() *MigrationReport {
+	report := MigrationReport{
+		Zones:      make([]MigrationZoneResult, 0, len(m.zones)),
+		RequestIDs: m.requestIDs,
+	}
+	for _, z := range m.zones {
+		report.Zones = append(report.Zones, *m.results[zoneKey(z.Zone.Zone)])
+	}
+	return &report
+}
+
+func zoneKey(zone string) string {
+	return strings.ToLower(strings.TrimSuffix(zone, "."))
+}
+
+// isTransientError reports whether an API call failed with a status code worth retrying
+func isTransientError(err error) bool {
+	var e *Error
+	if !errors.As(err, &e) {
+		return false
+	}
+	return e.StatusCode == http.StatusTooManyRequests || e.StatusCode >= http.StatusInternalServerError
+}
@@ -0,0 +1,221 @@
+package dns
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMigrateZones(t *testing.T) {
+	query := ZoneQueryString{Contract: "1-2AB34C", Group: "12345"}
+	primary := MigrationZone{Zone: ZoneCreate{Zone: "a.example", Type: "PRIMARY"}, ZoneFile: "a.example. 300 IN A 192.0.2.1\n"}
+	secondary := MigrationZone{Zone: ZoneCreate{Zone: "b.example", Type: "SECONDARY", Masters: []string{"192.0.2.53"}}}
+	alias := MigrationZone{Zone: ZoneCreate{Zone: "c.example", Type: "ALIAS", Target: "a.example"}}
+	plain := MigrationZone{Zone: ZoneCreate{Zone: "d.example", Type: "PRIMARY"}}
+
+	expectCreate := func(m *Mock, requestID string, zones ...MigrationZone) {
+		bulk := BulkZonesCreate{}
+		for _, z := range zones {
+			bulk.Zones = append(bulk.Zones, z.Zone)
+		}
+		m.On("CreateBulkZones", mock.Anything, CreateBulkZonesRequest{BulkZones: &bulk, ZoneQueryString: query}).
+			Return(&CreateBulkZonesResponse{RequestID: requestID}, nil).Once()
+	}
+	expectComplete := func(m *Mock, requestID string, created []string, failed ...BulkFailedZone) {
+		m.On("GetBulkZoneCreateStatus", mock.Anything, GetBulkZoneCreateStatusRequest{RequestID: requestID}).
+			Return(&GetBulkZoneCreateStatusResponse{RequestID: requestID, IsComplete: true}, nil).Once()
+		m.On("GetBulkZoneCreateResult", mock.Anything, GetBulkZoneCreateResultRequest{RequestID: requestID}).
+			Return(&GetBulkZoneCreateResultResponse{RequestID: requestID, SuccessfullyCreatedZones: created, FailedZones: failed}, nil).Once()
+	}
+
+	tests := map[string]struct {
+		params    MigrateZonesRequest
+		init      func(*Mock)
+		cancel    bool
+		expected  *MigrationReport
+		withError error
+	}{
+		"chunks, polling and retries": {
+			params: MigrateZonesRequest{
+				ZoneQueryString: query,
+				Zones:           []MigrationZone{primary, secondary, alias},
+				ChunkSize:       2,
+			},
+			init: func(m *Mock) {
+				expectCreate(m, "req-1", primary, secondary)
+				m.On("GetBulkZoneCreateStatus", mock.Anything, GetBulkZoneCreateStatusRequest{RequestID: "req-1"}).
+					Return(&GetBulkZoneCreateStatusResponse{RequestID: "req-1"}, nil).Once()
+				m.On("GetBulkZoneCreateStatus", mock.Anything, GetBulkZoneCreateStatusRequest{RequestID: "req-1"}).
+					Return(nil, &Error{StatusCode: http.StatusServiceUnavailable}).Once()
+				expectComplete(m, "req-1", []string{"a.example"}, BulkFailedZone{Zone: "b.example", FailureReason: "Internal error, try again later"})
+				expectCreate(m, "req-2", alias)
+				expectComplete(m, "req-2", []string{"c.example"})
+				expectCreate(m, "req-3", secondary)
+				expectComplete(m, "req-3", []string{"b.example"})
+				m.On("PostMasterZoneFile", mock.Anything, PostMasterZoneFileRequest{Zone: "a.example", FileData: primary.ZoneFile}).
+					Return(&Error{StatusCode: http.StatusTooManyRequests}).Once()
+				m.On("PostMasterZoneFile", mock.Anything, PostMasterZoneFileRequest{Zone: "a.example", FileData: primary.ZoneFile}).
+					Return(nil).Once()
+			},
+			expected: &MigrationReport{
+				Zones: []MigrationZoneResult{
+					{Zone: "a.example", Status: MigrationStatusCompleted, RequestID: "req-1", Attempts: 1},
+					{Zone: "b.example", Status: MigrationStatusCompleted, RequestID: "req-3", Attempts: 2},
+					{Zone: "c.example", Status: MigrationStatusCompleted, RequestID: "req-2", Attempts: 1},
+				},
+				RequestIDs: []string{"req-1", "req-2", "req-3"},
+			},
+		},
+		"permanent failure and failed zone file upload": {
+			params: MigrateZonesRequest{
+				ZoneQueryString: query,
+				Zones:           []MigrationZone{primary, secondary},
+			},
+			init: func(m *Mock) {
+				expectCreate(m, "req-1", primary, secondary)
+				expectComplete(m, "req-1", []string{"a.example"}, BulkFailedZone{Zone: "b.example", FailureReason: "ZONE_ALREADY_EXISTS"})
+				m.On("PostMasterZoneFile", mock.Anything, mock.Anything).
+					Return(&Error{StatusCode: http.StatusBadRequest, Title: "Invalid zone file"}).Once()
+			},
+			expected: &MigrationReport{
+				Zones: []MigrationZoneResult{
+					{Zone: "a.example", Status: MigrationStatusCreated, RequestID: "req-1", Attempts: 1,
+						Error: "upload zone file: Title: Invalid zone file; Type: ; Detail: "},
+					{Zone: "b.example", Status: MigrationStatusFailed, RequestID: "req-1", Attempts: 1, Error: "ZONE_ALREADY_EXISTS"},
+				},
+				RequestIDs: []string{"req-1"},
+			},
+		},
+		"bulk request status cannot be read": {
+			params: MigrateZonesRequest{
+				ZoneQueryString: query,
+				Zones:           []MigrationZone{secondary, alias},
+				ChunkSize:       1,
+			},
+			init: func(m *Mock) {
+				expectCreate(m, "req-1", secondary)
+				m.On("GetBulkZoneCreateStatus", mock.Anything, GetBulkZoneCreateStatusRequest{RequestID: "req-1"}).
+					Return(nil, &Error{StatusCode: http.StatusNotFound, Title: "Not found"}).Once()
+				expectCreate(m, "req-2", alias)
+				m.On("GetBulkZoneCreateStatus", mock.Anything, GetBulkZoneCreateStatusRequest{RequestID: "req-2"}).
+					Return(&GetBulkZoneCreateStatusResponse{RequestID: "req-2", IsComplete: true}, nil).Once()
+				m.On("GetBulkZoneCreateResult", mock.Anything, GetBulkZoneCreateResultRequest{RequestID: "req-2"}).
+					Return(nil, &Error{StatusCode: http.StatusBadGateway}).Once()
+				m.On("GetBulkZoneCreateResult", mock.Anything, GetBulkZoneCreateResultRequest{RequestID: "req-2"}).
+					Return(&GetBulkZoneCreateResultResponse{RequestID: "req-2", SuccessfullyCreatedZones: []string{"c.example"}}, nil).Once()
+			},
+			expected: &MigrationReport{
+				Zones: []MigrationZoneResult{
+					{Zone: "b.example", Status: MigrationStatusFailed, RequestID: "req-1", Attempts: 1,
+						Error: "bulk request req-1: Title: Not found; Type: ; Detail: "},
+					{Zone: "c.example", Status: MigrationStatusCompleted, RequestID: "req-2", Attempts: 1},
+				},
+				RequestIDs: []string{"req-1", "req-2"},
+			},
+		},
+		"retries disabled": {
+			params: MigrateZonesRequest{
+				ZoneQueryString: query,
+				Zones:           []MigrationZone{secondary},
+				MaxRetries:      -1,
+			},
+			init: func(m *Mock) {
+				m.On("CreateBulkZones", mock.Anything, mock.Anything).
+					Return(nil, &Error{StatusCode: http.StatusInternalServerError, Title: "Server error"}).Once()
+			},
+			expected: &MigrationReport{
+				Zones: []MigrationZoneResult{
+					{Zone: "b.example", Status: MigrationStatusFailed, Attempts: 1, Error: "Title: Server error; Type: ; Detail: "},
+				},
+			},
+		},
+		"resume": {
+			params: MigrateZonesRequest{
+				ZoneQueryString: query,
+				Zones:           []MigrationZone{primary, secondary, alias, plain},
+				Resume: &MigrationReport{
+					Zones: []MigrationZoneResult{
+						{Zone: "a.example", Status: MigrationStatusCreated, RequestID: "req-1", Attempts: 1, Error: "upload zone file: oops"},
+						{Zone: "b.example", Status: MigrationStatusCompleted, RequestID: "req-1", Attempts: 1},
+						{Zone: "c.example", Status: MigrationStatusPending, RequestID: "req-2", Attempts: 1},
+						{Zone: "d.example", Status: MigrationStatusFailed, RequestID: "req-2", Attempts: 1, Error: "Internal error"},
+					},
+					RequestIDs: []string{"req-1", "req-2"},
+				},
+			},
+			init: func(m *Mock) {
+				expectComplete(m, "req-2", []string{"c.example"})
+				expectCreate(m, "req-3", plain)
+				expectComplete(m, "req-3", []string{"d.example"})
+				m.On("PostMasterZoneFile", mock.Anything, PostMasterZoneFileRequest{Zone: "a.example", FileData: primary.ZoneFile}).
+					Return(nil).Once()
+			},
+			expected: &MigrationReport{
+				Zones: []MigrationZoneResult{
+					{Zone: "a.example", Status: MigrationStatusCompleted, RequestID: "req-1", Attempts: 1},
+					{Zone: "b.example", Status: MigrationStatusCompleted, RequestID: "req-1", Attempts: 1},
+					{Zone: "c.example", Status: MigrationStatusCompleted, RequestID: "req-2", Attempts: 1},
+					{Zone: "d.example", Status: MigrationStatusCompleted, RequestID: "req-3", Attempts: 2},
+				},
+				RequestIDs: []string{"req-1", "req-2", "req-3"},
+			},
+		},
+		"context cancelled": {
+			params: MigrateZonesRequest{
+				ZoneQueryString: query,
+				Zones:           []MigrationZone{secondary},
+			},
+			init: func(m *Mock) {
+				m.On("CreateBulkZones", mock.Anything, mock.Anything).Return(nil, context.Canceled).Once()
+			},
+			cancel: true,
+			expected: &MigrationReport{
+				Zones: []MigrationZoneResult{
+					{Zone: "b.example", Status: MigrationStatusPending, Attempts: 1},
+				},
+			},
+			withError: context.Canceled,
+		},
+		"validation error": {
+			params: MigrateZonesRequest{
+				ZoneQueryString: query,
+				Zones: []MigrationZone{
+					{Zone: ZoneCreate{Zone: "b.example", Type: "SECONDARY"}},
+					{Zone: ZoneCreate{Zone: "B.example.", Type: "ALIAS", Target: "a.example"}, ZoneFile: "x"},
+				},
+			},
+			withError: ErrStructValidation,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			m := &Mock{}
+			if test.init != nil {
+				test.init(m)
+			}
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			if test.cancel {
+				cancel()
+			}
+			test.params.PollInterval = time.Millisecond
+
+			report, err := MigrateZones(ctx, m, test.params)
+			m.AssertExpectations(t)
+			if test.withError != nil {
+				assert.True(t, errors.Is(err, test.withError), "want: %s; got: %s", test.withError, err)
+				assert.Equal(t, test.expected, report)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, test.expected, report)
+		})
+	}
+}
@@ -0,0 +1,44 @@
+package dns
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/akamai/AkamaiOPEN-edgegrid-golang/v11/pkg/operation"
+)
+
+// BulkZoneCreateOperation returns an Operation tracking a bulk zone create request. A completed request succeeds
+// even when some zones failed; they are listed by GetBulkZoneCreateResult
+func BulkZoneCreateOperation(client DNS, requestID string) *operation.Operation[*GetBulkZoneCreateStatusResponse] {
+	return operation.New(requestID, func(ctx context.Context) (operation.Status[*GetBulkZoneCreateStatusResponse], error) {
+		resp, err := client.GetBulkZoneCreateStatus(ctx, GetBulkZoneCreateStatusRequest{RequestID: requestID})
+		if err != nil {
+			return operation.Status[*GetBulkZoneCreateStatusResponse]{}, err
+		}
+		state, message := bulkState(resp.IsComplete, resp.ZonesSubmitted, resp.FailureCount)
+		return operation.Status[*GetBulkZoneCreateStatusResponse]{State: state, Message: message, Value: resp}, nil
+	})
+}
+
+// BulkZoneDeleteOperation returns an Operation tracking a bulk zone delete request. A completed request succeeds
+// even when some zones failed; they are listed by GetBulkZoneDeleteResult
+func BulkZoneDeleteOperation(client DNS, requestID string) *operation.Operation[*GetBulkZoneDeleteStatusResponse] {
+	return operation.New(requestID, func(ctx context.Context) (operation.Status[*GetBulkZoneDeleteStatusResponse], error) {
+		resp, err := client.GetBulkZoneDeleteStatus(ctx, GetBulkZoneDeleteStatusRequest{RequestID: requestID})
+		if err != nil {
+			return operation.Status[*GetBulkZoneDeleteStatusResponse]{}, err
+		}
+		state, message := bulkState(resp.IsComplete, resp.ZonesSubmitted, resp.FailureCount)
+		return operation.Status[*GetBulkZoneDeleteStatusResponse]{State: state, Message: message, Value: resp}, nil
+	})
+}
+
+func bulkState(complete bool, submitted, failed int) (operation.State, string) {
+	switch {
+	case !complete:
+		return operation.StatePending, ""
+	case failed > 0:
+		return operation.StateSucceeded, fmt.Sprintf("%d of %d zones failed", failed, submitted)
+	}
+	return operation.StateSucceeded, ""
+}
@@ -0,0 +1,54 @@
+package dns
+
+import (
+	"context"
+	"testing"
+
+	"github.com/akamai/AkamaiOPEN-edgegrid-golang/v11/pkg/operation"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBulkZoneOperations(t *testing.T) {
+	tests := map[string]struct {
+		complete        bool
+		failureCount    int
+		expectedState   operation.State
+		expectedMessage string
+	}{
+		"in progress": {
+			expectedState: operation.StatePending,
+		},
+		"complete": {
+			complete:      true,
+			expectedState: operation.StateSucceeded,
+		},
+		"complete with failed zones": {
+			complete:        true,
+			failureCount:    2,
+			expectedState:   operation.StateSucceeded,
+			expectedMessage: "2 of 5 zones failed",
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			m := &Mock{}
+			m.On("GetBulkZoneCreateStatus", mock.Anything, GetBulkZoneCreateStatusRequest{RequestID: "create"}).
+				Return(&GetBulkZoneCreateStatusResponse{RequestID: "create", ZonesSubmitted: 5, FailureCount: test.failureCount, IsComplete: test.complete}, nil).Once()
+			m.On("GetBulkZoneDeleteStatus", mock.Anything, GetBulkZoneDeleteStatusRequest{RequestID: "delete"}).
+				Return(&GetBulkZoneDeleteStatusResponse{RequestID: "delete", ZonesSubmitted: 5, FailureCount: test.failureCount, IsComplete: test.complete}, nil).Once()
+
+			created, err := BulkZoneCreateOperation(m, "create").Poll(context.Background())
+			require.NoError(t, err)
+			deleted, err := BulkZoneDeleteOperation(m, "delete").Poll(context.Background())
+			require.NoError(t, err)
+			m.AssertExpectations(t)
+			assert.Equal(t, test.expectedState, created.State)
+			assert.Equal(t, test.expectedMessage, created.Message)
+			assert.Equal(t, test.expectedState, deleted.State)
+			assert.Equal(t, test.expectedMessage, deleted.Message)
+		})
+	}
+}
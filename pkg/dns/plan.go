@@ -0,0 +1,318 @@
+package dns
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"sort"
+	"strings"
+
+	"github.com/akamai/AkamaiOPEN-edgegrid-golang/v11/pkg/edgegriderr"
+	validation "github.com/go-ozzo/ozzo-validation/v4"
+)
+
+type (
+	// PlanZoneRequest contains request parameters for PlanZone
+	PlanZoneRequest struct {
+		Zone string
+		// Desired lists every record set the zone should contain. Names may be absolute or "@" for the zone apex.
+		Desired []RecordSet
+		// ManageSOA allows the plan to change the SOA record set, which is protected by default
+		ManageSOA bool
+		// ManageApexNS allows the plan to change the NS record set at the zone apex, which is protected by default
+		ManageApexNS bool
+	}
+
+	// ZonePlan lists the record set changes which make a zone match the desired record sets
+	ZonePlan struct {
+		Zone    string
+		Changes []PlanChange
+		// Protected lists changes left out of the plan because they touch the SOA or apex NS record sets
+		Protected []PlanChange
+	}
+
+	// PlanChange is a single record set change of a ZonePlan. Current is nil for additions
+	// and Desired is nil for deletions.
+	PlanChange struct {
+		Op      ChangeListOp
+		Current *RecordSet
+		Desired *RecordSet
+	}
+
+	// ApplyPlanRequest contains request parameters for ApplyPlan
+	ApplyPlanRequest struct {
+		Plan *ZonePlan
+		// ChangeListThreshold is the number of changes above which the plan is applied atomically through a change list.
+		// It defaults to DefaultChangeListThreshold. A negative value always uses a change list.
+		ChangeListThreshold int
+	}
+
+	recordSetKey struct {
+		name       string
+		recordType string
+	}
+)
+
+// DefaultChangeListThreshold is the number of changes above which ApplyPlan uses a change list
+const DefaultChangeListThreshold = 10
+
+var (
+	// ErrPlanZone is returned when PlanZone fails
+	ErrPlanZone = errors.New("plan zone")
+	// ErrApplyPlan is returned when ApplyPlan fails
+	ErrApplyPlan = errors.New("apply plan")
+)
+
+// Validate validates PlanZoneRequest
+func (r PlanZoneRequest) Validate() error {
+	errs := validation.Errors{
+		"Zone": validation.Validate(r.Zone, validation.Required),
+	}
+	for i, rec := range r.Desired {
+		errs[fmt.Sprintf("Desired[%d]", i)] = validation.Errors{
+			"Name":  validation.Validate(rec.Name, validation.Required),
+			"Type":  validation.Validate(rec.Type, validation.Required),
+			"TTL":   validation.Validate(rec.TTL, validation.Required),
+			"Rdata": validation.Validate(rec.Rdata, validation.Required),
+		}.Filter()
+	}
+	return edgegriderr.ParseValidationErrors(errs)
+}
+
+// Validate validates ApplyPlanRequest
+func (r ApplyPlanRequest) Validate() error {
+	return edgegriderr.ParseValidationErrors(validation.Errors{
+		"Plan": validation.Validate(r.Plan, validation.Required),
+	})
+}
+
+// Validate validates ZonePlan
+func (p ZonePlan) Validate() error {
+	return validation.Errors{
+		"Zone": validation.Validate(p.Zone, validation.Required),
+	}.Filter()
+}
+
+// PlanZone compares the desired record sets with the record sets of the zone and returns the minimal set of
+// additions, edits and deletions, one per name and type. Record sets of the zone missing from Desired are deleted.
+//
+// RDATA is normalized the same way ProcessRdata does before comparison, and the order of RDATA within a set
+// is ignored. Changes of the SOA and apex NS record sets are moved to ZonePlan.Protected unless
+// ManageSOA or ManageApexNS are set. Deletions come first, then edits, then additions.
+func PlanZone(ctx context.Context, client DNS, params PlanZoneRequest) (*ZonePlan, error) {
+	if err := params.Validate(); err != nil {
+		return nil, fmt.Errorf("%s: %w: %s", ErrPlanZone, ErrStructValidation, err)
+	}
+
+	zone := normalizeName(params.Zone, "")
+	desired := make(map[recordSetKey]RecordSet, len(params.Desired))
+	for _, rec := range params.Desired {
+		rec.Name = normalizeName(rec.Name, zone)
+		rec.Type = strings.ToUpper(rec.Type)
+		if rec.Name != zone && !strings.HasSuffix(rec.Name, "."+zone) {
+			return nil, fmt.Errorf("%w: record set %s %s is outside of zone %s", ErrPlanZone, rec.Name, rec.Type, zone)
+		}
+		key := recordSetKey{name: rec.Name, recordType: rec.Type}
+		if _, ok := desired[key]; ok {
+			return nil, fmt.Errorf("%w: duplicate record set %s %s", ErrPlanZone, rec.Name, rec.Type)
+		}
+		desired[key] = rec
+	}
+
+	resp, err := client.GetRecordSets(ctx, GetRecordSetsRequest{
+		Zone:      params.Zone,
+		QueryArgs: &RecordSetQueryArgs{ShowAll: true},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrPlanZone, err)
+	}
+	current := make(map[recordSetKey]RecordSet, len(resp.RecordSets))
+	for _, rec := range resp.RecordSets {
+		rec.Name = normalizeName(rec.Name, zone)
+		rec.Type = strings.ToUpper(rec.Type)
+		current[recordSetKey{name: rec.Name, recordType: rec.Type}] = rec
+	}
+
+	plan := ZonePlan{Zone: params.Zone}
+	add := func(change PlanChange, key recordSetKey) {
+		if (key.recordType == "SOA" && !params.ManageSOA) || (key.recordType == "NS" && key.name == zone && !params.ManageApexNS) {
+			plan.Protected = append(plan.Protected, change)
+			return
+		}
+		plan.Changes = append(plan.Changes, change)
+	}
+	for key, rec := range desired {
+		cur, ok := current[key]
+		switch {
+		case !ok:
+			add(PlanChange{Op: ChangeListOpAdd, Desired: &rec}, key)
+		case !equalRecordSets(cur, rec):
+			add(PlanChange{Op: ChangeListOpEdit, Current: &cur, Desired: &rec}, key)
+		}
+	}
+	for key, cur := range current {
+		if _, ok := desired[key]; !ok {
+			add(PlanChange{Op: ChangeListOpDelete, Current: &cur}, key)
+		}
+	}
+	sortPlanChanges(plan.Changes)
+	sortPlanChanges(plan.Protected)
+
+	return &plan, nil
+}
+
+// ApplyPlan applies the changes of a ZonePlan. Small plans are applied record set by record set with CreateRecord,
+// UpdateRecord and DeleteRecord. Plans with more changes than ChangeListThreshold are applied atomically:
+// a change list is saved, every change is appended to it and the change list is submitted. When a change
+// cannot be appended or the change list cannot be submitted, the change list is deleted.
+func ApplyPlan(ctx context.Context, client DNS, params ApplyPlanRequest) error {
+	if err := params.Validate(); err != nil {
+		return fmt.Errorf("%s: %w: %s", ErrApplyPlan, ErrStructValidation, err)
+	}
+
+	plan := params.Plan
+	if len(plan.Changes) == 0 {
+		return nil
+	}
+
+	threshold := params.ChangeListThreshold
+	if threshold == 0 {
+		threshold = DefaultChangeListThreshold
+	}
+	if threshold < 0 || len(plan.Changes) > threshold {
+		return applyPlanWithChangeList(ctx, client, plan)
+	}
+
+	for _, change := range plan.Changes {
+		var err error
+		switch change.Op {
+		case ChangeListOpAdd:
+			err = client.CreateRecord(ctx, CreateRecordRequest{Zone: plan.Zone, Record: change.Desired.recordBody()})
+		case ChangeListOpEdit:
+			err = client.UpdateRecord(ctx, UpdateRecordRequest{Zone: plan.Zone, Record: change.Desired.recordBody()})
+		case ChangeListOpDelete:
+			err = client.DeleteRecord(ctx, DeleteRecordRequest{Zone: plan.Zone, Name: change.Current.Name, RecordType: change.Current.Type})
+		default:
+			err = fmt.Errorf("unknown operation")
+		}
+		if err != nil {
+			return fmt.Errorf("%w: %s: %w", ErrApplyPlan, change, err)
+		}
+	}
+	return nil
+}
+
+func applyPlanWithChangeList(ctx context.Context, client DNS, plan *ZonePlan) (err error) {
+	if err := client.SaveChangeList(ctx, SaveChangeListRequest{Zone: plan.Zone}); err != nil {
+		return fmt.Errorf("%w: %w", ErrApplyPlan, err)
+	}
+	// The saved change list blocks the next one for the zone, so it is discarded when it cannot be submitted.
+	defer func() {
+		if err == nil {
+			return
+		}
+		if deleteErr := client.DeleteChangeList(ctx, DeleteChangeListRequest{Zone: plan.Zone}); deleteErr != nil {
+			err = errors.Join(err, fmt.Errorf("%w: %w", ErrDeleteChangeList, deleteErr))
+		}
+	}()
+	for _, change := range plan.Changes {
+		set := change.Desired
+		if change.Op == ChangeListOpDelete {
+			set = change.Current
+		}
+		clChange := &ChangeListChange{Name: set.Name, Type: set.Type, Op: change.Op}
+		if change.Op != ChangeListOpDelete {
+			clChange.TTL = set.TTL
+			clChange.Rdata = set.Rdata
+		}
+		if err := client.AddChangeListChange(ctx, AddChangeListChangeRequest{Zone: plan.Zone, Change: clChange}); err != nil {
+			return fmt.Errorf("%w: %s: %w", ErrApplyPlan, change, err)
+		}
+	}
+	if err := client.SubmitChangeList(ctx, SubmitChangeListRequest{Zone: plan.Zone}); err != nil {
+		return fmt.Errorf("%w: %w", ErrApplyPlan, err)
+	}
+	return nil
+}
+
+// String returns the operation, name and type of the change
+func (c PlanChange) String() string {
+	set := c.Desired
+	if set == nil {
+		set = c.Current
+	}
+	if set == nil {
+		return string(c.Op)
+	}
+	return fmt.Sprintf("%s %s %s", c.Op, set.Name, set.Type)
+}
+
+func (rs *RecordSet) recordBody() *RecordBody {
+	return &RecordBody{
+		Name:       rs.Name,
+		RecordType: rs.Type,
+		TTL:        rs.TTL,
+		Target:     rs.Rdata,
+	}
+}
+
+// normalizeName returns the name in lower case without the trailing dot, replacing "@" with the zone
+func normalizeName(name, zone string) string {
+	if name == "@" {
+		return zone
+	}
+	return strings.ToLower(strings.TrimSuffix(name, "."))
+}
+
+// equalRecordSets compares TTL and RDATA of record sets, ignoring the order of RDATA
+func equalRecordSets(a, b RecordSet) bool {
+	if a.TTL != b.TTL || len(a.Rdata) != len(b.Rdata) {
+		return false
+	}
+	rdataA, rdataB := normalizeRdata(a.Type, a.Rdata), normalizeRdata(b.Type, b.Rdata)
+	for i := range rdataA {
+		if rdataA[i] != rdataB[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// normalizeRdata expands AAAA addresses and pads LOC coordinates like ProcessRdata,
+// leaving values which cannot be parsed untouched, and sorts the result
+func normalizeRdata(recordType string, rData []string) []string {
+	normalized := make([]string, 0, len(rData))
+	for _, str := range rData {
+		str = strings.TrimSpace(str)
+		switch recordType {
+		case "AAAA":
+			if addr := net.ParseIP(str); addr != nil {
+				str = fullIPv6(addr)
+			}
+		case "LOC":
+			if padded := padCoordinates(str); padded != "" {
+				str = padded
+			}
+		}
+		normalized = append(normalized, str)
+	}
+	sort.Strings(normalized)
+	return normalized
+}
+
+var planOpOrder = map[ChangeListOp]int{
+	ChangeListOpDelete: 0,
+	ChangeListOpEdit:   1,
+	ChangeListOpAdd:    2,
+}
+
+func sortPlanChanges(changes []PlanChange) {
+	sort.Slice(changes, func(i, j int) bool {
+		a, b := changes[i], changes[j]
+		if a.Op != b.Op {
+			return planOpOrder[a.Op] < planOpOrder[b.Op]
+		}
+		return a.String() < b.String()
+	})
+}
@@ -0,0 +1,233 @@
+package dns
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPlanZone(t *testing.T) {
+	current := []RecordSet{
+		{Name: "example.com", Type: "SOA", TTL: 86400, Rdata: []string{"a1-1.akam.net. hostmaster.example.com. 2024010101 3600 600 604800 300"}},
+		{Name: "example.com", Type: "NS", TTL: 86400, Rdata: []string{"a1-1.akam.net.", "a2-2.akam.net."}},
+		{Name: "example.com", Type: "A", TTL: 300, Rdata: []string{"192.0.2.1", "192.0.2.2"}},
+		{Name: "example.com", Type: "AAAA", TTL: 300, Rdata: []string{"2001:0db8:0000:0000:0000:0000:0000:0001"}},
+		{Name: "www.example.com", Type: "CNAME", TTL: 300, Rdata: []string{"example.com."}},
+		{Name: "old.example.com", Type: "TXT", TTL: 300, Rdata: []string{`"remove me"`}},
+	}
+
+	tests := map[string]struct {
+		params       PlanZoneRequest
+		expectedPlan *ZonePlan
+		withError    error
+	}{
+		"minimal plan with protected records": {
+			params: PlanZoneRequest{
+				Zone: "example.com",
+				Desired: []RecordSet{
+					{Name: "example.com.", Type: "soa", TTL: 86400, Rdata: []string{"a1-1.akam.net. hostmaster.example.com. 2024020202 3600 600 604800 300"}},
+					{Name: "@", Type: "NS", TTL: 86400, Rdata: []string{"a2-2.akam.net."}},
+					{Name: "example.com", Type: "A", TTL: 300, Rdata: []string{"192.0.2.2", "192.0.2.1"}},
+					{Name: "Example.com", Type: "AAAA", TTL: 300, Rdata: []string{"2001:db8::1"}},
+					{Name: "www.example.com", Type: "CNAME", TTL: 600, Rdata: []string{"example.com."}},
+					{Name: "api.example.com", Type: "A", TTL: 300, Rdata: []string{"192.0.2.10"}},
+				},
+			},
+			expectedPlan: &ZonePlan{
+				Zone: "example.com",
+				Changes: []PlanChange{
+					{Op: ChangeListOpDelete, Current: &current[5]},
+					{
+						Op:      ChangeListOpEdit,
+						Current: &current[4],
+						Desired: &RecordSet{Name: "www.example.com", Type: "CNAME", TTL: 600, Rdata: []string{"example.com."}},
+					},
+					{Op: ChangeListOpAdd, Desired: &RecordSet{Name: "api.example.com", Type: "A", TTL: 300, Rdata: []string{"192.0.2.10"}}},
+				},
+				Protected: []PlanChange{
+					{
+						Op:      ChangeListOpEdit,
+						Current: &current[1],
+						Desired: &RecordSet{Name: "example.com", Type: "NS", TTL: 86400, Rdata: []string{"a2-2.akam.net."}},
+					},
+					{
+						Op:      ChangeListOpEdit,
+						Current: &current[0],
+						Desired: &RecordSet{Name: "example.com", Type: "SOA", TTL: 86400, Rdata: []string{"a1-1.akam.net. hostmaster.example.com. 2024020202 3600 600 604800 300"}},
+					},
+				},
+			},
+		},
+		"managed SOA and apex NS are deleted": {
+			params: PlanZoneRequest{
+				Zone:         "example.com",
+				ManageSOA:    true,
+				ManageApexNS: true,
+				Desired:      current[2:5],
+			},
+			expectedPlan: &ZonePlan{
+				Zone: "example.com",
+				Changes: []PlanChange{
+					{Op: ChangeListOpDelete, Current: &current[1]},
+					{Op: ChangeListOpDelete, Current: &current[0]},
+					{Op: ChangeListOpDelete, Current: &current[5]},
+				},
+			},
+		},
+		"record set outside of zone": {
+			params: PlanZoneRequest{
+				Zone:    "example.com",
+				Desired: []RecordSet{{Name: "www.example.net", Type: "A", TTL: 300, Rdata: []string{"192.0.2.1"}}},
+			},
+			withError: ErrPlanZone,
+		},
+		"duplicate record set": {
+			params: PlanZoneRequest{
+				Zone: "example.com",
+				Desired: []RecordSet{
+					{Name: "www.example.com", Type: "A", TTL: 300, Rdata: []string{"192.0.2.1"}},
+					{Name: "WWW.example.com.", Type: "a", TTL: 300, Rdata: []string{"192.0.2.2"}},
+				},
+			},
+			withError: ErrPlanZone,
+		},
+		"validation error": {
+			params: PlanZoneRequest{
+				Zone:    "example.com",
+				Desired: []RecordSet{{Name: "www.example.com", Type: "A"}},
+			},
+			withError: ErrStructValidation,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			m := &Mock{}
+			m.On("GetRecordSets", mock.Anything, GetRecordSetsRequest{Zone: "example.com", QueryArgs: &RecordSetQueryArgs{ShowAll: true}}).
+				Return(&GetRecordSetsResponse{RecordSets: current}, nil).Maybe()
+
+			plan, err := PlanZone(context.Background(), m, test.params)
+			if test.withError != nil {
+				assert.True(t, errors.Is(err, test.withError), "want: %s; got: %s", test.withError, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, test.expectedPlan, plan)
+			m.AssertExpectations(t)
+		})
+	}
+}
+
+func TestApplyPlan(t *testing.T) {
+	www := RecordSet{Name: "www.example.com", Type: "A", TTL: 300, Rdata: []string{"192.0.2.1"}}
+	api := RecordSet{Name: "api.example.com", Type: "A", TTL: 300, Rdata: []string{"192.0.2.2"}}
+	old := RecordSet{Name: "old.example.com", Type: "TXT", TTL: 300, Rdata: []string{`"x"`}}
+	plan := &ZonePlan{
+		Zone: "example.com",
+		Changes: []PlanChange{
+			{Op: ChangeListOpDelete, Current: &old},
+			{Op: ChangeListOpEdit, Current: &www, Desired: &www},
+			{Op: ChangeListOpAdd, Desired: &api},
+		},
+	}
+
+	tests := map[string]struct {
+		params    ApplyPlanRequest
+		init      func(*Mock)
+		withError error
+	}{
+		"small plan uses record operations": {
+			params: ApplyPlanRequest{Plan: plan},
+			init: func(m *Mock) {
+				m.On("DeleteRecord", mock.Anything, DeleteRecordRequest{Zone: "example.com", Name: "old.example.com", RecordType: "TXT"}).Return(nil).Once()
+				m.On("UpdateRecord", mock.Anything, UpdateRecordRequest{Zone: "example.com", Record: &RecordBody{
+					Name: "www.example.com", RecordType: "A", TTL: 300, Target: []string{"192.0.2.1"},
+				}}).Return(nil).Once()
+				m.On("CreateRecord", mock.Anything, CreateRecordRequest{Zone: "example.com", Record: &RecordBody{
+					Name: "api.example.com", RecordType: "A", TTL: 300, Target: []string{"192.0.2.2"},
+				}}).Return(nil).Once()
+			},
+		},
+		"large plan uses change list": {
+			params: ApplyPlanRequest{Plan: plan, ChangeListThreshold: 2},
+			init: func(m *Mock) {
+				m.On("SaveChangeList", mock.Anything, SaveChangeListRequest{Zone: "example.com"}).Return(nil).Once()
+				m.On("AddChangeListChange", mock.Anything, AddChangeListChangeRequest{Zone: "example.com", Change: &ChangeListChange{
+					Name: "old.example.com", Type: "TXT", Op: ChangeListOpDelete,
+				}}).Return(nil).Once()
+				m.On("AddChangeListChange", mock.Anything, AddChangeListChangeRequest{Zone: "example.com", Change: &ChangeListChange{
+					Name: "www.example.com", Type: "A", Op: ChangeListOpEdit, TTL: 300, Rdata: []string{"192.0.2.1"},
+				}}).Return(nil).Once()
+				m.On("AddChangeListChange", mock.Anything, AddChangeListChangeRequest{Zone: "example.com", Change: &ChangeListChange{
+					Name: "api.example.com", Type: "A", Op: ChangeListOpAdd, TTL: 300, Rdata: []string{"192.0.2.2"},
+				}}).Return(nil).Once()
+				m.On("SubmitChangeList", mock.Anything, SubmitChangeListRequest{Zone: "example.com"}).Return(nil).Once()
+			},
+		},
+		"change list is deleted after a failure": {
+			params: ApplyPlanRequest{Plan: plan, ChangeListThreshold: -1},
+			init: func(m *Mock) {
+				m.On("SaveChangeList", mock.Anything, SaveChangeListRequest{Zone: "example.com"}).Return(nil).Once()
+				m.On("AddChangeListChange", mock.Anything, mock.Anything).Return(fmt.Errorf("oops")).Once()
+				m.On("DeleteChangeList", mock.Anything, DeleteChangeListRequest{Zone: "example.com"}).Return(nil).Once()
+			},
+			withError: ErrApplyPlan,
+		},
+		"change list is deleted after a failed submit": {
+			params: ApplyPlanRequest{Plan: plan, ChangeListThreshold: -1},
+			init: func(m *Mock) {
+				m.On("SaveChangeList", mock.Anything, SaveChangeListRequest{Zone: "example.com"}).Return(nil).Once()
+				m.On("AddChangeListChange", mock.Anything, mock.Anything).Return(nil).Times(3)
+				m.On("SubmitChangeList", mock.Anything, SubmitChangeListRequest{Zone: "example.com"}).Return(fmt.Errorf("oops")).Once()
+				m.On("DeleteChangeList", mock.Anything, DeleteChangeListRequest{Zone: "example.com"}).Return(nil).Once()
+			},
+			withError: ErrApplyPlan,
+		},
+		"change list cannot be deleted after a failure": {
+			params: ApplyPlanRequest{Plan: plan, ChangeListThreshold: -1},
+			init: func(m *Mock) {
+				m.On("SaveChangeList", mock.Anything, SaveChangeListRequest{Zone: "example.com"}).Return(nil).Once()
+				m.On("AddChangeListChange", mock.Anything, mock.Anything).Return(fmt.Errorf("oops")).Once()
+				m.On("DeleteChangeList", mock.Anything, DeleteChangeListRequest{Zone: "example.com"}).Return(fmt.Errorf("oops")).Once()
+			},
+			withError: ErrDeleteChangeList,
+		},
+		"record operation fails": {
+			params: ApplyPlanRequest{Plan: plan},
+			init: func(m *Mock) {
+				m.On("DeleteRecord", mock.Anything, mock.Anything).Return(ErrDeleteRecord).Once()
+			},
+			withError: ErrDeleteRecord,
+		},
+		"empty plan": {
+			params: ApplyPlanRequest{Plan: &ZonePlan{Zone: "example.com"}},
+		},
+		"validation error": {
+			params:    ApplyPlanRequest{Plan: &ZonePlan{}},
+			withError: ErrStructValidation,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			m := &Mock{}
+			if test.init != nil {
+				test.init(m)
+			}
+
+			err := ApplyPlan(context.Background(), m, test.params)
+			if test.withError != nil {
+				assert.True(t, errors.Is(err, test.withError), "want: %s; got: %s", test.withError, err)
+				m.AssertExpectations(t)
+				return
+			}
+			require.NoError(t, err)
+			m.AssertExpectations(t)
+		})
+	}
+}
@@ -0,0 +1,1104 @@
+package dns
+
+import (
+	"encoding"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/netip"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	validation "github.com/go-ozzo/ozzo-validation/v4"
+	"github.com/go-ozzo/ozzo-validation/v4/is"
+)
+
+type (
+	// RData is the typed RDATA of a single record. MarshalText returns the presentation format used in
+	// RecordSet.Rdata and fails when the RDATA is not valid, so typed values cannot produce malformed records.
+	RData interface {
+		encoding.TextMarshaler
+		// Type returns the record type of the RDATA
+		Type() string
+		// Validate validates the RDATA
+		Validate() error
+	}
+
+	// ARData is the RDATA of an A record
+	ARData struct {
+		Address netip.Addr
+	}
+
+	// AAAARData is the RDATA of an AAAA record
+	AAAARData struct {
+		Address netip.Addr
+	}
+
+	// CNAMERData is the RDATA of a CNAME record
+	CNAMERData struct {
+		Target string
+	}
+
+	// MXRData is the RDATA of an MX record
+	MXRData struct {
+		Preference uint16
+		Exchange   string
+	}
+
+	// SRVRData is the RDATA of an SRV record
+	SRVRData struct {
+		Priority uint16
+		Weight   uint16
+		Port     uint16
+		Target   string
+	}
+
+	// CAARData is the RDATA of a CAA record
+	CAARData struct {
+		Flags uint8
+		Tag   string
+		Value string
+	}
+
+	// TXTRData is the RDATA of a TXT record. Each of Strings is a character string of at most
+	// MaxTXTStringLength bytes; use NewTXTRData to split longer text.
+	TXTRData struct {
+		Strings []string
+	}
+
+	// SOARData is the RDATA of an SOA record
+	SOARData struct {
+		MName   string
+		RName   string
+		Serial  uint32
+		Refresh uint32
+		Retry   uint32
+		Expire  uint32
+		Minimum uint32
+	}
+
+	// DSRData is the RDATA of a DS record. Digest is hex encoded.
+	DSRData struct {
+		KeyTag     uint16
+		Algorithm  uint8
+		DigestType uint8
+		Digest     string
+	}
+
+	// DNSKEYRData is the RDATA of a DNSKEY record. PublicKey is base64 encoded.
+	DNSKEYRData struct {
+		Flags     uint16
+		Protocol  uint8
+		Algorithm uint8
+		PublicKey string
+	}
+
+	// SVCBRData is the RDATA of an SVCB record. Priority 0 is AliasMode, which takes no parameters.
+	SVCBRData struct {
+		Priority uint16
+		Target   string
+		Params   []SVCParam
+	}
+
+	// HTTPSRData is the RDATA of an HTTPS record, which has the same format as SVCB
+	HTTPSRData struct {
+		SVCBRData
+	}
+
+	// SVCParam is a single SvcParam of SVCB and HTTPS records. Value is empty for keys without a value.
+	SVCParam struct {
+		Key   string
+		Value string
+	}
+
+	// NAPTRRData is the RDATA of a NAPTR record
+	NAPTRRData struct {
+		Order       uint16
+		Preference  uint16
+		Flags       string
+		Service     string
+		Regexp      string
+		Replacement string
+	}
+
+	// TLSARData is the RDATA of a TLSA record. Certificate is hex encoded.
+	TLSARData struct {
+		Usage        uint8
+		Selector     uint8
+		MatchingType uint8
+		Certificate  string
+	}
+
+	// AKAMAICDNRData is the RDATA of an AKAMAICDN record, which points the name to an edge hostname
+	AKAMAICDNRData struct {
+		EdgeHostname string
+	}
+
+	// AKAMAITLCRData is the RDATA of an AKAMAITLC record
+	AKAMAITLCRData struct {
+		AnswerType string
+		DNSName    string
+	}
+
+	// RRSIGRData is the RDATA of an RRSIG record. Signature is base64 encoded.
+	RRSIGRData struct {
+		TypeCovered string
+		Algorithm   uint8
+		Labels      uint8
+		OriginalTTL uint32
+		Expiration  time.Time
+		Inception   time.Time
+		KeyTag      uint16
+		SignerName  string
+		Signature   string
+	}
+
+	rdataValue interface {
+		RData
+		encoding.TextUnmarshaler
+	}
+)
+
+const (
+	// MaxTXTStringLength is the maximum length in bytes of a single character string of a TXT record
+	MaxTXTStringLength = 255
+
+	// rrsigTimeFormat is the YYYYMMDDHHmmSS format of RRSIG expiration and inception
+	rrsigTimeFormat = "20060102150405"
+)
+
+var (
+	// ErrInvalidRData is returned when RDATA cannot be parsed or is not valid
+	ErrInvalidRData = errors.New("invalid rdata")
+	// ErrUnsupportedRecordType is returned when a record type has no typed RDATA
+	ErrUnsupportedRecordType = errors.New("unsupported record type")
+
+	rdataTypes = map[string]func() rdataValue{
+		"A":         func() rdataValue { return &ARData{} },
+		"AAAA":      func() rdataValue { return &AAAARData{} },
+		"CNAME":     func() rdataValue { return &CNAMERData{} },
+		"MX":        func() rdataValue { return &MXRData{} },
+		"SRV":       func() rdataValue { return &SRVRData{} },
+		"CAA":       func() rdataValue { return &CAARData{} },
+		"TXT":       func() rdataValue { return &TXTRData{} },
+		"SOA":       func() rdataValue { return &SOARData{} },
+		"DS":        func() rdataValue { return &DSRData{} },
+		"DNSKEY":    func() rdataValue { return &DNSKEYRData{} },
+		"SVCB":      func() rdataValue { return &SVCBRData{} },
+		"HTTPS":     func() rdataValue { return &HTTPSRData{} },
+		"NAPTR":     func() rdataValue { return &NAPTRRData{} },
+		"TLSA":      func() rdataValue { return &TLSARData{} },
+		"AKAMAICDN": func() rdataValue { return &AKAMAICDNRData{} },
+		"AKAMAITLC": func() rdataValue { return &AKAMAITLCRData{} },
+		"RRSIG":     func() rdataValue { return &RRSIGRData{} },
+	}
+
+	caaTagRegexp     = regexp.MustCompile(`^[A-Za-z0-9]{1,15}$`)
+	naptrFlagsRegexp = regexp.MustCompile(`^[A-Za-z0-9]*$`)
+
+	dsDigestLengths = map[uint8]int{1: 40, 2: 64, 3: 64, 4: 96}
+
+	// svcParamKeys lists the SvcParamKeys of RFC 9460 and whether they require a value
+	svcParamKeys = map[string]bool{
+		"mandatory":       true,
+		"alpn":            true,
+		"no-default-alpn": false,
+		"port":            true,
+		"ipv4hint":        true,
+		"ech":             true,
+		"ipv6hint":        true,
+	}
+)
+
+// UnmarshalRData parses RDATA of the record type from presentation format. The returned value is a pointer to
+// one of the typed RDATA structs, for example *MXRData for MX records.
+func UnmarshalRData(recordType, rdata string) (RData, error) {
+	newValue, ok := rdataTypes[strings.ToUpper(recordType)]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrUnsupportedRecordType, recordType)
+	}
+	value := newValue()
+	if err := value.UnmarshalText([]byte(rdata)); err != nil {
+		return nil, fmt.Errorf("%s %q: %w", strings.ToUpper(recordType), rdata, err)
+	}
+	return value, nil
+}
+
+// NewRecordSet builds a record set from typed RDATA. All values must have the same record type and be valid.
+func NewRecordSet(name string, ttl int, rdata ...RData) (*RecordSet, error) {
+	if len(rdata) == 0 {
+		return nil, fmt.Errorf("%w: record set %s has no RDATA", ErrInvalidRData, name)
+	}
+	set := RecordSet{
+		Name:  name,
+		Type:  rdata[0].Type(),
+		TTL:   ttl,
+		Rdata: make([]string, 0, len(rdata)),
+	}
+	if set.Type == "CNAME" && len(rdata) > 1 {
+		return nil, fmt.Errorf("%w: record set %s CNAME can have only one record", ErrInvalidRData, name)
+	}
+	for _, value := range rdata {
+		if value.Type() != set.Type {
+			return nil, fmt.Errorf("%w: record set %s mixes %s and %s RDATA", ErrInvalidRData, name, set.Type, value.Type())
+		}
+		text, err := value.MarshalText()
+		if err != nil {
+			return nil, fmt.Errorf("record set %s %s: %w", name, set.Type, err)
+		}
+		set.Rdata = append(set.Rdata, string(text))
+	}
+	return &set, nil
+}
+
+// TypedRData parses every RDATA of the record set with UnmarshalRData
+func (rs RecordSet) TypedRData() ([]RData, error) {
+	result := make([]RData, 0, len(rs.Rdata))
+	for _, rdata := range rs.Rdata {
+		value, err := UnmarshalRData(rs.Type, rdata)
+		if err != nil {
+			return nil, fmt.Errorf("record set %s: %w", rs.Name, err)
+		}
+		result = append(result, value)
+	}
+	return result, nil
+}
+
+// NewTXTRData splits text into character strings of at most MaxTXTStringLength bytes,
+// without breaking UTF-8 encoded characters
+func NewTXTRData(text string) TXTRData {
+	var chunks []string
+	for len(text) > MaxTXTStringLength {
+		cut := MaxTXTStringLength
+		for cut > 0 && !utf8.RuneStart(text[cut]) {
+			cut--
+		}
+		if cut == 0 {
+			cut = MaxTXTStringLength
+		}
+		chunks = append(chunks, text[:cut])
+		text = text[cut:]
+	}
+	return TXTRData{Strings: append(chunks, text)}
+}
+
+// Text returns the character strings of the record joined together
+func (r TXTRData) Text() string {
+	return strings.Join(r.Strings, "")
+}
+
+// Type returns the record type of the RDATA
+func (ARData) Type() string { return "A" }
+
+// Type returns the record type of the RDATA
+func (AAAARData) Type() string { return "AAAA" }
+
+// Type returns the record type of the RDATA
+func (CNAMERData) Type() string { return "CNAME" }
+
+// Type returns the record type of the RDATA
+func (MXRData) Type() string { return "MX" }
+
+// Type returns the record type of the RDATA
+func (SRVRData) Type() string { return "SRV" }
+
+// Type returns the record type of the RDATA
+func (CAARData) Type() string { return "CAA" }
+
+// Type returns the record type of the RDATA
+func (TXTRData) Type() string { return "TXT" }
+
+// Type returns the record type of the RDATA
+func (SOARData) Type() string { return "SOA" }
+
+// Type returns the record type of the RDATA
+func (DSRData) Type() string { return "DS" }
+
+// Type returns the record type of the RDATA
+func (DNSKEYRData) Type() string { return "DNSKEY" }
+
+// Type returns the record type of the RDATA
+func (SVCBRData) Type() string { return "SVCB" }
+
+// Type returns the record type of the RDATA
+func (HTTPSRData) Type() string { return "HTTPS" }
+
+// Type returns the record type of the RDATA
+func (NAPTRRData) Type() string { return "NAPTR" }
+
+// Type returns the record type of the RDATA
+func (TLSARData) Type() string { return "TLSA" }
+
+// Type returns the record type of the RDATA
+func (AKAMAICDNRData) Type() string { return "AKAMAICDN" }
+
+// Type returns the record type of the RDATA
+func (AKAMAITLCRData) Type() string { return "AKAMAITLC" }
+
+// Type returns the record type of the RDATA
+func (RRSIGRData) Type() string { return "RRSIG" }
+
+// Validate validates ARData
+func (r ARData) Validate() error {
+	return validation.Errors{
+		"Address": validation.Validate(r.Address, ipAddress(true)),
+	}.Filter()
+}
+
+// Validate validates AAAARData
+func (r AAAARData) Validate() error {
+	return validation.Errors{
+		"Address": validation.Validate(r.Address, ipAddress(false)),
+	}.Filter()
+}
+
+// Validate validates CNAMERData
+func (r CNAMERData) Validate() error {
+	return validation.Errors{
+		"Target": validation.Validate(r.Target, validation.Required, domainName(false)),
+	}.Filter()
+}
+
+// Validate validates MXRData
+func (r MXRData) Validate() error {
+	return validation.Errors{
+		"Exchange": validation.Validate(r.Exchange, validation.Required, domainName(true)),
+	}.Filter()
+}
+
+// Validate validates SRVRData
+func (r SRVRData) Validate() error {
+	return validation.Errors{
+		"Target": validation.Validate(r.Target, validation.Required, domainName(true)),
+	}.Filter()
+}
+
+// Validate validates CAARData
+func (r CAARData) Validate() error {
+	return validation.Errors{
+		"Tag": validation.Validate(r.Tag, validation.Required,
+			validation.Match(caaTagRegexp).Error("must contain at most 15 letters and digits")),
+	}.Filter()
+}
+
+// Validate validates TXTRData
+func (r TXTRData) Validate() error {
+	return validation.Errors{
+		"Strings": validation.Validate(r.Strings, validation.Required, validation.Each(
+			validation.Length(0, MaxTXTStringLength).Error(fmt.Sprintf("must be at most %d bytes long", MaxTXTStringLength)))),
+	}.Filter()
+}
+
+// Validate validates SOARData
+func (r SOARData) Validate() error {
+	return validation.Errors{
+		"MName": validation.Validate(r.MName, validation.Required, domainName(false)),
+		"RName": validation.Validate(r.RName, validation.Required, domainName(false)),
+	}.Filter()
+}
+
+// Validate validates DSRData
+func (r DSRData) Validate() error {
+	digestLength, knownDigest := dsDigestLengths[r.DigestType]
+	return validation.Errors{
+		"Algorithm": validation.Validate(r.Algorithm, validation.Required),
+		"DigestType": validation.Validate(r.DigestType, validation.By(func(interface{}) error {
+			if !knownDigest {
+				return errors.New("must be one of: 1, 2, 3, 4")
+			}
+			return nil
+		})),
+		"Digest": validation.Validate(r.Digest, validation.Required, is.Hexadecimal,
+			validation.When(knownDigest, validation.Length(digestLength, digestLength).
+				Error(fmt.Sprintf("must be %d hexadecimal characters long for digest type %d", digestLength, r.DigestType)))),
+	}.Filter()
+}
+
+// Validate validates DNSKEYRData
+func (r DNSKEYRData) Validate() error {
+	return validation.Errors{
+		"Protocol": validation.Validate(r.Protocol, validation.By(func(interface{}) error {
+			if r.Protocol != 3 {
+				return errors.New("must be 3")
+			}
+			return nil
+		})),
+		"Algorithm": validation.Validate(r.Algorithm, validation.Required),
+		"PublicKey": validation.Validate(r.PublicKey, validation.Required, is.Base64),
+	}.Filter()
+}
+
+// Validate validates SVCBRData
+func (r SVCBRData) Validate() error {
+	return validation.Errors{
+		"Target": validation.Validate(r.Target, validation.Required, domainName(true)),
+		"Params": validation.Validate(r.Params,
+			validation.When(r.Priority == 0, validation.Empty.Error("must be empty in AliasMode")),
+			validation.By(validateSVCParams)),
+	}.Filter()
+}
+
+// Validate validates NAPTRRData
+func (r NAPTRRData) Validate() error {
+	return validation.Errors{
+		"Flags": validation.Validate(r.Flags, validation.Match(naptrFlagsRegexp).Error("must contain only letters and digits")),
+		"Replacement": validation.Validate(r.Replacement, validation.Required, domainName(true),
+			validation.When(r.Regexp != "", validation.In(".").Error(`must be "." when Regexp is set`))),
+	}.Filter()
+}
+
+// Validate validates TLSARData
+func (r TLSARData) Validate() error {
+	return validation.Errors{
+		"Usage":        validation.Validate(r.Usage, validation.Max(uint8(3))),
+		"Selector":     validation.Validate(r.Selector, validation.Max(uint8(1))),
+		"MatchingType": validation.Validate(r.MatchingType, validation.Max(uint8(2))),
+		"Certificate": validation.Validate(r.Certificate, validation.Required, is.Hexadecimal,
+			validation.When(r.MatchingType == 1, validation.Length(64, 64).Error("must be 64 hexadecimal characters long for SHA-256")),
+			validation.When(r.MatchingType == 2, validation.Length(128, 128).Error("must be 128 hexadecimal characters long for SHA-512"))),
+	}.Filter()
+}
+
+// Validate validates AKAMAICDNRData
+func (r AKAMAICDNRData) Validate() error {
+	return validation.Errors{
+		"EdgeHostname": validation.Validate(r.EdgeHostname, validation.Required, domainName(false)),
+	}.Filter()
+}
+
+// Validate validates AKAMAITLCRData
+func (r AKAMAITLCRData) Validate() error {
+	return validation.Errors{
+		"AnswerType": validation.Validate(r.AnswerType, validation.Required),
+		"DNSName":    validation.Validate(r.DNSName, validation.Required, domainName(false)),
+	}.Filter()
+}
+
+// Validate validates RRSIGRData
+func (r RRSIGRData) Validate() error {
+	return validation.Errors{
+		"TypeCovered": validation.Validate(r.TypeCovered, validation.Required),
+		"Algorithm":   validation.Validate(r.Algorithm, validation.Required),
+		"Expiration": validation.Validate(r.Expiration, validation.Required, validation.By(func(interface{}) error {
+			if r.Expiration.Before(r.Inception) {
+				return errors.New("must not be before Inception")
+			}
+			return nil
+		})),
+		"Inception":  validation.Validate(r.Inception, validation.Required),
+		"SignerName": validation.Validate(r.SignerName, validation.Required, domainName(true)),
+		"Signature":  validation.Validate(r.Signature, validation.Required, is.Base64),
+	}.Filter()
+}
+
+// MarshalText returns the RDATA in presentation format
+func (r ARData) MarshalText() ([]byte, error) {
+	return marshalRData(r, r.Address.String())
+}
+
+// MarshalText returns the RDATA in presentation format
+func (r AAAARData) MarshalText() ([]byte, error) {
+	return marshalRData(r, r.Address.String())
+}
+
+// MarshalText returns the RDATA in presentation format
+func (r CNAMERData) MarshalText() ([]byte, error) {
+	return marshalRData(r, r.Target)
+}
+
+// MarshalText returns the RDATA in presentation format
+func (r MXRData) MarshalText() ([]byte, error) {
+	return marshalRData(r, fmt.Sprintf("%d %s", r.Preference, r.Exchange))
+}
+
+// MarshalText returns the RDATA in presentation format
+func (r SRVRData) MarshalText() ([]byte, error) {
+	return marshalRData(r, fmt.Sprintf("%d %d %d %s", r.Priority, r.Weight, r.Port, r.Target))
+}
+
+// MarshalText returns the RDATA in presentation format
+func (r CAARData) MarshalText() ([]byte, error) {
+	return marshalRData(r, fmt.Sprintf("%d %s %s", r.Flags, r.Tag, quoteString(r.Value)))
+}
+
+// MarshalText returns the RDATA in presentation format, with every character string quoted
+func (r TXTRData) MarshalText() ([]byte, error) {
+	quoted := make([]string, 0, len(r.Strings))
+	for _, s := range r.Strings {
+		quoted = append(quoted, quoteString(s))
+	}
+	return marshalRData(r, strings.Join(quoted, " "))
+}
+
+// MarshalText returns the RDATA in presentation format
+func (r SOARData) MarshalText() ([]byte, error) {
+	return marshalRData(r, fmt.Sprintf("%s %s %d %d %d %d %d", r.MName, r.RName, r.Serial, r.Refresh, r.Retry, r.Expire, r.Minimum))
+}
+
+// MarshalText returns the RDATA in presentation format
+func (r DSRData) MarshalText() ([]byte, error) {
+	return marshalRData(r, fmt.Sprintf("%d %d %d %s", r.KeyTag, r.Algorithm, r.DigestType, r.Digest))
+}
+
+// MarshalText returns the RDATA in presentation format
+func (r DNSKEYRData) MarshalText() ([]byte, error) {
+	return marshalRData(r, fmt.Sprintf("%d %d %d %s", r.Flags, r.Protocol, r.Algorithm, r.PublicKey))
+}
+
+// MarshalText returns the RDATA in presentation format
+func (r SVCBRData) MarshalText() ([]byte, error) {
+	fields := []string{strconv.Itoa(int(r.Priority)), r.Target}
+	for _, param := range r.Params {
+		switch {
+		case param.Value == "":
+			fields = append(fields, param.Key)
+		case strings.ContainsAny(param.Value, " \t\"\\;()") || strings.IndexFunc(param.Value, isControl) >= 0:
+			fields = append(fields, param.Key+"="+quoteString(param.Value))
+		default:
+			fields = append(fields, param.Key+"="+param.Value)
+		}
+	}
+	return marshalRData(r, strings.Join(fields, " "))
+}
+
+// MarshalText returns the RDATA in presentation format
+func (r NAPTRRData) MarshalText() ([]byte, error) {
+	return marshalRData(r, fmt.Sprintf("%d %d %s %s %s %s", r.Order, r.Preference,
+		quoteString(r.Flags), quoteString(r.Service), quoteString(r.Regexp), r.Replacement))
+}
+
+// MarshalText returns the RDATA in presentation format
+func (r TLSARData) MarshalText() ([]byte, error) {
+	return marshalRData(r, fmt.Sprintf("%d %d %d %s", r.Usage, r.Selector, r.MatchingType, r.Certificate))
+}
+
+// MarshalText returns the RDATA in presentation format
+func (r AKAMAICDNRData) MarshalText() ([]byte, error) {
+	return marshalRData(r, r.EdgeHostname)
+}
+
+// MarshalText returns the RDATA in presentation format
+func (r AKAMAITLCRData) MarshalText() ([]byte, error) {
+	return marshalRData(r, fmt.Sprintf("%s %s", r.AnswerType, r.DNSName))
+}
+
+// MarshalText returns the RDATA in presentation format, with times in the YYYYMMDDHHmmSS format
+func (r RRSIGRData) MarshalText() ([]byte, error) {
+	return marshalRData(r, fmt.Sprintf("%s %d %d %d %s %s %d %s %s", r.TypeCovered, r.Algorithm, r.Labels, r.OriginalTTL,
+		r.Expiration.UTC().Format(rrsigTimeFormat), r.Inception.UTC().Format(rrsigTimeFormat), r.KeyTag, r.SignerName, r.Signature))
+}
+
+// UnmarshalText parses the RDATA from presentation format
+func (r *ARData) UnmarshalText(text []byte) error {
+	fields, err := rdataFields(text, 1, 1)
+	if err != nil {
+		return err
+	}
+	if r.Address, err = netip.ParseAddr(fields[0]); err != nil {
+		return fmt.Errorf("%w: %s", ErrInvalidRData, err)
+	}
+	return validRData(r)
+}
+
+// UnmarshalText parses the RDATA from presentation format
+func (r *AAAARData) UnmarshalText(text []byte) error {
+	fields, err := rdataFields(text, 1, 1)
+	if err != nil {
+		return err
+	}
+	if r.Address, err = netip.ParseAddr(fields[0]); err != nil {
+		return fmt.Errorf("%w: %s", ErrInvalidRData, err)
+	}
+	return validRData(r)
+}
+
+// UnmarshalText parses the RDATA from presentation format
+func (r *CNAMERData) UnmarshalText(text []byte) error {
+	fields, err := rdataFields(text, 1, 1)
+	if err != nil {
+		return err
+	}
+	r.Target = fields[0]
+	return validRData(r)
+}
+
+// UnmarshalText parses the RDATA from presentation format
+func (r *MXRData) UnmarshalText(text []byte) error {
+	fields, err := rdataFields(text, 2, 2)
+	if err != nil {
+		return err
+	}
+	if r.Preference, err = parseUint16(fields[0]); err != nil {
+		return err
+	}
+	r.Exchange = fields[1]
+	return validRData(r)
+}
+
+// UnmarshalText parses the RDATA from presentation format
+func (r *SRVRData) UnmarshalText(text []byte) error {
+	fields, err := rdataFields(text, 4, 4)
+	if err != nil {
+		return err
+	}
+	if r.Priority, err = parseUint16(fields[0]); err != nil {
+		return err
+	}
+	if r.Weight, err = parseUint16(fields[1]); err != nil {
+		return err
+	}
+	if r.Port, err = parseUint16(fields[2]); err != nil {
+		return err
+	}
+	r.Target = fields[3]
+	return validRData(r)
+}
+
+// UnmarshalText parses the RDATA from presentation format
+func (r *CAARData) UnmarshalText(text []byte) error {
+	fields, err := rdataFields(text, 3, 3)
+	if err != nil {
+		return err
+	}
+	if r.Flags, err = parseUint8(fields[0]); err != nil {
+		return err
+	}
+	r.Tag, r.Value = fields[1], fields[2]
+	return validRData(r)
+}
+
+// UnmarshalText parses the RDATA from presentation format. Every quoted or unquoted field is a character string.
+func (r *TXTRData) UnmarshalText(text []byte) error {
+	fields, err := rdataFields(text, 1, -1)
+	if err != nil {
+		return err
+	}
+	r.Strings = fields
+	return validRData(r)
+}
+
+// UnmarshalText parses the RDATA from presentation format
+func (r *SOARData) UnmarshalText(text []byte) error {
+	fields, err := rdataFields(text, 7, 7)
+	if err != nil {
+		return err
+	}
+	r.MName, r.RName = fields[0], fields[1]
+	for i, value := range []*uint32{&r.Serial, &r.Refresh, &r.Retry, &r.Expire, &r.Minimum} {
+		if *value, err = parseUint32(fields[i+2]); err != nil {
+			return err
+		}
+	}
+	return validRData(r)
+}
+
+// UnmarshalText parses the RDATA from presentation format. A digest split into several fields is joined
+// and converted to upper case.
+func (r *DSRData) UnmarshalText(text []byte) error {
+	fields, err := rdataFields(text, 4, -1)
+	if err != nil {
+		return err
+	}
+	if r.KeyTag, err = parseUint16(fields[0]); err != nil {
+		return err
+	}
+	if r.Algorithm, err = parseUint8(fields[1]); err != nil {
+		return err
+	}
+	if r.DigestType, err = parseUint8(fields[2]); err != nil {
+		return err
+	}
+	r.Digest = strings.ToUpper(strings.Join(fields[3:], ""))
+	return validRData(r)
+}
+
+// UnmarshalText parses the RDATA from presentation format. A public key split into several fields is joined.
+func (r *DNSKEYRData) UnmarshalText(text []byte) error {
+	fields, err := rdataFields(text, 4, -1)
+	if err != nil {
+		return err
+	}
+	if r.Flags, err = parseUint16(fields[0]); err != nil {
+		return err
+	}
+	if r.Protocol, err = parseUint8(fields[1]); err != nil {
+		return err
+	}
+	if r.Algorithm, err = parseUint8(fields[2]); err != nil {
+		return err
+	}
+	r.PublicKey = strings.Join(fields[3:], "")
+	return validRData(r)
+}
+
+// UnmarshalText parses the RDATA from presentation format
+func (r *SVCBRData) UnmarshalText(text []byte) error {
+	fields, err := rdataFields(text, 2, -1)
+	if err != nil {
+		return err
+	}
+	if r.Priority, err = parseUint16(fields[0]); err != nil {
+		return err
+	}
+	r.Target = fields[1]
+	r.Params = nil
+	for _, field := range fields[2:] {
+		key, value, _ := strings.Cut(field, "=")
+		r.Params = append(r.Params, SVCParam{Key: strings.ToLower(key), Value: value})
+	}
+	return validRData(r)
+}
+
+// UnmarshalText parses the RDATA from presentation format
+func (r *NAPTRRData) UnmarshalText(text []byte) error {
+	fields, err := rdataFields(text, 6, 6)
+	if err != nil {
+		return err
+	}
+	if r.Order, err = parseUint16(fields[0]); err != nil {
+		return err
+	}
+	if r.Preference, err = parseUint16(fields[1]); err != nil {
+		return err
+	}
+	r.Flags, r.Service, r.Regexp, r.Replacement = fields[2], fields[3], fields[4], fields[5]
+	return validRData(r)
+}
+
+// UnmarshalText parses the RDATA from presentation format. Certificate data split into several fields is joined
+// and converted to upper case.
+func (r *TLSARData) UnmarshalText(text []byte) error {
+	fields, err := rdataFields(text, 4, -1)
+	if err != nil {
+		return err
+	}
+	if r.Usage, err = parseUint8(fields[0]); err != nil {
+		return err
+	}
+	if r.Selector, err = parseUint8(fields[1]); err != nil {
+		return err
+	}
+	if r.MatchingType, err = parseUint8(fields[2]); err != nil {
+		return err
+	}
+	r.Certificate = strings.ToUpper(strings.Join(fields[3:], ""))
+	return validRData(r)
+}
+
+// UnmarshalText parses the RDATA from presentation format
+func (r *AKAMAICDNRData) UnmarshalText(text []byte) error {
+	fields, err := rdataFields(text, 1, 1)
+	if err != nil {
+		return err
+	}
+	r.EdgeHostname = fields[0]
+	return validRData(r)
+}
+
+// UnmarshalText parses the RDATA from presentation format
+func (r *AKAMAITLCRData) UnmarshalText(text []byte) error {
+	fields, err := rdataFields(text, 2, 2)
+	if err != nil {
+		return err
+	}
+	r.AnswerType, r.DNSName = fields[0], fields[1]
+	return validRData(r)
+}
+
+// UnmarshalText parses the RDATA from presentation format. Times can be in the YYYYMMDDHHmmSS format or
+// seconds since the epoch, and a signature split into several fields is joined.
+func (r *RRSIGRData) UnmarshalText(text []byte) error {
+	fields, err := rdataFields(text, 9, -1)
+	if err != nil {
+		return err
+	}
+	r.TypeCovered = strings.ToUpper(fields[0])
+	if r.Algorithm, err = parseUint8(fields[1]); err != nil {
+		return err
+	}
+	if r.Labels, err = parseUint8(fields[2]); err != nil {
+		return err
+	}
+	if r.OriginalTTL, err = parseUint32(fields[3]); err != nil {
+		return err
+	}
+	if r.Expiration, err = parseRRSIGTime(fields[4]); err != nil {
+		return err
+	}
+	if r.Inception, err = parseRRSIGTime(fields[5]); err != nil {
+		return err
+	}
+	if r.KeyTag, err = parseUint16(fields[6]); err != nil {
+		return err
+	}
+	r.SignerName = fields[7]
+	r.Signature = strings.Join(fields[8:], "")
+	return validRData(r)
+}
+
+func marshalRData(r RData, text string) ([]byte, error) {
+	if err := validRData(r); err != nil {
+		return nil, err
+	}
+	return []byte(text), nil
+}
+
+func validRData(r RData) error {
+	if err := r.Validate(); err != nil {
+		return fmt.Errorf("%w: %s", ErrInvalidRData, err)
+	}
+	return nil
+}
+
+// rdataFields splits RDATA into fields and checks their number. A negative max allows any number of fields.
+func rdataFields(text []byte, min, max int) ([]string, error) {
+	fields, err := splitRDataFields(string(text))
+	if err != nil {
+		return nil, err
+	}
+	if len(fields) < min || (max >= 0 && len(fields) > max) {
+		expected := strconv.Itoa(min)
+		if max < 0 {
+			expected = "at least " + expected
+		} else if max != min {
+			expected = fmt.Sprintf("%d to %d", min, max)
+		}
+		return nil, fmt.Errorf("%w: expected %s fields, got %d", ErrInvalidRData, expected, len(fields))
+	}
+	return fields, nil
+}
+
+// splitRDataFields splits presentation format RDATA on whitespace. Quotes group text into a single field
+// and are removed, and backslash escapes, including \DDD, are resolved.
+func splitRDataFields(s string) ([]string, error) {
+	var fields []string
+	i := 0
+	for {
+		for i < len(s) && (s[i] == ' ' || s[i] == '\t') {
+			i++
+		}
+		if i >= len(s) {
+			return fields, nil
+		}
+
+		var b strings.Builder
+		inQuote := false
+		for ; i < len(s); i++ {
+			c := s[i]
+			if !inQuote && (c == ' ' || c == '\t') {
+				break
+			}
+			switch c {
+			case '"':
+				inQuote = !inQuote
+			case '\\':
+				switch {
+				case i+3 < len(s) && isDigit(s[i+1]) && isDigit(s[i+2]) && isDigit(s[i+3]):
+					n, _ := strconv.Atoi(s[i+1 : i+4])
+					if n > 255 {
+						return nil, fmt.Errorf("%w: invalid escape \\%s", ErrInvalidRData, s[i+1:i+4])
+					}
+					b.WriteByte(byte(n))
+					i += 3
+				case i+1 < len(s):
+					b.WriteByte(s[i+1])
+					i++
+				default:
+					return nil, fmt.Errorf("%w: trailing backslash", ErrInvalidRData)
+				}
+			default:
+				b.WriteByte(c)
+			}
+		}
+		if inQuote {
+			return nil, fmt.Errorf("%w: unterminated quoted string", ErrInvalidRData)
+		}
+		fields = append(fields, b.String())
+	}
+}
+
+// quoteString returns s as a quoted character string, escaping quotes, backslashes and control characters
+func quoteString(s string) string {
+	var b strings.Builder
+	b.WriteByte('"')
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case c == '"' || c == '\\':
+			b.WriteByte('\\')
+			b.WriteByte(c)
+		case c < 0x20 || c == 0x7f:
+			fmt.Fprintf(&b, "\\%03d", c)
+		default:
+			b.WriteByte(c)
+		}
+	}
+	b.WriteByte('"')
+	return b.String()
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+func isControl(r rune) bool {
+	return r < 0x20 || r == 0x7f
+}
+
+func parseUint8(field string) (uint8, error) {
+	value, err := parseUint(field, 8)
+	return uint8(value), err
+}
+
+func parseUint16(field string) (uint16, error) {
+	value, err := parseUint(field, 16)
+	return uint16(value), err
+}
+
+func parseUint32(field string) (uint32, error) {
+	value, err := parseUint(field, 32)
+	return uint32(value), err
+}
+
+func parseUint(field string, bitSize int) (uint64, error) {
+	value, err := strconv.ParseUint(field, 10, bitSize)
+	if err != nil {
+		return 0, fmt.Errorf("%w: %q is not a %d-bit unsigned number", ErrInvalidRData, field, bitSize)
+	}
+	return value, nil
+}
+
+func parseRRSIGTime(field string) (time.Time, error) {
+	if len(field) == len(rrsigTimeFormat) {
+		if t, err := time.Parse(rrsigTimeFormat, field); err == nil {
+			return t, nil
+		}
+	}
+	seconds, err := parseUint32(field)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("%w: %q is not a valid signature time", ErrInvalidRData, field)
+	}
+	return time.Unix(int64(seconds), 0).UTC(), nil
+}
+
+// ipAddress validates that a netip.Addr is an IPv4 or an IPv6 address
+func ipAddress(v4 bool) validation.Rule {
+	return validation.By(func(value interface{}) error {
+		addr, _ := value.(netip.Addr)
+		switch {
+		case !addr.IsValid():
+			return errors.New("cannot be blank")
+		case v4 && !addr.Is4():
+			return errors.New("must be an IPv4 address")
+		case !v4 && !addr.Is6():
+			return errors.New("must be an IPv6 address")
+		}
+		return nil
+	})
+}
+
+// domainName validates the syntax of a domain name, optionally allowing the root domain "."
+func domainName(allowRoot bool) validation.Rule {
+	return validation.By(func(value interface{}) error {
+		name, _ := value.(string)
+		if name == "" {
+			return nil
+		}
+		if name == "." {
+			if allowRoot {
+				return nil
+			}
+			return errors.New("must not be the root domain")
+		}
+		trimmed := strings.TrimSuffix(name, ".")
+		if len(trimmed) > 253 {
+			return errors.New("must be at most 253 characters long")
+		}
+		for _, label := range strings.Split(trimmed, ".") {
+			if label == "" || len(label) > 63 || strings.ContainsAny(label, " \t\"\\;()") {
+				return errors.New("must be a valid domain name")
+			}
+		}
+		return nil
+	})
+}
+
+// validateSVCParams checks the keys and values of SvcParams as defined by RFC 9460
+func validateSVCParams(value interface{}) error {
+	params, _ := value.([]SVCParam)
+	keys := make(map[string]bool, len(params))
+	for _, param := range params {
+		if keys[param.Key] {
+			return fmt.Errorf("duplicate key %s", param.Key)
+		}
+		keys[param.Key] = true
+
+		needsValue, known := svcParamKeys[param.Key]
+		if !known {
+			if !isGenericSVCParamKey(param.Key) {
+				return fmt.Errorf("unknown key %s", param.Key)
+			}
+			continue
+		}
+		if needsValue && param.Value == "" {
+			return fmt.Errorf("key %s requires a value", param.Key)
+		}
+		if !needsValue && param.Value != "" {
+			return fmt.Errorf("key %s does not take a value", param.Key)
+		}
+		if err := validateSVCParamValue(param); err != nil {
+			return fmt.Errorf("key %s: %s", param.Key, err)
+		}
+	}
+
+	for _, param := range params {
+		if param.Key != "mandatory" {
+			continue
+		}
+		for _, key := range strings.Split(param.Value, ",") {
+			if key == "mandatory" || !keys[key] {
+				return fmt.Errorf("mandatory key %s is not set", key)
+			}
+		}
+	}
+	return nil
+}
+
+func validateSVCParamValue(param SVCParam) error {
+	switch param.Key {
+	case "port":
+		if _, err := strconv.ParseUint(param.Value, 10, 16); err != nil {
+			return errors.New("must be a port number")
+		}
+	case "ipv4hint", "ipv6hint":
+		for _, hint := range strings.Split(param.Value, ",") {
+			addr, err := netip.ParseAddr(hint)
+			if err != nil || (param.Key == "ipv4hint") != addr.Is4() {
+				return fmt.Errorf("%q is not a valid address", hint)
+			}
+		}
+	case "ech":
+		if _, err := base64.StdEncoding.DecodeString(param.Value); err != nil {
+			return errors.New("must be base64 encoded")
+		}
+	case "alpn", "mandatory":
+		for _, item := range strings.Split(param.Value, ",") {
+			if item == "" {
+				return errors.New("must not contain empty items")
+			}
+		}
+	}
+	return nil
+}
+
+// isGenericSVCParamKey reports whether key has the keyNNNNN form
+func isGenericSVCParamKey(key string) bool {
+	if !strings.HasPrefix(key, "key") || len(key) == 3 {
+		return false
+	}
+	_, err := strconv.ParseUint(key[3:], 10, 16)
+	return err == nil
+}
@@ -0,0 +1,448 @@
+package dns
+
+import (
+	"errors"
+	"net/netip"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUnmarshalRData(t *testing.T) {
+	tests := map[string]struct {
+		recordType string
+		rdata      string
+		expected   RData
+		// marshaled is the expected presentation format when it differs from rdata
+		marshaled string
+		withError error
+	}{
+		"A": {
+			recordType: "A",
+			rdata:      "192.0.2.1",
+			expected:   &ARData{Address: netip.MustParseAddr("192.0.2.1")},
+		},
+		"A with IPv6 address": {
+			recordType: "A",
+			rdata:      "2001:db8::1",
+			withError:  ErrInvalidRData,
+		},
+		"AAAA in expanded form": {
+			recordType: "aaaa",
+			rdata:      "2001:0db8:0000:0000:0000:0000:0000:0001",
+			expected:   &AAAARData{Address: netip.MustParseAddr("2001:db8::1")},
+			marshaled:  "2001:db8::1",
+		},
+		"CNAME": {
+			recordType: "CNAME",
+			rdata:      "www.example.com.",
+			expected:   &CNAMERData{Target: "www.example.com."},
+		},
+		"CNAME to root": {
+			recordType: "CNAME",
+			rdata:      ".",
+			withError:  ErrInvalidRData,
+		},
+		"MX": {
+			recordType: "MX",
+			rdata:      "10 mail.example.com.",
+			expected:   &MXRData{Preference: 10, Exchange: "mail.example.com."},
+		},
+		"null MX": {
+			recordType: "MX",
+			rdata:      "0 .",
+			expected:   &MXRData{Exchange: "."},
+		},
+		"MX with too many fields": {
+			recordType: "MX",
+			rdata:      "10 mail.example.com. extra",
+			withError:  ErrInvalidRData,
+		},
+		"MX preference out of range": {
+			recordType: "MX",
+			rdata:      "65536 mail.example.com.",
+			withError:  ErrInvalidRData,
+		},
+		"SRV": {
+			recordType: "SRV",
+			rdata:      "10 60 5060 sip.example.com.",
+			expected:   &SRVRData{Priority: 10, Weight: 60, Port: 5060, Target: "sip.example.com."},
+		},
+		"CAA": {
+			recordType: "CAA",
+			rdata:      `0 issue "letsencrypt.org"`,
+			expected:   &CAARData{Tag: "issue", Value: "letsencrypt.org"},
+		},
+		"CAA with unquoted value": {
+			recordType: "CAA",
+			rdata:      "128 iodef mailto:security@example.com",
+			expected:   &CAARData{Flags: 128, Tag: "iodef", Value: "mailto:security@example.com"},
+			marshaled:  `128 iodef "mailto:security@example.com"`,
+		},
+		"CAA with invalid tag": {
+			recordType: "CAA",
+			rdata:      `0 is-sue "letsencrypt.org"`,
+			withError:  ErrInvalidRData,
+		},
+		"TXT with several strings and escapes": {
+			recordType: "TXT",
+			rdata:      `"v=spf1 -all" "say \"hi\"" "a\\b" "tab\009"`,
+			expected:   &TXTRData{Strings: []string{"v=spf1 -all", `say "hi"`, `a\b`, "tab\t"}},
+		},
+		"TXT unquoted": {
+			recordType: "TXT",
+			rdata:      "hello",
+			expected:   &TXTRData{Strings: []string{"hello"}},
+			marshaled:  `"hello"`,
+		},
+		"TXT with string too long": {
+			recordType: "TXT",
+			rdata:      `"` + strings.Repeat("a", 256) + `"`,
+			withError:  ErrInvalidRData,
+		},
+		"TXT with unterminated quote": {
+			recordType: "TXT",
+			rdata:      `"hello`,
+			withError:  ErrInvalidRData,
+		},
+		"SOA": {
+			recordType: "SOA",
+			rdata:      "a1-1.akam.net. hostmaster.example.com. 2024010101 3600 600 604800 300",
+			expected: &SOARData{
+				MName:   "a1-1.akam.net.",
+				RName:   "hostmaster.example.com.",
+				Serial:  2024010101,
+				Refresh: 3600,
+				Retry:   600,
+				Expire:  604800,
+				Minimum: 300,
+			},
+		},
+		"DS with split digest": {
+			recordType: "DS",
+			rdata:      "60485 5 1 2bb183af5f22588179a53b0a 98631fad1a292118",
+			expected:   &DSRData{KeyTag: 60485, Algorithm: 5, DigestType: 1, Digest: "2BB183AF5F22588179A53B0A98631FAD1A292118"},
+			marshaled:  "60485 5 1 2BB183AF5F22588179A53B0A98631FAD1A292118",
+		},
+		"DS with wrong digest length": {
+			recordType: "DS",
+			rdata:      "60485 5 2 2BB183AF5F22588179A53B0A98631FAD1A292118",
+			withError:  ErrInvalidRData,
+		},
+		"DS with unknown digest type": {
+			recordType: "DS",
+			rdata:      "60485 5 0 2BB183AF5F22588179A53B0A98631FAD1A292118",
+			withError:  ErrInvalidRData,
+		},
+		"DNSKEY": {
+			recordType: "DNSKEY",
+			rdata:      "257 3 13 mdsswUyr3DPW132mOi8V9xESWE8jTo0d xCjjnopKl+GqJxpVXckHAeF+KkxLbxILfDLUT0rAK9iUzy1L53eKGQ==",
+			expected: &DNSKEYRData{
+				Flags:     257,
+				Protocol:  3,
+				Algorithm: 13,
+				PublicKey: "mdsswUyr3DPW132mOi8V9xESWE8jTo0dxCjjnopKl+GqJxpVXckHAeF+KkxLbxILfDLUT0rAK9iUzy1L53eKGQ==",
+			},
+			marshaled: "257 3 13 mdsswUyr3DPW132mOi8V9xESWE8jTo0dxCjjnopKl+GqJxpVXckHAeF+KkxLbxILfDLUT0rAK9iUzy1L53eKGQ==",
+		},
+		"DNSKEY with wrong protocol": {
+			recordType: "DNSKEY",
+			rdata:      "257 0 13 mdsswUyr3DPW132mOi8V9xESWE8jTo0d",
+			withError:  ErrInvalidRData,
+		},
+		"SVCB": {
+			recordType: "SVCB",
+			rdata:      `1 svc.example.com. alpn="h2,h3" port=8443 ipv4hint=192.0.2.1,192.0.2.2 no-default-alpn mandatory=alpn,port`,
+			expected: &SVCBRData{
+				Priority: 1,
+				Target:   "svc.example.com.",
+				Params: []SVCParam{
+					{Key: "alpn", Value: "h2,h3"},
+					{Key: "port", Value: "8443"},
+					{Key: "ipv4hint", Value: "192.0.2.1,192.0.2.2"},
+					{Key: "no-default-alpn"},
+					{Key: "mandatory", Value: "alpn,port"},
+				},
+			},
+			marshaled: "1 svc.example.com. alpn=h2,h3 port=8443 ipv4hint=192.0.2.1,192.0.2.2 no-default-alpn mandatory=alpn,port",
+		},
+		"HTTPS alias mode": {
+			recordType: "HTTPS",
+			rdata:      "0 cdn.example.net.",
+			expected:   &HTTPSRData{SVCBRData{Target: "cdn.example.net."}},
+		},
+		"HTTPS with generic key": {
+			recordType: "HTTPS",
+			rdata:      `1 . key65000="a b"`,
+			expected:   &HTTPSRData{SVCBRData{Priority: 1, Target: ".", Params: []SVCParam{{Key: "key65000", Value: "a b"}}}},
+		},
+		"HTTPS alias mode with params": {
+			recordType: "HTTPS",
+			rdata:      "0 cdn.example.net. alpn=h2",
+			withError:  ErrInvalidRData,
+		},
+		"SVCB with duplicate key": {
+			recordType: "SVCB",
+			rdata:      "1 . port=443 port=8443",
+			withError:  ErrInvalidRData,
+		},
+		"SVCB with missing mandatory key": {
+			recordType: "SVCB",
+			rdata:      "1 . mandatory=alpn port=443",
+			withError:  ErrInvalidRData,
+		},
+		"SVCB with invalid IPv6 hint": {
+			recordType: "SVCB",
+			rdata:      "1 . ipv6hint=192.0.2.1",
+			withError:  ErrInvalidRData,
+		},
+		"NAPTR": {
+			recordType: "NAPTR",
+			rdata:      `100 10 "S" "SIP+D2U" "" _sip._udp.example.com.`,
+			expected:   &NAPTRRData{Order: 100, Preference: 10, Flags: "S", Service: "SIP+D2U", Replacement: "_sip._udp.example.com."},
+		},
+		"NAPTR with regexp": {
+			recordType: "NAPTR",
+			rdata:      `100 10 "U" "E2U+sip" "!^.*$!sip:info@example.com!" .`,
+			expected:   &NAPTRRData{Order: 100, Preference: 10, Flags: "U", Service: "E2U+sip", Regexp: "!^.*$!sip:info@example.com!", Replacement: "."},
+		},
+		"NAPTR with regexp and replacement": {
+			recordType: "NAPTR",
+			rdata:      `100 10 "U" "E2U+sip" "!^.*$!sip:info@example.com!" example.com.`,
+			withError:  ErrInvalidRData,
+		},
+		"TLSA": {
+			recordType: "TLSA",
+			rdata:      "3 1 1 0B9FA5A59EED715C26C1020C711B4F6EC42D58B0015E14337A39DAD301C5AFC3",
+			expected:   &TLSARData{Usage: 3, Selector: 1, MatchingType: 1, Certificate: "0B9FA5A59EED715C26C1020C711B4F6EC42D58B0015E14337A39DAD301C5AFC3"},
+		},
+		"TLSA with invalid usage": {
+			recordType: "TLSA",
+			rdata:      "4 1 1 0B9FA5A59EED715C26C1020C711B4F6EC42D58B0015E14337A39DAD301C5AFC3",
+			withError:  ErrInvalidRData,
+		},
+		"AKAMAICDN": {
+			recordType: "AKAMAICDN",
+			rdata:      "www.example.com.edgekey.net",
+			expected:   &AKAMAICDNRData{EdgeHostname: "www.example.com.edgekey.net"},
+		},
+		"AKAMAITLC": {
+			recordType: "AKAMAITLC",
+			rdata:      "DUAL tlc.example.com.",
+			expected:   &AKAMAITLCRData{AnswerType: "DUAL", DNSName: "tlc.example.com."},
+		},
+		"RRSIG": {
+			recordType: "RRSIG",
+			rdata:      "A 13 3 300 20241101000000 20241001000000 10771 example.net. c2lnbmF0dXJl",
+			expected: &RRSIGRData{
+				TypeCovered: "A",
+				Algorithm:   13,
+				Labels:      3,
+				OriginalTTL: 300,
+				Expiration:  time.Date(2024, 11, 1, 0, 0, 0, 0, time.UTC),
+				Inception:   time.Date(2024, 10, 1, 0, 0, 0, 0, time.UTC),
+				KeyTag:      10771,
+				SignerName:  "example.net.",
+				Signature:   "c2lnbmF0dXJl",
+			},
+		},
+		"RRSIG expiring before inception": {
+			recordType: "RRSIG",
+			rdata:      "A 13 3 300 20241001000000 20241101000000 10771 example.net. c2lnbmF0dXJl",
+			withError:  ErrInvalidRData,
+		},
+		"unsupported type": {
+			recordType: "HINFO",
+			rdata:      "PC Linux",
+			withError:  ErrUnsupportedRecordType,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			result, err := UnmarshalRData(test.recordType, test.rdata)
+			if test.withError != nil {
+				assert.True(t, errors.Is(err, test.withError), "want: %s; got: %s", test.withError, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, test.expected, result)
+			assert.Equal(t, strings.ToUpper(test.recordType), result.Type())
+
+			marshaled := test.marshaled
+			if marshaled == "" {
+				marshaled = test.rdata
+			}
+			text, err := result.MarshalText()
+			require.NoError(t, err)
+			assert.Equal(t, marshaled, string(text))
+
+			roundTrip, err := UnmarshalRData(test.recordType, string(text))
+			require.NoError(t, err)
+			assert.Equal(t, result, roundTrip)
+		})
+	}
+}
+
+func TestRData_MarshalText(t *testing.T) {
+	tests := map[string]struct {
+		rdata     RData
+		expected  string
+		withError error
+	}{
+		"TXT quotes control characters": {
+			rdata:    TXTRData{Strings: []string{"line\nbreak", ""}},
+			expected: `"line\010break" ""`,
+		},
+		"SVCB quotes values with spaces": {
+			rdata:    SVCBRData{Priority: 1, Target: ".", Params: []SVCParam{{Key: "key7", Value: `a "b"`}}},
+			expected: `1 . key7="a \"b\""`,
+		},
+		"A without address": {
+			rdata:     ARData{},
+			withError: ErrInvalidRData,
+		},
+		"MX without exchange": {
+			rdata:     MXRData{Preference: 10},
+			withError: ErrInvalidRData,
+		},
+		"CNAME with invalid name": {
+			rdata:     CNAMERData{Target: "www..example.com"},
+			withError: ErrInvalidRData,
+		},
+		"TXT without strings": {
+			rdata:     TXTRData{},
+			withError: ErrInvalidRData,
+		},
+		"SVCB with unknown key": {
+			rdata:     SVCBRData{Priority: 1, Target: ".", Params: []SVCParam{{Key: "foo", Value: "bar"}}},
+			withError: ErrInvalidRData,
+		},
+		"SVCB with value for no-default-alpn": {
+			rdata:     SVCBRData{Priority: 1, Target: ".", Params: []SVCParam{{Key: "no-default-alpn", Value: "x"}}},
+			withError: ErrInvalidRData,
+		},
+		"DS with non hexadecimal digest": {
+			rdata:     DSRData{KeyTag: 1, Algorithm: 8, DigestType: 1, Digest: strings.Repeat("Z", 40)},
+			withError: ErrInvalidRData,
+		},
+		"DNSKEY with invalid key": {
+			rdata:     DNSKEYRData{Flags: 256, Protocol: 3, Algorithm: 8, PublicKey: "not base64!"},
+			withError: ErrInvalidRData,
+		},
+		"NAPTR with invalid flags": {
+			rdata:     NAPTRRData{Flags: "S!", Replacement: "."},
+			withError: ErrInvalidRData,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			text, err := test.rdata.MarshalText()
+			if test.withError != nil {
+				assert.True(t, errors.Is(err, test.withError), "want: %s; got: %s", test.withError, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, test.expected, string(text))
+		})
+	}
+}
+
+func TestNewTXTRData(t *testing.T) {
+	tests := map[string]struct {
+		text            string
+		expectedLengths []int
+	}{
+		"empty": {
+			text:            "",
+			expectedLengths: []int{0},
+		},
+		"single string": {
+			text:            strings.Repeat("a", 255),
+			expectedLengths: []int{255},
+		},
+		"chunked": {
+			text:            strings.Repeat("a", 600),
+			expectedLengths: []int{255, 255, 90},
+		},
+		"multi-byte character is not split": {
+			text:            strings.Repeat("a", 254) + "é" + "b",
+			expectedLengths: []int{254, 3},
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			txt := NewTXTRData(test.text)
+			lengths := make([]int, 0, len(txt.Strings))
+			for _, s := range txt.Strings {
+				lengths = append(lengths, len(s))
+			}
+			assert.Equal(t, test.expectedLengths, lengths)
+			assert.Equal(t, test.text, txt.Text())
+			assert.NoError(t, txt.Validate())
+		})
+	}
+}
+
+func TestNewRecordSet(t *testing.T) {
+	tests := map[string]struct {
+		rdata     []RData
+		expected  *RecordSet
+		withError error
+	}{
+		"MX record set": {
+			rdata: []RData{
+				MXRData{Preference: 10, Exchange: "mx1.example.com."},
+				&MXRData{Preference: 20, Exchange: "mx2.example.com."},
+			},
+			expected: &RecordSet{
+				Name:  "example.com",
+				Type:  "MX",
+				TTL:   300,
+				Rdata: []string{"10 mx1.example.com.", "20 mx2.example.com."},
+			},
+		},
+		"no RDATA": {
+			withError: ErrInvalidRData,
+		},
+		"mixed types": {
+			rdata:     []RData{ARData{Address: netip.MustParseAddr("192.0.2.1")}, AAAARData{Address: netip.MustParseAddr("2001:db8::1")}},
+			withError: ErrInvalidRData,
+		},
+		"several CNAME records": {
+			rdata:     []RData{CNAMERData{Target: "a.example.com."}, CNAMERData{Target: "b.example.com."}},
+			withError: ErrInvalidRData,
+		},
+		"invalid RDATA": {
+			rdata:     []RData{SRVRData{Priority: 1}},
+			withError: ErrInvalidRData,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			set, err := NewRecordSet("example.com", 300, test.rdata...)
+			if test.withError != nil {
+				assert.True(t, errors.Is(err, test.withError), "want: %s; got: %s", test.withError, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, test.expected, set)
+
+			typed, err := set.TypedRData()
+			require.NoError(t, err)
+			assert.Len(t, typed, len(test.rdata))
+		})
+	}
+}
+
+func TestRecordSet_TypedRData(t *testing.T) {
+	set := RecordSet{Name: "example.com", Type: "TXT", TTL: 300, Rdata: []string{`"a" "b"`, `"c`}}
+	_, err := set.TypedRData()
+	assert.True(t, errors.Is(err, ErrInvalidRData), "want: %s; got: %s", ErrInvalidRData, err)
+}
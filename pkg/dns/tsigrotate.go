@@ -0,0 +1,213 @@
+package dns
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/akamai/AkamaiOPEN-edgegrid-golang/v11/pkg/edgegriderr"
+	validation "github.com/go-ozzo/ozzo-validation/v4"
+)
+
+type (
+	// RotateTSIGKeyRequest contains parameters for RotateTSIGKey
+	RotateTSIGKeyRequest struct {
+		// OldKey is the key to replace. It is used to find the zones which reference it.
+		OldKey TSIGKey
+		// NewKey is the replacement key. Name and Algorithm default to those of OldKey, and an empty Secret
+		// is generated with GenerateTSIGSecret.
+		NewKey TSIGKey
+		// Zones limits the rotation to the given zones. By default, every zone using OldKey is updated.
+		Zones []string
+		// DryRun only finds the zones and generates the new key, without updating any zone
+		DryRun bool
+	}
+
+	// TSIGRotationReport is the result of RotateTSIGKey
+	TSIGRotationReport struct {
+		// NewKey is the key the zones were moved to. It has to be configured on the primary name servers too.
+		NewKey TSIGKey `json:"newKey"`
+		// Zones are the zones selected for the rotation
+		Zones []string `json:"zones"`
+		// Aliases are the aliases of zones using OldKey. They follow their zones and are not updated directly.
+		Aliases []string `json:"aliases,omitempty"`
+		// Updated are the zones which were verified to use NewKey
+		Updated []string `json:"updated,omitempty"`
+		// Failed are the zones which could not be updated or verified
+		Failed []TSIGRotationFailure `json:"failed,omitempty"`
+		DryRun bool                  `json:"dryRun"`
+	}
+
+	// TSIGRotationFailure describes a zone which could not be moved to the new key
+	TSIGRotationFailure struct {
+		Zone  string `json:"zone"`
+		Error string `json:"error"`
+	}
+)
+
+var (
+	// ErrRotateTSIGKey is returned when RotateTSIGKey fails
+	ErrRotateTSIGKey = errors.New("rotate tsig key")
+	// ErrUnsupportedTSIGAlgorithm is returned when a TSIG algorithm is not known
+	ErrUnsupportedTSIGAlgorithm = errors.New("unsupported tsig algorithm")
+
+	// tsigSecretSizes maps TSIG algorithms to the length of their digests, which is the recommended secret length
+	tsigSecretSizes = map[string]int{
+		"hmac-md5":                 16,
+		"hmac-md5.sig-alg.reg.int": 16,
+		"hmac-sha1":                20,
+		"hmac-sha224":              28,
+		"hmac-sha256":              32,
+		"hmac-sha384":              48,
+		"hmac-sha512":              64,
+	}
+)
+
+// Validate validates RotateTSIGKeyRequest
+func (r RotateTSIGKeyRequest) Validate() error {
+	return edgegriderr.ParseValidationErrors(validation.Errors{
+		"OldKey": validation.Validate(&r.OldKey),
+		"NewKey.Algorithm": validation.Validate(r.NewKey.Algorithm, validation.By(func(interface{}) error {
+			if r.NewKey.Algorithm == "" {
+				return nil
+			}
+			return validateTSIGAlgorithm(r.NewKey.Algorithm)
+		})),
+	})
+}
+
+// GenerateTSIGSecret returns a random base64 encoded secret for the TSIG algorithm. The secret is as long as
+// the digest of the algorithm, as recommended by RFC 8945.
+func GenerateTSIGSecret(algorithm string) (string, error) {
+	size, ok := tsigSecretSizes[normalizeTSIGAlgorithm(algorithm)]
+	if !ok {
+		return "", fmt.Errorf("%w: %s", ErrUnsupportedTSIGAlgorithm, algorithm)
+	}
+	secret := make([]byte, size)
+	if _, err := rand.Read(secret); err != nil {
+		return "", fmt.Errorf("failed to generate tsig secret: %w", err)
+	}
+	return base64.StdEncoding.EncodeToString(secret), nil
+}
+
+// RotateTSIGKey moves the zones which use OldKey to a new key. The new key is generated locally unless its
+// secret is provided, the zones are updated with a single UpdateTSIGKeyBulk request, and every zone is read
+// back with GetTSIGKey to verify it uses the new key. When the bulk request fails, zones are updated one by
+// one with UpdateTSIGKey so that the zones which cannot be updated are known.
+//
+// Zones which could not be updated are listed in TSIGRotationReport.Failed; they are not an error. The report
+// is returned together with an error when the zones could not be listed.
+func RotateTSIGKey(ctx context.Context, client DNS, params RotateTSIGKeyRequest) (*TSIGRotationReport, error) {
+	if err := params.Validate(); err != nil {
+		return nil, fmt.Errorf("%s: %w: %s", ErrRotateTSIGKey, ErrStructValidation, err)
+	}
+
+	newKey := params.NewKey
+	if newKey.Name == "" {
+		newKey.Name = params.OldKey.Name
+	}
+	if newKey.Algorithm == "" {
+		newKey.Algorithm = params.OldKey.Algorithm
+	}
+	if newKey.Secret == "" {
+		secret, err := GenerateTSIGSecret(newKey.Algorithm)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %w", ErrRotateTSIGKey, err)
+		}
+		newKey.Secret = secret
+	}
+	report := &TSIGRotationReport{NewKey: newKey, DryRun: params.DryRun}
+
+	used, err := client.GetTSIGKeyZones(ctx, GetTSIGKeyZonesRequest{TsigKey: &params.OldKey})
+	if err != nil {
+		return report, fmt.Errorf("%w: %w", ErrRotateTSIGKey, err)
+	}
+	report.Zones, report.Aliases = selectTSIGZones(used, params.Zones, report)
+	if len(report.Zones) == 0 || params.DryRun {
+		return report, nil
+	}
+
+	failed := make(map[string]string)
+	err = client.UpdateTSIGKeyBulk(ctx, UpdateTSIGKeyBulkRequest{
+		TSIGKeyBulk: &TSIGKeyBulkPost{Key: &newKey, Zones: report.Zones},
+	})
+	if err != nil {
+		if ctx.Err() != nil {
+			return report, fmt.Errorf("%w: %w", ErrRotateTSIGKey, err)
+		}
+		for _, zone := range report.Zones {
+			if err := client.UpdateTSIGKey(ctx, UpdateTSIGKeyRequest{TsigKey: &newKey, Zone: zone}); err != nil {
+				failed[zone] = err.Error()
+			}
+		}
+	}
+
+	for _, zone := range report.Zones {
+		if _, ok := failed[zone]; ok {
+			continue
+		}
+		if err := verifyTSIGKey(ctx, client, zone, newKey); err != nil {
+			failed[zone] = err.Error()
+			continue
+		}
+		report.Updated = append(report.Updated, zone)
+	}
+	for _, zone := range report.Zones {
+		if reason, ok := failed[zone]; ok {
+			report.Failed = append(report.Failed, TSIGRotationFailure{Zone: zone, Error: reason})
+		}
+	}
+
+	return report, nil
+}
+
+// selectTSIGZones returns the zones to rotate and their aliases. Zones requested by the caller which do not use
+// the old key are added to the report as failures.
+func selectTSIGZones(used *GetTSIGKeyZonesResponse, requested []string, report *TSIGRotationReport) ([]string, []string) {
+	zones := append([]string(nil), used.Zones...)
+	if len(requested) > 0 {
+		inUse := make(map[string]string, len(used.Zones))
+		for _, zone := range used.Zones {
+			inUse[zoneKey(zone)] = zone
+		}
+		zones = zones[:0]
+		for _, zone := range requested {
+			if z, ok := inUse[zoneKey(zone)]; ok {
+				zones = append(zones, z)
+				continue
+			}
+			report.Failed = append(report.Failed, TSIGRotationFailure{Zone: zone, Error: "zone does not use the old key"})
+		}
+	}
+	sort.Strings(zones)
+	aliases := append([]string(nil), used.Aliases...)
+	sort.Strings(aliases)
+	return zones, aliases
+}
+
+func verifyTSIGKey(ctx context.Context, client DNS, zone string, want TSIGKey) error {
+	got, err := client.GetTSIGKey(ctx, GetTSIGKeyRequest{Zone: zone})
+	if err != nil {
+		return fmt.Errorf("verify: %w", err)
+	}
+	if got.Name != want.Name || normalizeTSIGAlgorithm(got.Algorithm) != normalizeTSIGAlgorithm(want.Algorithm) ||
+		got.Secret != want.Secret {
+		return fmt.Errorf("verify: zone uses key %q (%s) instead of the new key", got.Name, got.Algorithm)
+	}
+	return nil
+}
+
+func validateTSIGAlgorithm(algorithm string) error {
+	if _, ok := tsigSecretSizes[normalizeTSIGAlgorithm(algorithm)]; !ok {
+		return ErrUnsupportedTSIGAlgorithm
+	}
+	return nil
+}
+
+func normalizeTSIGAlgorithm(algorithm string) string {
+	return strings.TrimSuffix(strings.ToLower(algorithm), ".")
+}
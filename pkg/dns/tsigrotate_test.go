@@ -0,0 +1,182 @@
+package dns
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGenerateTSIGSecret(t *testing.T) {
+	tests := map[string]struct {
+		algorithm string
+		size      int
+		withError error
+	}{
+		"hmac-sha256":           {algorithm: "hmac-sha256", size: 32},
+		"hmac-sha512 uppercase": {algorithm: "HMAC-SHA512.", size: 64},
+		"hmac-md5 long name":    {algorithm: "hmac-md5.sig-alg.reg.int", size: 16},
+		"unsupported":           {algorithm: "gss-tsig", withError: ErrUnsupportedTSIGAlgorithm},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			secret, err := GenerateTSIGSecret(test.algorithm)
+			if test.withError != nil {
+				assert.True(t, errors.Is(err, test.withError), "want: %s; got: %s", test.withError, err)
+				return
+			}
+			require.NoError(t, err)
+			decoded, err := base64.StdEncoding.DecodeString(secret)
+			require.NoError(t, err)
+			assert.Len(t, decoded, test.size)
+
+			other, err := GenerateTSIGSecret(test.algorithm)
+			require.NoError(t, err)
+			assert.NotEqual(t, secret, other)
+		})
+	}
+}
+
+func TestRotateTSIGKey(t *testing.T) {
+	oldKey := TSIGKey{Name: "transfer.example", Algorithm: "hmac-sha256", Secret: "b2xkIHNlY3JldA=="}
+	newKey := TSIGKey{Name: "transfer.example", Algorithm: "hmac-sha256", Secret: "bmV3IHNlY3JldA=="}
+	used := &GetTSIGKeyZonesResponse{Zones: []string{"b.example", "a.example"}, Aliases: []string{"alias.example"}}
+
+	expectZones := func(m *Mock) {
+		m.On("GetTSIGKeyZones", mock.Anything, GetTSIGKeyZonesRequest{TsigKey: &oldKey}).Return(used, nil).Once()
+	}
+	expectKey := func(m *Mock, zone string, key TSIGKey) {
+		m.On("GetTSIGKey", mock.Anything, GetTSIGKeyRequest{Zone: zone}).
+			Return(&GetTSIGKeyResponse{TSIGKey: key, ZoneCount: 2}, nil).Once()
+	}
+
+	tests := map[string]struct {
+		params    RotateTSIGKeyRequest
+		init      func(*Mock)
+		expected  *TSIGRotationReport
+		withError error
+	}{
+		"bulk update": {
+			params: RotateTSIGKeyRequest{OldKey: oldKey, NewKey: TSIGKey{Secret: newKey.Secret}},
+			init: func(m *Mock) {
+				expectZones(m)
+				m.On("UpdateTSIGKeyBulk", mock.Anything, UpdateTSIGKeyBulkRequest{
+					TSIGKeyBulk: &TSIGKeyBulkPost{Key: &newKey, Zones: []string{"a.example", "b.example"}},
+				}).Return(nil).Once()
+				expectKey(m, "a.example", newKey)
+				expectKey(m, "b.example", TSIGKey{Name: newKey.Name, Algorithm: "HMAC-SHA256.", Secret: newKey.Secret})
+			},
+			expected: &TSIGRotationReport{
+				NewKey:  newKey,
+				Zones:   []string{"a.example", "b.example"},
+				Aliases: []string{"alias.example"},
+				Updated: []string{"a.example", "b.example"},
+			},
+		},
+		"bulk update fails, zones updated one by one": {
+			params: RotateTSIGKeyRequest{OldKey: oldKey, NewKey: newKey},
+			init: func(m *Mock) {
+				expectZones(m)
+				m.On("UpdateTSIGKeyBulk", mock.Anything, mock.Anything).
+					Return(&Error{StatusCode: http.StatusBadRequest, Title: "Bad request"}).Once()
+				m.On("UpdateTSIGKey", mock.Anything, UpdateTSIGKeyRequest{TsigKey: &newKey, Zone: "a.example"}).
+					Return(&Error{StatusCode: http.StatusForbidden, Title: "Forbidden"}).Once()
+				m.On("UpdateTSIGKey", mock.Anything, UpdateTSIGKeyRequest{TsigKey: &newKey, Zone: "b.example"}).
+					Return(nil).Once()
+				expectKey(m, "b.example", newKey)
+			},
+			expected: &TSIGRotationReport{
+				NewKey:  newKey,
+				Zones:   []string{"a.example", "b.example"},
+				Aliases: []string{"alias.example"},
+				Updated: []string{"b.example"},
+				Failed: []TSIGRotationFailure{
+					{Zone: "a.example", Error: "Title: Forbidden; Type: ; Detail: "},
+				},
+			},
+		},
+		"verification fails": {
+			params: RotateTSIGKeyRequest{OldKey: oldKey, NewKey: newKey, Zones: []string{"A.example.", "c.example"}},
+			init: func(m *Mock) {
+				expectZones(m)
+				m.On("UpdateTSIGKeyBulk", mock.Anything, UpdateTSIGKeyBulkRequest{
+					TSIGKeyBulk: &TSIGKeyBulkPost{Key: &newKey, Zones: []string{"a.example"}},
+				}).Return(nil).Once()
+				expectKey(m, "a.example", oldKey)
+			},
+			expected: &TSIGRotationReport{
+				NewKey:  newKey,
+				Zones:   []string{"a.example"},
+				Aliases: []string{"alias.example"},
+				Failed: []TSIGRotationFailure{
+					{Zone: "c.example", Error: "zone does not use the old key"},
+					{Zone: "a.example", Error: `verify: zone uses key "transfer.example" (hmac-sha256) instead of the new key`},
+				},
+			},
+		},
+		"dry run": {
+			params: RotateTSIGKeyRequest{OldKey: oldKey, NewKey: newKey, DryRun: true},
+			init:   expectZones,
+			expected: &TSIGRotationReport{
+				NewKey:  newKey,
+				Zones:   []string{"a.example", "b.example"},
+				Aliases: []string{"alias.example"},
+				DryRun:  true,
+			},
+		},
+		"listing zones fails": {
+			params: RotateTSIGKeyRequest{OldKey: oldKey, NewKey: newKey},
+			init: func(m *Mock) {
+				m.On("GetTSIGKeyZones", mock.Anything, mock.Anything).
+					Return(nil, &Error{StatusCode: http.StatusInternalServerError}).Once()
+			},
+			expected:  &TSIGRotationReport{NewKey: newKey},
+			withError: ErrRotateTSIGKey,
+		},
+		"validation error": {
+			params:    RotateTSIGKeyRequest{OldKey: TSIGKey{Name: "transfer.example"}, NewKey: TSIGKey{Algorithm: "gss-tsig"}},
+			withError: ErrStructValidation,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			m := &Mock{}
+			if test.init != nil {
+				test.init(m)
+			}
+
+			report, err := RotateTSIGKey(context.Background(), m, test.params)
+			m.AssertExpectations(t)
+			if test.withError != nil {
+				assert.True(t, errors.Is(err, test.withError), "want: %s; got: %s", test.withError, err)
+				assert.Equal(t, test.expected, report)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, test.expected, report)
+		})
+	}
+}
+
+func TestRotateTSIGKeyGeneratesSecret(t *testing.T) {
+	oldKey := TSIGKey{Name: "transfer.example", Algorithm: "hmac-sha512", Secret: "b2xkIHNlY3JldA=="}
+	m := &Mock{}
+	m.On("GetTSIGKeyZones", mock.Anything, mock.Anything).
+		Return(&GetTSIGKeyZonesResponse{Zones: []string{"a.example"}}, nil).Once()
+
+	report, err := RotateTSIGKey(context.Background(), m, RotateTSIGKeyRequest{OldKey: oldKey, DryRun: true})
+	require.NoError(t, err)
+	m.AssertExpectations(t)
+	assert.Equal(t, oldKey.Name, report.NewKey.Name)
+	assert.Equal(t, oldKey.Algorithm, report.NewKey.Algorithm)
+	secret, err := base64.StdEncoding.DecodeString(report.NewKey.Secret)
+	require.NoError(t, err)
+	assert.Len(t, secret, 64)
+}
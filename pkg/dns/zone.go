@@ -158,6 +158,29 @@ type (
 	// SubmitChangeListRequest contains request parameters for SubmitChangeList
 	SubmitChangeListRequest ZoneCreate
 
+	// ChangeListOp is the operation of a change appended to a Change List
+	ChangeListOp string
+
+	// ChangeListChange describes a record set change appended to a Change List
+	ChangeListChange struct {
+		Name  string       `json:"name"`
+		Type  string       `json:"type"`
+		Op    ChangeListOp `json:"op"`
+		TTL   int          `json:"ttl,omitempty"`
+		Rdata []string     `json:"rdata,omitempty"`
+	}
+
+	// AddChangeListChangeRequest contains request parameters for AddChangeListChange
+	AddChangeListChangeRequest struct {
+		Zone   string
+		Change *ChangeListChange
+	}
+
+	// DeleteChangeListRequest contains request parameters for DeleteChangeList
+	DeleteChangeListRequest struct {
+		Zone string
+	}
+
 	// UpdateZoneRequest contains request parameters for UpdateZone
 	UpdateZoneRequest struct {
 		CreateZone *ZoneCreate
@@ -199,6 +222,15 @@ type (
 	}
 )
 
+const (
+	// ChangeListOpAdd adds a record set
+	ChangeListOpAdd ChangeListOp = "ADD"
+	// ChangeListOpEdit replaces a record set
+	ChangeListOpEdit ChangeListOp = "EDIT"
+	// ChangeListOpDelete removes a record set
+	ChangeListOpDelete ChangeListOp = "DELETE"
+)
+
 var (
 	// ErrGetZone is returned when GetZone fails
 	ErrGetZone = errors.New("get zone")
@@ -214,6 +246,10 @@ var (
 	ErrSaveChangeList = errors.New("save change list")
 	// ErrSubmitChangeList is returned when SubmitChangeList fails
 	ErrSubmitChangeList = errors.New("submit change list")
+	// ErrAddChangeListChange is returned when AddChangeListChange fails
+	ErrAddChangeListChange = errors.New("add change list change")
+	// ErrDeleteChangeList is returned when DeleteChangeList fails
+	ErrDeleteChangeList = errors.New("delete change list")
 	// ErrGetZoneNames is returned when GetZoneNames fails
 	ErrGetZoneNames = errors.New("get zone names")
 	// ErrGetZoneNameTypes is returned when GetZoneNameTypes fails
@@ -242,6 +278,32 @@ func (r SubmitChangeListRequest) Validate() error {
 	})
 }
 
+// Validate validates AddChangeListChangeRequest
+func (r AddChangeListChangeRequest) Validate() error {
+	return edgegriderr.ParseValidationErrors(validation.Errors{
+		"Zone":   validation.Validate(r.Zone, validation.Required),
+		"Change": validation.Validate(r.Change, validation.Required),
+	})
+}
+
+// Validate validates DeleteChangeListRequest
+func (r DeleteChangeListRequest) Validate() error {
+	return edgegriderr.ParseValidationErrors(validation.Errors{
+		"Zone": validation.Validate(r.Zone, validation.Required),
+	})
+}
+
+// Validate validates ChangeListChange
+func (c ChangeListChange) Validate() error {
+	return validation.Errors{
+		"Name":  validation.Validate(c.Name, validation.Required),
+		"Type":  validation.Validate(c.Type, validation.Required),
+		"Op":    validation.Validate(c.Op, validation.Required, validation.In(ChangeListOpAdd, ChangeListOpEdit, ChangeListOpDelete)),
+		"TTL":   validation.Validate(c.TTL, validation.When(c.Op != ChangeListOpDelete, validation.Required)),
+		"Rdata": validation.Validate(c.Rdata, validation.When(c.Op != ChangeListOpDelete, validation.Required)),
+	}.Filter()
+}
+
 // Validate validates SaveChangelistRequest
 func (r SaveChangeListRequest) Validate() error {
 	return edgegriderr.ParseValidationErrors(validation.Errors{
@@ -626,6 +688,71 @@ func (d *dns) SubmitChangeList(ctx context.Context, params SubmitChangeListReque
 	return nil
 }
 
+func (d *dns) AddChangeListChange(ctx context.Context, params AddChangeListChangeRequest) error {
+	zoneWriteLock.Lock()
+	defer zoneWriteLock.Unlock()
+
+	logger := d.Log(ctx)
+	logger.Debug("AddChangeListChange")
+
+	if err := params.Validate(); err != nil {
+		return fmt.Errorf("%s: %w: %s", ErrAddChangeListChange, ErrStructValidation, err)
+	}
+
+	reqBody, err := convertStructToReqBody(params.Change)
+	if err != nil {
+		return fmt.Errorf("failed to generate request body: %w", err)
+	}
+
+	postURL := fmt.Sprintf("/config-dns/v2/changelists/%s/recordsets/add-change", params.Zone)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, postURL, reqBody)
+	if err != nil {
+		return fmt.Errorf("failed to create AddChangeListChange request: %w", err)
+	}
+
+	resp, err := d.Exec(req, nil)
+	if err != nil {
+		return fmt.Errorf("AddChangeListChange request failed: %w", err)
+	}
+	defer session.CloseResponseBody(resp)
+
+	if resp.StatusCode != http.StatusNoContent {
+		return d.Error(resp)
+	}
+
+	return nil
+}
+
+func (d *dns) DeleteChangeList(ctx context.Context, params DeleteChangeListRequest) error {
+	zoneWriteLock.Lock()
+	defer zoneWriteLock.Unlock()
+
+	logger := d.Log(ctx)
+	logger.Debug("DeleteChangeList")
+
+	if err := params.Validate(); err != nil {
+		return fmt.Errorf("%s: %w: %s", ErrDeleteChangeList, ErrStructValidation, err)
+	}
+
+	deleteURL := fmt.Sprintf("/config-dns/v2/changelists/%s", params.Zone)
+	req, err := http.NewRequestWithContext(ctx, http.MethodDelete, deleteURL, nil)
+	if err != nil {
+		return fmt.Errorf("failed to create DeleteChangeList request: %w", err)
+	}
+
+	resp, err := d.Exec(req, nil)
+	if err != nil {
+		return fmt.Errorf("DeleteChangeList request failed: %w", err)
+	}
+	defer session.CloseResponseBody(resp)
+
+	if resp.StatusCode != http.StatusNoContent {
+		return d.Error(resp)
+	}
+
+	return nil
+}
+
 func (d *dns) UpdateZone(ctx context.Context, params UpdateZoneRequest) error {
 	// This lock will restrict the concurrency of API calls
 	// to 1 save request at a time. This is needed for the Soa.Serial value which
@@ -1008,6 +1008,154 @@ func TestDNS_SubmitChangelist(t *testing.T) {
 	}
 }
 
+func TestDNS_AddChangeListChange(t *testing.T) {
+	tests := map[string]struct {
+		params              AddChangeListChangeRequest
+		responseStatus      int
+		responseBody        string
+		expectedPath        string
+		expectedRequestBody string
+		withError           error
+	}{
+		"204 No Content": {
+			params: AddChangeListChangeRequest{
+				Zone: "example.com",
+				Change: &ChangeListChange{
+					Name:  "www.example.com",
+					Type:  "A",
+					Op:    ChangeListOpAdd,
+					TTL:   300,
+					Rdata: []string{"192.0.2.1"},
+				},
+			},
+			responseStatus:      http.StatusNoContent,
+			expectedPath:        "/config-dns/v2/changelists/example.com/recordsets/add-change",
+			expectedRequestBody: `{"name":"www.example.com","type":"A","op":"ADD","ttl":300,"rdata":["192.0.2.1"]}`,
+		},
+		"204 No Content delete": {
+			params: AddChangeListChangeRequest{
+				Zone:   "example.com",
+				Change: &ChangeListChange{Name: "www.example.com", Type: "A", Op: ChangeListOpDelete},
+			},
+			responseStatus:      http.StatusNoContent,
+			expectedPath:        "/config-dns/v2/changelists/example.com/recordsets/add-change",
+			expectedRequestBody: `{"name":"www.example.com","type":"A","op":"DELETE"}`,
+		},
+		"500 internal server error": {
+			params: AddChangeListChangeRequest{
+				Zone:   "example.com",
+				Change: &ChangeListChange{Name: "www.example.com", Type: "A", Op: ChangeListOpDelete},
+			},
+			responseStatus: http.StatusInternalServerError,
+			responseBody: `
+{
+	"type": "internal_error",
+    "title": "Internal Server Error",
+    "detail": "Error adding change",
+    "status": 500
+}`,
+			expectedPath:        "/config-dns/v2/changelists/example.com/recordsets/add-change",
+			expectedRequestBody: `{"name":"www.example.com","type":"A","op":"DELETE"}`,
+			withError: &Error{
+				Type:       "internal_error",
+				Title:      "Internal Server Error",
+				Detail:     "Error adding change",
+				StatusCode: http.StatusInternalServerError,
+			},
+		},
+		"validation error": {
+			params: AddChangeListChangeRequest{
+				Zone:   "example.com",
+				Change: &ChangeListChange{Name: "www.example.com", Type: "A", Op: ChangeListOpAdd},
+			},
+			withError: ErrStructValidation,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			mockServer := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				assert.Equal(t, test.expectedPath, r.URL.String())
+				assert.Equal(t, http.MethodPost, r.Method)
+				body, err := io.ReadAll(r.Body)
+				assert.NoError(t, err)
+				assert.JSONEq(t, test.expectedRequestBody, string(body))
+				w.WriteHeader(test.responseStatus)
+				if len(test.responseBody) > 0 {
+					_, err := w.Write([]byte(test.responseBody))
+					assert.NoError(t, err)
+				}
+			}))
+			client := mockAPIClient(t, mockServer)
+			err := client.AddChangeListChange(context.Background(), test.params)
+			if test.withError != nil {
+				assert.True(t, errors.Is(err, test.withError), "want: %s; got: %s", test.withError, err)
+				return
+			}
+			require.NoError(t, err)
+		})
+	}
+}
+
+func TestDNS_DeleteChangeList(t *testing.T) {
+	tests := map[string]struct {
+		params         DeleteChangeListRequest
+		responseStatus int
+		responseBody   string
+		expectedPath   string
+		withError      error
+	}{
+		"204 No Content": {
+			params:         DeleteChangeListRequest{Zone: "example.com"},
+			responseStatus: http.StatusNoContent,
+			expectedPath:   "/config-dns/v2/changelists/example.com",
+		},
+		"404 not found": {
+			params:         DeleteChangeListRequest{Zone: "example.com"},
+			responseStatus: http.StatusNotFound,
+			responseBody: `
+{
+	"type": "not_found",
+    "title": "Not Found",
+    "detail": "Change list not found",
+    "status": 404
+}`,
+			expectedPath: "/config-dns/v2/changelists/example.com",
+			withError: &Error{
+				Type:       "not_found",
+				Title:      "Not Found",
+				Detail:     "Change list not found",
+				StatusCode: http.StatusNotFound,
+			},
+		},
+		"validation error": {
+			params:    DeleteChangeListRequest{},
+			withError: ErrStructValidation,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			mockServer := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				assert.Equal(t, test.expectedPath, r.URL.String())
+				assert.Equal(t, http.MethodDelete, r.Method)
+				w.WriteHeader(test.responseStatus)
+				if len(test.responseBody) > 0 {
+					_, err := w.Write([]byte(test.responseBody))
+					assert.NoError(t, err)
+				}
+			}))
+			client := mockAPIClient(t, mockServer)
+			err := client.DeleteChangeList(context.Background(), test.params)
+			if test.withError != nil {
+				assert.True(t, errors.Is(err, test.withError), "want: %s; got: %s", test.withError, err)
+				return
+			}
+			require.NoError(t, err)
+		})
+	}
+}
+
 func TestDNS_UpdateZone(t *testing.T) {
 	tests := map[string]struct {
 		params         UpdateZoneRequest
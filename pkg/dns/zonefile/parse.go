@@ -0,0 +1,438 @@
+package zonefile
+
+import (
+	"fmt"
+	"io"
+	"net"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/akamai/AkamaiOPEN-edgegrid-golang/v11/pkg/dns"
+)
+
+type (
+	parser struct {
+		origin     string
+		defaultTTL int
+		hasTTL     bool
+		lastTTL    int
+		hasLastTTL bool
+		owner      string
+		sets       []*dns.RecordSet
+		index      map[setKey]*dns.RecordSet
+		seen       map[setKey]map[string]struct{}
+	}
+
+	setKey struct {
+		name   string
+		rrType string
+	}
+
+	// token is a single field of a master file entry. Quoted tokens keep their quotes and
+	// escaped characters keep their backslash, so the presentation format is preserved.
+	token struct {
+		value  string
+		quoted bool
+	}
+
+	// entry is a logical line of a master file, which may span several physical lines within parentheses.
+	entry struct {
+		line       int
+		blankOwner bool
+		tokens     []token
+	}
+)
+
+var (
+	typePattern  = regexp.MustCompile(`^[A-Z][A-Z0-9-]*$`)
+	classPattern = regexp.MustCompile(`^(IN|CS|CH|HS|CLASS[0-9]+)$`)
+)
+
+// Parse reads a master file and returns its records grouped into record sets, in order of first appearance.
+//
+// The $ORIGIN and $TTL directives, multi-line entries in parentheses, comments, escapes, blank owner names,
+// "@" and relative names are supported. $INCLUDE is rejected. Owner names are lower-cased and returned without
+// the trailing dot, like in the Edge DNS API. Domain names in RDATA are made absolute. TXT and SPF strings
+// are quoted, and base64 or hex values split by whitespace are joined. Duplicate records are dropped, and
+// when the records of a set have different TTLs the lowest one is used.
+func Parse(r io.Reader, opts ...Option) ([]dns.RecordSet, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrParse, err)
+	}
+
+	p := parser{
+		index: make(map[setKey]*dns.RecordSet),
+		seen:  make(map[setKey]map[string]struct{}),
+	}
+	for _, opt := range opts {
+		opt(&p)
+	}
+
+	entries, err := lex(string(data))
+	if err != nil {
+		return nil, err
+	}
+	for _, e := range entries {
+		if err := p.entry(e); err != nil {
+			return nil, err
+		}
+	}
+
+	sets := make([]dns.RecordSet, 0, len(p.sets))
+	for _, set := range p.sets {
+		sets = append(sets, *set)
+	}
+	return sets, nil
+}
+
+func lex(input string) ([]entry, error) {
+	var (
+		entries     []entry
+		current     entry
+		value       strings.Builder
+		inToken     bool
+		quoted      bool
+		inQuotes    bool
+		depth       int
+		line        = 1
+		atLineStart = true
+	)
+
+	flush := func() {
+		if inToken {
+			current.tokens = append(current.tokens, token{value: value.String(), quoted: quoted})
+			value.Reset()
+			inToken, quoted = false, false
+		}
+	}
+	endEntry := func() {
+		flush()
+		if len(current.tokens) > 0 {
+			entries = append(entries, current)
+		}
+		current = entry{}
+	}
+
+	for i := 0; i < len(input); i++ {
+		c := input[i]
+		if atLineStart {
+			current.line = line
+			current.blankOwner = c == ' ' || c == '\t'
+			atLineStart = false
+		}
+
+		if inQuotes {
+			switch c {
+			case '\\':
+				value.WriteByte(c)
+				if i+1 < len(input) {
+					i++
+					value.WriteByte(input[i])
+				}
+			case '"':
+				value.WriteByte(c)
+				inQuotes = false
+				flush()
+			case '\n':
+				return nil, &ParseError{Line: line, Msg: "unterminated quoted string"}
+			default:
+				value.WriteByte(c)
+			}
+			continue
+		}
+
+		switch c {
+		case '\\':
+			inToken = true
+			value.WriteByte(c)
+			if i+1 < len(input) {
+				i++
+				value.WriteByte(input[i])
+			}
+		case '"':
+			flush()
+			inToken, quoted, inQuotes = true, true, true
+			value.WriteByte(c)
+		case ';':
+			flush()
+			for i+1 < len(input) && input[i+1] != '\n' {
+				i++
+			}
+		case '(':
+			flush()
+			depth++
+		case ')':
+			flush()
+			if depth == 0 {
+				return nil, &ParseError{Line: line, Msg: "unbalanced closing parenthesis"}
+			}
+			depth--
+		case ' ', '\t', '\r':
+			flush()
+		case '\n':
+			line++
+			if depth == 0 {
+				endEntry()
+				atLineStart = true
+			} else {
+				flush()
+			}
+		default:
+			inToken = true
+			value.WriteByte(c)
+		}
+	}
+
+	if inQuotes {
+		return nil, &ParseError{Line: line, Msg: "unterminated quoted string"}
+	}
+	if depth > 0 {
+		return nil, &ParseError{Line: current.line, Msg: "unbalanced opening parenthesis"}
+	}
+	endEntry()
+	return entries, nil
+}
+
+func (p *parser) entry(e entry) error {
+	fail := func(format string, args ...interface{}) error {
+		return &ParseError{Line: e.line, Msg: fmt.Sprintf(format, args...)}
+	}
+
+	tokens := e.tokens
+	if !e.blankOwner && !tokens[0].quoted && strings.HasPrefix(tokens[0].value, "$") {
+		return p.directive(e)
+	}
+
+	if e.blankOwner {
+		if p.owner == "" {
+			return fail("record without owner name")
+		}
+	} else {
+		owner, err := p.name(tokens[0].value)
+		if err != nil {
+			return fail("%s", err)
+		}
+		p.owner = strings.ToLower(owner)
+		tokens = tokens[1:]
+	}
+
+	var ttl int
+	var hasTTL, hasClass bool
+fields:
+	for len(tokens) > 0 && !tokens[0].quoted {
+		value := tokens[0].value
+		switch {
+		case !hasTTL && value[0] >= '0' && value[0] <= '9':
+			var err error
+			if ttl, err = parseTTL(value); err != nil {
+				return fail("%s", err)
+			}
+			hasTTL = true
+		case !hasClass && classPattern.MatchString(strings.ToUpper(value)):
+			if !strings.EqualFold(value, "IN") {
+				return fail("unsupported class %s", value)
+			}
+			hasClass = true
+		default:
+			break fields
+		}
+		tokens = tokens[1:]
+	}
+
+	if len(tokens) == 0 {
+		return fail("missing record type")
+	}
+	rrType := strings.ToUpper(tokens[0].value)
+	if tokens[0].quoted || !typePattern.MatchString(rrType) {
+		return fail("invalid record type %s", tokens[0].value)
+	}
+
+	rdata, err := p.rdata(rrType, tokens[1:])
+	if err != nil {
+		return fail("%s record: %s", rrType, err)
+	}
+
+	switch {
+	case hasTTL:
+		p.lastTTL, p.hasLastTTL = ttl, true
+	case p.hasTTL:
+		ttl = p.defaultTTL
+	case p.hasLastTTL:
+		ttl = p.lastTTL
+	default:
+		return fail("no TTL for %s record and no $TTL directive", rrType)
+	}
+
+	p.add(strings.TrimSuffix(p.owner, "."), rrType, ttl, rdata)
+	return nil
+}
+
+func (p *parser) directive(e entry) error {
+	fail := func(format string, args ...interface{}) error {
+		return &ParseError{Line: e.line, Msg: fmt.Sprintf(format, args...)}
+	}
+
+	directive := strings.ToUpper(e.tokens[0].value)
+	switch directive {
+	case "$ORIGIN":
+		if len(e.tokens) != 2 {
+			return fail("$ORIGIN takes exactly one name")
+		}
+		origin, err := p.name(e.tokens[1].value)
+		if err != nil {
+			return fail("%s", err)
+		}
+		p.origin = strings.ToLower(origin)
+	case "$TTL":
+		if len(e.tokens) != 2 {
+			return fail("$TTL takes exactly one value")
+		}
+		ttl, err := parseTTL(e.tokens[1].value)
+		if err != nil {
+			return fail("%s", err)
+		}
+		p.defaultTTL, p.hasTTL = ttl, true
+	case "$INCLUDE":
+		return fail("$INCLUDE is not supported")
+	default:
+		return fail("unsupported directive %s", e.tokens[0].value)
+	}
+	return nil
+}
+
+// name returns the absolute form of a name written in the master file.
+func (p *parser) name(name string) (string, error) {
+	switch {
+	case name == "@":
+		if p.origin == "" {
+			return "", fmt.Errorf("@ used without origin")
+		}
+		return p.origin, nil
+	case isAbsolute(name):
+		return name, nil
+	case p.origin == "":
+		return "", fmt.Errorf("relative name %s used without origin", name)
+	case p.origin == ".":
+		return name + ".", nil
+	default:
+		return name + "." + p.origin, nil
+	}
+}
+
+func (p *parser) rdata(rrType string, tokens []token) (string, error) {
+	if len(tokens) == 0 {
+		return "", fmt.Errorf("missing RDATA")
+	}
+
+	values := make([]string, 0, len(tokens))
+	for _, t := range tokens {
+		values = append(values, t.value)
+	}
+	// RFC 3597 generic RDATA is kept as written
+	if values[0] == `\#` {
+		return strings.Join(values, " "), nil
+	}
+
+	layout, ok := rdataLayouts[rrType]
+	if !ok {
+		return strings.Join(values, " "), nil
+	}
+	if len(values) < layout.fields {
+		return "", fmt.Errorf("expected at least %d RDATA fields, got %d", layout.fields, len(values))
+	}
+
+	if layout.text {
+		for i, t := range tokens {
+			if !t.quoted {
+				values[i] = `"` + t.value + `"`
+			}
+		}
+	}
+	for _, i := range layout.names {
+		name, err := p.name(values[i])
+		if err != nil {
+			return "", err
+		}
+		values[i] = name
+	}
+	if layout.joinFrom > 0 && len(values) > layout.joinFrom {
+		values = append(values[:layout.joinFrom], strings.Join(values[layout.joinFrom:], ""))
+	}
+
+	switch rrType {
+	case "A":
+		if ip := net.ParseIP(values[0]); ip == nil || ip.To4() == nil {
+			return "", fmt.Errorf("invalid IPv4 address %s", values[0])
+		}
+	case "AAAA":
+		if ip := net.ParseIP(values[0]); ip == nil || ip.To4() != nil {
+			return "", fmt.Errorf("invalid IPv6 address %s", values[0])
+		}
+	}
+
+	return strings.Join(values, " "), nil
+}
+
+func (p *parser) add(name, rrType string, ttl int, rdata string) {
+	key := setKey{name: name, rrType: rrType}
+	set, ok := p.index[key]
+	if !ok {
+		set = &dns.RecordSet{Name: name, Type: rrType, TTL: ttl}
+		p.index[key] = set
+		p.seen[key] = make(map[string]struct{})
+		p.sets = append(p.sets, set)
+	}
+	if ttl < set.TTL {
+		set.TTL = ttl
+	}
+	if _, ok := p.seen[key][rdata]; ok {
+		return
+	}
+	p.seen[key][rdata] = struct{}{}
+	set.Rdata = append(set.Rdata, rdata)
+}
+
+// parseTTL parses a TTL given in seconds or with the s, m, h, d and w units, such as 1h30m.
+func parseTTL(value string) (int, error) {
+	if ttl, err := strconv.ParseUint(value, 10, 31); err == nil {
+		return int(ttl), nil
+	}
+
+	var total, number uint64
+	var hasNumber bool
+	for i := 0; i < len(value); i++ {
+		c := value[i]
+		if c >= '0' && c <= '9' {
+			number = number*10 + uint64(c-'0')
+			hasNumber = true
+			if number > 1<<31-1 {
+				return 0, fmt.Errorf("invalid TTL %s", value)
+			}
+			continue
+		}
+		if !hasNumber {
+			return 0, fmt.Errorf("invalid TTL %s", value)
+		}
+		switch c {
+		case 's', 'S':
+		case 'm', 'M':
+			number *= 60
+		case 'h', 'H':
+			number *= 60 * 60
+		case 'd', 'D':
+			number *= 24 * 60 * 60
+		case 'w', 'W':
+			number *= 7 * 24 * 60 * 60
+		default:
+			return 0, fmt.Errorf("invalid TTL %s", value)
+		}
+		total += number
+		number, hasNumber = 0, false
+	}
+	if hasNumber || total > 1<<31-1 {
+		return 0, fmt.Errorf("invalid TTL %s", value)
+	}
+	return int(total), nil
+}
@@ -0,0 +1,200 @@
+package zonefile
+
+import (
+	"errors"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/akamai/AkamaiOPEN-edgegrid-golang/v11/pkg/dns"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParse(t *testing.T) {
+	tests := map[string]struct {
+		zone         string
+		opts         []Option
+		expectedSets []dns.RecordSet
+		withError    func(*testing.T, error)
+	}{
+		"origin from option and default TTL": {
+			zone: "@ A 192.0.2.1\nwww CNAME @\n",
+			opts: []Option{WithOrigin("Example.com"), WithDefaultTTL(300)},
+			expectedSets: []dns.RecordSet{
+				{Name: "example.com", Type: "A", TTL: 300, Rdata: []string{"192.0.2.1"}},
+				{Name: "www.example.com", Type: "CNAME", TTL: 300, Rdata: []string{"example.com."}},
+			},
+		},
+		"last explicit TTL is reused": {
+			zone: "$ORIGIN example.com.\nwww 600 A 192.0.2.1\n A 192.0.2.2\napi A 192.0.2.3\n",
+			expectedSets: []dns.RecordSet{
+				{Name: "www.example.com", Type: "A", TTL: 600, Rdata: []string{"192.0.2.1", "192.0.2.2"}},
+				{Name: "api.example.com", Type: "A", TTL: 600, Rdata: []string{"192.0.2.3"}},
+			},
+		},
+		"lowest TTL of a set wins": {
+			zone: "www.example.com. 600 A 192.0.2.1\nwww.example.com. 60 A 192.0.2.2\n",
+			expectedSets: []dns.RecordSet{
+				{Name: "www.example.com", Type: "A", TTL: 60, Rdata: []string{"192.0.2.1", "192.0.2.2"}},
+			},
+		},
+		"relative origin and case insensitive owners": {
+			zone: "$ORIGIN example.com.\n$ORIGIN sub\n$TTL 1d\nWWW a 192.0.2.1\nwww A 192.0.2.2\n",
+			expectedSets: []dns.RecordSet{
+				{Name: "www.sub.example.com", Type: "A", TTL: 86400, Rdata: []string{"192.0.2.1", "192.0.2.2"}},
+			},
+		},
+		"parentheses inside quotes and comments": {
+			zone: "$TTL 60\nexample.com. TXT \"a (b) ; c\" ; (comment\n",
+			expectedSets: []dns.RecordSet{
+				{Name: "example.com", Type: "TXT", TTL: 60, Rdata: []string{`"a (b) ; c"`}},
+			},
+		},
+		"$INCLUDE is rejected": {
+			zone: "$ORIGIN example.com.\n$INCLUDE other.zone\n",
+			withError: func(t *testing.T, err error) {
+				var parseErr *ParseError
+				require.True(t, errors.As(err, &parseErr), "want: *ParseError; got: %s", err)
+				assert.Equal(t, 2, parseErr.Line)
+				assert.Equal(t, "zone file parse: line 2: $INCLUDE is not supported", err.Error())
+			},
+		},
+		"relative name without origin": {
+			zone:      "www 300 A 192.0.2.1\n",
+			withError: expectParseError(1, "relative name www used without origin"),
+		},
+		"missing TTL": {
+			zone:      "www.example.com. A 192.0.2.1\n",
+			withError: expectParseError(1, "no TTL for A record and no $TTL directive"),
+		},
+		"blank owner on first record": {
+			zone:      "$TTL 60\n  A 192.0.2.1\n",
+			withError: expectParseError(2, "record without owner name"),
+		},
+		"unsupported class": {
+			zone:      "$TTL 60\nexample.com. CH TXT \"x\"\n",
+			withError: expectParseError(2, "unsupported class CH"),
+		},
+		"invalid TTL": {
+			zone:      "$TTL 1x\n",
+			withError: expectParseError(1, "invalid TTL 1x"),
+		},
+		"too few fields": {
+			zone:      "$TTL 60\nexample.com. MX 10\n",
+			withError: expectParseError(2, "MX record: expected at least 2 RDATA fields, got 1"),
+		},
+		"invalid address": {
+			zone:      "$TTL 60\nexample.com. AAAA 192.0.2.1\n",
+			withError: expectParseError(2, "AAAA record: invalid IPv6 address 192.0.2.1"),
+		},
+		"unterminated quoted string": {
+			zone:      "$TTL 60\nexample.com. TXT \"abc\n",
+			withError: expectParseError(2, "unterminated quoted string"),
+		},
+		"unbalanced parenthesis": {
+			zone:      "$TTL 60\nexample.com. SOA ns. host. ( 1 2 3 4 5\n",
+			withError: expectParseError(2, "unbalanced opening parenthesis"),
+		},
+		"missing type": {
+			zone:      "$TTL 60\nexample.com. 300 IN\n",
+			withError: expectParseError(2, "missing record type"),
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			sets, err := Parse(strings.NewReader(test.zone), test.opts...)
+			if test.withError != nil {
+				require.Error(t, err)
+				assert.True(t, errors.Is(err, ErrParse), "want: %s; got: %s", ErrParse, err)
+				test.withError(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, test.expectedSets, sets)
+		})
+	}
+}
+
+func expectParseError(line int, msg string) func(*testing.T, error) {
+	return func(t *testing.T, err error) {
+		var parseErr *ParseError
+		require.True(t, errors.As(err, &parseErr), "want: *ParseError; got: %s", err)
+		assert.Equal(t, &ParseError{Line: line, Msg: msg}, parseErr)
+	}
+}
+
+func TestParse_AllTypes(t *testing.T) {
+	f, err := os.Open("testdata/TestParse/example.com.zone")
+	require.NoError(t, err)
+	defer func() {
+		require.NoError(t, f.Close())
+	}()
+
+	sets, err := Parse(f)
+	require.NoError(t, err)
+	assert.Equal(t, []dns.RecordSet{
+		{Name: "example.com", Type: "SOA", TTL: 3600, Rdata: []string{"a1-1.akam.net. hostmaster.example.com. 2024010101 3600 600 604800 300"}},
+		{Name: "example.com", Type: "NS", TTL: 86400, Rdata: []string{"a1-1.akam.net.", "a2-2.akam.net."}},
+		{Name: "example.com", Type: "A", TTL: 300, Rdata: []string{"192.0.2.1", "192.0.2.2"}},
+		{Name: "example.com", Type: "AAAA", TTL: 3600, Rdata: []string{"2001:db8::1"}},
+		{Name: "example.com", Type: "MX", TTL: 3600, Rdata: []string{"10 mail.example.com.", "20 mail.example.net."}},
+		{Name: "example.com", Type: "TXT", TTL: 3600, Rdata: []string{`"v=spf1 include:_spf.example.net -all"`}},
+		{Name: "example.com", Type: "SPF", TTL: 3600, Rdata: []string{`"v=spf1 -all"`}},
+		{Name: "example.com", Type: "CAA", TTL: 3600, Rdata: []string{`0 issue "letsencrypt.org"`, `0 iodef "mailto:security@example.com"`}},
+		{Name: "example.com", Type: "HINFO", TTL: 3600, Rdata: []string{`"INTEL" "LINUX"`}},
+		{Name: "example.com", Type: "LOC", TTL: 3600, Rdata: []string{"51 30 12.748 N 0 7 39.611 W 0.00m 1m 10000m 10m"}},
+		{Name: "example.com", Type: "DNSKEY", TTL: 3600, Rdata: []string{"257 3 13 mdsswUyr3DPW132mOi8V9xESWE8jTo0dxCjjnopKl+GqJxpVXckHAeF+KkxLbxILfDLUT0rAK9iUzy1L53eKGQ=="}},
+		{Name: "example.com", Type: "DS", TTL: 3600, Rdata: []string{"60485 13 2 D4B7D520E7BB5F0F67674A0CCEB1E3E0614B93C4F9E99B8383F6A1E4469DA50A"}},
+		{Name: "example.com", Type: "CERT", TTL: 3600, Rdata: []string{"PGP 0 0 mQGiBDnY2vERBAD3cOxqoAYHYzS+xttvuyN9wZS8CrgwLIlT8Ewo/CCFI11PEO+gJyNPvWPRQsyt1SE60reaIsie2bQTg3DYIg0PmH+ZOlNkpKesPULzdlw4Rx3dD/M3Lkrm977h4Y70ZKC+tbvoYKCCOIkUVevny1PVZ+/nGjWiM1iWqFLs2DwuQwCg/65i9x2bSf8HsThiX/LhHbvXgQ"}},
+		{Name: "example.com", Type: "NSEC3PARAM", TTL: 3600, Rdata: []string{"1 0 10 AABBCCDD"}},
+		{Name: "example.com", Type: "TLSA", TTL: 3600, Rdata: []string{"3 1 1 0C72AC70B745AC19998811B131D662C9AC69DBDBE7CB23E5B514B56664C5D3D6"}},
+		{Name: "example.com", Type: "SSHFP", TTL: 3600, Rdata: []string{"4 2 123456789ABCDEF67890123456789ABCDEF67890123456789ABCDEF123456789"}},
+		{Name: "example.com", Type: "HTTPS", TTL: 3600, Rdata: []string{"1 . alpn=h3,h2"}},
+		{Name: "example.com", Type: "SVCB", TTL: 3600, Rdata: []string{"1 svc.example.net. port=8443"}},
+		{Name: "_sip._tcp.example.com", Type: "SRV", TTL: 3600, Rdata: []string{"10 60 5060 sip.example.com."}},
+		{Name: "_sip._tcp.example.com", Type: "NAPTR", TTL: 3600, Rdata: []string{`100 10 "U" "E2U+sip" "!^.*$!sip:info@example.com!" .`}},
+		{Name: "www.example.com", Type: "CNAME", TTL: 3600, Rdata: []string{"example.com."}},
+		{Name: "mail.example.com", Type: "A", TTL: 3600, Rdata: []string{"192.0.2.25"}},
+		{Name: "mail.example.com", Type: "RP", TTL: 3600, Rdata: []string{"admin.example.com. txt.example.com."}},
+		{Name: "mail.example.com", Type: "AFSDB", TTL: 3600, Rdata: []string{"1 afsdb.example.com."}},
+		{Name: "sub.example.com", Type: "NS", TTL: 3600, Rdata: []string{"ns1.sub.example.com."}},
+		{Name: "sub.example.com", Type: "DS", TTL: 3600, Rdata: []string{"12345 8 2 ABCD"}},
+		{Name: "txt.example.com", Type: "TXT", TTL: 3600, Rdata: []string{`"unquoted" "quoted with \"escape\"" "semi\;colon"`}},
+		{Name: `escaped\.dot.example.com`, Type: "TXT", TTL: 3600, Rdata: []string{`"a b"`}},
+		{Name: "p.example.com", Type: "PTR", TTL: 3600, Rdata: []string{"target.example.net."}},
+		{Name: "cdn.example.com", Type: "AKAMAICDN", TTL: 3600, Rdata: []string{"cdn.example.com.edgekey.net"}},
+		{Name: "tlc.example.com", Type: "AKAMAITLC", TTL: 3600, Rdata: []string{"DUAL cdn.example.com.edgekey.net"}},
+		{Name: "hashed.example.com", Type: "NSEC3", TTL: 3600, Rdata: []string{"1 1 12 AABBCCDD 2VPTU5TIMAMQTTGL4LUU9KG21E0AOR3S A RRSIG"}},
+		{Name: "signed.example.com", Type: "RRSIG", TTL: 3600, Rdata: []string{"A 13 3 300 20240201000000 20240101000000 60485 example.com. oJB1W6WNGv+ldvQ3WDG0MQkg5IEhjRip8WTrPYGv07h108dUKGMeDPKijVCHX3DDKdfb+v6oB9wfuh3DTJXUAfI/M0zmO/zz8bW0Rznl8O3tGNazPwQKkRN20XPXV6nwwfoXmJQbsLNrLfkGJ5D6fwFm8nN+6pBzeDQfsS3Ap3o="}},
+		{Name: "wild.example.com", Type: "TYPE65534", TTL: 300, Rdata: []string{`\# 4 0A000001`}},
+	}, sets)
+}
+
+func TestParseTTL(t *testing.T) {
+	tests := map[string]struct {
+		value    string
+		expected int
+		withErr  bool
+	}{
+		"seconds":          {value: "3600", expected: 3600},
+		"units":            {value: "1h30m", expected: 5400},
+		"upper case units": {value: "1W2D", expected: 777600},
+		"trailing number":  {value: "1h30", withErr: true},
+		"unknown unit":     {value: "1y", withErr: true},
+		"too large":        {value: "4294967296", withErr: true},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			ttl, err := parseTTL(test.value)
+			if test.withErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, test.expected, ttl)
+		})
+	}
+}
@@ -0,0 +1,85 @@
+package zonefile
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/akamai/AkamaiOPEN-edgegrid-golang/v11/pkg/dns"
+)
+
+// Serialize returns record sets as canonical master file text. See Write.
+func Serialize(sets []dns.RecordSet) (string, error) {
+	var b strings.Builder
+	if err := Write(&b, sets); err != nil {
+		return "", err
+	}
+	return b.String(), nil
+}
+
+// Write writes record sets as canonical master file text, one record per line with an absolute owner name,
+// explicit TTL and class. Names are sorted in DNS canonical order, so the zone apex comes first.
+// For each name SOA is written first, then NS, then the other types alphabetically. Records keep the order of
+// RecordSet.Rdata. The output can be read back with Parse and uploaded with dns.PostMasterZoneFile.
+func Write(w io.Writer, sets []dns.RecordSet) error {
+	sorted := make([]dns.RecordSet, 0, len(sets))
+	for _, set := range sets {
+		if set.Name == "" || set.Type == "" {
+			return fmt.Errorf("%w: record set without name or type", ErrSerialize)
+		}
+		if set.TTL < 0 {
+			return fmt.Errorf("%w: %s %s: negative TTL", ErrSerialize, set.Name, set.Type)
+		}
+		sorted = append(sorted, set)
+	}
+	sort.SliceStable(sorted, func(i, j int) bool {
+		if c := compareNames(sorted[i].Name, sorted[j].Name); c != 0 {
+			return c < 0
+		}
+		return typeRank(sorted[i].Type) < typeRank(sorted[j].Type)
+	})
+
+	buf := bufio.NewWriter(w)
+	for _, set := range sorted {
+		owner := absolute(set.Name)
+		rrType := strings.ToUpper(set.Type)
+		for _, rdata := range set.Rdata {
+			if strings.ContainsAny(rdata, "\n\r") {
+				return fmt.Errorf("%w: %s %s: RDATA contains a line break", ErrSerialize, set.Name, set.Type)
+			}
+			if _, err := fmt.Fprintf(buf, "%s\t%d\tIN\t%s\t%s\n", owner, set.TTL, rrType, rdata); err != nil {
+				return fmt.Errorf("%w: %w", ErrSerialize, err)
+			}
+		}
+	}
+	if err := buf.Flush(); err != nil {
+		return fmt.Errorf("%w: %w", ErrSerialize, err)
+	}
+	return nil
+}
+
+// compareNames compares names in DNS canonical order (RFC 4034, section 6.1).
+func compareNames(a, b string) int {
+	labelsA := splitLabels(strings.ToLower(strings.TrimSuffix(a, ".")))
+	labelsB := splitLabels(strings.ToLower(strings.TrimSuffix(b, ".")))
+	for i, j := len(labelsA)-1, len(labelsB)-1; i >= 0 && j >= 0; i, j = i-1, j-1 {
+		if c := strings.Compare(labelsA[i], labelsB[j]); c != 0 {
+			return c
+		}
+	}
+	return len(labelsA) - len(labelsB)
+}
+
+// typeRank orders SOA before NS, and both before the other types.
+func typeRank(rrType string) string {
+	switch strings.ToUpper(rrType) {
+	case "SOA":
+		return "0"
+	case "NS":
+		return "1"
+	default:
+		return "2" + strings.ToUpper(rrType)
+	}
+}
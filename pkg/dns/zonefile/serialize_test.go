@@ -0,0 +1,75 @@
+package zonefile
+
+import (
+	"errors"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/akamai/AkamaiOPEN-edgegrid-golang/v11/pkg/dns"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSerialize(t *testing.T) {
+	tests := map[string]struct {
+		sets      []dns.RecordSet
+		expected  string
+		withError error
+	}{
+		"canonical order": {
+			sets: []dns.RecordSet{
+				{Name: "www.example.com", Type: "CNAME", TTL: 300, Rdata: []string{"example.com."}},
+				{Name: "a.b.example.com", Type: "A", TTL: 300, Rdata: []string{"192.0.2.3"}},
+				{Name: "z.example.com", Type: "A", TTL: 300, Rdata: []string{"192.0.2.2"}},
+				{Name: "example.com", Type: "A", TTL: 300, Rdata: []string{"192.0.2.1"}},
+				{Name: "example.com", Type: "NS", TTL: 86400, Rdata: []string{"a1-1.akam.net.", "a2-2.akam.net."}},
+				{Name: "example.com", Type: "SOA", TTL: 86400, Rdata: []string{"a1-1.akam.net. hostmaster.example.com. 1 3600 600 604800 300"}},
+			},
+			expected: "example.com.\t86400\tIN\tSOA\ta1-1.akam.net. hostmaster.example.com. 1 3600 600 604800 300\n" +
+				"example.com.\t86400\tIN\tNS\ta1-1.akam.net.\n" +
+				"example.com.\t86400\tIN\tNS\ta2-2.akam.net.\n" +
+				"example.com.\t300\tIN\tA\t192.0.2.1\n" +
+				"a.b.example.com.\t300\tIN\tA\t192.0.2.3\n" +
+				"www.example.com.\t300\tIN\tCNAME\texample.com.\n" +
+				"z.example.com.\t300\tIN\tA\t192.0.2.2\n",
+		},
+		"missing name": {
+			sets:      []dns.RecordSet{{Type: "A", TTL: 300, Rdata: []string{"192.0.2.1"}}},
+			withError: ErrSerialize,
+		},
+		"line break in RDATA": {
+			sets:      []dns.RecordSet{{Name: "example.com", Type: "TXT", TTL: 300, Rdata: []string{"\"a\nb\""}}},
+			withError: ErrSerialize,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			out, err := Serialize(test.sets)
+			if test.withError != nil {
+				assert.True(t, errors.Is(err, test.withError), "want: %s; got: %s", test.withError, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, test.expected, out)
+		})
+	}
+}
+
+func TestSerialize_RoundTrip(t *testing.T) {
+	in, err := os.ReadFile("testdata/TestParse/example.com.zone")
+	require.NoError(t, err)
+	expected, err := os.ReadFile("testdata/TestSerialize/example.com.zone")
+	require.NoError(t, err)
+
+	sets, err := Parse(strings.NewReader(string(in)))
+	require.NoError(t, err)
+	out, err := Serialize(sets)
+	require.NoError(t, err)
+	assert.Equal(t, string(expected), out)
+
+	reparsed, err := Parse(strings.NewReader(out))
+	require.NoError(t, err)
+	assert.ElementsMatch(t, sets, reparsed)
+}
@@ -0,0 +1,138 @@
+// Package zonefile converts between RFC 1035 master files, as returned by dns.GetMasterZoneFile and accepted by
+// dns.PostMasterZoneFile, and the structured []dns.RecordSet form used by the record set operations.
+package zonefile
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+type (
+	// Option configures Parse.
+	Option func(*parser)
+
+	// ParseError describes a problem found in a master file.
+	ParseError struct {
+		Line int
+		Msg  string
+	}
+
+	// rdataLayout describes the presentation format of the RDATA of a record type.
+	rdataLayout struct {
+		// fields is the minimal number of RDATA fields.
+		fields int
+		// names lists positions of fields holding domain names, which are made absolute.
+		names []int
+		// joinFrom is the position from which the remaining fields form a single base64 or hex value,
+		// which may be split by whitespace in master files. Zero disables joining.
+		joinFrom int
+		// text marks types whose RDATA consists of character strings only.
+		text bool
+	}
+)
+
+var (
+	// ErrParse is returned when a master file cannot be parsed.
+	ErrParse = errors.New("zone file parse")
+
+	// ErrSerialize is returned when record sets cannot be serialized.
+	ErrSerialize = errors.New("zone file serialize")
+)
+
+// rdataLayouts covers every type handled by dns.ParseRData and the other types supported by Edge DNS.
+// Types not listed here are kept as written, with whitespace normalized.
+var rdataLayouts = map[string]rdataLayout{
+	"A":          {fields: 1},
+	"AAAA":       {fields: 1},
+	"AFSDB":      {fields: 2, names: []int{1}},
+	"AKAMAICDN":  {fields: 1},
+	"AKAMAITLC":  {fields: 2},
+	"CAA":        {fields: 3},
+	"CERT":       {fields: 4, joinFrom: 3},
+	"CNAME":      {fields: 1, names: []int{0}},
+	"DNSKEY":     {fields: 4, joinFrom: 3},
+	"DS":         {fields: 4, joinFrom: 3},
+	"HINFO":      {fields: 2},
+	"HTTPS":      {fields: 2, names: []int{1}},
+	"LOC":        {fields: 5},
+	"MX":         {fields: 2, names: []int{1}},
+	"NAPTR":      {fields: 6, names: []int{5}},
+	"NS":         {fields: 1, names: []int{0}},
+	"NSEC3":      {fields: 5},
+	"NSEC3PARAM": {fields: 4},
+	"PTR":        {fields: 1, names: []int{0}},
+	"RP":         {fields: 2, names: []int{0, 1}},
+	"RRSIG":      {fields: 9, names: []int{7}, joinFrom: 8},
+	"SOA":        {fields: 7, names: []int{0, 1}},
+	"SPF":        {fields: 1, text: true},
+	"SRV":        {fields: 4, names: []int{3}},
+	"SSHFP":      {fields: 3, joinFrom: 2},
+	"SVCB":       {fields: 2, names: []int{1}},
+	"TLSA":       {fields: 4, joinFrom: 3},
+	"TXT":        {fields: 1, text: true},
+}
+
+// Error returns the error message.
+func (e *ParseError) Error() string {
+	return fmt.Sprintf("%s: line %d: %s", ErrParse, e.Line, e.Msg)
+}
+
+// Unwrap returns ErrParse.
+func (e *ParseError) Unwrap() error {
+	return ErrParse
+}
+
+// WithOrigin sets the origin used for relative names until the master file sets its own with $ORIGIN.
+func WithOrigin(origin string) Option {
+	return func(p *parser) {
+		p.origin = absolute(strings.ToLower(origin))
+	}
+}
+
+// WithDefaultTTL sets the TTL of records which have no TTL and are not preceded by a $TTL directive.
+func WithDefaultTTL(ttl int) Option {
+	return func(p *parser) {
+		p.defaultTTL = ttl
+		p.hasTTL = true
+	}
+}
+
+// absolute appends the root label to the name unless it is already absolute.
+func absolute(name string) string {
+	if name == "" || isAbsolute(name) {
+		return name
+	}
+	return name + "."
+}
+
+// isAbsolute reports whether the name ends with an unescaped dot.
+func isAbsolute(name string) bool {
+	if !strings.HasSuffix(name, ".") {
+		return false
+	}
+	backslashes := 0
+	for i := len(name) - 2; i >= 0 && name[i] == '\\'; i-- {
+		backslashes++
+	}
+	return backslashes%2 == 0
+}
+
+// splitLabels splits a name on unescaped dots.
+func splitLabels(name string) []string {
+	var labels []string
+	start := 0
+	for i := 0; i < len(name); i++ {
+		switch name[i] {
+		case '\\':
+			i++
+		case '.':
+			labels = append(labels, name[start:i])
+			start = i + 1
+		}
+	}
+	if start < len(name) {
+		labels = append(labels, name[start:])
+	}
+	return labels
+}
@@ -0,0 +1,377 @@
+// Package dvsolver fulfils CPS domain validation challenges. DNS challenges are published as TXT records in
+// Edge DNS, HTTP challenges through a pluggable Publisher.
+package dvsolver
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/akamai/AkamaiOPEN-edgegrid-golang/v11/pkg/cps"
+	"github.com/akamai/AkamaiOPEN-edgegrid-golang/v11/pkg/dns"
+	"github.com/akamai/AkamaiOPEN-edgegrid-golang/v11/pkg/edgegriderr"
+	validation "github.com/go-ozzo/ozzo-validation/v4"
+)
+
+type (
+	// Challenge is a domain validation challenge selected for a domain.
+	Challenge struct {
+		Domain string
+		cps.Challenge
+	}
+
+	// Publisher publishes the response of a challenge where the certificate authority can see it, and removes it
+	// once the domain is validated.
+	Publisher interface {
+		Publish(ctx context.Context, challenge Challenge) error
+		Cleanup(ctx context.Context, challenge Challenge) error
+	}
+
+	// DNSPublisher publishes DNS challenges as TXT records in Edge DNS. Challenges of different domains sharing
+	// a record name, such as a domain and its wildcard, are published as values of the same record set.
+	DNSPublisher struct {
+		Client dns.DNS
+		// Zones are the zones the records may be created in. When empty, the zone of a record is looked up
+		// with GetZone, starting from the record name and removing leading labels.
+		Zones []string
+		// TTL of the created records. It defaults to DefaultTTL.
+		TTL int
+
+		mu    sync.Mutex
+		zones map[string]string
+	}
+
+	// SolveRequest is used to call Solve.
+	SolveRequest struct {
+		EnrollmentID int
+		ChangeID     int
+		// Publishers maps challenge types, such as ChallengeTypeDNS, to the publisher of those challenges
+		Publishers map[string]Publisher
+		// PreferredTypes is the order in which challenge types are chosen when a domain offers several of them.
+		// It defaults to ChallengeTypeDNS followed by ChallengeTypeHTTP.
+		PreferredTypes []string
+		// PollInterval is the interval between change status checks. It defaults to DefaultPollInterval.
+		PollInterval time.Duration
+		// Timeout limits the time spent waiting for validation. There is no limit when zero.
+		Timeout time.Duration
+	}
+
+	// SolveResult describes the challenges handled by Solve.
+	SolveResult struct {
+		// Published are the challenges which were published and cleaned up
+		Published []Challenge
+		// Skipped are the domains which were already validated
+		Skipped []string
+	}
+)
+
+const (
+	// ChallengeTypeDNS is the type of challenges answered with a TXT record
+	ChallengeTypeDNS = "dns-01"
+	// ChallengeTypeHTTP is the type of challenges answered with an HTTP token
+	ChallengeTypeHTTP = "http-01"
+
+	// DefaultTTL is the TTL of TXT records created by DNSPublisher
+	DefaultTTL = 60
+	// DefaultPollInterval is the default interval between change status checks
+	DefaultPollInterval = 30 * time.Second
+)
+
+var (
+	// ErrSolve is returned when Solve fails.
+	ErrSolve = errors.New("solve domain validation challenges")
+	// ErrNoPublisher is returned when a domain offers no challenge with a publisher.
+	ErrNoPublisher = errors.New("no publisher for the challenges of the domain")
+	// ErrValidationFailed is returned when the certificate authority rejects a challenge.
+	ErrValidationFailed = errors.New("domain validation failed")
+	// ErrCleanup is returned when a published challenge cannot be removed.
+	ErrCleanup = errors.New("cleanup")
+	// ErrZoneNotFound is returned when DNSPublisher cannot find the zone of a record.
+	ErrZoneNotFound = errors.New("zone not found")
+)
+
+// Validate validates SolveRequest.
+func (r SolveRequest) Validate() error {
+	return edgegriderr.ParseValidationErrors(validation.Errors{
+		"EnrollmentID": validation.Validate(r.EnrollmentID, validation.Required),
+		"ChangeID":     validation.Validate(r.ChangeID, validation.Required),
+		"Publishers":   validation.Validate(r.Publishers, validation.Required),
+		"PollInterval": validation.Validate(r.PollInterval, validation.Min(time.Duration(0))),
+		"Timeout":      validation.Validate(r.Timeout, validation.Min(time.Duration(0))),
+	})
+}
+
+// Solve fulfils the domain validation challenges of a change. For each domain which is not validated yet, it
+// publishes a challenge of the first preferred type with a publisher, acknowledges the challenges with
+// AcknowledgeDVChallenges and waits until the change no longer waits for them. Published challenges are removed
+// afterwards, also when validation fails.
+func Solve(ctx context.Context, client cps.CPS, params SolveRequest) (*SolveResult, error) {
+	if err := params.Validate(); err != nil {
+		return nil, fmt.Errorf("%s: %w: %s", ErrSolve, cps.ErrStructValidation, err)
+	}
+	preferred := params.PreferredTypes
+	if len(preferred) == 0 {
+		preferred = []string{ChallengeTypeDNS, ChallengeTypeHTTP}
+	}
+
+	changeReq := cps.GetChangeRequest{EnrollmentID: params.EnrollmentID, ChangeID: params.ChangeID}
+	dvs, err := client.GetChangeLetsEncryptChallenges(ctx, changeReq)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrSolve, err)
+	}
+
+	result := &SolveResult{}
+	var challenges []Challenge
+	for _, dv := range dvs.DV {
+		if isValidated(dv) {
+			result.Skipped = append(result.Skipped, dv.Domain)
+			continue
+		}
+		challenge, ok := selectChallenge(dv, preferred, params.Publishers)
+		if !ok {
+			return nil, fmt.Errorf("%w: %w: %s", ErrSolve, ErrNoPublisher, dv.Domain)
+		}
+		challenges = append(challenges, challenge)
+	}
+	if len(challenges) == 0 {
+		return result, nil
+	}
+
+	err = func() error {
+		for _, challenge := range challenges {
+			if err := params.Publishers[challenge.Type].Publish(ctx, challenge); err != nil {
+				return fmt.Errorf("publish %s challenge for %s: %w", challenge.Type, challenge.Domain, err)
+			}
+			result.Published = append(result.Published, challenge)
+		}
+
+		if err := client.AcknowledgeDVChallenges(ctx, cps.AcknowledgementRequest{
+			Acknowledgement: cps.Acknowledgement{Acknowledgement: cps.AcknowledgementAcknowledge},
+			EnrollmentID:    params.EnrollmentID,
+			ChangeID:        params.ChangeID,
+		}); err != nil {
+			return err
+		}
+		return waitForValidation(ctx, client, params)
+	}()
+
+	// cleanup runs on a context which is not canceled, so that records are removed after a timeout as well
+	cleanupCtx := context.WithoutCancel(ctx)
+	var cleanupErrs []error
+	for _, challenge := range result.Published {
+		if cleanupErr := params.Publishers[challenge.Type].Cleanup(cleanupCtx, challenge); cleanupErr != nil {
+			cleanupErrs = append(cleanupErrs, fmt.Errorf("%w: %s challenge for %s: %w", ErrCleanup, challenge.Type, challenge.Domain, cleanupErr))
+		}
+	}
+	if err = errors.Join(append([]error{err}, cleanupErrs...)...); err != nil {
+		return result, fmt.Errorf("%w: %w", ErrSolve, err)
+	}
+	return result, nil
+}
+
+// NewChangeStepHandler returns a cps.ChangeStepHandler which solves the DV challenges step of a change, for use
+// with cps.DriveChange. EnrollmentID and ChangeID of params are taken from the step.
+func NewChangeStepHandler(params SolveRequest) cps.ChangeStepHandler {
+	return func(ctx context.Context, client cps.CPS, step cps.ChangeStep) error {
+		params.EnrollmentID, params.ChangeID = step.EnrollmentID, step.ChangeID
+		_, err := Solve(ctx, client, params)
+		return err
+	}
+}
+
+// waitForValidation polls the change until it no longer waits for DV challenges
+func waitForValidation(ctx context.Context, client cps.CPS, params SolveRequest) error {
+	if params.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, params.Timeout)
+		defer cancel()
+	}
+	interval := params.PollInterval
+	if interval == 0 {
+		interval = DefaultPollInterval
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("waiting for validation: %w", ctx.Err())
+		case <-time.After(interval):
+		}
+
+		change, err := client.GetChangeStatus(ctx, cps.GetChangeStatusRequest{EnrollmentID: params.EnrollmentID, ChangeID: params.ChangeID})
+		if err != nil {
+			return err
+		}
+		if change.StatusInfo != nil && change.StatusInfo.Error != nil {
+			return fmt.Errorf("%w: %s: %s", ErrValidationFailed, change.StatusInfo.Error.Code, change.StatusInfo.Error.Description)
+		}
+		waiting := slices.ContainsFunc(cps.DecodeChangeSteps(params.EnrollmentID, params.ChangeID, change.AllowedInput),
+			func(step cps.ChangeStep) bool { return step.Type == cps.ChangeStepDVChallenges })
+		if !waiting {
+			return nil
+		}
+
+		dvs, err := client.GetChangeLetsEncryptChallenges(ctx, cps.GetChangeRequest{EnrollmentID: params.EnrollmentID, ChangeID: params.ChangeID})
+		if err != nil {
+			return err
+		}
+		var failures []string
+		for _, dv := range dvs.DV {
+			if strings.EqualFold(dv.Status, "invalid") || dv.Error != "" {
+				failures = append(failures, fmt.Sprintf("%s: %s", dv.Domain, dv.Error))
+			}
+		}
+		if len(failures) > 0 {
+			return fmt.Errorf("%w: %s", ErrValidationFailed, strings.Join(failures, "; "))
+		}
+	}
+}
+
+func isValidated(dv cps.DV) bool {
+	return strings.EqualFold(dv.Status, "valid") || strings.EqualFold(dv.ValidationStatus, "VALIDATED")
+}
+
+func selectChallenge(dv cps.DV, preferred []string, publishers map[string]Publisher) (Challenge, bool) {
+	for _, challengeType := range preferred {
+		if _, ok := publishers[challengeType]; !ok {
+			continue
+		}
+		for _, c := range dv.Challenges {
+			if c.Type == challengeType {
+				return Challenge{Domain: dv.Domain, Challenge: c}, true
+			}
+		}
+	}
+	return Challenge{}, false
+}
+
+// Publish adds the challenge response to the TXT record named by the challenge FullPath
+func (p *DNSPublisher) Publish(ctx context.Context, challenge Challenge) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	name, zone, current, err := p.lookup(ctx, challenge)
+	if err != nil {
+		return err
+	}
+	value := txtValue(challenge.ResponseBody)
+	if current == nil {
+		return p.Client.CreateRecord(ctx, dns.CreateRecordRequest{
+			Zone:   zone,
+			Record: &dns.RecordBody{Name: name, RecordType: "TXT", TTL: p.ttl(), Target: []string{value}},
+		})
+	}
+	if slices.ContainsFunc(current.Target, func(t string) bool { return sameTXT(t, value) }) {
+		return nil
+	}
+	return p.Client.UpdateRecord(ctx, dns.UpdateRecordRequest{
+		Zone:   zone,
+		Record: &dns.RecordBody{Name: name, RecordType: "TXT", TTL: current.TTL, Target: append(slices.Clone(current.Target), value)},
+	})
+}
+
+// Cleanup removes the challenge response from its TXT record, deleting the record when no other value is left
+func (p *DNSPublisher) Cleanup(ctx context.Context, challenge Challenge) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	name, zone, current, err := p.lookup(ctx, challenge)
+	if err != nil || current == nil {
+		return err
+	}
+	value := txtValue(challenge.ResponseBody)
+	remaining := slices.DeleteFunc(slices.Clone(current.Target), func(t string) bool { return sameTXT(t, value) })
+	switch {
+	case len(remaining) == len(current.Target):
+		return nil
+	case len(remaining) == 0:
+		return p.Client.DeleteRecord(ctx, dns.DeleteRecordRequest{Zone: zone, Name: name, RecordType: "TXT"})
+	default:
+		return p.Client.UpdateRecord(ctx, dns.UpdateRecordRequest{
+			Zone:   zone,
+			Record: &dns.RecordBody{Name: name, RecordType: "TXT", TTL: current.TTL, Target: remaining},
+		})
+	}
+}
+
+// lookup returns the record name and zone of a challenge, and its current TXT record, if any
+func (p *DNSPublisher) lookup(ctx context.Context, challenge Challenge) (string, string, *dns.GetRecordResponse, error) {
+	name := strings.ToLower(strings.TrimSuffix(challenge.FullPath, "."))
+	if name == "" {
+		return "", "", nil, fmt.Errorf("challenge for %s has no record name", challenge.Domain)
+	}
+	zone, err := p.findZone(ctx, name)
+	if err != nil {
+		return "", "", nil, err
+	}
+	record, err := p.Client.GetRecord(ctx, dns.GetRecordRequest{Zone: zone, Name: name, RecordType: "TXT"})
+	if isNotFound(err) {
+		return name, zone, nil, nil
+	}
+	if err != nil {
+		return "", "", nil, err
+	}
+	return name, zone, record, nil
+}
+
+func (p *DNSPublisher) findZone(ctx context.Context, name string) (string, error) {
+	if len(p.Zones) > 0 {
+		best := ""
+		for _, zone := range p.Zones {
+			zone = strings.ToLower(strings.TrimSuffix(zone, "."))
+			if (name == zone || strings.HasSuffix(name, "."+zone)) && len(zone) > len(best) {
+				best = zone
+			}
+		}
+		if best == "" {
+			return "", fmt.Errorf("%w: %s", ErrZoneNotFound, name)
+		}
+		return best, nil
+	}
+
+	if zone, ok := p.zones[name]; ok {
+		return zone, nil
+	}
+	labels := strings.Split(name, ".")
+	for i := 0; i < len(labels)-1; i++ {
+		candidate := strings.Join(labels[i:], ".")
+		_, err := p.Client.GetZone(ctx, dns.GetZoneRequest{Zone: candidate})
+		if isNotFound(err) {
+			continue
+		}
+		if err != nil {
+			return "", err
+		}
+		if p.zones == nil {
+			p.zones = make(map[string]string)
+		}
+		p.zones[name] = candidate
+		return candidate, nil
+	}
+	return "", fmt.Errorf("%w: %s", ErrZoneNotFound, name)
+}
+
+func (p *DNSPublisher) ttl() int {
+	if p.TTL > 0 {
+		return p.TTL
+	}
+	return DefaultTTL
+}
+
+func txtValue(s string) string {
+	return `"` + s + `"`
+}
+
+func sameTXT(a, b string) bool {
+	return strings.Trim(a, `"`) == strings.Trim(b, `"`)
+}
+
+func isNotFound(err error) bool {
+	var e *dns.Error
+	return errors.As(err, &e) && e.StatusCode == http.StatusNotFound
+}
@@ -0,0 +1,347 @@
+package dvsolver
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/akamai/AkamaiOPEN-edgegrid-golang/v11/pkg/cps"
+	"github.com/akamai/AkamaiOPEN-edgegrid-golang/v11/pkg/dns"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+type fakePublisher struct {
+	published  []string
+	cleaned    []string
+	publishErr map[string]error
+	cleanupErr map[string]error
+}
+
+func (f *fakePublisher) Publish(_ context.Context, challenge Challenge) error {
+	if err := f.publishErr[challenge.Domain]; err != nil {
+		return err
+	}
+	f.published = append(f.published, challenge.Domain)
+	return nil
+}
+
+func (f *fakePublisher) Cleanup(_ context.Context, challenge Challenge) error {
+	f.cleaned = append(f.cleaned, challenge.Domain)
+	return f.cleanupErr[challenge.Domain]
+}
+
+var (
+	changeReq = cps.GetChangeRequest{EnrollmentID: 1, ChangeID: 2}
+	statusReq = cps.GetChangeStatusRequest{EnrollmentID: 1, ChangeID: 2}
+	ackReq    = cps.AcknowledgementRequest{
+		Acknowledgement: cps.Acknowledgement{Acknowledgement: cps.AcknowledgementAcknowledge},
+		EnrollmentID:    1,
+		ChangeID:        2,
+	}
+	awaitingDV = &cps.Change{
+		StatusInfo: &cps.StatusInfo{State: "awaiting-input", Status: "coodinate-domain-validation"},
+		AllowedInput: []cps.AllowedInput{{
+			Type:              "lets-encrypt-challenges",
+			RequiredToProceed: true,
+			Info:              "/cps/v2/enrollments/1/changes/2/input/info/lets-encrypt-challenges",
+		}},
+	}
+	validated = &cps.Change{StatusInfo: &cps.StatusInfo{State: "running", Status: "wait-letsencrypt-cert-issuance"}}
+	notFound  = &dns.Error{StatusCode: http.StatusNotFound, Title: "Not Found"}
+)
+
+func dv(domain, status string, challenges ...cps.Challenge) cps.DV {
+	return cps.DV{Domain: domain, Status: status, Challenges: challenges}
+}
+
+func dnsChallenge(fullPath, response string) cps.Challenge {
+	return cps.Challenge{Type: ChallengeTypeDNS, FullPath: fullPath, ResponseBody: response, Status: "pending"}
+}
+
+func httpChallenge(domain string) cps.Challenge {
+	return cps.Challenge{Type: ChallengeTypeHTTP, FullPath: "http://" + domain + "/.well-known/acme-challenge/token", ResponseBody: "token.key", Status: "pending"}
+}
+
+func TestSolveWithDNSPublisher(t *testing.T) {
+	c := &cps.Mock{}
+	d := &dns.Mock{}
+	name := "_acme-challenge.example.com"
+	zoneReq := func(zone string) dns.GetZoneRequest { return dns.GetZoneRequest{Zone: zone} }
+	recordReq := dns.GetRecordRequest{Zone: "example.com", Name: name, RecordType: "TXT"}
+	record := func(values ...string) *dns.GetRecordResponse {
+		return &dns.GetRecordResponse{Name: name, RecordType: "TXT", TTL: 60, Target: values}
+	}
+
+	c.On("GetChangeLetsEncryptChallenges", mock.Anything, changeReq).Return(&cps.DVArray{DV: []cps.DV{
+		dv("example.com", "pending", httpChallenge("example.com"), dnsChallenge("_acme-challenge.example.com.", "apex")),
+		dv("*.example.com", "pending", dnsChallenge("_acme-challenge.example.com", "wildcard")),
+		dv("www.example.com", "valid", dnsChallenge("_acme-challenge.www.example.com", "www")),
+	}}, nil).Once()
+
+	// publish
+	d.On("GetZone", mock.Anything, zoneReq(name)).Return(nil, notFound).Once()
+	d.On("GetZone", mock.Anything, zoneReq("example.com")).Return(&dns.GetZoneResponse{Zone: "example.com"}, nil).Once()
+	d.On("GetRecord", mock.Anything, recordReq).Return(nil, notFound).Once()
+	d.On("CreateRecord", mock.Anything, dns.CreateRecordRequest{Zone: "example.com",
+		Record: &dns.RecordBody{Name: name, RecordType: "TXT", TTL: 60, Target: []string{`"apex"`}}}).Return(nil).Once()
+	d.On("GetRecord", mock.Anything, recordReq).Return(record(`"apex"`), nil).Once()
+	d.On("UpdateRecord", mock.Anything, dns.UpdateRecordRequest{Zone: "example.com",
+		Record: &dns.RecordBody{Name: name, RecordType: "TXT", TTL: 60, Target: []string{`"apex"`, `"wildcard"`}}}).Return(nil).Once()
+
+	// acknowledge and wait
+	c.On("AcknowledgeDVChallenges", mock.Anything, ackReq).Return(nil).Once()
+	c.On("GetChangeStatus", mock.Anything, statusReq).Return(awaitingDV, nil).Once()
+	c.On("GetChangeLetsEncryptChallenges", mock.Anything, changeReq).Return(&cps.DVArray{DV: []cps.DV{
+		dv("example.com", "pending"), dv("*.example.com", "pending"),
+	}}, nil).Once()
+	c.On("GetChangeStatus", mock.Anything, statusReq).Return(validated, nil).Once()
+
+	// cleanup
+	d.On("GetRecord", mock.Anything, recordReq).Return(record(`"apex"`, `"wildcard"`), nil).Once()
+	d.On("UpdateRecord", mock.Anything, dns.UpdateRecordRequest{Zone: "example.com",
+		Record: &dns.RecordBody{Name: name, RecordType: "TXT", TTL: 60, Target: []string{`"wildcard"`}}}).Return(nil).Once()
+	d.On("GetRecord", mock.Anything, recordReq).Return(record(`"wildcard"`), nil).Once()
+	d.On("DeleteRecord", mock.Anything, dns.DeleteRecordRequest{Zone: "example.com", Name: name, RecordType: "TXT"}).Return(nil).Once()
+
+	result, err := Solve(context.Background(), c, SolveRequest{
+		EnrollmentID: 1,
+		ChangeID:     2,
+		Publishers:   map[string]Publisher{ChallengeTypeDNS: &DNSPublisher{Client: d}},
+		PollInterval: time.Millisecond,
+	})
+	require.NoError(t, err)
+	c.AssertExpectations(t)
+	d.AssertExpectations(t)
+
+	assert.Equal(t, []string{"www.example.com"}, result.Skipped)
+	require.Len(t, result.Published, 2)
+	assert.Equal(t, "example.com", result.Published[0].Domain)
+	assert.Equal(t, ChallengeTypeDNS, result.Published[0].Type)
+	assert.Equal(t, "*.example.com", result.Published[1].Domain)
+}
+
+func TestSolve(t *testing.T) {
+	challenges := &cps.DVArray{DV: []cps.DV{
+		dv("a.example.com", "pending", dnsChallenge("_acme-challenge.a.example.com", "a"), httpChallenge("a.example.com")),
+		dv("b.example.com", "pending", httpChallenge("b.example.com")),
+	}}
+
+	tests := map[string]struct {
+		params       SolveRequest
+		dnsPublisher *fakePublisher
+		init         func(*cps.Mock)
+		published    []string
+		cleaned      []string
+		dnsPublished []string
+		// httpCleanupErr are the errors of the HTTP publisher cleanup per domain
+		httpCleanupErr map[string]error
+		withError      []error
+	}{
+		"http preferred": {
+			params: SolveRequest{PreferredTypes: []string{ChallengeTypeHTTP, ChallengeTypeDNS}},
+			init: func(c *cps.Mock) {
+				c.On("GetChangeLetsEncryptChallenges", mock.Anything, changeReq).Return(challenges, nil).Once()
+				c.On("AcknowledgeDVChallenges", mock.Anything, ackReq).Return(nil).Once()
+				c.On("GetChangeStatus", mock.Anything, statusReq).Return(validated, nil).Once()
+			},
+			published: []string{"a.example.com", "b.example.com"},
+			cleaned:   []string{"a.example.com", "b.example.com"},
+		},
+		"dns preferred": {
+			dnsPublisher: &fakePublisher{},
+			init: func(c *cps.Mock) {
+				c.On("GetChangeLetsEncryptChallenges", mock.Anything, changeReq).Return(challenges, nil).Once()
+				c.On("AcknowledgeDVChallenges", mock.Anything, ackReq).Return(nil).Once()
+				c.On("GetChangeStatus", mock.Anything, statusReq).Return(validated, nil).Once()
+			},
+			published:    []string{"a.example.com", "b.example.com"},
+			cleaned:      []string{"a.example.com", "b.example.com"},
+			dnsPublished: []string{"a.example.com"},
+		},
+		"validation fails": {
+			init: func(c *cps.Mock) {
+				c.On("GetChangeLetsEncryptChallenges", mock.Anything, changeReq).Return(challenges, nil).Once()
+				c.On("AcknowledgeDVChallenges", mock.Anything, ackReq).Return(nil).Once()
+				c.On("GetChangeStatus", mock.Anything, statusReq).Return(awaitingDV, nil).Once()
+				c.On("GetChangeLetsEncryptChallenges", mock.Anything, changeReq).Return(&cps.DVArray{DV: []cps.DV{
+					{Domain: "a.example.com", Status: "invalid", Error: "token not found"},
+					{Domain: "b.example.com", Status: "pending"},
+				}}, nil).Once()
+			},
+			published: []string{"a.example.com", "b.example.com"},
+			cleaned:   []string{"a.example.com", "b.example.com"},
+			withError: []error{ErrSolve, ErrValidationFailed},
+		},
+		"change error": {
+			init: func(c *cps.Mock) {
+				c.On("GetChangeLetsEncryptChallenges", mock.Anything, changeReq).Return(challenges, nil).Once()
+				c.On("AcknowledgeDVChallenges", mock.Anything, ackReq).Return(nil).Once()
+				c.On("GetChangeStatus", mock.Anything, statusReq).Return(&cps.Change{StatusInfo: &cps.StatusInfo{
+					Error: &cps.StatusInfoError{Code: "DV_FAILED", Description: "validation failed"},
+				}}, nil).Once()
+			},
+			published: []string{"a.example.com", "b.example.com"},
+			cleaned:   []string{"a.example.com", "b.example.com"},
+			withError: []error{ErrSolve, ErrValidationFailed},
+		},
+		"timeout": {
+			params: SolveRequest{PollInterval: time.Hour, Timeout: 10 * time.Millisecond},
+			init: func(c *cps.Mock) {
+				c.On("GetChangeLetsEncryptChallenges", mock.Anything, changeReq).Return(challenges, nil).Once()
+				c.On("AcknowledgeDVChallenges", mock.Anything, ackReq).Return(nil).Once()
+			},
+			published: []string{"a.example.com", "b.example.com"},
+			cleaned:   []string{"a.example.com", "b.example.com"},
+			withError: []error{ErrSolve, context.DeadlineExceeded},
+		},
+		"publish fails": {
+			dnsPublisher: &fakePublisher{publishErr: map[string]error{"a.example.com": errors.New("zone locked")}},
+			params:       SolveRequest{PreferredTypes: []string{ChallengeTypeHTTP, ChallengeTypeDNS}},
+			init: func(c *cps.Mock) {
+				c.On("GetChangeLetsEncryptChallenges", mock.Anything, changeReq).Return(&cps.DVArray{DV: []cps.DV{
+					dv("b.example.com", "pending", httpChallenge("b.example.com")),
+					dv("a.example.com", "pending", dnsChallenge("_acme-challenge.a.example.com", "a")),
+				}}, nil).Once()
+			},
+			published: []string{"b.example.com"},
+			cleaned:   []string{"b.example.com"},
+			withError: []error{ErrSolve},
+		},
+		"cleanup fails": {
+			dnsPublisher:   &fakePublisher{},
+			params:         SolveRequest{PreferredTypes: []string{ChallengeTypeHTTP}},
+			httpCleanupErr: map[string]error{"a.example.com": errors.New("permission denied")},
+			init: func(c *cps.Mock) {
+				c.On("GetChangeLetsEncryptChallenges", mock.Anything, changeReq).Return(challenges, nil).Once()
+				c.On("AcknowledgeDVChallenges", mock.Anything, ackReq).Return(nil).Once()
+				c.On("GetChangeStatus", mock.Anything, statusReq).Return(validated, nil).Once()
+			},
+			published: []string{"a.example.com", "b.example.com"},
+			cleaned:   []string{"a.example.com", "b.example.com"},
+			withError: []error{ErrSolve, ErrCleanup},
+		},
+		"no publisher": {
+			params: SolveRequest{PreferredTypes: []string{ChallengeTypeDNS}},
+			init: func(c *cps.Mock) {
+				c.On("GetChangeLetsEncryptChallenges", mock.Anything, changeReq).Return(challenges, nil).Once()
+			},
+			withError: []error{ErrSolve, ErrNoPublisher},
+		},
+		"all validated": {
+			init: func(c *cps.Mock) {
+				c.On("GetChangeLetsEncryptChallenges", mock.Anything, changeReq).
+					Return(&cps.DVArray{DV: []cps.DV{{Domain: "a.example.com", ValidationStatus: "VALIDATED"}}}, nil).Once()
+			},
+		},
+		"get challenges fails": {
+			init: func(c *cps.Mock) {
+				c.On("GetChangeLetsEncryptChallenges", mock.Anything, changeReq).Return(nil, &cps.Error{StatusCode: http.StatusNotFound}).Once()
+			},
+			withError: []error{ErrSolve},
+		},
+		"validation error": {
+			params:    SolveRequest{PollInterval: -time.Second},
+			withError: []error{cps.ErrStructValidation},
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			c := &cps.Mock{}
+			if test.init != nil {
+				test.init(c)
+			}
+			httpPublisher := &fakePublisher{cleanupErr: test.httpCleanupErr}
+			params := test.params
+			if params.PollInterval == 0 {
+				params.PollInterval = time.Millisecond
+			}
+			if params.PollInterval > 0 {
+				params.EnrollmentID, params.ChangeID = 1, 2
+				params.Publishers = map[string]Publisher{ChallengeTypeHTTP: httpPublisher}
+				if test.dnsPublisher != nil {
+					params.Publishers[ChallengeTypeDNS] = test.dnsPublisher
+				}
+			}
+
+			_, err := Solve(context.Background(), c, params)
+			c.AssertExpectations(t)
+			var published, cleaned []string
+			for _, p := range []*fakePublisher{httpPublisher, test.dnsPublisher} {
+				if p != nil {
+					published = append(published, p.published...)
+					cleaned = append(cleaned, p.cleaned...)
+				}
+			}
+			assert.ElementsMatch(t, test.published, published)
+			assert.ElementsMatch(t, test.cleaned, cleaned)
+			if test.dnsPublished != nil {
+				assert.Equal(t, test.dnsPublished, test.dnsPublisher.published)
+			}
+			if test.withError != nil {
+				for _, e := range test.withError {
+					assert.True(t, errors.Is(err, e), "want: %s; got: %s", e, err)
+				}
+				return
+			}
+			require.NoError(t, err)
+		})
+	}
+}
+
+func TestDNSPublisherZones(t *testing.T) {
+	d := &dns.Mock{}
+	publisher := &DNSPublisher{Client: d, Zones: []string{"example.com", "sub.example.com.", "example.net"}, TTL: 300}
+	challenge := Challenge{Domain: "www.sub.example.com", Challenge: dnsChallenge("_acme-challenge.www.sub.example.com", "token")}
+	recordReq := dns.GetRecordRequest{Zone: "sub.example.com", Name: "_acme-challenge.www.sub.example.com", RecordType: "TXT"}
+
+	d.On("GetRecord", mock.Anything, recordReq).Return(nil, notFound).Once()
+	d.On("CreateRecord", mock.Anything, dns.CreateRecordRequest{Zone: "sub.example.com", Record: &dns.RecordBody{
+		Name: "_acme-challenge.www.sub.example.com", RecordType: "TXT", TTL: 300, Target: []string{`"token"`},
+	}}).Return(nil).Once()
+	require.NoError(t, publisher.Publish(context.Background(), challenge))
+
+	d.On("GetRecord", mock.Anything, recordReq).Return(&dns.GetRecordResponse{Target: []string{"token"}}, nil).Once()
+	require.NoError(t, publisher.Publish(context.Background(), challenge))
+
+	d.On("GetRecord", mock.Anything, recordReq).Return(nil, notFound).Once()
+	require.NoError(t, publisher.Cleanup(context.Background(), challenge))
+	d.AssertExpectations(t)
+
+	err := publisher.Publish(context.Background(), Challenge{Domain: "example.org", Challenge: dnsChallenge("_acme-challenge.example.org", "x")})
+	assert.True(t, errors.Is(err, ErrZoneNotFound), "got: %s", err)
+}
+
+func TestNewChangeStepHandler(t *testing.T) {
+	c := &cps.Mock{}
+	c.On("GetChangeStatus", mock.Anything, statusReq).Return(awaitingDV, nil).Once()
+	c.On("GetChangeLetsEncryptChallenges", mock.Anything, changeReq).Return(&cps.DVArray{DV: []cps.DV{
+		dv("a.example.com", "pending", httpChallenge("a.example.com")),
+	}}, nil).Twice()
+	c.On("AcknowledgeDVChallenges", mock.Anything, ackReq).Return(nil).Once()
+	c.On("GetChangeStatus", mock.Anything, statusReq).Return(validated, nil).Once()
+	c.On("GetChangeStatus", mock.Anything, statusReq).
+		Return(&cps.Change{StatusInfo: &cps.StatusInfo{State: "complete", Status: cps.ChangeStatusComplete}}, nil).Once()
+
+	publisher := &fakePublisher{}
+	result, err := cps.DriveChange(context.Background(), c, cps.DriveChangeRequest{
+		EnrollmentID: 1,
+		ChangeID:     2,
+		PollInterval: time.Millisecond,
+		Handlers: map[cps.ChangeStepType]cps.ChangeStepHandler{
+			cps.ChangeStepDVChallenges: NewChangeStepHandler(SolveRequest{
+				Publishers:   map[string]Publisher{ChallengeTypeHTTP: publisher},
+				PollInterval: time.Millisecond,
+			}),
+		},
+	})
+	require.NoError(t, err)
+	c.AssertExpectations(t)
+	assert.Equal(t, []string{"a.example.com"}, publisher.published)
+	assert.Equal(t, []string{"a.example.com"}, publisher.cleaned)
+	assert.Equal(t, []cps.HandledChangeStep{{Type: cps.ChangeStepDVChallenges, Status: "coodinate-domain-validation"}}, result.Steps)
+}
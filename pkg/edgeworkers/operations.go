@@ -0,0 +1,38 @@
+package edgeworkers
+
+import (
+	"context"
+	"fmt"
+	"slices"
+
+	"github.com/akamai/AkamaiOPEN-edgegrid-golang/v11/pkg/operation"
+)
+
+const (
+	edgeKVStatusInitialized = "INITIALIZED"
+	edgeKVStatusFailed      = "FAILED"
+)
+
+// EdgeKVInitializationOperation returns an Operation tracking the EdgeKV initialization started with InitializeEdgeKV.
+// It succeeds once the account and both networks are initialized
+func EdgeKVInitializationOperation(client Edgeworkers) *operation.Operation[*EdgeKVInitializationStatus] {
+	return operation.New("edgekv-initialization", func(ctx context.Context) (operation.Status[*EdgeKVInitializationStatus], error) {
+		resp, err := client.GetEdgeKVInitializationStatus(ctx)
+		if err != nil {
+			return operation.Status[*EdgeKVInitializationStatus]{}, err
+		}
+		status := operation.Status[*EdgeKVInitializationStatus]{
+			State:   operation.StatePending,
+			Message: fmt.Sprintf("account: %s, staging: %s, production: %s", resp.AccountStatus, resp.StagingStatus, resp.ProductionStatus),
+			Value:   resp,
+		}
+		statuses := []string{resp.AccountStatus, resp.StagingStatus, resp.ProductionStatus}
+		switch {
+		case slices.Contains(statuses, edgeKVStatusFailed):
+			status.State = operation.StateFailed
+		case !slices.ContainsFunc(statuses, func(s string) bool { return s != edgeKVStatusInitialized }):
+			status.State = operation.StateSucceeded
+		}
+		return status, nil
+	})
+}
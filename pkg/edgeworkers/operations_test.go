@@ -0,0 +1,47 @@
+package edgeworkers
+
+import (
+	"context"
+	"testing"
+
+	"github.com/akamai/AkamaiOPEN-edgegrid-golang/v11/pkg/operation"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEdgeKVInitializationOperation(t *testing.T) {
+	tests := map[string]struct {
+		status   EdgeKVInitializationStatus
+		expected operation.State
+		message  string
+	}{
+		"pending": {
+			status:   EdgeKVInitializationStatus{AccountStatus: "INITIALIZED", StagingStatus: "INITIALIZED", ProductionStatus: "PENDING"},
+			expected: operation.StatePending,
+			message:  "account: INITIALIZED, staging: INITIALIZED, production: PENDING",
+		},
+		"initialized": {
+			status:   EdgeKVInitializationStatus{AccountStatus: "INITIALIZED", StagingStatus: "INITIALIZED", ProductionStatus: "INITIALIZED"},
+			expected: operation.StateSucceeded,
+			message:  "account: INITIALIZED, staging: INITIALIZED, production: INITIALIZED",
+		},
+		"failed": {
+			status:   EdgeKVInitializationStatus{AccountStatus: "INITIALIZED", StagingStatus: "FAILED", ProductionStatus: "PENDING"},
+			expected: operation.StateFailed,
+			message:  "account: INITIALIZED, staging: FAILED, production: PENDING",
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			m := &Mock{}
+			m.On("GetEdgeKVInitializationStatus", mock.Anything).Return(&test.status, nil).Once()
+			status, err := EdgeKVInitializationOperation(m).Poll(context.Background())
+			m.AssertExpectations(t)
+			require.NoError(t, err)
+			assert.Equal(t, test.expected, status.State)
+			assert.Equal(t, test.message, status.Message)
+		})
+	}
+}
@@ -0,0 +1,389 @@
+package gtm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+
+	"github.com/akamai/AkamaiOPEN-edgegrid-golang/v11/pkg/edgegriderr"
+	validation "github.com/go-ozzo/ozzo-validation/v4"
+)
+
+type (
+	// ExportDomainRequest contains request parameters for ExportDomain
+	ExportDomainRequest struct {
+		DomainName string
+	}
+
+	// ImportDomainRequest contains request parameters for ImportDomain
+	ImportDomainRequest struct {
+		// Domain is the document to import, usually returned by ExportDomain
+		Domain *Domain
+		// DomainName overrides the name of the domain in the document, for example to copy a domain
+		DomainName string
+		// QueryArgs are used to create the domain
+		QueryArgs *DomainQueryArgs
+		// ExistingDomain skips creating the domain and only adds its datacenters, maps, resources and properties
+		ExistingDomain bool
+	}
+
+	// ImportDomainResult is the result of ImportDomain
+	ImportDomainResult struct {
+		DomainName string `json:"domainName"`
+		// DatacenterIDs maps datacenter IDs in the document to the IDs of the created datacenters
+		DatacenterIDs map[int]int `json:"datacenterIds"`
+		// Created lists the created objects in the order they were created, for example "datacenter:3131"
+		// or "property:www"
+		Created []string `json:"created,omitempty"`
+	}
+)
+
+var (
+	// ErrExportDomain is returned when ExportDomain fails
+	ErrExportDomain = errors.New("export domain")
+	// ErrImportDomain is returned when ImportDomain fails
+	ErrImportDomain = errors.New("import domain")
+)
+
+// Validate validates ExportDomainRequest
+func (r ExportDomainRequest) Validate() error {
+	return edgegriderr.ParseValidationErrors(validation.Errors{
+		"DomainName": validation.Validate(r.DomainName, validation.Required),
+	})
+}
+
+// Validate validates ImportDomainRequest. Besides required fields, it checks that every traffic target, resource
+// instance and map assignment refers to a datacenter of the document, every property map refers to a map of
+// the matching type, and that datacenter IDs and object names are unique. Default datacenters may be referenced
+// without being part of the document.
+func (r ImportDomainRequest) Validate() error {
+	errs := validation.Errors{
+		"Domain": validation.Validate(r.Domain, validation.Required),
+	}
+	if r.Domain != nil {
+		maps.Copy(errs, validateDomainReferences(r.Domain))
+	}
+	return edgegriderr.ParseValidationErrors(errs)
+}
+
+// ExportDomain reads a domain with all of its datacenters, maps, resources and properties into a single document.
+// Read-only fields, such as links, status and modification details, are removed so the document can be passed
+// to ImportDomain.
+func ExportDomain(ctx context.Context, client GTM, params ExportDomainRequest) (*Domain, error) {
+	if err := params.Validate(); err != nil {
+		return nil, fmt.Errorf("%s: %w: %s", ErrExportDomain, ErrStructValidation, err)
+	}
+
+	resp, err := client.GetDomain(ctx, GetDomainRequest{DomainName: params.DomainName})
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrExportDomain, err)
+	}
+
+	domain := Domain(*resp)
+	domain.Links = nil
+	domain.Status = nil
+	domain.LastModified = ""
+	domain.LastModifiedBy = ""
+	domain.ModificationComments = ""
+	for i := range domain.Datacenters {
+		domain.Datacenters[i].Links = nil
+	}
+	for i := range domain.GeographicMaps {
+		domain.GeographicMaps[i].Links = nil
+	}
+	for i := range domain.CIDRMaps {
+		domain.CIDRMaps[i].Links = nil
+	}
+	for i := range domain.ASMaps {
+		domain.ASMaps[i].Links = nil
+	}
+	for i := range domain.Resources {
+		domain.Resources[i].Links = nil
+	}
+	for i := range domain.Properties {
+		domain.Properties[i].Links = nil
+		domain.Properties[i].LastModified = ""
+		for j := range domain.Properties[i].LivenessTests {
+			domain.Properties[i].LivenessTests[j].Links = nil
+		}
+	}
+
+	return &domain, nil
+}
+
+// ImportDomain creates a domain from a document returned by ExportDomain. References are validated before any call
+// is made. The domain is created first, followed by its datacenters. Datacenters get new IDs, except for the
+// default datacenters (MapDefaultDC, Ipv4DefaultDC and Ipv6DefaultDC), which are also created when only referenced.
+// The IDs in traffic targets, resource instances and map assignments are remapped before maps, resources and
+// properties are created.
+//
+// On failure, the result lists the objects created so far.
+func ImportDomain(ctx context.Context, client GTM, params ImportDomainRequest) (*ImportDomainResult, error) {
+	if err := params.Validate(); err != nil {
+		return nil, fmt.Errorf("%s: %w: %s", ErrImportDomain, ErrStructValidation, err)
+	}
+
+	doc := params.Domain
+	result := &ImportDomainResult{DomainName: doc.Name, DatacenterIDs: make(map[int]int, len(doc.Datacenters))}
+	if params.DomainName != "" {
+		result.DomainName = params.DomainName
+	}
+	domainName := result.DomainName
+
+	if !params.ExistingDomain {
+		domain := *doc
+		domain.Name = domainName
+		domain.Datacenters, domain.GeographicMaps, domain.CIDRMaps, domain.ASMaps = nil, nil, nil, nil
+		domain.Resources, domain.Properties = nil, nil
+		if _, err := client.CreateDomain(ctx, CreateDomainRequest{Domain: &domain, QueryArgs: params.QueryArgs}); err != nil {
+			return result, fmt.Errorf("%w: %w", ErrImportDomain, err)
+		}
+		result.Created = append(result.Created, "domain:"+domainName)
+	}
+
+	for _, dc := range doc.Datacenters {
+		id, err := importDatacenter(ctx, client, domainName, dc)
+		if err != nil {
+			return result, fmt.Errorf("%w: datacenter %d: %w", ErrImportDomain, dc.DatacenterID, err)
+		}
+		result.DatacenterIDs[dc.DatacenterID] = id
+		result.Created = append(result.Created, fmt.Sprintf("datacenter:%d", id))
+	}
+	for _, id := range []int{MapDefaultDC, Ipv4DefaultDC, Ipv6DefaultDC} {
+		if _, ok := result.DatacenterIDs[id]; ok || !referencesDatacenter(doc, id) {
+			continue
+		}
+		if _, err := importDatacenter(ctx, client, domainName, Datacenter{DatacenterID: id}); err != nil {
+			return result, fmt.Errorf("%w: datacenter %d: %w", ErrImportDomain, id, err)
+		}
+		result.DatacenterIDs[id] = id
+		result.Created = append(result.Created, fmt.Sprintf("datacenter:%d", id))
+	}
+	remap := func(base DatacenterBase) DatacenterBase {
+		base.DatacenterID = result.DatacenterIDs[base.DatacenterID]
+		return base
+	}
+
+	for _, m := range doc.GeographicMaps {
+		geoMap := m
+		geoMap.DefaultDatacenter = remapDefaultDatacenter(m.DefaultDatacenter, remap)
+		geoMap.Assignments = slices.Clone(m.Assignments)
+		for i := range geoMap.Assignments {
+			geoMap.Assignments[i].DatacenterBase = remap(geoMap.Assignments[i].DatacenterBase)
+		}
+		if _, err := client.CreateGeoMap(ctx, CreateGeoMapRequest{GeoMap: &geoMap, DomainName: domainName}); err != nil {
+			return result, fmt.Errorf("%w: geographic map %q: %w", ErrImportDomain, m.Name, err)
+		}
+		result.Created = append(result.Created, "geographicMap:"+m.Name)
+	}
+	for _, m := range doc.CIDRMaps {
+		cidrMap := m
+		cidrMap.DefaultDatacenter = remapDefaultDatacenter(m.DefaultDatacenter, remap)
+		cidrMap.Assignments = slices.Clone(m.Assignments)
+		for i := range cidrMap.Assignments {
+			cidrMap.Assignments[i].DatacenterBase = remap(cidrMap.Assignments[i].DatacenterBase)
+		}
+		if _, err := client.CreateCIDRMap(ctx, CreateCIDRMapRequest{CIDR: &cidrMap, DomainName: domainName}); err != nil {
+			return result, fmt.Errorf("%w: cidr map %q: %w", ErrImportDomain, m.Name, err)
+		}
+		result.Created = append(result.Created, "cidrMap:"+m.Name)
+	}
+	for _, m := range doc.ASMaps {
+		asMap := m
+		asMap.DefaultDatacenter = remapDefaultDatacenter(m.DefaultDatacenter, remap)
+		asMap.Assignments = slices.Clone(m.Assignments)
+		for i := range asMap.Assignments {
+			asMap.Assignments[i].DatacenterBase = remap(asMap.Assignments[i].DatacenterBase)
+		}
+		if _, err := client.CreateASMap(ctx, CreateASMapRequest{ASMap: &asMap, DomainName: domainName}); err != nil {
+			return result, fmt.Errorf("%w: as map %q: %w", ErrImportDomain, m.Name, err)
+		}
+		result.Created = append(result.Created, "asMap:"+m.Name)
+	}
+
+	for _, r := range doc.Resources {
+		resource := r
+		resource.ResourceInstances = slices.Clone(r.ResourceInstances)
+		for i := range resource.ResourceInstances {
+			resource.ResourceInstances[i].DatacenterID = result.DatacenterIDs[resource.ResourceInstances[i].DatacenterID]
+		}
+		if _, err := client.CreateResource(ctx, CreateResourceRequest{Resource: &resource, DomainName: domainName}); err != nil {
+			return result, fmt.Errorf("%w: resource %q: %w", ErrImportDomain, r.Name, err)
+		}
+		result.Created = append(result.Created, "resource:"+r.Name)
+	}
+
+	for _, p := range doc.Properties {
+		property := p
+		property.TrafficTargets = slices.Clone(p.TrafficTargets)
+		for i := range property.TrafficTargets {
+			property.TrafficTargets[i].DatacenterID = result.DatacenterIDs[property.TrafficTargets[i].DatacenterID]
+		}
+		if _, err := client.CreateProperty(ctx, CreatePropertyRequest{Property: &property, DomainName: domainName}); err != nil {
+			return result, fmt.Errorf("%w: property %q: %w", ErrImportDomain, p.Name, err)
+		}
+		result.Created = append(result.Created, "property:"+p.Name)
+	}
+
+	return result, nil
+}
+
+// importDatacenter creates a datacenter and returns its ID. Default datacenters are created with their dedicated
+// calls and keep their IDs.
+func importDatacenter(ctx context.Context, client GTM, domainName string, dc Datacenter) (int, error) {
+	var created *Datacenter
+	var err error
+	switch dc.DatacenterID {
+	case MapDefaultDC:
+		created, err = client.CreateMapsDefaultDatacenter(ctx, domainName)
+	case Ipv4DefaultDC:
+		created, err = client.CreateIPv4DefaultDatacenter(ctx, domainName)
+	case Ipv6DefaultDC:
+		created, err = client.CreateIPv6DefaultDatacenter(ctx, domainName)
+	default:
+		dc.DatacenterID = 0
+		dc.Links = nil
+		var resp *CreateDatacenterResponse
+		resp, err = client.CreateDatacenter(ctx, CreateDatacenterRequest{Datacenter: &dc, DomainName: domainName})
+		if resp != nil {
+			created = resp.Resource
+		}
+	}
+	if err != nil {
+		return 0, err
+	}
+	if created == nil {
+		return 0, errors.New("no datacenter in the response")
+	}
+	return created.DatacenterID, nil
+}
+
+func remapDefaultDatacenter(base *DatacenterBase, remap func(DatacenterBase) DatacenterBase) *DatacenterBase {
+	if base == nil {
+		return nil
+	}
+	remapped := remap(*base)
+	return &remapped
+}
+
+func isDefaultDatacenter(id int) bool {
+	return id == MapDefaultDC || id == Ipv4DefaultDC || id == Ipv6DefaultDC
+}
+
+// referencesDatacenter reports whether any map, resource or property of the domain refers to the datacenter
+func referencesDatacenter(d *Domain, id int) bool {
+	for _, m := range d.GeographicMaps {
+		if m.DefaultDatacenter != nil && m.DefaultDatacenter.DatacenterID == id ||
+			slices.ContainsFunc(m.Assignments, func(a GeoAssignment) bool { return a.DatacenterID == id }) {
+			return true
+		}
+	}
+	for _, m := range d.CIDRMaps {
+		if m.DefaultDatacenter != nil && m.DefaultDatacenter.DatacenterID == id ||
+			slices.ContainsFunc(m.Assignments, func(a CIDRAssignment) bool { return a.DatacenterID == id }) {
+			return true
+		}
+	}
+	for _, m := range d.ASMaps {
+		if m.DefaultDatacenter != nil && m.DefaultDatacenter.DatacenterID == id ||
+			slices.ContainsFunc(m.Assignments, func(a ASAssignment) bool { return a.DatacenterID == id }) {
+			return true
+		}
+	}
+	for _, r := range d.Resources {
+		if slices.ContainsFunc(r.ResourceInstances, func(i ResourceInstance) bool { return i.DatacenterID == id }) {
+			return true
+		}
+	}
+	for _, p := range d.Properties {
+		if slices.ContainsFunc(p.TrafficTargets, func(t TrafficTarget) bool { return t.DatacenterID == id }) {
+			return true
+		}
+	}
+	return false
+}
+
+// validateDomainReferences checks the references between objects of a domain document
+func validateDomainReferences(d *Domain) validation.Errors {
+	errs := validation.Errors{}
+	datacenters := make(map[int]bool, len(d.Datacenters))
+	for i, dc := range d.Datacenters {
+		key := fmt.Sprintf("Datacenters[%d].DatacenterID", i)
+		switch {
+		case dc.DatacenterID == 0:
+			errs[key] = errors.New("cannot be blank")
+		case datacenters[dc.DatacenterID]:
+			errs[key] = fmt.Errorf("datacenter %d is a duplicate", dc.DatacenterID)
+		}
+		datacenters[dc.DatacenterID] = true
+	}
+	checkDatacenter := func(key string, id int) {
+		if !datacenters[id] && !isDefaultDatacenter(id) {
+			errs[key] = fmt.Errorf("refers to datacenter %d, which is not in the domain", id)
+		}
+	}
+	mapNames := map[string]map[string]bool{"geographic": {}, "cidrmapping": {}, "asmapping": {}}
+	checkName := func(key, kind, name string) {
+		if mapNames[kind][name] {
+			errs[key] = fmt.Errorf("%q is a duplicate", name)
+		}
+		mapNames[kind][name] = true
+	}
+
+	for i, m := range d.GeographicMaps {
+		checkName(fmt.Sprintf("GeographicMaps[%d].Name", i), "geographic", m.Name)
+		if m.DefaultDatacenter != nil {
+			checkDatacenter(fmt.Sprintf("GeographicMaps[%d].DefaultDatacenter", i), m.DefaultDatacenter.DatacenterID)
+		}
+		for j, a := range m.Assignments {
+			checkDatacenter(fmt.Sprintf("GeographicMaps[%d].Assignments[%d]", i, j), a.DatacenterID)
+		}
+	}
+	for i, m := range d.CIDRMaps {
+		checkName(fmt.Sprintf("CIDRMaps[%d].Name", i), "cidrmapping", m.Name)
+		if m.DefaultDatacenter != nil {
+			checkDatacenter(fmt.Sprintf("CIDRMaps[%d].DefaultDatacenter", i), m.DefaultDatacenter.DatacenterID)
+		}
+		for j, a := range m.Assignments {
+			checkDatacenter(fmt.Sprintf("CIDRMaps[%d].Assignments[%d]", i, j), a.DatacenterID)
+		}
+	}
+	for i, m := range d.ASMaps {
+		checkName(fmt.Sprintf("ASMaps[%d].Name", i), "asmapping", m.Name)
+		if m.DefaultDatacenter != nil {
+			checkDatacenter(fmt.Sprintf("ASMaps[%d].DefaultDatacenter", i), m.DefaultDatacenter.DatacenterID)
+		}
+		for j, a := range m.Assignments {
+			checkDatacenter(fmt.Sprintf("ASMaps[%d].Assignments[%d]", i, j), a.DatacenterID)
+		}
+	}
+
+	resources := make(map[string]bool, len(d.Resources))
+	for i, r := range d.Resources {
+		if resources[r.Name] {
+			errs[fmt.Sprintf("Resources[%d].Name", i)] = fmt.Errorf("%q is a duplicate", r.Name)
+		}
+		resources[r.Name] = true
+		for j, instance := range r.ResourceInstances {
+			checkDatacenter(fmt.Sprintf("Resources[%d].ResourceInstances[%d]", i, j), instance.DatacenterID)
+		}
+	}
+
+	properties := make(map[string]bool, len(d.Properties))
+	for i, p := range d.Properties {
+		if properties[p.Name] {
+			errs[fmt.Sprintf("Properties[%d].Name", i)] = fmt.Errorf("%q is a duplicate", p.Name)
+		}
+		properties[p.Name] = true
+		for j, t := range p.TrafficTargets {
+			checkDatacenter(fmt.Sprintf("Properties[%d].TrafficTargets[%d]", i, j), t.DatacenterID)
+		}
+		if names, ok := mapNames[p.Type]; ok && !names[p.MapName] {
+			errs[fmt.Sprintf("Properties[%d].MapName", i)] = fmt.Errorf("refers to %s map %q, which is not in the domain", p.Type, p.MapName)
+		}
+	}
+
+	return errs
+}
@@ -0,0 +1,222 @@
+package gtm
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExportDomain(t *testing.T) {
+	m := &Mock{}
+	m.On("GetDomain", mock.Anything, GetDomainRequest{DomainName: "example.akadns.net"}).Return(&GetDomainResponse{
+		Name:           "example.akadns.net",
+		Type:           "weighted",
+		LastModified:   "2024-01-01T00:00:00.000+00:00",
+		LastModifiedBy: "admin",
+		Status:         &ResponseStatus{PropagationStatus: "COMPLETE"},
+		Links:          []Link{{Rel: "self", Href: "/config-gtm/v1/domains/example.akadns.net"}},
+		Datacenters:    []Datacenter{{DatacenterID: 3131, Nickname: "dc1", Links: []Link{{Rel: "self"}}}},
+		GeographicMaps: []GeoMap{{Name: "geo", Links: []Link{{Rel: "self"}}}},
+		Resources:      []Resource{{Name: "cpu", Links: []Link{{Rel: "self"}}}},
+		Properties: []Property{{
+			Name:          "www",
+			LastModified:  "2024-01-01T00:00:00.000+00:00",
+			Links:         []Link{{Rel: "self"}},
+			LivenessTests: []LivenessTest{{Name: "http", Links: []Link{{Rel: "self"}}}},
+		}},
+	}, nil).Once()
+
+	domain, err := ExportDomain(context.Background(), m, ExportDomainRequest{DomainName: "example.akadns.net"})
+	require.NoError(t, err)
+	m.AssertExpectations(t)
+	assert.Equal(t, &Domain{
+		Name:           "example.akadns.net",
+		Type:           "weighted",
+		Datacenters:    []Datacenter{{DatacenterID: 3131, Nickname: "dc1"}},
+		GeographicMaps: []GeoMap{{Name: "geo"}},
+		Resources:      []Resource{{Name: "cpu"}},
+		Properties:     []Property{{Name: "www", LivenessTests: []LivenessTest{{Name: "http"}}}},
+	}, domain)
+
+	_, err = ExportDomain(context.Background(), m, ExportDomainRequest{})
+	assert.True(t, errors.Is(err, ErrStructValidation), "want: %s; got: %s", ErrStructValidation, err)
+}
+
+func TestImportDomain(t *testing.T) {
+	document := func() *Domain {
+		return &Domain{
+			Name: "example.akadns.net",
+			Type: "full",
+			Datacenters: []Datacenter{
+				{DatacenterID: 3131, Nickname: "dc1"},
+				{DatacenterID: 3132, Nickname: "dc2"},
+			},
+			GeographicMaps: []GeoMap{{
+				Name:              "geo",
+				DefaultDatacenter: &DatacenterBase{DatacenterID: MapDefaultDC, Nickname: "Default Datacenter"},
+				Assignments:       []GeoAssignment{{DatacenterBase: DatacenterBase{DatacenterID: 3132, Nickname: "dc2"}, Countries: []string{"PL"}}},
+			}},
+			CIDRMaps: []CIDRMap{{
+				Name:              "cidr",
+				DefaultDatacenter: &DatacenterBase{DatacenterID: 3131},
+				Assignments:       []CIDRAssignment{{DatacenterBase: DatacenterBase{DatacenterID: 3132}, Blocks: []string{"192.0.2.0/24"}}},
+			}},
+			ASMaps: []ASMap{{
+				Name:              "as",
+				DefaultDatacenter: &DatacenterBase{DatacenterID: 3131},
+				Assignments:       []ASAssignment{{DatacenterBase: DatacenterBase{DatacenterID: 3132}, ASNumbers: []int64{64496}}},
+			}},
+			Resources: []Resource{{
+				Name:              "cpu",
+				Type:              "XML load object via HTTP",
+				ResourceInstances: []ResourceInstance{{DatacenterID: 3131}},
+			}},
+			Properties: []Property{
+				{Name: "www", Type: "weighted-round-robin", TrafficTargets: []TrafficTarget{{DatacenterID: 3131, Weight: 1}, {DatacenterID: 3132, Weight: 2}}},
+				{Name: "geo", Type: "geographic", MapName: "geo", TrafficTargets: []TrafficTarget{{DatacenterID: 3132}}},
+			},
+		}
+	}
+	expectDatacenters := func(m *Mock, domainName string) {
+		m.On("CreateDatacenter", mock.Anything, CreateDatacenterRequest{Datacenter: &Datacenter{Nickname: "dc1"}, DomainName: domainName}).
+			Return(&CreateDatacenterResponse{Resource: &Datacenter{DatacenterID: 10, Nickname: "dc1"}}, nil).Once()
+		m.On("CreateDatacenter", mock.Anything, CreateDatacenterRequest{Datacenter: &Datacenter{Nickname: "dc2"}, DomainName: domainName}).
+			Return(&CreateDatacenterResponse{Resource: &Datacenter{DatacenterID: 11, Nickname: "dc2"}}, nil).Once()
+		m.On("CreateMapsDefaultDatacenter", mock.Anything, domainName).
+			Return(&Datacenter{DatacenterID: MapDefaultDC}, nil).Once()
+	}
+
+	tests := map[string]struct {
+		params    ImportDomainRequest
+		init      func(*Mock)
+		expected  *ImportDomainResult
+		withError error
+	}{
+		"ok": {
+			params: ImportDomainRequest{Domain: document(), QueryArgs: &DomainQueryArgs{ContractID: "1-2AB34C"}},
+			init: func(m *Mock) {
+				m.On("CreateDomain", mock.Anything, CreateDomainRequest{
+					Domain:    &Domain{Name: "example.akadns.net", Type: "full"},
+					QueryArgs: &DomainQueryArgs{ContractID: "1-2AB34C"},
+				}).Return(&CreateDomainResponse{}, nil).Once()
+				expectDatacenters(m, "example.akadns.net")
+				m.On("CreateGeoMap", mock.Anything, CreateGeoMapRequest{DomainName: "example.akadns.net", GeoMap: &GeoMap{
+					Name:              "geo",
+					DefaultDatacenter: &DatacenterBase{DatacenterID: MapDefaultDC, Nickname: "Default Datacenter"},
+					Assignments:       []GeoAssignment{{DatacenterBase: DatacenterBase{DatacenterID: 11, Nickname: "dc2"}, Countries: []string{"PL"}}},
+				}}).Return(&CreateGeoMapResponse{}, nil).Once()
+				m.On("CreateCIDRMap", mock.Anything, CreateCIDRMapRequest{DomainName: "example.akadns.net", CIDR: &CIDRMap{
+					Name:              "cidr",
+					DefaultDatacenter: &DatacenterBase{DatacenterID: 10},
+					Assignments:       []CIDRAssignment{{DatacenterBase: DatacenterBase{DatacenterID: 11}, Blocks: []string{"192.0.2.0/24"}}},
+				}}).Return(&CreateCIDRMapResponse{}, nil).Once()
+				m.On("CreateASMap", mock.Anything, CreateASMapRequest{DomainName: "example.akadns.net", ASMap: &ASMap{
+					Name:              "as",
+					DefaultDatacenter: &DatacenterBase{DatacenterID: 10},
+					Assignments:       []ASAssignment{{DatacenterBase: DatacenterBase{DatacenterID: 11}, ASNumbers: []int64{64496}}},
+				}}).Return(&CreateASMapResponse{}, nil).Once()
+				m.On("CreateResource", mock.Anything, CreateResourceRequest{DomainName: "example.akadns.net", Resource: &Resource{
+					Name:              "cpu",
+					Type:              "XML load object via HTTP",
+					ResourceInstances: []ResourceInstance{{DatacenterID: 10}},
+				}}).Return(&CreateResourceResponse{}, nil).Once()
+				m.On("CreateProperty", mock.Anything, CreatePropertyRequest{DomainName: "example.akadns.net", Property: &Property{
+					Name: "www", Type: "weighted-round-robin", TrafficTargets: []TrafficTarget{{DatacenterID: 10, Weight: 1}, {DatacenterID: 11, Weight: 2}},
+				}}).Return(&CreatePropertyResponse{}, nil).Once()
+				m.On("CreateProperty", mock.Anything, CreatePropertyRequest{DomainName: "example.akadns.net", Property: &Property{
+					Name: "geo", Type: "geographic", MapName: "geo", TrafficTargets: []TrafficTarget{{DatacenterID: 11}},
+				}}).Return(&CreatePropertyResponse{}, nil).Once()
+			},
+			expected: &ImportDomainResult{
+				DomainName:    "example.akadns.net",
+				DatacenterIDs: map[int]int{3131: 10, 3132: 11, MapDefaultDC: MapDefaultDC},
+				Created: []string{
+					"domain:example.akadns.net", "datacenter:10", "datacenter:11", "datacenter:5400",
+					"geographicMap:geo", "cidrMap:cidr", "asMap:as", "resource:cpu", "property:www", "property:geo",
+				},
+			},
+		},
+		"existing domain with another name, failed property": {
+			params: ImportDomainRequest{Domain: document(), DomainName: "copy.akadns.net", ExistingDomain: true},
+			init: func(m *Mock) {
+				expectDatacenters(m, "copy.akadns.net")
+				m.On("CreateGeoMap", mock.Anything, mock.Anything).Return(&CreateGeoMapResponse{}, nil).Once()
+				m.On("CreateCIDRMap", mock.Anything, mock.Anything).Return(&CreateCIDRMapResponse{}, nil).Once()
+				m.On("CreateASMap", mock.Anything, mock.Anything).Return(&CreateASMapResponse{}, nil).Once()
+				m.On("CreateResource", mock.Anything, mock.Anything).Return(&CreateResourceResponse{}, nil).Once()
+				m.On("CreateProperty", mock.Anything, mock.Anything).Return(nil, &Error{StatusCode: http.StatusBadRequest}).Once()
+			},
+			expected: &ImportDomainResult{
+				DomainName:    "copy.akadns.net",
+				DatacenterIDs: map[int]int{3131: 10, 3132: 11, MapDefaultDC: MapDefaultDC},
+				Created: []string{
+					"datacenter:10", "datacenter:11", "datacenter:5400",
+					"geographicMap:geo", "cidrMap:cidr", "asMap:as", "resource:cpu",
+				},
+			},
+			withError: ErrImportDomain,
+		},
+		"dangling references": {
+			params: ImportDomainRequest{Domain: func() *Domain {
+				d := document()
+				d.Datacenters = d.Datacenters[:1]
+				d.Properties[1].MapName = "missing"
+				d.Resources = append(d.Resources, d.Resources[0])
+				return d
+			}()},
+			withError: ErrStructValidation,
+		},
+		"missing domain": {
+			params:    ImportDomainRequest{},
+			withError: ErrStructValidation,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			m := &Mock{}
+			if test.init != nil {
+				test.init(m)
+			}
+
+			result, err := ImportDomain(context.Background(), m, test.params)
+			m.AssertExpectations(t)
+			if test.withError != nil {
+				assert.True(t, errors.Is(err, test.withError), "want: %s; got: %s", test.withError, err)
+				assert.Equal(t, test.expected, result)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, test.expected, result)
+		})
+	}
+}
+
+func TestImportDomainRequestValidate(t *testing.T) {
+	d := &Domain{
+		Name:        "example.akadns.net",
+		Type:        "full",
+		Datacenters: []Datacenter{{DatacenterID: 3131}, {DatacenterID: 3131}},
+		CIDRMaps:    []CIDRMap{{Name: "cidr", Assignments: []CIDRAssignment{{DatacenterBase: DatacenterBase{DatacenterID: 1}}}}},
+		Properties: []Property{
+			{Name: "www", Type: "asmapping", MapName: "cidr", TrafficTargets: []TrafficTarget{{DatacenterID: Ipv4DefaultDC}, {DatacenterID: 2}}},
+		},
+	}
+
+	err := ImportDomainRequest{Domain: d}.Validate()
+	require.Error(t, err)
+	for _, msg := range []string{
+		"Datacenters[1].DatacenterID: datacenter 3131 is a duplicate",
+		"CIDRMaps[0].Assignments[0]: refers to datacenter 1, which is not in the domain",
+		"Properties[0].TrafficTargets[1]: refers to datacenter 2, which is not in the domain",
+		`Properties[0].MapName: refers to asmapping map "cidr", which is not in the domain`,
+	} {
+		assert.Contains(t, err.Error(), msg)
+	}
+	assert.NotContains(t, err.Error(), "TrafficTargets[0]")
+}
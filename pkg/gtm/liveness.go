@@ -0,0 +1,506 @@
+package gtm
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/textproto"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/akamai/AkamaiOPEN-edgegrid-golang/v11/pkg/edgegriderr"
+	validation "github.com/go-ozzo/ozzo-validation/v4"
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+type (
+	// RunLivenessTestRequest contains parameters for RunLivenessTest
+	RunLivenessTestRequest struct {
+		Test *LivenessTest
+		// Server is the server to test, as listed in the servers of a traffic target
+		Server string
+		// Hostname is sent in the Host header and as the TLS server name. It defaults to Server.
+		Hostname string
+		// DefaultErrorPenalty and DefaultTimeoutPenalty are the penalties of the domain, used when the test does not
+		// set its own. They default to DefaultLivenessErrorPenalty and DefaultLivenessTimeoutPenalty.
+		DefaultErrorPenalty   float64
+		DefaultTimeoutPenalty float64
+	}
+
+	// LivenessTestResult is the result of RunLivenessTest
+	LivenessTestResult struct {
+		Passed bool
+		// TimedOut is set when the server did not answer within the test timeout
+		TimedOut bool
+		// Score is the duration of the test in seconds when it passed, and the error or timeout penalty otherwise
+		Score    float64
+		Duration time.Duration
+		// StatusCode is the HTTP status code of HTTP and HTTPS tests
+		StatusCode int
+		// Reasons explain why the test failed
+		Reasons []string
+	}
+)
+
+const (
+	// DefaultLivenessErrorPenalty is the penalty of a failed liveness test when neither the test nor the domain set one
+	DefaultLivenessErrorPenalty = 75
+	// DefaultLivenessTimeoutPenalty is the penalty of a timed out liveness test when neither the test nor the domain
+	// set one
+	DefaultLivenessTimeoutPenalty = 25
+	// DefaultLivenessTestTimeout is the timeout of a liveness test which does not set one
+	DefaultLivenessTestTimeout = 25 * time.Second
+
+	maxLivenessResponseSize = 1 << 20
+)
+
+var (
+	// ErrRunLivenessTest is returned when RunLivenessTest fails
+	ErrRunLivenessTest = errors.New("run liveness test")
+	// ErrUnsupportedTestProtocol is returned when RunLivenessTest does not support the protocol of a liveness test
+	ErrUnsupportedTestProtocol = errors.New("unsupported test protocol")
+
+	defaultTestPorts = map[string]int{"HTTP": 80, "HTTPS": 443, "FTP": 21, "DNS": 53}
+
+	dnsResourceTypes = map[string]dnsmessage.Type{
+		"A": dnsmessage.TypeA, "AAAA": dnsmessage.TypeAAAA, "CNAME": dnsmessage.TypeCNAME, "MX": dnsmessage.TypeMX,
+		"NS": dnsmessage.TypeNS, "PTR": dnsmessage.TypePTR, "SOA": dnsmessage.TypeSOA, "SRV": dnsmessage.TypeSRV,
+		"TXT": dnsmessage.TypeTXT,
+	}
+)
+
+// Validate validates RunLivenessTestRequest
+func (r RunLivenessTestRequest) Validate() error {
+	errs := validation.Errors{
+		"Test":   validation.Validate(r.Test, validation.Required),
+		"Server": validation.Validate(r.Server, validation.Required),
+	}
+	if r.Test != nil {
+		protocol := strings.ToUpper(r.Test.TestObjectProtocol)
+		errs["Test.TestObjectProtocol"] = validation.Validate(protocol, validation.Required,
+			validation.In("HTTP", "HTTPS", "TCP", "TCPS", "DNS", "FTP").ErrorObject(
+				validation.NewError("validation_in_invalid", ErrUnsupportedTestProtocol.Error())))
+		errs["Test.TestObjectPort"] = validation.Validate(r.Test.TestObjectPort, validation.Min(0), validation.Max(65535),
+			validation.When(protocol == "TCP" || protocol == "TCPS", validation.Required))
+		errs["Test.TestObject"] = validation.Validate(r.Test.TestObject, validation.When(protocol == "DNS" || protocol == "FTP", validation.Required))
+		errs["Test.ResourceType"] = validation.Validate(strings.ToUpper(r.Test.ResourceType), validation.When(protocol == "DNS",
+			validation.By(func(interface{}) error {
+				if _, ok := dnsResourceTypes[strings.ToUpper(r.Test.ResourceType)]; !ok && r.Test.ResourceType != "" {
+					return fmt.Errorf("unsupported resource type %q", r.Test.ResourceType)
+				}
+				return nil
+			})))
+	}
+	return edgegriderr.ParseValidationErrors(errs)
+}
+
+// RunLivenessTest runs a liveness test against a single server from the local machine and interprets the outcome
+// the way GTM does. HTTP and HTTPS tests request TestObject without following redirects and fail on the status
+// classes selected with HTTPError3xx, HTTPError4xx and HTTPError5xx. TCP and TCPS tests send RequestString.
+// DNS tests query TestObject for ResourceType. FTP tests log in and download TestObject. When ResponseString is
+// set, the response has to contain it.
+//
+// A passed test scores its duration in seconds. A failed test scores ErrorPenalty, or TimeoutPenalty when it timed
+// out; penalties which are not set fall back to the defaults of the request. An error is returned only for invalid
+// requests, never for failed tests.
+func RunLivenessTest(ctx context.Context, params RunLivenessTestRequest) (*LivenessTestResult, error) {
+	if err := params.Validate(); err != nil {
+		return nil, fmt.Errorf("%s: %w: %s", ErrRunLivenessTest, ErrStructValidation, err)
+	}
+
+	test := params.Test
+	timeout := DefaultLivenessTestTimeout
+	if test.TestTimeout > 0 {
+		timeout = time.Duration(float64(test.TestTimeout) * float64(time.Second))
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	protocol := strings.ToUpper(test.TestObjectProtocol)
+	port := test.TestObjectPort
+	if port == 0 {
+		port = defaultTestPorts[protocol]
+	}
+	p := livenessProbe{
+		test:     test,
+		address:  net.JoinHostPort(params.Server, strconv.Itoa(port)),
+		hostname: params.Hostname,
+	}
+	if p.hostname == "" {
+		p.hostname = params.Server
+	}
+
+	start := time.Now()
+	var err error
+	result := &LivenessTestResult{}
+	switch protocol {
+	case "HTTP", "HTTPS":
+		result.StatusCode, err = p.http(ctx, strings.ToLower(protocol))
+	case "TCP", "TCPS":
+		err = p.tcp(ctx, protocol == "TCPS")
+	case "DNS":
+		err = p.dns(ctx)
+	case "FTP":
+		err = p.ftp(ctx)
+	}
+	result.Duration = time.Since(start)
+
+	if err == nil {
+		result.Passed = true
+		result.Score = result.Duration.Seconds()
+		return result, nil
+	}
+	var netErr net.Error
+	if errors.Is(err, context.DeadlineExceeded) || errors.As(err, &netErr) && netErr.Timeout() {
+		result.TimedOut = true
+		result.Score = penalty(test.TimeoutPenalty, params.DefaultTimeoutPenalty, DefaultLivenessTimeoutPenalty)
+		result.Reasons = append(result.Reasons, fmt.Sprintf("timed out after %s: %s", timeout, err))
+		return result, nil
+	}
+	result.Score = penalty(test.ErrorPenalty, params.DefaultErrorPenalty, DefaultLivenessErrorPenalty)
+	for _, e := range unwrapJoined(err) {
+		result.Reasons = append(result.Reasons, e.Error())
+	}
+	return result, nil
+}
+
+type livenessProbe struct {
+	test     *LivenessTest
+	address  string
+	hostname string
+}
+
+func (p livenessProbe) http(ctx context.Context, scheme string) (int, error) {
+	tlsConfig, err := p.tlsConfig()
+	if err != nil {
+		return 0, err
+	}
+	client := &http.Client{
+		Transport: &http.Transport{TLSClientConfig: tlsConfig, DisableKeepAlives: true},
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+
+	method := http.MethodGet
+	if p.test.HTTPMethod != nil && *p.test.HTTPMethod != "" {
+		method = strings.ToUpper(*p.test.HTTPMethod)
+	}
+	var body io.Reader
+	if p.test.HTTPRequestBody != nil {
+		body = strings.NewReader(*p.test.HTTPRequestBody)
+	}
+	path := p.test.TestObject
+	if !strings.HasPrefix(path, "/") {
+		path = "/" + path
+	}
+	req, err := http.NewRequestWithContext(ctx, method, scheme+"://"+p.address+path, body)
+	if err != nil {
+		return 0, err
+	}
+	req.Host = p.hostname
+	for _, h := range p.test.HTTPHeaders {
+		if strings.EqualFold(h.Name, "Host") {
+			req.Host = h.Value
+			continue
+		}
+		req.Header.Add(h.Name, h.Value)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	var errs []error
+	switch class := resp.StatusCode / 100; {
+	case class == 3 && p.test.HTTPError3xx, class == 4 && p.test.HTTPError4xx, class == 5 && p.test.HTTPError5xx:
+		errs = append(errs, fmt.Errorf("HTTP status %d is treated as an error", resp.StatusCode))
+	}
+	if p.test.ResponseString != "" {
+		content, err := io.ReadAll(io.LimitReader(resp.Body, maxLivenessResponseSize))
+		if err != nil {
+			return resp.StatusCode, err
+		}
+		if !bytes.Contains(content, []byte(p.test.ResponseString)) {
+			errs = append(errs, fmt.Errorf("response does not contain %q", p.test.ResponseString))
+		}
+	}
+	return resp.StatusCode, errors.Join(errs...)
+}
+
+func (p livenessProbe) tcp(ctx context.Context, useTLS bool) error {
+	var conn net.Conn
+	var err error
+	if useTLS {
+		tlsConfig, cfgErr := p.tlsConfig()
+		if cfgErr != nil {
+			return cfgErr
+		}
+		conn, err = (&tls.Dialer{Config: tlsConfig}).DialContext(ctx, "tcp", p.address)
+	} else {
+		conn, err = (&net.Dialer{}).DialContext(ctx, "tcp", p.address)
+	}
+	if err != nil {
+		return err
+	}
+	defer func() { _ = conn.Close() }()
+	if deadline, ok := ctx.Deadline(); ok {
+		_ = conn.SetDeadline(deadline)
+	}
+
+	if p.test.RequestString != "" {
+		if _, err := io.WriteString(conn, p.test.RequestString); err != nil {
+			return err
+		}
+	}
+	if p.test.ResponseString == "" {
+		return nil
+	}
+	return readUntil(conn, p.test.ResponseString)
+}
+
+func (p livenessProbe) dns(ctx context.Context) error {
+	name, err := dnsmessage.NewName(dnsFQDN(p.test.TestObject))
+	if err != nil {
+		return err
+	}
+	qtype := dnsmessage.TypeA
+	if p.test.ResourceType != "" {
+		qtype = dnsResourceTypes[strings.ToUpper(p.test.ResourceType)]
+	}
+	id := uint16(time.Now().UnixNano())
+	query := dnsmessage.Message{
+		Header:    dnsmessage.Header{ID: id, RecursionDesired: p.test.RecursionRequested},
+		Questions: []dnsmessage.Question{{Name: name, Type: qtype, Class: dnsmessage.ClassINET}},
+	}
+	packed, err := query.Pack()
+	if err != nil {
+		return err
+	}
+
+	conn, err := (&net.Dialer{}).DialContext(ctx, "udp", p.address)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = conn.Close() }()
+	if deadline, ok := ctx.Deadline(); ok {
+		_ = conn.SetDeadline(deadline)
+	}
+	if _, err := conn.Write(packed); err != nil {
+		return err
+	}
+
+	buf := make([]byte, 65535)
+	for {
+		n, err := conn.Read(buf)
+		if err != nil {
+			return err
+		}
+		var resp dnsmessage.Message
+		if err := resp.Unpack(buf[:n]); err != nil || resp.ID != id || !resp.Response {
+			continue
+		}
+		if resp.RCode != dnsmessage.RCodeSuccess {
+			return fmt.Errorf("DNS response code %s", resp.RCode)
+		}
+		if p.test.AnswersRequired {
+			for _, a := range resp.Answers {
+				if a.Header.Type == qtype {
+					return nil
+				}
+			}
+			return fmt.Errorf("no %s answer for %s", strings.TrimPrefix(qtype.String(), "Type"), name)
+		}
+		return nil
+	}
+}
+
+func (p livenessProbe) ftp(ctx context.Context) error {
+	dialer := &net.Dialer{}
+	conn, err := dialer.DialContext(ctx, "tcp", p.address)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = conn.Close() }()
+	if deadline, ok := ctx.Deadline(); ok {
+		_ = conn.SetDeadline(deadline)
+	}
+	c := textproto.NewConn(conn)
+
+	if _, _, err := c.ReadResponse(220); err != nil {
+		return fmt.Errorf("greeting: %w", err)
+	}
+	user := p.test.TestObjectUsername
+	if user == "" {
+		user = "anonymous"
+	}
+	code, _, err := ftpCmd(c, 0, "USER %s", user)
+	if err != nil {
+		return fmt.Errorf("login: %w", err)
+	}
+	if code == 331 {
+		if _, _, err := ftpCmd(c, 230, "PASS %s", p.test.TestObjectPassword); err != nil {
+			return fmt.Errorf("login: %w", err)
+		}
+	} else if code != 230 {
+		return fmt.Errorf("login: unexpected reply %d", code)
+	}
+	if _, _, err := ftpCmd(c, 200, "TYPE I"); err != nil {
+		return err
+	}
+	_, msg, err := ftpCmd(c, 227, "PASV")
+	if err != nil {
+		return err
+	}
+	dataAddress, err := parsePASV(msg, conn.RemoteAddr())
+	if err != nil {
+		return err
+	}
+	data, err := dialer.DialContext(ctx, "tcp", dataAddress)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = data.Close() }()
+	if deadline, ok := ctx.Deadline(); ok {
+		_ = data.SetDeadline(deadline)
+	}
+
+	if _, _, err := ftpCmd(c, 1, "RETR %s", p.test.TestObject); err != nil {
+		return fmt.Errorf("retrieve %s: %w", p.test.TestObject, err)
+	}
+	content, err := io.ReadAll(io.LimitReader(data, maxLivenessResponseSize))
+	if err != nil {
+		return err
+	}
+	_ = data.Close()
+	if _, _, err := c.ReadResponse(2); err != nil {
+		return fmt.Errorf("retrieve %s: %w", p.test.TestObject, err)
+	}
+	_, _ = c.Cmd("QUIT")
+
+	if p.test.ResponseString != "" && !bytes.Contains(content, []byte(p.test.ResponseString)) {
+		return fmt.Errorf("response does not contain %q", p.test.ResponseString)
+	}
+	return nil
+}
+
+func (p livenessProbe) tlsConfig() (*tls.Config, error) {
+	cfg := &tls.Config{
+		ServerName:         p.hostname,
+		InsecureSkipVerify: !p.test.PeerCertificateVerification,
+	}
+	if len(p.test.AlternateCACertificates) > 0 {
+		pool, err := x509.SystemCertPool()
+		if err != nil {
+			pool = x509.NewCertPool()
+		}
+		for _, ca := range p.test.AlternateCACertificates {
+			if !pool.AppendCertsFromPEM([]byte(ca)) {
+				return nil, errors.New("invalid alternate CA certificate")
+			}
+		}
+		cfg.RootCAs = pool
+	}
+	if p.test.SSLClientCertificate != "" || p.test.SSLClientPrivateKey != "" {
+		cert, err := tls.X509KeyPair([]byte(p.test.SSLClientCertificate), []byte(p.test.SSLClientPrivateKey))
+		if err != nil {
+			return nil, fmt.Errorf("client certificate: %w", err)
+		}
+		cfg.Certificates = []tls.Certificate{cert}
+	}
+	return cfg, nil
+}
+
+// ftpCmd sends a command and reads its reply. An expectCode of 0 accepts any reply.
+func ftpCmd(c *textproto.Conn, expectCode int, format string, args ...interface{}) (int, string, error) {
+	id, err := c.Cmd(format, args...)
+	if err != nil {
+		return 0, "", err
+	}
+	c.StartResponse(id)
+	defer c.EndResponse(id)
+	if expectCode == 0 {
+		code, msg, err := c.ReadResponse(0)
+		var protoErr *textproto.Error
+		if errors.As(err, &protoErr) {
+			return protoErr.Code, protoErr.Msg, nil
+		}
+		return code, msg, err
+	}
+	return c.ReadResponse(expectCode)
+}
+
+// parsePASV returns the data connection address from a reply such as "Entering Passive Mode (h1,h2,h3,h4,p1,p2)".
+// The host of the control connection is used, as servers behind NAT often report a private address.
+func parsePASV(msg string, control net.Addr) (string, error) {
+	start, end := strings.Index(msg, "("), strings.Index(msg, ")")
+	if start < 0 || end < start {
+		return "", fmt.Errorf("invalid PASV reply %q", msg)
+	}
+	fields := strings.Split(msg[start+1:end], ",")
+	if len(fields) != 6 {
+		return "", fmt.Errorf("invalid PASV reply %q", msg)
+	}
+	hi, err1 := strconv.Atoi(strings.TrimSpace(fields[4]))
+	lo, err2 := strconv.Atoi(strings.TrimSpace(fields[5]))
+	if err1 != nil || err2 != nil {
+		return "", fmt.Errorf("invalid PASV reply %q", msg)
+	}
+	host, _, err := net.SplitHostPort(control.String())
+	if err != nil {
+		return "", err
+	}
+	return net.JoinHostPort(host, strconv.Itoa(hi<<8|lo)), nil
+}
+
+// readUntil reads from r until the content contains s
+func readUntil(r io.Reader, s string) error {
+	var content []byte
+	buf := make([]byte, 4096)
+	for len(content) < maxLivenessResponseSize {
+		n, err := r.Read(buf)
+		content = append(content, buf[:n]...)
+		if bytes.Contains(content, []byte(s)) {
+			return nil
+		}
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return fmt.Errorf("response does not contain %q", s)
+}
+
+func dnsFQDN(name string) string {
+	if strings.HasSuffix(name, ".") {
+		return name
+	}
+	return name + "."
+}
+
+func penalty(values ...float64) float64 {
+	for _, v := range values {
+		if v > 0 {
+			return v
+		}
+	}
+	return 0
+}
+
+func unwrapJoined(err error) []error {
+	if joined, ok := err.(interface{ Unwrap() []error }); ok {
+		return joined.Unwrap()
+	}
+	return []error{err}
+}
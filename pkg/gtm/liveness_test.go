@@ -0,0 +1,437 @@
+package gtm
+
+import (
+	"bufio"
+	"context"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+func TestRunLivenessTest(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/health", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = fmt.Fprintf(w, "status: OK, host: %s, token: %s, method: %s", r.Host, r.Header.Get("X-Token"), r.Method)
+	})
+	mux.HandleFunc("/moved", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/health", http.StatusFound)
+	})
+	mux.HandleFunc("/broken", func(w http.ResponseWriter, _ *http.Request) {
+		http.Error(w, "status: OK", http.StatusServiceUnavailable)
+	})
+	mux.HandleFunc("/slow", func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-r.Context().Done():
+		case <-time.After(time.Second):
+		}
+	})
+	httpServer := httptest.NewServer(mux)
+	defer httpServer.Close()
+	tlsServer := httptest.NewUnstartedServer(mux)
+	tlsServer.Config.ErrorLog = log.New(io.Discard, "", 0)
+	tlsServer.StartTLS()
+	defer tlsServer.Close()
+
+	httpHost, httpPort := splitTestAddr(t, httpServer.Listener.Addr())
+	tlsHost, tlsPort := splitTestAddr(t, tlsServer.Listener.Addr())
+	serverCA := string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: tlsServer.Certificate().Raw}))
+	post := "POST"
+
+	tests := map[string]struct {
+		params    RunLivenessTestRequest
+		expected  *LivenessTestResult
+		withError error
+	}{
+		"http passes": {
+			params: RunLivenessTestRequest{
+				Test: &LivenessTest{TestObjectProtocol: "HTTP", TestObjectPort: httpPort, TestObject: "/health",
+					ResponseString: "host: www.example.com, token: secret, method: POST", HTTPMethod: &post,
+					HTTPHeaders: []HTTPHeader{{Name: "X-Token", Value: "secret"}}},
+				Server:   httpHost,
+				Hostname: "www.example.com",
+			},
+			expected: &LivenessTestResult{Passed: true, StatusCode: http.StatusOK},
+		},
+		"http host header": {
+			params: RunLivenessTestRequest{
+				Test: &LivenessTest{TestObjectProtocol: "http", TestObjectPort: httpPort, TestObject: "health",
+					ResponseString: "host: origin.example.com", HTTPHeaders: []HTTPHeader{{Name: "Host", Value: "origin.example.com"}}},
+				Server: httpHost,
+			},
+			expected: &LivenessTestResult{Passed: true, StatusCode: http.StatusOK},
+		},
+		"http response string missing": {
+			params: RunLivenessTestRequest{
+				Test:   &LivenessTest{TestObjectProtocol: "HTTP", TestObjectPort: httpPort, TestObject: "/health", ResponseString: "healthy"},
+				Server: httpHost,
+			},
+			expected: &LivenessTestResult{Score: DefaultLivenessErrorPenalty, StatusCode: http.StatusOK,
+				Reasons: []string{`response does not contain "healthy"`}},
+		},
+		"http 5xx is an error": {
+			params: RunLivenessTestRequest{
+				Test: &LivenessTest{TestObjectProtocol: "HTTP", TestObjectPort: httpPort, TestObject: "/broken", ResponseString: "status: OK",
+					HTTPError5xx: true, ErrorPenalty: 100},
+				Server:              httpHost,
+				DefaultErrorPenalty: 50,
+			},
+			expected: &LivenessTestResult{Score: 100, StatusCode: http.StatusServiceUnavailable,
+				Reasons: []string{"HTTP status 503 is treated as an error"}},
+		},
+		"http 5xx is not an error": {
+			params: RunLivenessTestRequest{
+				Test:   &LivenessTest{TestObjectProtocol: "HTTP", TestObjectPort: httpPort, TestObject: "/broken", ResponseString: "status: OK"},
+				Server: httpHost,
+			},
+			expected: &LivenessTestResult{Passed: true, StatusCode: http.StatusServiceUnavailable},
+		},
+		"http redirect is not followed": {
+			params: RunLivenessTestRequest{
+				Test:                &LivenessTest{TestObjectProtocol: "HTTP", TestObjectPort: httpPort, TestObject: "/moved", HTTPError3xx: true},
+				Server:              httpHost,
+				DefaultErrorPenalty: 50,
+			},
+			expected: &LivenessTestResult{Score: 50, StatusCode: http.StatusFound,
+				Reasons: []string{"HTTP status 302 is treated as an error"}},
+		},
+		"http timeout": {
+			params: RunLivenessTestRequest{
+				Test:   &LivenessTest{TestObjectProtocol: "HTTP", TestObjectPort: httpPort, TestObject: "/slow", TestTimeout: 0.05},
+				Server: httpHost,
+			},
+			expected: &LivenessTestResult{TimedOut: true, Score: DefaultLivenessTimeoutPenalty},
+		},
+		"https without verification": {
+			params: RunLivenessTestRequest{
+				Test:   &LivenessTest{TestObjectProtocol: "HTTPS", TestObjectPort: tlsPort, TestObject: "/health", ResponseString: "status: OK"},
+				Server: tlsHost,
+			},
+			expected: &LivenessTestResult{Passed: true, StatusCode: http.StatusOK},
+		},
+		"https with alternate CA": {
+			params: RunLivenessTestRequest{
+				Test: &LivenessTest{TestObjectProtocol: "HTTPS", TestObjectPort: tlsPort, TestObject: "/health",
+					PeerCertificateVerification: true, AlternateCACertificates: []string{serverCA}},
+				Server:   tlsHost,
+				Hostname: "example.com",
+			},
+			expected: &LivenessTestResult{Passed: true, StatusCode: http.StatusOK},
+		},
+		"https untrusted certificate": {
+			params: RunLivenessTestRequest{
+				Test: &LivenessTest{TestObjectProtocol: "HTTPS", TestObjectPort: tlsPort, TestObject: "/health",
+					PeerCertificateVerification: true},
+				Server: tlsHost,
+			},
+			expected: &LivenessTestResult{Score: DefaultLivenessErrorPenalty},
+		},
+		"connection refused": {
+			params: RunLivenessTestRequest{
+				Test:   &LivenessTest{TestObjectProtocol: "TCP", TestObjectPort: closedPort(t)},
+				Server: "127.0.0.1",
+			},
+			expected: &LivenessTestResult{Score: DefaultLivenessErrorPenalty},
+		},
+		"unsupported protocol": {
+			params:    RunLivenessTestRequest{Test: &LivenessTest{TestObjectProtocol: "SMTP"}, Server: "127.0.0.1"},
+			withError: ErrStructValidation,
+		},
+		"tcp without port": {
+			params:    RunLivenessTestRequest{Test: &LivenessTest{TestObjectProtocol: "TCP"}, Server: "127.0.0.1"},
+			withError: ErrStructValidation,
+		},
+		"missing server": {
+			params:    RunLivenessTestRequest{Test: &LivenessTest{TestObjectProtocol: "HTTP"}},
+			withError: ErrStructValidation,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			result, err := RunLivenessTest(context.Background(), test.params)
+			if test.withError != nil {
+				assert.True(t, errors.Is(err, test.withError), "want: %s; got: %s", test.withError, err)
+				return
+			}
+			require.NoError(t, err)
+			assertLivenessResult(t, test.expected, result)
+		})
+	}
+}
+
+func TestRunLivenessTestTCP(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer func() { _ = listener.Close() }()
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer func() { _ = conn.Close() }()
+				line, _ := bufio.NewReader(conn).ReadString('\n')
+				if line == "PING\r\n" {
+					_, _ = io.WriteString(conn, "+PONG\r\n")
+				}
+			}()
+		}
+	}()
+	host, port := splitTestAddr(t, listener.Addr())
+
+	tests := map[string]struct {
+		test     *LivenessTest
+		expected *LivenessTestResult
+	}{
+		"passes": {
+			test:     &LivenessTest{TestObjectProtocol: "TCP", TestObjectPort: port, RequestString: "PING\r\n", ResponseString: "PONG"},
+			expected: &LivenessTestResult{Passed: true},
+		},
+		"connect only": {
+			test:     &LivenessTest{TestObjectProtocol: "TCP", TestObjectPort: port},
+			expected: &LivenessTestResult{Passed: true},
+		},
+		"unexpected response": {
+			test:     &LivenessTest{TestObjectProtocol: "TCP", TestObjectPort: port, RequestString: "HELLO\r\n", ResponseString: "PONG"},
+			expected: &LivenessTestResult{Score: DefaultLivenessErrorPenalty, Reasons: []string{`response does not contain "PONG"`}},
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			result, err := RunLivenessTest(context.Background(), RunLivenessTestRequest{Test: test.test, Server: host})
+			require.NoError(t, err)
+			assertLivenessResult(t, test.expected, result)
+		})
+	}
+}
+
+func TestRunLivenessTestDNS(t *testing.T) {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer func() { _ = conn.Close() }()
+	go func() {
+		buf := make([]byte, 512)
+		for {
+			n, addr, err := conn.ReadFrom(buf)
+			if err != nil {
+				return
+			}
+			var query dnsmessage.Message
+			if err := query.Unpack(buf[:n]); err != nil || len(query.Questions) != 1 {
+				continue
+			}
+			q := query.Questions[0]
+			resp := dnsmessage.Message{
+				Header:    dnsmessage.Header{ID: query.ID, Response: true, Authoritative: true, RecursionDesired: query.RecursionDesired},
+				Questions: query.Questions,
+			}
+			switch {
+			case q.Name.String() != "www.example.com.":
+				resp.RCode = dnsmessage.RCodeNameError
+			case q.Type == dnsmessage.TypeA:
+				resp.Answers = []dnsmessage.Resource{{
+					Header: dnsmessage.ResourceHeader{Name: q.Name, Type: dnsmessage.TypeA, Class: dnsmessage.ClassINET, TTL: 60},
+					Body:   &dnsmessage.AResource{A: [4]byte{192, 0, 2, 1}},
+				}}
+			}
+			packed, _ := resp.Pack()
+			_, _ = conn.WriteTo(packed, addr)
+		}
+	}()
+	host, port := splitTestAddr(t, conn.LocalAddr())
+
+	tests := map[string]struct {
+		test     *LivenessTest
+		expected *LivenessTestResult
+	}{
+		"answer": {
+			test:     &LivenessTest{TestObjectProtocol: "DNS", TestObject: "www.example.com", AnswersRequired: true, RecursionRequested: true},
+			expected: &LivenessTestResult{Passed: true},
+		},
+		"no answer allowed": {
+			test:     &LivenessTest{TestObjectProtocol: "DNS", TestObject: "www.example.com", ResourceType: "aaaa"},
+			expected: &LivenessTestResult{Passed: true},
+		},
+		"no answer": {
+			test:     &LivenessTest{TestObjectProtocol: "DNS", TestObject: "www.example.com.", ResourceType: "AAAA", AnswersRequired: true},
+			expected: &LivenessTestResult{Score: DefaultLivenessErrorPenalty, Reasons: []string{"no AAAA answer for www.example.com."}},
+		},
+		"name error": {
+			test:     &LivenessTest{TestObjectProtocol: "DNS", TestObject: "missing.example.com"},
+			expected: &LivenessTestResult{Score: DefaultLivenessErrorPenalty, Reasons: []string{"DNS response code RCodeNameError"}},
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			test.test.TestObjectPort = port
+			result, err := RunLivenessTest(context.Background(), RunLivenessTestRequest{Test: test.test, Server: host})
+			require.NoError(t, err)
+			assertLivenessResult(t, test.expected, result)
+		})
+	}
+}
+
+func TestRunLivenessTestFTP(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer func() { _ = listener.Close() }()
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go serveFTP(conn, map[string]string{"/health.txt": "status: OK"})
+		}
+	}()
+	host, port := splitTestAddr(t, listener.Addr())
+
+	tests := map[string]struct {
+		test     *LivenessTest
+		expected *LivenessTestResult
+	}{
+		"passes": {
+			test: &LivenessTest{TestObjectProtocol: "FTP", TestObject: "/health.txt", ResponseString: "OK",
+				TestObjectUsername: "gtm", TestObjectPassword: "secret"},
+			expected: &LivenessTestResult{Passed: true},
+		},
+		"wrong password": {
+			test: &LivenessTest{TestObjectProtocol: "FTP", TestObject: "/health.txt", TestObjectUsername: "gtm",
+				TestObjectPassword: "wrong"},
+			expected: &LivenessTestResult{Score: DefaultLivenessErrorPenalty, Reasons: []string{`login: 530 "Login incorrect."`}},
+		},
+		"missing file": {
+			test: &LivenessTest{TestObjectProtocol: "FTP", TestObject: "/missing.txt", TestObjectUsername: "gtm",
+				TestObjectPassword: "secret"},
+			expected: &LivenessTestResult{Score: DefaultLivenessErrorPenalty,
+				Reasons: []string{`retrieve /missing.txt: 550 "No such file."`}},
+		},
+		"unexpected content": {
+			test: &LivenessTest{TestObjectProtocol: "FTP", TestObject: "/health.txt", ResponseString: "healthy",
+				TestObjectUsername: "gtm", TestObjectPassword: "secret"},
+			expected: &LivenessTestResult{Score: DefaultLivenessErrorPenalty, Reasons: []string{`response does not contain "healthy"`}},
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			test.test.TestObjectPort = port
+			result, err := RunLivenessTest(context.Background(), RunLivenessTestRequest{Test: test.test, Server: host})
+			require.NoError(t, err)
+			assertLivenessResult(t, test.expected, result)
+		})
+	}
+}
+
+// assertLivenessResult compares results ignoring timing and, for results without expected reasons, the reasons
+func assertLivenessResult(t *testing.T, expected, actual *LivenessTestResult) {
+	require.NotNil(t, actual)
+	assert.Equal(t, expected.Passed, actual.Passed, "reasons: %v", actual.Reasons)
+	assert.Equal(t, expected.TimedOut, actual.TimedOut)
+	assert.Equal(t, expected.StatusCode, actual.StatusCode)
+	if expected.Passed {
+		assert.Equal(t, actual.Duration.Seconds(), actual.Score)
+		assert.Empty(t, actual.Reasons)
+		return
+	}
+	assert.Equal(t, expected.Score, actual.Score)
+	if expected.Reasons != nil {
+		assert.Equal(t, expected.Reasons, actual.Reasons)
+	} else {
+		assert.NotEmpty(t, actual.Reasons)
+	}
+}
+
+func splitTestAddr(t *testing.T, addr net.Addr) (string, int) {
+	host, port, err := net.SplitHostPort(addr.String())
+	require.NoError(t, err)
+	p, err := strconv.Atoi(port)
+	require.NoError(t, err)
+	return host, p
+}
+
+func closedPort(t *testing.T) int {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	_, port := splitTestAddr(t, listener.Addr())
+	require.NoError(t, listener.Close())
+	return port
+}
+
+// serveFTP serves a single passive mode FTP session for user gtm with password secret
+func serveFTP(conn net.Conn, files map[string]string) {
+	defer func() { _ = conn.Close() }()
+	r := bufio.NewReader(conn)
+	reply := func(format string, args ...interface{}) {
+		_, _ = fmt.Fprintf(conn, format+"\r\n", args...)
+	}
+	var data net.Listener
+	defer func() {
+		if data != nil {
+			_ = data.Close()
+		}
+	}()
+
+	reply("220 Service ready.")
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return
+		}
+		cmd, arg, _ := strings.Cut(strings.TrimSpace(line), " ")
+		switch strings.ToUpper(cmd) {
+		case "USER":
+			reply("331 Password required.")
+		case "PASS":
+			if arg != "secret" {
+				reply("530 Login incorrect.")
+				continue
+			}
+			reply("230 Logged in.")
+		case "TYPE":
+			reply("200 Type set.")
+		case "PASV":
+			if data, err = net.Listen("tcp", "127.0.0.1:0"); err != nil {
+				reply("425 Cannot open data connection.")
+				continue
+			}
+			port := data.Addr().(*net.TCPAddr).Port
+			reply("227 Entering Passive Mode (127,0,0,1,%d,%d).", port>>8, port&0xff)
+		case "RETR":
+			content, ok := files[arg]
+			if !ok {
+				reply("550 No such file.")
+				continue
+			}
+			dataConn, err := data.Accept()
+			if err != nil {
+				return
+			}
+			reply("150 Opening data connection.")
+			_, _ = io.WriteString(dataConn, content)
+			_ = dataConn.Close()
+			reply("226 Transfer complete.")
+		case "QUIT":
+			reply("221 Bye.")
+			return
+		default:
+			reply("502 Command not implemented.")
+		}
+	}
+}
@@ -0,0 +1,46 @@
+package gtm
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/akamai/AkamaiOPEN-edgegrid-golang/v11/pkg/operation"
+)
+
+// DeleteDomainsOperation returns an Operation tracking a request to delete domains. A completed request succeeds
+// even when some domains failed, which is reported in the status message
+func DeleteDomainsOperation(client GTM, requestID string) *operation.Operation[*DeleteDomainsStatusResponse] {
+	return operation.New(requestID, func(ctx context.Context) (operation.Status[*DeleteDomainsStatusResponse], error) {
+		resp, err := client.GetDeleteDomainsStatus(ctx, DeleteDomainsStatusRequest{RequestID: requestID})
+		if err != nil {
+			return operation.Status[*DeleteDomainsStatusResponse]{}, err
+		}
+		status := operation.Status[*DeleteDomainsStatusResponse]{State: operation.StatePending, Value: resp}
+		if resp.IsComplete {
+			status.State = operation.StateSucceeded
+		}
+		if resp.IsComplete && resp.FailureCount > 0 {
+			status.Message = fmt.Sprintf("%d of %d domains failed", resp.FailureCount, resp.DomainsSubmitted)
+		}
+		return status, nil
+	})
+}
+
+// DomainPropagationOperation returns an Operation tracking the propagation of the last change of a domain to the GTM
+// name servers. It succeeds once the propagation status is COMPLETE and fails when it is DENIED
+func DomainPropagationOperation(client GTM, domainName string) *operation.Operation[*ResponseStatus] {
+	return operation.New(domainName, func(ctx context.Context) (operation.Status[*ResponseStatus], error) {
+		resp, err := client.GetDomainStatus(ctx, GetDomainStatusRequest{DomainName: domainName})
+		if err != nil {
+			return operation.Status[*ResponseStatus]{}, err
+		}
+		status := operation.Status[*ResponseStatus]{State: operation.StatePending, Message: resp.Message, Value: (*ResponseStatus)(resp)}
+		switch resp.PropagationStatus {
+		case PropagationStatusComplete:
+			status.State = operation.StateSucceeded
+		case PropagationStatusDenied:
+			status.State = operation.StateFailed
+		}
+		return status, nil
+	})
+}
@@ -0,0 +1,71 @@
+package gtm
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/akamai/AkamaiOPEN-edgegrid-golang/v11/pkg/operation"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDeleteDomainsOperation(t *testing.T) {
+	tests := map[string]struct {
+		failureCount    int
+		expectedMessage string
+	}{
+		"all domains deleted": {},
+		"some domains failed": {
+			failureCount:    1,
+			expectedMessage: "1 of 2 domains failed",
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			m := &Mock{}
+			m.On("GetDeleteDomainsStatus", mock.Anything, DeleteDomainsStatusRequest{RequestID: "1"}).
+				Return(&DeleteDomainsStatusResponse{RequestID: "1", DomainsSubmitted: 2}, nil).Once()
+			m.On("GetDeleteDomainsStatus", mock.Anything, DeleteDomainsStatusRequest{RequestID: "1"}).
+				Return(&DeleteDomainsStatusResponse{RequestID: "1", DomainsSubmitted: 2, FailureCount: test.failureCount, IsComplete: true}, nil).Once()
+
+			var states []operation.State
+			op := DeleteDomainsOperation(m, "1")
+			op.Backoff = operation.ConstantBackoff(time.Millisecond)
+			op.OnProgress = func(e operation.Event[*DeleteDomainsStatusResponse]) { states = append(states, e.Status.State) }
+			status, err := op.Wait(context.Background())
+			m.AssertExpectations(t)
+			require.NoError(t, err)
+			assert.Equal(t, []operation.State{operation.StatePending, operation.StateSucceeded}, states)
+			assert.Equal(t, test.expectedMessage, status.Message)
+		})
+	}
+}
+
+func TestDomainPropagationOperation(t *testing.T) {
+	tests := map[string]struct {
+		propagationStatus string
+		expected          operation.State
+	}{
+		"pending":  {propagationStatus: PropagationStatusPending, expected: operation.StatePending},
+		"complete": {propagationStatus: PropagationStatusComplete, expected: operation.StateSucceeded},
+		"denied":   {propagationStatus: PropagationStatusDenied, expected: operation.StateFailed},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			m := &Mock{}
+			m.On("GetDomainStatus", mock.Anything, GetDomainStatusRequest{DomainName: "example.akadns.net"}).
+				Return(&GetDomainStatusResponse{PropagationStatus: test.propagationStatus, Message: "message"}, nil).Once()
+			op := DomainPropagationOperation(m, "example.akadns.net")
+			status, err := op.Poll(context.Background())
+			m.AssertExpectations(t)
+			require.NoError(t, err)
+			assert.Equal(t, "example.akadns.net", op.ID)
+			assert.Equal(t, test.expected, status.State)
+			assert.Equal(t, "message", status.Message)
+		})
+	}
+}
@@ -0,0 +1,348 @@
+package gtm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"slices"
+	"sort"
+	"time"
+
+	"github.com/akamai/AkamaiOPEN-edgegrid-golang/v11/pkg/edgegriderr"
+	"github.com/akamai/AkamaiOPEN-edgegrid-golang/v11/pkg/operation"
+	validation "github.com/go-ozzo/ozzo-validation/v4"
+)
+
+type (
+	// PlanPropertyRequest contains request parameters for PlanProperty
+	PlanPropertyRequest struct {
+		DomainName string
+		// Desired is the complete property, as it is sent with UpdateProperty. Fields left at their zero value are
+		// compared as zero values, so fields defaulted by the server must be set too. Start from the property returned
+		// by GetProperty to change only some fields.
+		Desired *Property
+	}
+
+	// PropertyPlan describes the changes which make a property match the desired one. Current is nil when the
+	// property does not exist yet.
+	PropertyPlan struct {
+		DomainName string
+		Current    *Property
+		Desired    *Property
+		Diffs      []PropertyDiff
+	}
+
+	// PropertyDiff is a single changed field of a property. Path identifies traffic targets by datacenter ID and
+	// liveness tests by name, for example "TrafficTargets[3131].Weight" or "LivenessTests[http].TestInterval".
+	// Current is nil for added items and Desired is nil for removed items.
+	PropertyDiff struct {
+		Path    string
+		Current interface{}
+		Desired interface{}
+	}
+
+	// ApplyPropertyPlanRequest contains request parameters for ApplyPropertyPlan
+	ApplyPropertyPlanRequest struct {
+		Plan *PropertyPlan
+	}
+
+	// WaitForPropagationRequest contains request parameters for WaitForPropagation
+	WaitForPropagationRequest struct {
+		DomainName string
+		// PollInterval is the interval between status checks. It defaults to DefaultPropagationPollInterval.
+		PollInterval time.Duration
+		// Timeout limits the time spent waiting. By default, WaitForPropagation waits until the context is done.
+		Timeout time.Duration
+	}
+
+	// PropagationError is returned by WaitForPropagation when a change was denied or did not propagate in time.
+	// It matches ErrPropagationDenied, or ErrPropagationTimeout together with the error of the context, with errors.Is.
+	PropagationError struct {
+		DomainName string
+		// Status is the last status returned by GetDomainStatus, if any
+		Status *ResponseStatus
+		Err    error
+	}
+)
+
+const (
+	// PropagationStatusPending means the change is still propagating
+	PropagationStatusPending = "PENDING"
+	// PropagationStatusComplete means the change propagated to the GTM name servers
+	PropagationStatusComplete = "COMPLETE"
+	// PropagationStatusDenied means the change was rejected
+	PropagationStatusDenied = "DENIED"
+
+	// DefaultPropagationPollInterval is the default interval between status checks of WaitForPropagation
+	DefaultPropagationPollInterval = 30 * time.Second
+)
+
+var (
+	// ErrPlanProperty is returned when PlanProperty fails
+	ErrPlanProperty = errors.New("plan property")
+	// ErrApplyPropertyPlan is returned when ApplyPropertyPlan fails
+	ErrApplyPropertyPlan = errors.New("apply property plan")
+	// ErrWaitForPropagation is returned when WaitForPropagation fails
+	ErrWaitForPropagation = errors.New("wait for propagation")
+	// ErrPropagationDenied is returned when the domain status is DENIED
+	ErrPropagationDenied = errors.New("propagation denied")
+	// ErrPropagationTimeout is returned when the domain status is not COMPLETE in time
+	ErrPropagationTimeout = errors.New("propagation timeout")
+
+	// ignoredPropertyFields are read-only fields which are not compared
+	ignoredPropertyFields = map[string]bool{"Links": true, "LastModified": true}
+)
+
+// Validate validates PlanPropertyRequest
+func (r PlanPropertyRequest) Validate() error {
+	return edgegriderr.ParseValidationErrors(validation.Errors{
+		"DomainName": validation.Validate(r.DomainName, validation.Required),
+		"Desired":    validation.Validate(r.Desired, validation.Required),
+	})
+}
+
+// Validate validates ApplyPropertyPlanRequest
+func (r ApplyPropertyPlanRequest) Validate() error {
+	return edgegriderr.ParseValidationErrors(validation.Errors{
+		"Plan": validation.Validate(r.Plan, validation.Required),
+	})
+}
+
+// Validate validates PropertyPlan
+func (p PropertyPlan) Validate() error {
+	return validation.Errors{
+		"DomainName": validation.Validate(p.DomainName, validation.Required),
+		"Desired":    validation.Validate(p.Desired, validation.Required),
+	}.Filter()
+}
+
+// Validate validates WaitForPropagationRequest
+func (r WaitForPropagationRequest) Validate() error {
+	return edgegriderr.ParseValidationErrors(validation.Errors{
+		"DomainName":   validation.Validate(r.DomainName, validation.Required),
+		"PollInterval": validation.Validate(r.PollInterval, validation.Min(time.Duration(0))),
+		"Timeout":      validation.Validate(r.Timeout, validation.Min(time.Duration(0))),
+	})
+}
+
+// HasChanges reports whether applying the plan would change anything
+func (p *PropertyPlan) HasChanges() bool {
+	return p.Current == nil || len(p.Diffs) > 0
+}
+
+func (e *PropagationError) Error() string {
+	msg := fmt.Sprintf("%s: domain %q: %s", ErrWaitForPropagation, e.DomainName, e.Err)
+	if e.Status != nil && e.Status.Message != "" {
+		msg += ": " + e.Status.Message
+	}
+	return msg
+}
+
+// Unwrap returns ErrWaitForPropagation and the reason of the failure
+func (e *PropagationError) Unwrap() []error {
+	return []error{ErrWaitForPropagation, e.Err}
+}
+
+// PlanProperty compares the desired property with the current one and returns the differences. Every field of the
+// desired property is compared, including zero values, as applying the plan replaces the whole property. Traffic
+// targets are matched by datacenter ID and liveness tests by name, so their order does not matter, and neither does
+// the order of servers in a traffic target. Links and LastModified are ignored.
+func PlanProperty(ctx context.Context, client GTM, params PlanPropertyRequest) (*PropertyPlan, error) {
+	if err := params.Validate(); err != nil {
+		return nil, fmt.Errorf("%s: %w: %s", ErrPlanProperty, ErrStructValidation, err)
+	}
+
+	plan := &PropertyPlan{DomainName: params.DomainName, Desired: params.Desired}
+	current, err := client.GetProperty(ctx, GetPropertyRequest{DomainName: params.DomainName, PropertyName: params.Desired.Name})
+	if err != nil {
+		if errors.Is(err, ErrNotFound) {
+			return plan, nil
+		}
+		return nil, fmt.Errorf("%w: %w", ErrPlanProperty, err)
+	}
+	plan.Current = (*Property)(current)
+	plan.Diffs = diffProperties(plan.Current, plan.Desired)
+
+	return plan, nil
+}
+
+// ApplyPropertyPlan creates or updates the property of the plan. The returned status can be followed with
+// WaitForPropagation. Plans without changes are not applied, and a status with PropagationStatusComplete and no
+// ChangeID is returned, as there is nothing to propagate.
+func ApplyPropertyPlan(ctx context.Context, client GTM, params ApplyPropertyPlanRequest) (*ResponseStatus, error) {
+	if err := params.Validate(); err != nil {
+		return nil, fmt.Errorf("%s: %w: %s", ErrApplyPropertyPlan, ErrStructValidation, err)
+	}
+
+	plan := params.Plan
+	if !plan.HasChanges() {
+		return &ResponseStatus{PropagationStatus: PropagationStatusComplete}, nil
+	}
+	if plan.Current == nil {
+		resp, err := client.CreateProperty(ctx, CreatePropertyRequest{DomainName: plan.DomainName, Property: plan.Desired})
+		if err != nil {
+			return nil, fmt.Errorf("%w: %w", ErrApplyPropertyPlan, err)
+		}
+		return resp.Status, nil
+	}
+	resp, err := client.UpdateProperty(ctx, UpdatePropertyRequest{DomainName: plan.DomainName, Property: plan.Desired})
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrApplyPropertyPlan, err)
+	}
+	return resp.Status, nil
+}
+
+// WaitForPropagation polls GetDomainStatus until the propagation status of the domain is COMPLETE. A DENIED status
+// and running out of time are reported as a *PropagationError. Transient errors of GetDomainStatus are not retried.
+func WaitForPropagation(ctx context.Context, client GTM, params WaitForPropagationRequest) (*ResponseStatus, error) {
+	if err := params.Validate(); err != nil {
+		return nil, fmt.Errorf("%s: %w: %s", ErrWaitForPropagation, ErrStructValidation, err)
+	}
+
+	interval := params.PollInterval
+	if interval == 0 {
+		interval = DefaultPropagationPollInterval
+	}
+	if params.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, params.Timeout)
+		defer cancel()
+	}
+
+	var last *ResponseStatus
+	op := DomainPropagationOperation(client, params.DomainName)
+	op.Backoff = operation.ConstantBackoff(interval)
+	op.OnProgress = func(e operation.Event[*ResponseStatus]) {
+		if e.Err == nil {
+			last = e.Status.Value
+		}
+	}
+	_, err := op.Wait(ctx)
+	switch {
+	case err == nil:
+		return last, nil
+	case errors.Is(err, operation.ErrOperationFailed):
+		return last, &PropagationError{DomainName: params.DomainName, Status: last, Err: ErrPropagationDenied}
+	case ctx.Err() != nil:
+		return last, &PropagationError{DomainName: params.DomainName, Status: last, Err: fmt.Errorf("%w: %w", ErrPropagationTimeout, ctx.Err())}
+	}
+	return last, fmt.Errorf("%w: %w", ErrWaitForPropagation, err)
+}
+
+func diffProperties(current, desired *Property) []PropertyDiff {
+	var diffs []PropertyDiff
+	cv, dv := reflect.ValueOf(*current), reflect.ValueOf(*desired)
+	for i := 0; i < cv.NumField(); i++ {
+		name := cv.Type().Field(i).Name
+		switch {
+		case ignoredPropertyFields[name]:
+		case name == "TrafficTargets":
+			diffs = append(diffs, diffTrafficTargets(current.TrafficTargets, desired.TrafficTargets)...)
+		case name == "LivenessTests":
+			diffs = append(diffs, diffLivenessTests(current.LivenessTests, desired.LivenessTests)...)
+		default:
+			diffs = append(diffs, diffField(name, cv.Field(i).Interface(), dv.Field(i).Interface())...)
+		}
+	}
+	return diffs
+}
+
+func diffTrafficTargets(current, desired []TrafficTarget) []PropertyDiff {
+	index := func(targets []TrafficTarget) map[int]TrafficTarget {
+		m := make(map[int]TrafficTarget, len(targets))
+		for _, t := range targets {
+			t.Servers = slices.Clone(t.Servers)
+			sort.Strings(t.Servers)
+			m[t.DatacenterID] = t
+		}
+		return m
+	}
+	cur, des := index(current), index(desired)
+	ids := make([]int, 0, len(cur)+len(des))
+	for id := range cur {
+		ids = append(ids, id)
+	}
+	for id := range des {
+		if _, ok := cur[id]; !ok {
+			ids = append(ids, id)
+		}
+	}
+	sort.Ints(ids)
+
+	var diffs []PropertyDiff
+	for _, id := range ids {
+		path := fmt.Sprintf("TrafficTargets[%d]", id)
+		c, inCurrent := cur[id]
+		d, inDesired := des[id]
+		switch {
+		case !inCurrent:
+			diffs = append(diffs, PropertyDiff{Path: path, Desired: d})
+		case !inDesired:
+			diffs = append(diffs, PropertyDiff{Path: path, Current: c})
+		default:
+			diffs = append(diffs, diffStruct(path, reflect.ValueOf(c), reflect.ValueOf(d))...)
+		}
+	}
+	return diffs
+}
+
+func diffLivenessTests(current, desired []LivenessTest) []PropertyDiff {
+	index := func(tests []LivenessTest) map[string]LivenessTest {
+		m := make(map[string]LivenessTest, len(tests))
+		for _, t := range tests {
+			t.Links = nil
+			m[t.Name] = t
+		}
+		return m
+	}
+	cur, des := index(current), index(desired)
+	names := make([]string, 0, len(cur)+len(des))
+	for name := range cur {
+		names = append(names, name)
+	}
+	for name := range des {
+		if _, ok := cur[name]; !ok {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	var diffs []PropertyDiff
+	for _, name := range names {
+		path := fmt.Sprintf("LivenessTests[%s]", name)
+		c, inCurrent := cur[name]
+		d, inDesired := des[name]
+		switch {
+		case !inCurrent:
+			diffs = append(diffs, PropertyDiff{Path: path, Desired: d})
+		case !inDesired:
+			diffs = append(diffs, PropertyDiff{Path: path, Current: c})
+		default:
+			diffs = append(diffs, diffStruct(path, reflect.ValueOf(c), reflect.ValueOf(d))...)
+		}
+	}
+	return diffs
+}
+
+func diffStruct(prefix string, current, desired reflect.Value) []PropertyDiff {
+	var diffs []PropertyDiff
+	for i := 0; i < current.NumField(); i++ {
+		path := prefix + "." + current.Type().Field(i).Name
+		diffs = append(diffs, diffField(path, current.Field(i).Interface(), desired.Field(i).Interface())...)
+	}
+	return diffs
+}
+
+// diffField compares two values of a field. Nil and empty slices are equal, as are nil pointers and pointers to
+// equal values.
+func diffField(path string, current, desired interface{}) []PropertyDiff {
+	c, d := reflect.ValueOf(current), reflect.ValueOf(desired)
+	if c.Kind() == reflect.Slice && c.Len() == 0 && d.Len() == 0 {
+		return nil
+	}
+	if reflect.DeepEqual(current, desired) {
+		return nil
+	}
+	return []PropertyDiff{{Path: path, Current: current, Desired: desired}}
+}
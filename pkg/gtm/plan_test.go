@@ -0,0 +1,291 @@
+package gtm
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPlanProperty(t *testing.T) {
+	current := func() *Property {
+		return &Property{
+			Name:                 "www",
+			Type:                 "weighted-round-robin",
+			ScoreAggregationType: "worst",
+			HandoutMode:          "normal",
+			HandoutLimit:         1,
+			LastModified:         "2024-01-01T00:00:00.000+00:00",
+			Links:                []Link{{Rel: "self"}},
+			TrafficTargets: []TrafficTarget{
+				{DatacenterID: 3132, Enabled: true, Weight: 1, Servers: []string{"192.0.2.2", "192.0.2.1"}},
+				{DatacenterID: 3131, Enabled: true, Weight: 1, Servers: []string{"192.0.2.3"}},
+			},
+			LivenessTests: []LivenessTest{
+				{Name: "http", TestInterval: 60, TestObjectProtocol: "HTTP", Links: []Link{{Rel: "self"}}},
+			},
+		}
+	}
+
+	tests := map[string]struct {
+		desired   *Property
+		init      func(*Mock)
+		expected  *PropertyPlan
+		withError error
+	}{
+		"no changes": {
+			desired: func() *Property {
+				p := current()
+				p.Links, p.LastModified, p.LivenessTests[0].Links = nil, "", nil
+				p.TrafficTargets[0], p.TrafficTargets[1] = p.TrafficTargets[1], p.TrafficTargets[0]
+				p.TrafficTargets[1].Servers = []string{"192.0.2.1", "192.0.2.2"}
+				return p
+			}(),
+			init: func(m *Mock) {
+				m.On("GetProperty", mock.Anything, GetPropertyRequest{DomainName: "example.akadns.net", PropertyName: "www"}).
+					Return((*GetPropertyResponse)(current()), nil).Once()
+			},
+			expected: &PropertyPlan{DomainName: "example.akadns.net", Current: current()},
+		},
+		"changes": {
+			desired: func() *Property {
+				p := current()
+				p.HandoutLimit = 2
+				p.TrafficTargets = []TrafficTarget{
+					{DatacenterID: 3131, Enabled: true, Weight: 3, Servers: []string{"192.0.2.3"}},
+					{DatacenterID: 3133, Enabled: true, Weight: 1},
+				}
+				p.LivenessTests = []LivenessTest{
+					{Name: "http", TestInterval: 30, TestObjectProtocol: "HTTP"},
+					{Name: "tcp", TestObjectProtocol: "TCP"},
+				}
+				return p
+			}(),
+			init: func(m *Mock) {
+				m.On("GetProperty", mock.Anything, mock.Anything).Return((*GetPropertyResponse)(current()), nil).Once()
+			},
+			expected: &PropertyPlan{
+				DomainName: "example.akadns.net",
+				Current:    current(),
+				Diffs: []PropertyDiff{
+					{Path: "HandoutLimit", Current: 1, Desired: 2},
+					{Path: "TrafficTargets[3131].Weight", Current: float64(1), Desired: float64(3)},
+					{Path: "TrafficTargets[3132]", Current: TrafficTarget{DatacenterID: 3132, Enabled: true, Weight: 1, Servers: []string{"192.0.2.1", "192.0.2.2"}}},
+					{Path: "TrafficTargets[3133]", Desired: TrafficTarget{DatacenterID: 3133, Enabled: true, Weight: 1}},
+					{Path: "LivenessTests[http].TestInterval", Current: 60, Desired: 30},
+					{Path: "LivenessTests[tcp]", Desired: LivenessTest{Name: "tcp", TestObjectProtocol: "TCP"}},
+				},
+			},
+		},
+		"partial desired property": {
+			desired: &Property{
+				Name:                 "www",
+				Type:                 "weighted-round-robin",
+				ScoreAggregationType: "worst",
+				HandoutMode:          "normal",
+				TrafficTargets:       current().TrafficTargets,
+				LivenessTests:        []LivenessTest{{Name: "http", TestObjectProtocol: "HTTP"}},
+			},
+			init: func(m *Mock) {
+				m.On("GetProperty", mock.Anything, mock.Anything).Return((*GetPropertyResponse)(current()), nil).Once()
+			},
+			expected: &PropertyPlan{
+				DomainName: "example.akadns.net",
+				Current:    current(),
+				Diffs: []PropertyDiff{
+					{Path: "HandoutLimit", Current: 1, Desired: 0},
+					{Path: "LivenessTests[http].TestInterval", Current: 60, Desired: 0},
+				},
+			},
+		},
+		"new property": {
+			desired: current(),
+			init: func(m *Mock) {
+				m.On("GetProperty", mock.Anything, mock.Anything).Return(nil, &Error{StatusCode: http.StatusNotFound}).Once()
+			},
+			expected: &PropertyPlan{DomainName: "example.akadns.net"},
+		},
+		"get property fails": {
+			desired: current(),
+			init: func(m *Mock) {
+				m.On("GetProperty", mock.Anything, mock.Anything).Return(nil, &Error{StatusCode: http.StatusInternalServerError}).Once()
+			},
+			withError: ErrPlanProperty,
+		},
+		"validation error": {
+			withError: ErrStructValidation,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			m := &Mock{}
+			if test.init != nil {
+				test.init(m)
+			}
+
+			plan, err := PlanProperty(context.Background(), m, PlanPropertyRequest{DomainName: "example.akadns.net", Desired: test.desired})
+			m.AssertExpectations(t)
+			if test.withError != nil {
+				assert.True(t, errors.Is(err, test.withError), "want: %s; got: %s", test.withError, err)
+				return
+			}
+			require.NoError(t, err)
+			test.expected.Desired = test.desired
+			assert.Equal(t, test.expected, plan)
+			assert.Equal(t, test.expected.Current == nil || len(test.expected.Diffs) > 0, plan.HasChanges())
+		})
+	}
+}
+
+func TestApplyPropertyPlan(t *testing.T) {
+	desired := &Property{Name: "www", Type: "failover", ScoreAggregationType: "worst", HandoutMode: "normal"}
+	status := &ResponseStatus{ChangeID: "abc", PropagationStatus: PropagationStatusPending}
+
+	tests := map[string]struct {
+		plan      *PropertyPlan
+		init      func(*Mock)
+		expected  *ResponseStatus
+		withError error
+	}{
+		"create": {
+			plan: &PropertyPlan{DomainName: "example.akadns.net", Desired: desired},
+			init: func(m *Mock) {
+				m.On("CreateProperty", mock.Anything, CreatePropertyRequest{DomainName: "example.akadns.net", Property: desired}).
+					Return(&CreatePropertyResponse{Status: status}, nil).Once()
+			},
+			expected: status,
+		},
+		"update": {
+			plan: &PropertyPlan{DomainName: "example.akadns.net", Current: &Property{Name: "www"}, Desired: desired,
+				Diffs: []PropertyDiff{{Path: "Type", Current: "", Desired: "failover"}}},
+			init: func(m *Mock) {
+				m.On("UpdateProperty", mock.Anything, UpdatePropertyRequest{DomainName: "example.akadns.net", Property: desired}).
+					Return(&UpdatePropertyResponse{Status: status}, nil).Once()
+			},
+			expected: status,
+		},
+		"no changes": {
+			plan:     &PropertyPlan{DomainName: "example.akadns.net", Current: desired, Desired: desired},
+			expected: &ResponseStatus{PropagationStatus: PropagationStatusComplete},
+		},
+		"update fails": {
+			plan: &PropertyPlan{DomainName: "example.akadns.net", Current: &Property{Name: "www"}, Desired: desired,
+				Diffs: []PropertyDiff{{Path: "Type"}}},
+			init: func(m *Mock) {
+				m.On("UpdateProperty", mock.Anything, mock.Anything).Return(nil, &Error{StatusCode: http.StatusBadRequest}).Once()
+			},
+			withError: ErrApplyPropertyPlan,
+		},
+		"validation error": {
+			plan:      &PropertyPlan{Desired: desired},
+			withError: ErrStructValidation,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			m := &Mock{}
+			if test.init != nil {
+				test.init(m)
+			}
+
+			result, err := ApplyPropertyPlan(context.Background(), m, ApplyPropertyPlanRequest{Plan: test.plan})
+			m.AssertExpectations(t)
+			if test.withError != nil {
+				assert.True(t, errors.Is(err, test.withError), "want: %s; got: %s", test.withError, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, test.expected, result)
+		})
+	}
+}
+
+func TestWaitForPropagation(t *testing.T) {
+	statusRequest := GetDomainStatusRequest{DomainName: "example.akadns.net"}
+	pending := &GetDomainStatusResponse{PropagationStatus: PropagationStatusPending}
+
+	tests := map[string]struct {
+		params    WaitForPropagationRequest
+		init      func(*Mock)
+		expected  *ResponseStatus
+		withError []error
+	}{
+		"complete": {
+			params: WaitForPropagationRequest{DomainName: "example.akadns.net", PollInterval: time.Millisecond},
+			init: func(m *Mock) {
+				m.On("GetDomainStatus", mock.Anything, statusRequest).Return(pending, nil).Twice()
+				m.On("GetDomainStatus", mock.Anything, statusRequest).
+					Return(&GetDomainStatusResponse{PropagationStatus: PropagationStatusComplete, ChangeID: "abc"}, nil).Once()
+			},
+			expected: &ResponseStatus{PropagationStatus: PropagationStatusComplete, ChangeID: "abc"},
+		},
+		"denied": {
+			params: WaitForPropagationRequest{DomainName: "example.akadns.net", PollInterval: time.Millisecond},
+			init: func(m *Mock) {
+				m.On("GetDomainStatus", mock.Anything, statusRequest).
+					Return(&GetDomainStatusResponse{PropagationStatus: PropagationStatusDenied, Message: "Invalid map"}, nil).Once()
+			},
+			expected:  &ResponseStatus{PropagationStatus: PropagationStatusDenied, Message: "Invalid map"},
+			withError: []error{ErrWaitForPropagation, ErrPropagationDenied},
+		},
+		"timeout": {
+			params: WaitForPropagationRequest{DomainName: "example.akadns.net", PollInterval: time.Hour, Timeout: 10 * time.Millisecond},
+			init: func(m *Mock) {
+				m.On("GetDomainStatus", mock.Anything, statusRequest).Return(pending, nil).Once()
+			},
+			expected:  (*ResponseStatus)(pending),
+			withError: []error{ErrWaitForPropagation, ErrPropagationTimeout, context.DeadlineExceeded},
+		},
+		"status fails": {
+			params: WaitForPropagationRequest{DomainName: "example.akadns.net"},
+			init: func(m *Mock) {
+				m.On("GetDomainStatus", mock.Anything, statusRequest).Return(nil, &Error{StatusCode: http.StatusForbidden}).Once()
+			},
+			withError: []error{ErrWaitForPropagation},
+		},
+		"validation error": {
+			params:    WaitForPropagationRequest{PollInterval: -time.Second},
+			withError: []error{ErrStructValidation},
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			m := &Mock{}
+			if test.init != nil {
+				test.init(m)
+			}
+
+			status, err := WaitForPropagation(context.Background(), m, test.params)
+			m.AssertExpectations(t)
+			assert.Equal(t, test.expected, status)
+			if test.withError != nil {
+				for _, e := range test.withError {
+					assert.True(t, errors.Is(err, e), "want: %s; got: %s", e, err)
+				}
+				return
+			}
+			require.NoError(t, err)
+		})
+	}
+}
+
+func TestPropagationError(t *testing.T) {
+	err := error(&PropagationError{
+		DomainName: "example.akadns.net",
+		Status:     &ResponseStatus{PropagationStatus: PropagationStatusDenied, Message: "Invalid map"},
+		Err:        ErrPropagationDenied,
+	})
+	assert.Equal(t, `wait for propagation: domain "example.akadns.net": propagation denied: Invalid map`, err.Error())
+
+	var propagationErr *PropagationError
+	require.True(t, errors.As(err, &propagationErr))
+	assert.Equal(t, "Invalid map", propagationErr.Status.Message)
+}
@@ -0,0 +1,335 @@
+package gtm
+
+import (
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"net/netip"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/akamai/AkamaiOPEN-edgegrid-golang/v11/pkg/edgegriderr"
+	validation "github.com/go-ozzo/ozzo-validation/v4"
+)
+
+type (
+	// SimulateTrafficRequest contains parameters for SimulateTraffic
+	SimulateTrafficRequest struct {
+		Property *Property
+		// GeoMap, CIDRMap and ASMap is the map named by Property.MapName. One of them is required for geographic,
+		// cidrmapping and asmapping properties respectively.
+		GeoMap  *GeoMap
+		CIDRMap *CIDRMap
+		ASMap   *ASMap
+		Client  SimulatedClient
+		// Down marks datacenters whose liveness tests fail. Datacenters which are not listed are up.
+		Down map[int]bool
+	}
+
+	// SimulatedClient describes the resolver asking for the property. IP is used by cidrmapping properties and to
+	// pick an answer of weighted properties, Country by geographic properties and ASN by asmapping properties.
+	SimulatedClient struct {
+		IP      netip.Addr
+		Country string
+		ASN     int64
+	}
+
+	// TrafficSimulation is the result of SimulateTraffic
+	TrafficSimulation struct {
+		// DatacenterID is the datacenter handed out to the client. It is 0 when a backup is handed out.
+		DatacenterID int
+		// Servers are the addresses handed out, limited by the handout mode and limit of the property
+		Servers []string
+		// HandoutCName is handed out instead of Servers when the traffic target has one
+		HandoutCName string
+		// Backup is set when no datacenter is live and the backup CNAME or IP of the property is handed out
+		Backup bool
+		// FailOpen is set when no datacenter is live and there is no backup, so the datacenters are treated as live
+		FailOpen bool
+		// Shares is the expected share of answers per datacenter ID over many clients
+		Shares map[int]float64
+		// Reason explains the decision
+		Reason string
+	}
+)
+
+const (
+	// PropertyTypeWeightedRoundRobin distributes answers by weight
+	PropertyTypeWeightedRoundRobin = "weighted-round-robin"
+	// PropertyTypeWeightedRoundRobinLoadFeedback distributes answers by weight adjusted with load feedback
+	PropertyTypeWeightedRoundRobinLoadFeedback = "weighted-round-robin-load-feedback"
+	// PropertyTypeWeightedHashed distributes answers by weight, always giving a client the same answer
+	PropertyTypeWeightedHashed = "weighted-hashed"
+	// PropertyTypeFailover hands out the live traffic target with the highest weight
+	PropertyTypeFailover = "failover"
+	// PropertyTypeRankedFailover hands out the live traffic targets with the lowest precedence
+	PropertyTypeRankedFailover = "ranked-failover"
+	// PropertyTypeGeographic maps client countries to datacenters with a GeoMap
+	PropertyTypeGeographic = "geographic"
+	// PropertyTypeCIDRMapping maps client addresses to datacenters with a CIDRMap
+	PropertyTypeCIDRMapping = "cidrmapping"
+	// PropertyTypeASMapping maps client autonomous systems to datacenters with an ASMap
+	PropertyTypeASMapping = "asmapping"
+)
+
+var (
+	// ErrSimulateTraffic is returned when SimulateTraffic fails
+	ErrSimulateTraffic = errors.New("simulate traffic")
+	// ErrUnsupportedPropertyType is returned when SimulateTraffic does not support the type of the property
+	ErrUnsupportedPropertyType = errors.New("unsupported property type")
+)
+
+// Validate validates SimulateTrafficRequest
+func (r SimulateTrafficRequest) Validate() error {
+	errs := validation.Errors{
+		"Property": validation.Validate(r.Property, validation.Required),
+	}
+	if r.Property != nil {
+		switch r.Property.Type {
+		case PropertyTypeGeographic:
+			errs["GeoMap"] = validation.Validate(r.GeoMap, validation.Required)
+			errs["Client.Country"] = validation.Validate(r.Client.Country, validation.Required)
+		case PropertyTypeCIDRMapping:
+			errs["CIDRMap"] = validation.Validate(r.CIDRMap, validation.Required)
+			errs["Client.IP"] = validation.Validate(r.Client.IP, validation.By(validateClientIP))
+		case PropertyTypeWeightedRoundRobin, PropertyTypeWeightedRoundRobinLoadFeedback, PropertyTypeWeightedHashed,
+			PropertyTypeRankedFailover:
+			errs["Client.IP"] = validation.Validate(r.Client.IP, validation.By(validateClientIP))
+		case PropertyTypeASMapping:
+			errs["ASMap"] = validation.Validate(r.ASMap, validation.Required)
+			errs["Client.ASN"] = validation.Validate(r.Client.ASN, validation.Required)
+		}
+	}
+	return edgegriderr.ParseValidationErrors(errs)
+}
+
+// validateClientIP checks that a client IP is set, as netip.Addr zero values are not blank to the validation package
+func validateClientIP(value interface{}) error {
+	if ip, _ := value.(netip.Addr); !ip.IsValid() {
+		return errors.New("invalid client IP")
+	}
+	return nil
+}
+
+// SimulateTraffic computes offline which datacenter and servers a property hands out to a client, given the
+// liveness of datacenters. It supports weighted, failover, ranked-failover, geographic, cidrmapping and asmapping
+// properties and can be used to preview the effect of changing weights, precedences or maps.
+//
+// The simulation follows these rules:
+//   - only enabled traffic targets of datacenters which are not down are considered;
+//   - weighted properties split answers by weight, and a client gets an answer chosen by hashing its IP;
+//   - failover properties hand out the target with the highest weight, and ranked-failover properties split answers
+//     by weight between the targets with the lowest precedence which have a weight;
+//   - map properties hand out the target of the assigned datacenter, or of the default datacenter of the map when the
+//     assigned one is down or the client is not assigned;
+//   - when no target is live, the backup CNAME or IP is handed out, or, without a backup, the property fails open and
+//     targets are handed out as if they were live.
+//
+// Server liveness, load feedback and performance measurements are not simulated.
+func SimulateTraffic(params SimulateTrafficRequest) (*TrafficSimulation, error) {
+	if err := params.Validate(); err != nil {
+		return nil, fmt.Errorf("%s: %w: %s", ErrSimulateTraffic, ErrStructValidation, err)
+	}
+
+	sim, err := simulate(params, params.Down)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrSimulateTraffic, err)
+	}
+	if sim != nil {
+		return sim, nil
+	}
+
+	p := params.Property
+	if p.BackupCName != "" || p.BackupIP != "" {
+		sim := &TrafficSimulation{Backup: true, HandoutCName: p.BackupCName, Reason: "no live datacenter, backup handed out"}
+		if p.BackupCName == "" {
+			sim.Servers = []string{p.BackupIP}
+		}
+		return sim, nil
+	}
+	sim, err = simulate(params, nil)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrSimulateTraffic, err)
+	}
+	if sim == nil {
+		return nil, fmt.Errorf("%w: no enabled traffic target", ErrSimulateTraffic)
+	}
+	sim.FailOpen = true
+	sim.Reason = "no live datacenter, failing open: " + sim.Reason
+	return sim, nil
+}
+
+// simulate returns nil when no live traffic target can be handed out
+func simulate(params SimulateTrafficRequest, down map[int]bool) (*TrafficSimulation, error) {
+	p := params.Property
+	live := make(map[int]TrafficTarget, len(p.TrafficTargets))
+	var order []int
+	for _, t := range p.TrafficTargets {
+		if t.Enabled && !down[t.DatacenterID] {
+			live[t.DatacenterID] = t
+			order = append(order, t.DatacenterID)
+		}
+	}
+
+	switch p.Type {
+	case PropertyTypeWeightedRoundRobin, PropertyTypeWeightedRoundRobinLoadFeedback, PropertyTypeWeightedHashed:
+		return handoutWeighted(p, params.Client, live, order, "weighted split"), nil
+
+	case PropertyTypeFailover:
+		var best *TrafficTarget
+		for _, id := range order {
+			t := live[id]
+			if best == nil || t.Weight > best.Weight {
+				best = &t
+			}
+		}
+		if best == nil {
+			return nil, nil
+		}
+		sim := handout(p, *best, fmt.Sprintf("live target with the highest weight %g", best.Weight))
+		sim.Shares = map[int]float64{best.DatacenterID: 1}
+		return sim, nil
+
+	case PropertyTypeRankedFailover:
+		precedence := func(id int) int {
+			if live[id].Precedence == nil {
+				return 0
+			}
+			return *live[id].Precedence
+		}
+		ranks := make([]int, 0, len(order))
+		for _, id := range order {
+			ranks = append(ranks, precedence(id))
+		}
+		slices.Sort(ranks)
+		for _, rank := range slices.Compact(ranks) {
+			ranked := slices.DeleteFunc(slices.Clone(order), func(id int) bool { return precedence(id) != rank })
+			if sim := handoutWeighted(p, params.Client, live, ranked, fmt.Sprintf("live targets with precedence %d", rank)); sim != nil {
+				return sim, nil
+			}
+		}
+		return nil, nil
+
+	case PropertyTypeGeographic:
+		assigned, mapped := 0, false
+		for _, a := range params.GeoMap.Assignments {
+			if slices.ContainsFunc(a.Countries, func(c string) bool { return strings.EqualFold(c, params.Client.Country) }) {
+				assigned, mapped = a.DatacenterID, true
+				break
+			}
+		}
+		return handoutMapped(p, live, params.GeoMap.DefaultDatacenter, assigned, mapped,
+			fmt.Sprintf("country %s", strings.ToUpper(params.Client.Country))), nil
+
+	case PropertyTypeCIDRMapping:
+		assigned, mapped, bits := 0, false, -1
+		for _, a := range params.CIDRMap.Assignments {
+			for _, block := range a.Blocks {
+				prefix, err := netip.ParsePrefix(block)
+				if err != nil {
+					return nil, fmt.Errorf("cidr map %q: %w", params.CIDRMap.Name, err)
+				}
+				if prefix.Contains(params.Client.IP) && prefix.Bits() > bits {
+					assigned, mapped, bits = a.DatacenterID, true, prefix.Bits()
+				}
+			}
+		}
+		return handoutMapped(p, live, params.CIDRMap.DefaultDatacenter, assigned, mapped,
+			fmt.Sprintf("address %s", params.Client.IP)), nil
+
+	case PropertyTypeASMapping:
+		assigned, mapped := 0, false
+		for _, a := range params.ASMap.Assignments {
+			if slices.Contains(a.ASNumbers, params.Client.ASN) {
+				assigned, mapped = a.DatacenterID, true
+				break
+			}
+		}
+		return handoutMapped(p, live, params.ASMap.DefaultDatacenter, assigned, mapped,
+			fmt.Sprintf("AS%d", params.Client.ASN)), nil
+	}
+
+	return nil, fmt.Errorf("%w: %q", ErrUnsupportedPropertyType, p.Type)
+}
+
+// handoutWeighted splits answers between the given targets by weight and picks the answer of the client
+func handoutWeighted(p *Property, client SimulatedClient, live map[int]TrafficTarget, ids []int, reason string) *TrafficSimulation {
+	var total float64
+	for _, id := range ids {
+		total += live[id].Weight
+	}
+	if total == 0 {
+		return nil
+	}
+
+	shares := make(map[int]float64, len(ids))
+	sorted := slices.Clone(ids)
+	sort.Ints(sorted)
+	for _, id := range sorted {
+		if w := live[id].Weight; w > 0 {
+			shares[id] = w / total
+		}
+	}
+
+	h := fnv.New64a()
+	h.Write(client.IP.AsSlice())
+	point := float64(h.Sum64()%1_000_000) / 1_000_000 * total
+	chosen := sorted[len(sorted)-1]
+	for _, id := range sorted {
+		if w := live[id].Weight; w > 0 {
+			if point < w {
+				chosen = id
+				break
+			}
+			point -= w
+		}
+	}
+
+	sim := handout(p, live[chosen], reason)
+	sim.Shares = shares
+	return sim
+}
+
+// handoutMapped hands out the target of the assigned datacenter, or of the default datacenter of the map
+func handoutMapped(p *Property, live map[int]TrafficTarget, defaultDC *DatacenterBase, assigned int, mapped bool, client string) *TrafficSimulation {
+	if mapped {
+		if t, ok := live[assigned]; ok {
+			sim := handout(p, t, fmt.Sprintf("%s is assigned to datacenter %d", client, assigned))
+			sim.Shares = map[int]float64{assigned: 1}
+			return sim
+		}
+	}
+	if defaultDC == nil {
+		return nil
+	}
+	t, ok := live[defaultDC.DatacenterID]
+	if !ok {
+		return nil
+	}
+	reason := fmt.Sprintf("%s is not assigned, default datacenter %d", client, defaultDC.DatacenterID)
+	if mapped {
+		reason = fmt.Sprintf("datacenter %d assigned to %s is down, default datacenter %d", assigned, client, defaultDC.DatacenterID)
+	}
+	sim := handout(p, t, reason)
+	sim.Shares = map[int]float64{defaultDC.DatacenterID: 1}
+	return sim
+}
+
+// handout returns the answer of a traffic target, applying the handout mode and limit of the property
+func handout(p *Property, t TrafficTarget, reason string) *TrafficSimulation {
+	sim := &TrafficSimulation{DatacenterID: t.DatacenterID, Reason: reason}
+	if t.HandoutCName != "" {
+		sim.HandoutCName = t.HandoutCName
+		return sim
+	}
+	servers := slices.Clone(t.Servers)
+	switch {
+	case p.HandoutMode == "one-ip" && len(servers) > 1:
+		servers = servers[:1]
+	case p.HandoutLimit > 0 && len(servers) > p.HandoutLimit:
+		servers = servers[:p.HandoutLimit]
+	}
+	sim.Servers = servers
+	return sim
+}
@@ -0,0 +1,246 @@
+package gtm
+
+import (
+	"errors"
+	"net/netip"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSimulateTraffic(t *testing.T) {
+	property := func(propertyType string, targets ...TrafficTarget) *Property {
+		return &Property{
+			Name:                 "www",
+			Type:                 propertyType,
+			ScoreAggregationType: "worst",
+			HandoutMode:          "normal",
+			HandoutLimit:         2,
+			TrafficTargets:       targets,
+		}
+	}
+	precedence := func(p int) *int { return &p }
+	dc1 := TrafficTarget{DatacenterID: 3131, Enabled: true, Weight: 1, Servers: []string{"192.0.2.1", "192.0.2.2", "192.0.2.3"}}
+	dc2 := TrafficTarget{DatacenterID: 3132, Enabled: true, Weight: 3, Servers: []string{"198.51.100.1"}}
+	dc3 := TrafficTarget{DatacenterID: 3133, Enabled: true, HandoutCName: "origin.example.com"}
+	disabled := TrafficTarget{DatacenterID: 3134, Weight: 100, Servers: []string{"203.0.113.1"}}
+	client := SimulatedClient{IP: netip.MustParseAddr("192.0.2.200"), Country: "pl", ASN: 64496}
+
+	geoMap := &GeoMap{
+		Name:              "geo",
+		DefaultDatacenter: &DatacenterBase{DatacenterID: 3133},
+		Assignments: []GeoAssignment{
+			{DatacenterBase: DatacenterBase{DatacenterID: 3131}, Countries: []string{"DE", "PL"}},
+			{DatacenterBase: DatacenterBase{DatacenterID: 3132}, Countries: []string{"US"}},
+		},
+	}
+	cidrMap := &CIDRMap{
+		Name:              "cidr",
+		DefaultDatacenter: &DatacenterBase{DatacenterID: 3133},
+		Assignments: []CIDRAssignment{
+			{DatacenterBase: DatacenterBase{DatacenterID: 3131}, Blocks: []string{"192.0.2.0/24"}},
+			{DatacenterBase: DatacenterBase{DatacenterID: 3132}, Blocks: []string{"192.0.2.128/25"}},
+		},
+	}
+	asMap := &ASMap{
+		Name:              "as",
+		DefaultDatacenter: &DatacenterBase{DatacenterID: 3133},
+		Assignments: []ASAssignment{
+			{DatacenterBase: DatacenterBase{DatacenterID: 3132}, ASNumbers: []int64{64496, 64497}},
+		},
+	}
+
+	tests := map[string]struct {
+		params      SimulateTrafficRequest
+		expected    *TrafficSimulation
+		withError   error
+		withMessage string
+	}{
+		"weighted": {
+			params: SimulateTrafficRequest{Property: property(PropertyTypeWeightedRoundRobin, dc1, dc2, disabled), Client: client},
+			expected: &TrafficSimulation{
+				DatacenterID: 3132,
+				Servers:      []string{"198.51.100.1"},
+				Shares:       map[int]float64{3131: 0.25, 3132: 0.75},
+				Reason:       "weighted split",
+			},
+		},
+		"weighted, datacenter down": {
+			params: SimulateTrafficRequest{Property: property(PropertyTypeWeightedHashed, dc1, dc2), Client: client, Down: map[int]bool{3132: true}},
+			expected: &TrafficSimulation{
+				DatacenterID: 3131,
+				Servers:      []string{"192.0.2.1", "192.0.2.2"},
+				Shares:       map[int]float64{3131: 1},
+				Reason:       "weighted split",
+			},
+		},
+		"failover": {
+			params: SimulateTrafficRequest{Property: property(PropertyTypeFailover, dc1, dc2), Client: client},
+			expected: &TrafficSimulation{
+				DatacenterID: 3132,
+				Servers:      []string{"198.51.100.1"},
+				Shares:       map[int]float64{3132: 1},
+				Reason:       "live target with the highest weight 3",
+			},
+		},
+		"failover to backup target": {
+			params: SimulateTrafficRequest{Property: property(PropertyTypeFailover, dc1, dc2), Down: map[int]bool{3132: true}},
+			expected: &TrafficSimulation{
+				DatacenterID: 3131,
+				Servers:      []string{"192.0.2.1", "192.0.2.2"},
+				Shares:       map[int]float64{3131: 1},
+				Reason:       "live target with the highest weight 1",
+			},
+		},
+		"ranked failover": {
+			params: SimulateTrafficRequest{
+				Property: func() *Property {
+					p := property(PropertyTypeRankedFailover,
+						TrafficTarget{DatacenterID: 3131, Enabled: true, Weight: 1, Precedence: precedence(10), Servers: []string{"192.0.2.1"}},
+						TrafficTarget{DatacenterID: 3132, Enabled: true, Weight: 1, Precedence: precedence(20), Servers: []string{"198.51.100.1"}},
+						TrafficTarget{DatacenterID: 3133, Enabled: true, Weight: 1, Precedence: precedence(20), Servers: []string{"203.0.113.1"}},
+						TrafficTarget{DatacenterID: 3134, Enabled: true, Precedence: precedence(0), Servers: []string{"203.0.113.2"}},
+					)
+					p.HandoutMode = "one-ip"
+					return p
+				}(),
+				Client: client,
+				Down:   map[int]bool{3131: true},
+			},
+			expected: &TrafficSimulation{
+				DatacenterID: 3132,
+				Servers:      []string{"198.51.100.1"},
+				Shares:       map[int]float64{3132: 0.5, 3133: 0.5},
+				Reason:       "live targets with precedence 20",
+			},
+		},
+		"geographic": {
+			params: SimulateTrafficRequest{Property: property(PropertyTypeGeographic, dc1, dc2, dc3), GeoMap: geoMap, Client: client},
+			expected: &TrafficSimulation{
+				DatacenterID: 3131,
+				Servers:      []string{"192.0.2.1", "192.0.2.2"},
+				Shares:       map[int]float64{3131: 1},
+				Reason:       "country PL is assigned to datacenter 3131",
+			},
+		},
+		"geographic, assigned datacenter down": {
+			params: SimulateTrafficRequest{Property: property(PropertyTypeGeographic, dc1, dc2, dc3), GeoMap: geoMap, Client: client,
+				Down: map[int]bool{3131: true}},
+			expected: &TrafficSimulation{
+				DatacenterID: 3133,
+				HandoutCName: "origin.example.com",
+				Shares:       map[int]float64{3133: 1},
+				Reason:       "datacenter 3131 assigned to country PL is down, default datacenter 3133",
+			},
+		},
+		"cidr mapping, longest prefix": {
+			params: SimulateTrafficRequest{Property: property(PropertyTypeCIDRMapping, dc1, dc2, dc3), CIDRMap: cidrMap, Client: client},
+			expected: &TrafficSimulation{
+				DatacenterID: 3132,
+				Servers:      []string{"198.51.100.1"},
+				Shares:       map[int]float64{3132: 1},
+				Reason:       "address 192.0.2.200 is assigned to datacenter 3132",
+			},
+		},
+		"cidr mapping, not assigned": {
+			params: SimulateTrafficRequest{Property: property(PropertyTypeCIDRMapping, dc1, dc2, dc3), CIDRMap: cidrMap,
+				Client: SimulatedClient{IP: netip.MustParseAddr("2001:db8::1")}},
+			expected: &TrafficSimulation{
+				DatacenterID: 3133,
+				HandoutCName: "origin.example.com",
+				Shares:       map[int]float64{3133: 1},
+				Reason:       "address 2001:db8::1 is not assigned, default datacenter 3133",
+			},
+		},
+		"as mapping": {
+			params: SimulateTrafficRequest{Property: property(PropertyTypeASMapping, dc1, dc2, dc3), ASMap: asMap, Client: client},
+			expected: &TrafficSimulation{
+				DatacenterID: 3132,
+				Servers:      []string{"198.51.100.1"},
+				Shares:       map[int]float64{3132: 1},
+				Reason:       "AS64496 is assigned to datacenter 3132",
+			},
+		},
+		"all down, backup ip": {
+			params: SimulateTrafficRequest{
+				Property: func() *Property {
+					p := property(PropertyTypeFailover, dc1, dc2)
+					p.BackupIP = "203.0.113.53"
+					return p
+				}(),
+				Down: map[int]bool{3131: true, 3132: true},
+			},
+			expected: &TrafficSimulation{Backup: true, Servers: []string{"203.0.113.53"}, Reason: "no live datacenter, backup handed out"},
+		},
+		"all down, fail open": {
+			params: SimulateTrafficRequest{Property: property(PropertyTypeASMapping, dc2, dc3), ASMap: asMap, Client: client,
+				Down: map[int]bool{3132: true, 3133: true}},
+			expected: &TrafficSimulation{
+				DatacenterID: 3132,
+				Servers:      []string{"198.51.100.1"},
+				FailOpen:     true,
+				Shares:       map[int]float64{3132: 1},
+				Reason:       "no live datacenter, failing open: AS64496 is assigned to datacenter 3132",
+			},
+		},
+		"unsupported type": {
+			params:    SimulateTrafficRequest{Property: property("qtr", dc1)},
+			withError: ErrUnsupportedPropertyType,
+		},
+		"weighted, missing client IP": {
+			params:      SimulateTrafficRequest{Property: property(PropertyTypeWeightedHashed, dc1, dc2)},
+			withError:   ErrStructValidation,
+			withMessage: "Client.IP: invalid client IP",
+		},
+		"cidr mapping, missing client IP": {
+			params:      SimulateTrafficRequest{Property: property(PropertyTypeCIDRMapping, dc1), CIDRMap: cidrMap},
+			withError:   ErrStructValidation,
+			withMessage: "Client.IP: invalid client IP",
+		},
+		"missing map": {
+			params:    SimulateTrafficRequest{Property: property(PropertyTypeCIDRMapping, dc1)},
+			withError: ErrStructValidation,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			sim, err := SimulateTraffic(test.params)
+			if test.withError != nil {
+				assert.True(t, errors.Is(err, test.withError), "want: %s; got: %s", test.withError, err)
+				assert.Contains(t, err.Error(), test.withMessage)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, test.expected, sim)
+		})
+	}
+}
+
+func TestSimulateTrafficWeightedDistribution(t *testing.T) {
+	p := &Property{
+		Name:                 "www",
+		Type:                 PropertyTypeWeightedHashed,
+		ScoreAggregationType: "worst",
+		HandoutMode:          "normal",
+		TrafficTargets: []TrafficTarget{
+			{DatacenterID: 1, Enabled: true, Weight: 20},
+			{DatacenterID: 2, Enabled: true, Weight: 80},
+		},
+	}
+
+	counts := map[int]int{}
+	for i := 0; i < 1000; i++ {
+		ip := netip.AddrFrom4([4]byte{10, byte(i >> 8), byte(i), 1})
+		sim, err := SimulateTraffic(SimulateTrafficRequest{Property: p, Client: SimulatedClient{IP: ip}})
+		require.NoError(t, err)
+		counts[sim.DatacenterID]++
+
+		again, err := SimulateTraffic(SimulateTrafficRequest{Property: p, Client: SimulatedClient{IP: ip}})
+		require.NoError(t, err)
+		assert.Equal(t, sim.DatacenterID, again.DatacenterID)
+	}
+	assert.InDelta(t, 200, counts[1], 60)
+	assert.InDelta(t, 800, counts[2], 60)
+}
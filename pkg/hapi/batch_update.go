@@ -0,0 +1,313 @@
+package hapi
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/akamai/AkamaiOPEN-edgegrid-golang/v11/pkg/edgegriderr"
+	"github.com/akamai/AkamaiOPEN-edgegrid-golang/v11/pkg/operation"
+	validation "github.com/go-ozzo/ozzo-validation/v4"
+)
+
+type (
+	// BatchUpdateEdgeHostnamesRequest is used to apply the same patch to many edge hostnames
+	BatchUpdateEdgeHostnamesRequest struct {
+		EdgeHostnames []BatchEdgeHostname
+		// Body is the patch applied to every edge hostname. Its paths are validated as in UpdateEdgeHostname.
+		Body              []UpdateEdgeHostnameRequestBody
+		Comments          string
+		StatusUpdateEmail []string
+		// Concurrency limits the number of edge hostnames updated at the same time. It defaults to DefaultBatchConcurrency.
+		Concurrency int
+		// DryRun only reports the planned updates. Edge hostnames with an ID are fetched to skip those which
+		// already have the patched values.
+		DryRun bool
+		// Rollback restores the previous values of updated edge hostnames when any update fails.
+		// It requires the ID of every edge hostname.
+		Rollback bool
+		// PollInterval is the interval between change request checks. It defaults to DefaultChangeRequestPollInterval.
+		PollInterval time.Duration
+	}
+
+	// BatchEdgeHostname identifies an edge hostname updated by BatchUpdateEdgeHostnames
+	BatchEdgeHostname struct {
+		DNSZone    string
+		RecordName string
+		// EdgeHostnameID is used to read the current values of the edge hostname. Required for rollback.
+		EdgeHostnameID int
+	}
+
+	// BatchUpdateEdgeHostnamesResult is a summary of BatchUpdateEdgeHostnames
+	BatchUpdateEdgeHostnamesResult struct {
+		// Results are in the order of the requested edge hostnames
+		Results    []EdgeHostnameUpdateResult
+		Planned    int
+		Unchanged  int
+		Succeeded  int
+		Failed     int
+		RolledBack int
+	}
+
+	// EdgeHostnameUpdateResult is the result of updating a single edge hostname
+	EdgeHostnameUpdateResult struct {
+		EdgeHostname BatchEdgeHostname
+		Status       EdgeHostnameUpdateStatus
+		// ChangeID is the change request of the update
+		ChangeID int
+		// RollbackChangeID is the change request restoring the previous values
+		RollbackChangeID int
+		// Previous are the operations which restore the values the edge hostname had before the update
+		Previous []UpdateEdgeHostnameRequestBody
+		Err      error
+	}
+
+	// EdgeHostnameUpdateStatus is the status of an edge hostname in a batch update
+	EdgeHostnameUpdateStatus string
+)
+
+const (
+	// EdgeHostnameUpdatePlanned is the status of edge hostnames which would be updated in a dry run
+	EdgeHostnameUpdatePlanned EdgeHostnameUpdateStatus = "PLANNED"
+	// EdgeHostnameUpdateUnchanged is the status of edge hostnames which already have the patched values
+	EdgeHostnameUpdateUnchanged EdgeHostnameUpdateStatus = "UNCHANGED"
+	// EdgeHostnameUpdateSucceeded is the status of updated edge hostnames
+	EdgeHostnameUpdateSucceeded EdgeHostnameUpdateStatus = "SUCCEEDED"
+	// EdgeHostnameUpdateFailed is the status of edge hostnames which could not be updated
+	EdgeHostnameUpdateFailed EdgeHostnameUpdateStatus = "FAILED"
+	// EdgeHostnameUpdateRolledBack is the status of updated edge hostnames whose previous values were restored
+	EdgeHostnameUpdateRolledBack EdgeHostnameUpdateStatus = "ROLLED_BACK"
+	// EdgeHostnameUpdateRollbackFailed is the status of updated edge hostnames whose previous values could not be restored
+	EdgeHostnameUpdateRollbackFailed EdgeHostnameUpdateStatus = "ROLLBACK_FAILED"
+
+	// ChangeRequestStatusPending is the status of a change request which is not processed yet
+	ChangeRequestStatusPending = "PENDING"
+	// ChangeRequestStatusSucceeded is the status of a completed change request
+	ChangeRequestStatusSucceeded = "SUCCEEDED"
+	// ChangeRequestStatusFailed is the status of a failed change request
+	ChangeRequestStatusFailed = "FAILED"
+
+	// DefaultBatchConcurrency is the number of edge hostnames updated at the same time
+	DefaultBatchConcurrency = 4
+	// DefaultChangeRequestPollInterval is the interval between change request checks
+	DefaultChangeRequestPollInterval = 10 * time.Second
+)
+
+var (
+	// ErrBatchUpdateEdgeHostnames is returned when BatchUpdateEdgeHostnames fails
+	ErrBatchUpdateEdgeHostnames = errors.New("batch update edge hostnames")
+	// ErrChangeRequestFailed is returned when a change request fails
+	ErrChangeRequestFailed = errors.New("change request failed")
+)
+
+// Validate validates BatchUpdateEdgeHostnamesRequest
+func (r BatchUpdateEdgeHostnamesRequest) Validate() error {
+	return edgegriderr.ParseValidationErrors(validation.Errors{
+		"EdgeHostnames": validation.Validate(r.EdgeHostnames, validation.Required),
+		"Body":          validation.Validate(r.Body, validation.Required),
+		"Concurrency":   validation.Validate(r.Concurrency, validation.Min(0)),
+		"PollInterval":  validation.Validate(r.PollInterval, validation.Min(time.Duration(0))),
+	})
+}
+
+// Validate validates BatchEdgeHostname
+func (e BatchEdgeHostname) Validate() error {
+	return validation.Errors{
+		"DNSZone":    validation.Validate(e.DNSZone, validation.Required),
+		"RecordName": validation.Validate(e.RecordName, validation.Required),
+	}.Filter()
+}
+
+// BatchUpdateEdgeHostnames applies the same patch to many edge hostnames and waits for each change request to complete.
+// When an edge hostname has an ID, its current values are read first, so that edge hostnames which already
+// have the patched values are skipped and updated edge hostnames can be rolled back.
+//
+// The returned summary covers every edge hostname. An error matching ErrBatchUpdateEdgeHostnames is returned
+// together with the summary when any update fails.
+func BatchUpdateEdgeHostnames(ctx context.Context, client HAPI, params BatchUpdateEdgeHostnamesRequest) (*BatchUpdateEdgeHostnamesResult, error) {
+	if err := params.Validate(); err != nil {
+		return nil, fmt.Errorf("%s: %w: %s", ErrBatchUpdateEdgeHostnames, ErrStructValidation, err)
+	}
+	for i, ehn := range params.EdgeHostnames {
+		if err := ehn.Validate(); err != nil {
+			return nil, fmt.Errorf("%s: %w: EdgeHostnames[%d]: %s", ErrBatchUpdateEdgeHostnames, ErrStructValidation, i, err)
+		}
+		if params.Rollback && ehn.EdgeHostnameID == 0 {
+			return nil, fmt.Errorf("%s: %w: EdgeHostnames[%d]: EdgeHostnameID is required for rollback", ErrBatchUpdateEdgeHostnames, ErrStructValidation, i)
+		}
+	}
+	if params.Concurrency == 0 {
+		params.Concurrency = DefaultBatchConcurrency
+	}
+	if params.PollInterval == 0 {
+		params.PollInterval = DefaultChangeRequestPollInterval
+	}
+
+	result := &BatchUpdateEdgeHostnamesResult{Results: make([]EdgeHostnameUpdateResult, len(params.EdgeHostnames))}
+	forEachEdgeHostname(params.Concurrency, len(params.EdgeHostnames), func(i int) {
+		result.Results[i] = updateEdgeHostname(ctx, client, params, params.EdgeHostnames[i])
+	})
+
+	failed := false
+	for _, r := range result.Results {
+		failed = failed || r.Status == EdgeHostnameUpdateFailed
+	}
+	if failed && params.Rollback {
+		forEachEdgeHostname(params.Concurrency, len(result.Results), func(i int) {
+			if result.Results[i].Status == EdgeHostnameUpdateSucceeded {
+				rollbackEdgeHostname(ctx, client, params, &result.Results[i])
+			}
+		})
+	}
+
+	var errs []error
+	for _, r := range result.Results {
+		switch r.Status {
+		case EdgeHostnameUpdatePlanned:
+			result.Planned++
+		case EdgeHostnameUpdateUnchanged:
+			result.Unchanged++
+		case EdgeHostnameUpdateSucceeded:
+			result.Succeeded++
+		case EdgeHostnameUpdateRolledBack:
+			result.RolledBack++
+		case EdgeHostnameUpdateFailed, EdgeHostnameUpdateRollbackFailed:
+			result.Failed++
+			errs = append(errs, fmt.Errorf("%s.%s: %w", r.EdgeHostname.RecordName, r.EdgeHostname.DNSZone, r.Err))
+		}
+	}
+	if len(errs) > 0 {
+		return result, fmt.Errorf("%w: %w", ErrBatchUpdateEdgeHostnames, errors.Join(errs...))
+	}
+	return result, nil
+}
+
+// WaitForChangeRequest polls a change request until it succeeds or fails. An error matching ErrChangeRequestFailed
+// is returned for failed change requests.
+func WaitForChangeRequest(ctx context.Context, client HAPI, changeID int, pollInterval time.Duration) (*ChangeRequest, error) {
+	if pollInterval <= 0 {
+		pollInterval = DefaultChangeRequestPollInterval
+	}
+	op := ChangeRequestOperation(client, changeID)
+	op.Backoff = operation.ConstantBackoff(pollInterval)
+	status, err := op.Wait(ctx)
+	switch {
+	case errors.Is(err, operation.ErrOperationFailed):
+		return status.Value, fmt.Errorf("%w: change %d: %s", ErrChangeRequestFailed, changeID, status.Message)
+	case err != nil:
+		return nil, err
+	}
+	return status.Value, nil
+}
+
+func forEachEdgeHostname(concurrency, n int, f func(i int)) {
+	sem := make(chan struct{}, concurrency)
+	var wg sync.WaitGroup
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(i int) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			f(i)
+		}(i)
+	}
+	wg.Wait()
+}
+
+func updateEdgeHostname(ctx context.Context, client HAPI, params BatchUpdateEdgeHostnamesRequest, ehn BatchEdgeHostname) EdgeHostnameUpdateResult {
+	result := EdgeHostnameUpdateResult{EdgeHostname: ehn, Status: EdgeHostnameUpdatePlanned}
+	if ehn.EdgeHostnameID != 0 {
+		current, err := client.GetEdgeHostname(ctx, ehn.EdgeHostnameID)
+		if err != nil {
+			result.Status, result.Err = EdgeHostnameUpdateFailed, err
+			return result
+		}
+		changed := false
+		for _, op := range params.Body {
+			value, err := currentValue(current, op.Path)
+			if err != nil {
+				result.Status, result.Err = EdgeHostnameUpdateFailed, err
+				return result
+			}
+			changed = changed || value != op.Value
+			result.Previous = append(result.Previous, UpdateEdgeHostnameRequestBody{Op: op.Op, Path: op.Path, Value: value})
+		}
+		if !changed {
+			result.Status = EdgeHostnameUpdateUnchanged
+			return result
+		}
+	}
+	if params.DryRun {
+		return result
+	}
+
+	resp, err := client.UpdateEdgeHostname(ctx, UpdateEdgeHostnameRequest{
+		DNSZone:           ehn.DNSZone,
+		RecordName:        ehn.RecordName,
+		StatusUpdateEmail: params.StatusUpdateEmail,
+		Comments:          params.Comments,
+		Body:              params.Body,
+	})
+	if err != nil {
+		result.Status, result.Err = EdgeHostnameUpdateFailed, err
+		return result
+	}
+	result.ChangeID = resp.ChangeID
+	if _, err := WaitForChangeRequest(ctx, client, resp.ChangeID, params.PollInterval); err != nil {
+		result.Status, result.Err = EdgeHostnameUpdateFailed, err
+		return result
+	}
+	result.Status = EdgeHostnameUpdateSucceeded
+	return result
+}
+
+func rollbackEdgeHostname(ctx context.Context, client HAPI, params BatchUpdateEdgeHostnamesRequest, result *EdgeHostnameUpdateResult) {
+	comments := "Rollback"
+	if params.Comments != "" {
+		comments = "Rollback: " + params.Comments
+	}
+	resp, err := client.UpdateEdgeHostname(ctx, UpdateEdgeHostnameRequest{
+		DNSZone:           result.EdgeHostname.DNSZone,
+		RecordName:        result.EdgeHostname.RecordName,
+		StatusUpdateEmail: params.StatusUpdateEmail,
+		Comments:          comments,
+		Body:              result.Previous,
+	})
+	if err == nil {
+		result.RollbackChangeID = resp.ChangeID
+		_, err = WaitForChangeRequest(ctx, client, resp.ChangeID, params.PollInterval)
+	}
+	if err != nil {
+		result.Status, result.Err = EdgeHostnameUpdateRollbackFailed, fmt.Errorf("rollback: %w", err)
+		return
+	}
+	result.Status = EdgeHostnameUpdateRolledBack
+}
+
+// currentValue reads the value at a patch path from the JSON representation of the edge hostname, so that
+// it can be compared with the patched value and restored on rollback
+func currentValue(ehn *GetEdgeHostnameResponse, path string) (string, error) {
+	data, err := json.Marshal(ehn)
+	if err != nil {
+		return "", err
+	}
+	var members map[string]interface{}
+	if err := json.Unmarshal(data, &members); err != nil {
+		return "", err
+	}
+	switch value := members[strings.TrimPrefix(path, "/")].(type) {
+	case string:
+		return value, nil
+	case float64:
+		return strconv.FormatFloat(value, 'f', -1, 64), nil
+	case bool:
+		return strconv.FormatBool(value), nil
+	}
+	return "", fmt.Errorf("current value of %s cannot be read, so it cannot be compared or rolled back", path)
+}
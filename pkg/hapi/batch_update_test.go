@@ -0,0 +1,232 @@
+package hapi
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBatchUpdateEdgeHostnames(t *testing.T) {
+	ttlPatch := []UpdateEdgeHostnameRequestBody{{Op: "replace", Path: "/ttl", Value: "300"}}
+	restoreTTL := []UpdateEdgeHostnameRequestBody{{Op: "replace", Path: "/ttl", Value: "21600"}}
+	ipPatch := []UpdateEdgeHostnameRequestBody{{Op: "replace", Path: "/ipVersionBehavior", Value: "IPV6_IPV4_DUALSTACK"}}
+	restoreIP := []UpdateEdgeHostnameRequestBody{{Op: "replace", Path: "/ipVersionBehavior", Value: "IPV4"}}
+	www := BatchEdgeHostname{DNSZone: "edgekey.net", RecordName: "www.example.com", EdgeHostnameID: 1}
+	api := BatchEdgeHostname{DNSZone: "edgekey.net", RecordName: "api.example.com", EdgeHostnameID: 2}
+	img := BatchEdgeHostname{DNSZone: "edgesuite.net", RecordName: "img.example.com"}
+	update := func(ehn BatchEdgeHostname, body []UpdateEdgeHostnameRequestBody, comments string) UpdateEdgeHostnameRequest {
+		return UpdateEdgeHostnameRequest{DNSZone: ehn.DNSZone, RecordName: ehn.RecordName, Comments: comments, Body: body}
+	}
+	change := func(id int, statuses ...string) func(*Mock) {
+		return func(m *Mock) {
+			for _, status := range statuses {
+				m.On("GetChangeRequest", mock.Anything, GetChangeRequest{ChangeID: id}).
+					Return(&ChangeRequest{ChangeID: int64(id), Status: status, StatusMessage: "status " + status}, nil).Once()
+			}
+		}
+	}
+
+	tests := map[string]struct {
+		params    BatchUpdateEdgeHostnamesRequest
+		init      []func(*Mock)
+		expected  *BatchUpdateEdgeHostnamesResult
+		withError []error
+	}{
+		"updates edge hostnames and skips unchanged": {
+			params: BatchUpdateEdgeHostnamesRequest{EdgeHostnames: []BatchEdgeHostname{www, api, img}, Body: ttlPatch, Comments: "TTL"},
+			init: []func(*Mock){
+				func(m *Mock) {
+					m.On("GetEdgeHostname", mock.Anything, 1).Return(&GetEdgeHostnameResponse{TTL: 21600}, nil).Once()
+					m.On("GetEdgeHostname", mock.Anything, 2).Return(&GetEdgeHostnameResponse{TTL: 300}, nil).Once()
+					m.On("UpdateEdgeHostname", mock.Anything, update(www, ttlPatch, "TTL")).Return(&UpdateEdgeHostnameResponse{ChangeID: 10}, nil).Once()
+					m.On("UpdateEdgeHostname", mock.Anything, update(img, ttlPatch, "TTL")).Return(&UpdateEdgeHostnameResponse{ChangeID: 11}, nil).Once()
+				},
+				change(10, ChangeRequestStatusPending, ChangeRequestStatusSucceeded),
+				change(11, ChangeRequestStatusSucceeded),
+			},
+			expected: &BatchUpdateEdgeHostnamesResult{
+				Results: []EdgeHostnameUpdateResult{
+					{EdgeHostname: www, Status: EdgeHostnameUpdateSucceeded, ChangeID: 10, Previous: restoreTTL},
+					{EdgeHostname: api, Status: EdgeHostnameUpdateUnchanged, Previous: ttlPatch},
+					{EdgeHostname: img, Status: EdgeHostnameUpdateSucceeded, ChangeID: 11},
+				},
+				Unchanged: 1,
+				Succeeded: 2,
+			},
+		},
+		"dry run": {
+			params: BatchUpdateEdgeHostnamesRequest{
+				EdgeHostnames: []BatchEdgeHostname{www, img},
+				Body:          []UpdateEdgeHostnameRequestBody{{Op: "replace", Path: "/ipVersionBehavior", Value: "IPV6_IPV4_DUALSTACK"}},
+				DryRun:        true,
+			},
+			init: []func(*Mock){
+				func(m *Mock) {
+					m.On("GetEdgeHostname", mock.Anything, 1).Return(&GetEdgeHostnameResponse{IPVersionBehavior: "IPV4"}, nil).Once()
+				},
+			},
+			expected: &BatchUpdateEdgeHostnamesResult{
+				Results: []EdgeHostnameUpdateResult{
+					{EdgeHostname: www, Status: EdgeHostnameUpdatePlanned, Previous: []UpdateEdgeHostnameRequestBody{{Op: "replace", Path: "/ipVersionBehavior", Value: "IPV4"}}},
+					{EdgeHostname: img, Status: EdgeHostnameUpdatePlanned},
+				},
+				Planned: 2,
+			},
+		},
+		"failure rolls back updated edge hostnames": {
+			params: BatchUpdateEdgeHostnamesRequest{EdgeHostnames: []BatchEdgeHostname{www, api}, Body: ttlPatch, Rollback: true, Concurrency: 1},
+			init: []func(*Mock){
+				func(m *Mock) {
+					m.On("GetEdgeHostname", mock.Anything, 1).Return(&GetEdgeHostnameResponse{TTL: 21600}, nil).Once()
+					m.On("GetEdgeHostname", mock.Anything, 2).Return(&GetEdgeHostnameResponse{TTL: 21600}, nil).Once()
+					m.On("UpdateEdgeHostname", mock.Anything, update(www, ttlPatch, "")).Return(&UpdateEdgeHostnameResponse{ChangeID: 10}, nil).Once()
+					m.On("UpdateEdgeHostname", mock.Anything, update(api, ttlPatch, "")).Return(&UpdateEdgeHostnameResponse{ChangeID: 11}, nil).Once()
+					m.On("UpdateEdgeHostname", mock.Anything, update(www, restoreTTL, "Rollback")).Return(&UpdateEdgeHostnameResponse{ChangeID: 12}, nil).Once()
+				},
+				change(10, ChangeRequestStatusSucceeded),
+				change(11, ChangeRequestStatusFailed),
+				change(12, ChangeRequestStatusSucceeded),
+			},
+			expected: &BatchUpdateEdgeHostnamesResult{
+				Results: []EdgeHostnameUpdateResult{
+					{EdgeHostname: www, Status: EdgeHostnameUpdateRolledBack, ChangeID: 10, RollbackChangeID: 12, Previous: restoreTTL},
+					{EdgeHostname: api, Status: EdgeHostnameUpdateFailed, ChangeID: 11, Previous: restoreTTL},
+				},
+				Failed:     1,
+				RolledBack: 1,
+			},
+			withError: []error{ErrBatchUpdateEdgeHostnames, ErrChangeRequestFailed},
+		},
+		"failure rolls back the IP version behavior": {
+			params: BatchUpdateEdgeHostnamesRequest{EdgeHostnames: []BatchEdgeHostname{www, api}, Body: ipPatch, Rollback: true, Concurrency: 1},
+			init: []func(*Mock){
+				func(m *Mock) {
+					m.On("GetEdgeHostname", mock.Anything, 1).Return(&GetEdgeHostnameResponse{TTL: 300, IPVersionBehavior: "IPV4"}, nil).Once()
+					m.On("GetEdgeHostname", mock.Anything, 2).Return(&GetEdgeHostnameResponse{TTL: 300, IPVersionBehavior: "IPV4"}, nil).Once()
+					m.On("UpdateEdgeHostname", mock.Anything, update(www, ipPatch, "")).Return(&UpdateEdgeHostnameResponse{ChangeID: 10}, nil).Once()
+					m.On("UpdateEdgeHostname", mock.Anything, update(api, ipPatch, "")).Return(nil, ErrUpdateEdgeHostname).Once()
+					m.On("UpdateEdgeHostname", mock.Anything, update(www, restoreIP, "Rollback")).Return(&UpdateEdgeHostnameResponse{ChangeID: 12}, nil).Once()
+				},
+				change(10, ChangeRequestStatusSucceeded),
+				change(12, ChangeRequestStatusSucceeded),
+			},
+			expected: &BatchUpdateEdgeHostnamesResult{
+				Results: []EdgeHostnameUpdateResult{
+					{EdgeHostname: www, Status: EdgeHostnameUpdateRolledBack, ChangeID: 10, RollbackChangeID: 12, Previous: restoreIP},
+					{EdgeHostname: api, Status: EdgeHostnameUpdateFailed, Previous: restoreIP},
+				},
+				Failed:     1,
+				RolledBack: 1,
+			},
+			withError: []error{ErrBatchUpdateEdgeHostnames, ErrUpdateEdgeHostname},
+		},
+		"unsupported path": {
+			params: BatchUpdateEdgeHostnamesRequest{
+				EdgeHostnames: []BatchEdgeHostname{www},
+				Body:          []UpdateEdgeHostnameRequestBody{{Op: "replace", Path: "/map", Value: "a;example.akamai.net"}},
+				Rollback:      true,
+			},
+			withError: []error{ErrStructValidation},
+		},
+		"failed rollback": {
+			params: BatchUpdateEdgeHostnamesRequest{EdgeHostnames: []BatchEdgeHostname{www, api}, Body: ttlPatch, Rollback: true, Concurrency: 1},
+			init: []func(*Mock){
+				func(m *Mock) {
+					m.On("GetEdgeHostname", mock.Anything, 1).Return(&GetEdgeHostnameResponse{TTL: 21600}, nil).Once()
+					m.On("GetEdgeHostname", mock.Anything, 2).Return(nil, ErrGetEdgeHostname).Once()
+					m.On("UpdateEdgeHostname", mock.Anything, update(www, ttlPatch, "")).Return(&UpdateEdgeHostnameResponse{ChangeID: 10}, nil).Once()
+					m.On("UpdateEdgeHostname", mock.Anything, update(www, restoreTTL, "Rollback")).Return(nil, ErrUpdateEdgeHostname).Once()
+				},
+				change(10, ChangeRequestStatusSucceeded),
+			},
+			expected: &BatchUpdateEdgeHostnamesResult{
+				Results: []EdgeHostnameUpdateResult{
+					{EdgeHostname: www, Status: EdgeHostnameUpdateRollbackFailed, ChangeID: 10, Previous: restoreTTL},
+					{EdgeHostname: api, Status: EdgeHostnameUpdateFailed},
+				},
+				Failed: 2,
+			},
+			withError: []error{ErrBatchUpdateEdgeHostnames, ErrGetEdgeHostname, ErrUpdateEdgeHostname},
+		},
+		"rollback without edge hostname ID": {
+			params:    BatchUpdateEdgeHostnamesRequest{EdgeHostnames: []BatchEdgeHostname{www, img}, Body: ttlPatch, Rollback: true},
+			withError: []error{ErrStructValidation},
+		},
+		"validation error": {
+			params:    BatchUpdateEdgeHostnamesRequest{EdgeHostnames: []BatchEdgeHostname{www}},
+			withError: []error{ErrStructValidation},
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			m := &Mock{}
+			for _, init := range test.init {
+				init(m)
+			}
+			test.params.PollInterval = time.Millisecond
+			result, err := BatchUpdateEdgeHostnames(context.Background(), m, test.params)
+			m.AssertExpectations(t)
+			if test.withError != nil {
+				for _, e := range test.withError {
+					assert.True(t, errors.Is(err, e), "want: %s; got: %s", e, err)
+				}
+			} else {
+				require.NoError(t, err)
+			}
+			if test.expected == nil {
+				assert.Nil(t, result)
+				return
+			}
+			require.NotNil(t, result)
+			for i := range result.Results {
+				result.Results[i].Err = nil
+			}
+			assert.Equal(t, test.expected, result)
+		})
+	}
+}
+
+func TestWaitForChangeRequest(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	m := &Mock{}
+	m.On("GetChangeRequest", mock.Anything, GetChangeRequest{ChangeID: 1}).
+		Return(&ChangeRequest{Status: ChangeRequestStatusPending}, nil).
+		Run(func(mock.Arguments) { cancel() }).Once()
+
+	_, err := WaitForChangeRequest(ctx, m, 1, time.Hour)
+	assert.True(t, errors.Is(err, context.Canceled))
+	m.AssertExpectations(t)
+}
+
+func TestCurrentValue(t *testing.T) {
+	ehn := &GetEdgeHostnameResponse{TTL: 300, IPVersionBehavior: "IPV4", UseDefaultTTL: true}
+
+	tests := map[string]struct {
+		path      string
+		expected  string
+		withError bool
+	}{
+		"number":        {path: "/ttl", expected: "300"},
+		"string":        {path: "/ipVersionBehavior", expected: "IPV4"},
+		"bool":          {path: "/useDefaultTtl", expected: "true"},
+		"missing value": {path: "/map", withError: true},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			value, err := currentValue(ehn, test.path)
+			if test.withError {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, test.expected, value)
+		})
+	}
+}
@@ -0,0 +1,26 @@
+package hapi
+
+import (
+	"context"
+	"strconv"
+
+	"github.com/akamai/AkamaiOPEN-edgegrid-golang/v11/pkg/operation"
+)
+
+// ChangeRequestOperation returns an Operation tracking a change request
+func ChangeRequestOperation(client HAPI, changeID int) *operation.Operation[*ChangeRequest] {
+	return operation.New(strconv.Itoa(changeID), func(ctx context.Context) (operation.Status[*ChangeRequest], error) {
+		change, err := client.GetChangeRequest(ctx, GetChangeRequest{ChangeID: changeID})
+		if err != nil {
+			return operation.Status[*ChangeRequest]{}, err
+		}
+		status := operation.Status[*ChangeRequest]{State: operation.StatePending, Message: change.StatusMessage, Value: change}
+		switch change.Status {
+		case ChangeRequestStatusSucceeded:
+			status.State = operation.StateSucceeded
+		case ChangeRequestStatusFailed:
+			status.State = operation.StateFailed
+		}
+		return status, nil
+	})
+}
@@ -0,0 +1,37 @@
+package hapi
+
+import (
+	"context"
+	"testing"
+
+	"github.com/akamai/AkamaiOPEN-edgegrid-golang/v11/pkg/operation"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestChangeRequestOperation(t *testing.T) {
+	tests := map[string]struct {
+		status   string
+		expected operation.State
+	}{
+		"pending":   {status: ChangeRequestStatusPending, expected: operation.StatePending},
+		"succeeded": {status: ChangeRequestStatusSucceeded, expected: operation.StateSucceeded},
+		"failed":    {status: ChangeRequestStatusFailed, expected: operation.StateFailed},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			m := &Mock{}
+			m.On("GetChangeRequest", mock.Anything, GetChangeRequest{ChangeID: 10}).
+				Return(&ChangeRequest{ChangeID: 10, Status: test.status, StatusMessage: "message"}, nil).Once()
+			op := ChangeRequestOperation(m, 10)
+			status, err := op.Poll(context.Background())
+			m.AssertExpectations(t)
+			require.NoError(t, err)
+			assert.Equal(t, "10", op.ID)
+			assert.Equal(t, test.expected, status.State)
+			assert.Equal(t, "message", status.Message)
+		})
+	}
+}
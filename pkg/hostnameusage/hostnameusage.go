@@ -0,0 +1,383 @@
+// Package hostnameusage joins the edge hostnames known to HAPI with the property hostnames in PAPI which CNAME to them,
+// so that edge hostnames still in use are not deleted and orphaned ones can be cleaned up.
+package hostnameusage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/akamai/AkamaiOPEN-edgegrid-golang/v11/pkg/edgegriderr"
+	"github.com/akamai/AkamaiOPEN-edgegrid-golang/v11/pkg/hapi"
+	"github.com/akamai/AkamaiOPEN-edgegrid-golang/v11/pkg/papi"
+	validation "github.com/go-ozzo/ozzo-validation/v4"
+)
+
+type (
+	// Clients groups the API clients used to build the usage map.
+	Clients struct {
+		PAPI papi.PAPI
+		HAPI hapi.HAPI
+	}
+
+	// MapRequest is used to call Map.
+	MapRequest struct {
+		ContractID string
+		// GroupID selects the edge hostnames in scope. Properties of every group of the contract are searched for usages.
+		GroupID string
+		// IncludeLatestVersions also reports use in the latest versions of properties which are not active.
+		// Edge hostnames used only by inactive versions are then not reported as orphaned.
+		IncludeLatestVersions bool
+	}
+
+	// UsageMap is the result of Map.
+	UsageMap struct {
+		// EdgeHostnames are the edge hostnames of the contract and group, followed by edge hostnames of other
+		// contracts or groups which are used by properties of the contract
+		EdgeHostnames []EdgeHostnameUsage
+	}
+
+	// EdgeHostnameUsage describes where an edge hostname is used.
+	EdgeHostnameUsage struct {
+		// EdgeHostnameID is the HAPI edge hostname ID
+		EdgeHostnameID int
+		Domain         string
+		RecordName     string
+		DNSZone        string
+		ProductID      string
+		// InScope is set for edge hostnames of the requested contract and group
+		InScope bool
+		Usages  []Usage
+	}
+
+	// Usage is a property hostname which CNAMEs to an edge hostname.
+	Usage struct {
+		PropertyID      string
+		PropertyName    string
+		PropertyVersion int
+		Network         Network
+		Hostname        string
+	}
+
+	// Network is the network, or the latest version, in which a property hostname is used.
+	Network string
+
+	// DeleteRequest is used to call DeleteEdgeHostname.
+	DeleteRequest struct {
+		MapRequest
+		EdgeHostnameID    int
+		StatusUpdateEmail []string
+		Comments          string
+	}
+)
+
+const (
+	// NetworkStaging is the staging network
+	NetworkStaging Network = "STAGING"
+	// NetworkProduction is the production network
+	NetworkProduction Network = "PRODUCTION"
+	// NetworkLatest is the latest version of a property, when it is not active
+	NetworkLatest Network = "LATEST"
+
+	propertyTypeHostnameBucket = "HOSTNAME_BUCKET"
+	edgeHostnameIDPrefix       = "ehn_"
+	activeHostnamesPageSize    = 999
+)
+
+var (
+	// ErrMap is returned when Map fails.
+	ErrMap = errors.New("map edge hostname usage")
+	// ErrDeleteEdgeHostname is returned when DeleteEdgeHostname fails.
+	ErrDeleteEdgeHostname = errors.New("delete edge hostname")
+	// ErrEdgeHostnameInUse is returned when an edge hostname which is in use would be deleted.
+	ErrEdgeHostnameInUse = errors.New("edge hostname is in use")
+	// ErrEdgeHostnameNotFound is returned when an edge hostname to delete is not found.
+	ErrEdgeHostnameNotFound = errors.New("edge hostname not found")
+	// ErrStructValidation is returned when given struct validation failed.
+	ErrStructValidation = errors.New("struct validation")
+)
+
+// Validate validates MapRequest.
+func (r MapRequest) Validate() error {
+	return edgegriderr.ParseValidationErrors(validation.Errors{
+		"ContractID": validation.Validate(r.ContractID, validation.Required),
+		"GroupID":    validation.Validate(r.GroupID, validation.Required),
+	})
+}
+
+// Validate validates DeleteRequest.
+func (r DeleteRequest) Validate() error {
+	return edgegriderr.ParseValidationErrors(validation.Errors{
+		"ContractID":     validation.Validate(r.ContractID, validation.Required),
+		"GroupID":        validation.Validate(r.GroupID, validation.Required),
+		"EdgeHostnameID": validation.Validate(r.EdgeHostnameID, validation.Required),
+	})
+}
+
+// Orphaned returns true when no property hostname uses the edge hostname.
+func (u EdgeHostnameUsage) Orphaned() bool {
+	return len(u.Usages) == 0
+}
+
+// Orphaned returns the edge hostnames of the contract and group which no property hostname uses.
+func (m *UsageMap) Orphaned() []EdgeHostnameUsage {
+	var orphaned []EdgeHostnameUsage
+	for _, ehn := range m.EdgeHostnames {
+		if ehn.InScope && ehn.Orphaned() {
+			orphaned = append(orphaned, ehn)
+		}
+	}
+	return orphaned
+}
+
+// Find returns the edge hostname with the given HAPI ID.
+func (m *UsageMap) Find(edgeHostnameID int) (*EdgeHostnameUsage, bool) {
+	for i := range m.EdgeHostnames {
+		if m.EdgeHostnames[i].EdgeHostnameID == edgeHostnameID {
+			return &m.EdgeHostnames[i], true
+		}
+	}
+	return nil, false
+}
+
+// Map lists the edge hostnames of a contract and group and, for each of them, every property hostname which CNAMEs
+// to it in the staging and production networks. Properties of all groups of the contract are searched, as a property
+// may use an edge hostname created in another group. Hostnames of classic properties are read from their active
+// versions and hostnames of hostname bucket properties from their active property hostnames.
+//
+// Edge hostnames are listed with PAPI, as HAPI has no list operation; when a HAPI client is given, the record
+// name and DNS zone of each edge hostname are read from HAPI.
+func Map(ctx context.Context, clients Clients, params MapRequest) (*UsageMap, error) {
+	if err := params.Validate(); err != nil {
+		return nil, fmt.Errorf("%s: %w: %s", ErrMap, ErrStructValidation, err)
+	}
+
+	edgeHostnames, err := clients.PAPI.GetEdgeHostnames(ctx, papi.GetEdgeHostnamesRequest{
+		ContractID: params.ContractID,
+		GroupID:    params.GroupID,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrMap, err)
+	}
+	result := &UsageMap{}
+	for _, ehn := range edgeHostnames.EdgeHostnames.Items {
+		id, err := parseEdgeHostnameID(ehn.ID)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %w", ErrMap, err)
+		}
+		usage := EdgeHostnameUsage{
+			EdgeHostnameID: id,
+			Domain:         ehn.Domain,
+			RecordName:     ehn.DomainPrefix,
+			DNSZone:        ehn.DomainSuffix,
+			ProductID:      ehn.ProductID,
+			InScope:        true,
+		}
+		if clients.HAPI != nil {
+			details, err := clients.HAPI.GetEdgeHostname(ctx, id)
+			if err != nil {
+				return nil, fmt.Errorf("%w: %w", ErrMap, err)
+			}
+			usage.RecordName, usage.DNSZone = details.RecordName, details.DNSZone
+		}
+		result.EdgeHostnames = append(result.EdgeHostnames, usage)
+	}
+
+	groups, err := clients.PAPI.GetGroups(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrMap, err)
+	}
+	for _, group := range groups.Groups.Items {
+		if !slices.Contains(group.ContractIDs, params.ContractID) {
+			continue
+		}
+		groupParams := params
+		groupParams.GroupID = group.GroupID
+		if err := result.addGroupHostnames(ctx, clients.PAPI, groupParams); err != nil {
+			return nil, fmt.Errorf("%w: %w", ErrMap, err)
+		}
+	}
+	return result, nil
+}
+
+// DeleteEdgeHostname deletes an edge hostname of a contract and group with HAPI, after checking with a freshly
+// built usage map that no property hostname uses it. An error matching ErrEdgeHostnameInUse is returned otherwise.
+// The latest versions of properties are always checked, whatever the value of IncludeLatestVersions.
+func DeleteEdgeHostname(ctx context.Context, clients Clients, params DeleteRequest) (*hapi.DeleteEdgeHostnameResponse, error) {
+	if err := params.Validate(); err != nil {
+		return nil, fmt.Errorf("%s: %w: %s", ErrDeleteEdgeHostname, ErrStructValidation, err)
+	}
+	if clients.HAPI == nil {
+		return nil, fmt.Errorf("%w: HAPI client is required", ErrDeleteEdgeHostname)
+	}
+	mapRequest := params.MapRequest
+	mapRequest.IncludeLatestVersions = true
+	usageMap, err := Map(ctx, clients, mapRequest)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrDeleteEdgeHostname, err)
+	}
+	ehn, ok := usageMap.Find(params.EdgeHostnameID)
+	if !ok || !ehn.InScope {
+		return nil, fmt.Errorf("%w: %w: %d", ErrDeleteEdgeHostname, ErrEdgeHostnameNotFound, params.EdgeHostnameID)
+	}
+	if !ehn.Orphaned() {
+		return nil, fmt.Errorf("%w: %w: %s is used by %s", ErrDeleteEdgeHostname, ErrEdgeHostnameInUse, ehn.Domain, describeUsages(ehn.Usages))
+	}
+
+	resp, err := clients.HAPI.DeleteEdgeHostname(ctx, hapi.DeleteEdgeHostnameRequest{
+		DNSZone:           ehn.DNSZone,
+		RecordName:        ehn.RecordName,
+		StatusUpdateEmail: params.StatusUpdateEmail,
+		Comments:          params.Comments,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrDeleteEdgeHostname, err)
+	}
+	return resp, nil
+}
+
+// addGroupHostnames adds usages by the properties of the contract and group in params.
+func (m *UsageMap) addGroupHostnames(ctx context.Context, client papi.PAPI, params MapRequest) error {
+	properties, err := client.GetProperties(ctx, papi.GetPropertiesRequest{
+		ContractID: params.ContractID,
+		GroupID:    params.GroupID,
+	})
+	if err != nil {
+		return fmt.Errorf("group %s: %w", params.GroupID, err)
+	}
+	for _, property := range properties.Properties.Items {
+		if property.PropertyType != nil && *property.PropertyType == propertyTypeHostnameBucket {
+			err = m.addActiveHostnames(ctx, client, params, property)
+		} else {
+			err = m.addVersionHostnames(ctx, client, params, property)
+		}
+		if err != nil {
+			return fmt.Errorf("property %s: %w", property.PropertyID, err)
+		}
+	}
+	return nil
+}
+
+func (m *UsageMap) addVersionHostnames(ctx context.Context, client papi.PAPI, params MapRequest, property *papi.Property) error {
+	versions := map[Network]int{}
+	if property.StagingVersion != nil {
+		versions[NetworkStaging] = *property.StagingVersion
+	}
+	if property.ProductionVersion != nil {
+		versions[NetworkProduction] = *property.ProductionVersion
+	}
+	if params.IncludeLatestVersions && property.LatestVersion != 0 &&
+		property.LatestVersion != versions[NetworkStaging] && property.LatestVersion != versions[NetworkProduction] {
+		versions[NetworkLatest] = property.LatestVersion
+	}
+
+	for _, network := range []Network{NetworkStaging, NetworkProduction, NetworkLatest} {
+		version, ok := versions[network]
+		if !ok {
+			continue
+		}
+		hostnames, err := client.GetPropertyVersionHostnames(ctx, papi.GetPropertyVersionHostnamesRequest{
+			PropertyID:      property.PropertyID,
+			PropertyVersion: version,
+			ContractID:      params.ContractID,
+			GroupID:         params.GroupID,
+		})
+		if err != nil {
+			return err
+		}
+		for _, hostname := range hostnames.Hostnames.Items {
+			if err := m.addUsage(hostname.EdgeHostnameID, hostname.CnameTo, Usage{
+				PropertyID:      property.PropertyID,
+				PropertyName:    property.PropertyName,
+				PropertyVersion: version,
+				Network:         network,
+				Hostname:        hostname.CnameFrom,
+			}); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (m *UsageMap) addActiveHostnames(ctx context.Context, client papi.PAPI, params MapRequest, property *papi.Property) error {
+	for offset := 0; ; offset += activeHostnamesPageSize {
+		hostnames, err := client.ListActivePropertyHostnames(ctx, papi.ListActivePropertyHostnamesRequest{
+			PropertyID: property.PropertyID,
+			Offset:     offset,
+			Limit:      activeHostnamesPageSize,
+			ContractID: params.ContractID,
+			GroupID:    params.GroupID,
+		})
+		if err != nil {
+			return err
+		}
+		for _, hostname := range hostnames.Hostnames.Items {
+			usage := Usage{PropertyID: property.PropertyID, PropertyName: property.PropertyName, Hostname: hostname.CnameFrom}
+			if hostname.StagingEdgeHostnameID != "" || hostname.StagingCnameTo != "" {
+				usage.Network = NetworkStaging
+				if err := m.addUsage(hostname.StagingEdgeHostnameID, hostname.StagingCnameTo, usage); err != nil {
+					return err
+				}
+			}
+			if hostname.ProductionEdgeHostnameID != "" || hostname.ProductionCnameTo != "" {
+				usage.Network = NetworkProduction
+				if err := m.addUsage(hostname.ProductionEdgeHostnameID, hostname.ProductionCnameTo, usage); err != nil {
+					return err
+				}
+			}
+		}
+		if hostnames.Hostnames.NextLink == nil || len(hostnames.Hostnames.Items) == 0 {
+			return nil
+		}
+	}
+}
+
+// addUsage adds a usage to the edge hostname with the given PAPI ID or, when the ID is not set, the given domain.
+// Edge hostnames which are not in the map are added to it.
+func (m *UsageMap) addUsage(papiID, domain string, usage Usage) error {
+	if papiID == "" && domain == "" {
+		return nil
+	}
+	var id int
+	if papiID != "" {
+		var err error
+		if id, err = parseEdgeHostnameID(papiID); err != nil {
+			return err
+		}
+	}
+	i := slices.IndexFunc(m.EdgeHostnames, func(ehn EdgeHostnameUsage) bool {
+		if id != 0 {
+			return ehn.EdgeHostnameID == id
+		}
+		return strings.EqualFold(ehn.Domain, domain)
+	})
+	if i < 0 {
+		m.EdgeHostnames = append(m.EdgeHostnames, EdgeHostnameUsage{EdgeHostnameID: id, Domain: domain})
+		i = len(m.EdgeHostnames) - 1
+	}
+	if m.EdgeHostnames[i].Domain == "" {
+		m.EdgeHostnames[i].Domain = domain
+	}
+	m.EdgeHostnames[i].Usages = append(m.EdgeHostnames[i].Usages, usage)
+	return nil
+}
+
+func parseEdgeHostnameID(id string) (int, error) {
+	n, err := strconv.Atoi(strings.TrimPrefix(id, edgeHostnameIDPrefix))
+	if err != nil {
+		return 0, fmt.Errorf("invalid edge hostname ID %q", id)
+	}
+	return n, nil
+}
+
+func describeUsages(usages []Usage) string {
+	descriptions := make([]string, 0, len(usages))
+	for _, u := range usages {
+		descriptions = append(descriptions, fmt.Sprintf("%s (%s v%d %s)", u.Hostname, u.PropertyName, u.PropertyVersion, u.Network))
+	}
+	return strings.Join(descriptions, ", ")
+}
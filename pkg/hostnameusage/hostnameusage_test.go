@@ -0,0 +1,249 @@
+package hostnameusage
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/akamai/AkamaiOPEN-edgegrid-golang/v11/pkg/hapi"
+	"github.com/akamai/AkamaiOPEN-edgegrid-golang/v11/pkg/papi"
+	"github.com/akamai/AkamaiOPEN-edgegrid-golang/v11/pkg/ptr"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+var (
+	mapRequest = MapRequest{ContractID: "ctr_1", GroupID: "grp_2"}
+
+	wwwEHN = EdgeHostnameUsage{EdgeHostnameID: 1, Domain: "www.example.com.edgekey.net", RecordName: "www.example.com", DNSZone: "edgekey.net", ProductID: "prd_Fresca", InScope: true}
+	apiEHN = EdgeHostnameUsage{EdgeHostnameID: 2, Domain: "api.example.com.edgekey.net", RecordName: "api.example.com", DNSZone: "edgekey.net", ProductID: "prd_Fresca", InScope: true}
+	oldEHN = EdgeHostnameUsage{EdgeHostnameID: 3, Domain: "old.example.com.edgesuite.net", RecordName: "old.example.com", DNSZone: "edgesuite.net", ProductID: "prd_Fresca", InScope: true}
+
+	oldLatestHostname = papi.Hostname{CnameFrom: "old.example.com", CnameTo: "OLD.example.com.edgesuite.net"}
+)
+
+func mockEdgeHostnames(p *papi.Mock, h *hapi.Mock) {
+	items := make([]papi.EdgeHostnameGetItem, 0, 3)
+	for _, ehn := range []EdgeHostnameUsage{wwwEHN, apiEHN, oldEHN} {
+		items = append(items, papi.EdgeHostnameGetItem{
+			ID:           "ehn_" + string(rune('0'+ehn.EdgeHostnameID)),
+			Domain:       ehn.Domain,
+			ProductID:    ehn.ProductID,
+			DomainPrefix: ehn.RecordName,
+			DomainSuffix: ehn.DNSZone,
+		})
+		if h != nil {
+			h.On("GetEdgeHostname", mock.Anything, ehn.EdgeHostnameID).
+				Return(&hapi.GetEdgeHostnameResponse{RecordName: ehn.RecordName, DNSZone: ehn.DNSZone}, nil).Once()
+		}
+	}
+	p.On("GetEdgeHostnames", mock.Anything, papi.GetEdgeHostnamesRequest{ContractID: "ctr_1", GroupID: "grp_2"}).
+		Return(&papi.GetEdgeHostnamesResponse{EdgeHostnames: papi.EdgeHostnameItems{Items: items}}, nil).Once()
+}
+
+func mockGroups(p *papi.Mock) {
+	p.On("GetGroups", mock.Anything).Return(&papi.GetGroupsResponse{Groups: papi.GroupItems{Items: []*papi.Group{
+		{GroupID: "grp_2", ContractIDs: []string{"ctr_1"}},
+		{GroupID: "grp_3", ContractIDs: []string{"ctr_1", "ctr_9"}},
+		{GroupID: "grp_4", ContractIDs: []string{"ctr_9"}},
+	}}}, nil).Once()
+}
+
+// mockProperties mocks the properties of groups grp_2 and grp_3. When latest is set, the latest version
+// of the classic property is read and holds latestHostnames.
+func mockProperties(p *papi.Mock, latest bool, latestHostnames ...papi.Hostname) {
+	mockGroups(p)
+	p.On("GetProperties", mock.Anything, papi.GetPropertiesRequest{ContractID: "ctr_1", GroupID: "grp_2"}).
+		Return(&papi.GetPropertiesResponse{Properties: papi.PropertiesItems{Items: []*papi.Property{
+			{PropertyID: "prp_1", PropertyName: "classic", LatestVersion: 3, StagingVersion: ptr.To(2), ProductionVersion: ptr.To(1)},
+			{PropertyID: "prp_2", PropertyName: "bucket", PropertyType: ptr.To("HOSTNAME_BUCKET")},
+		}}}, nil).Once()
+	versionHostnames := func(version int, hostnames ...papi.Hostname) {
+		p.On("GetPropertyVersionHostnames", mock.Anything, papi.GetPropertyVersionHostnamesRequest{
+			PropertyID: "prp_1", PropertyVersion: version, ContractID: "ctr_1", GroupID: "grp_2",
+		}).Return(&papi.GetPropertyVersionHostnamesResponse{Hostnames: papi.HostnameResponseItems{Items: hostnames}}, nil).Once()
+	}
+	versionHostnames(2, papi.Hostname{CnameFrom: "www.example.com", EdgeHostnameID: "ehn_1", CnameTo: "www.example.com.edgekey.net"},
+		papi.Hostname{CnameFrom: "other.example.com", CnameTo: "other.example.com.edgekey.net"})
+	versionHostnames(1, papi.Hostname{CnameFrom: "www.example.com", EdgeHostnameID: "ehn_1"})
+	if latest {
+		versionHostnames(3, latestHostnames...)
+	}
+	p.On("ListActivePropertyHostnames", mock.Anything, papi.ListActivePropertyHostnamesRequest{
+		PropertyID: "prp_2", Limit: 999, ContractID: "ctr_1", GroupID: "grp_2",
+	}).Return(&papi.ListActivePropertyHostnamesResponse{Hostnames: papi.HostnamesResponseItems{
+		Items:    []papi.HostnameItem{{CnameFrom: "api.example.com", StagingEdgeHostnameID: "ehn_2"}},
+		NextLink: ptr.To("/papi/v1/properties/prp_2/hostnames?offset=999"),
+	}}, nil).Once()
+	p.On("ListActivePropertyHostnames", mock.Anything, papi.ListActivePropertyHostnamesRequest{
+		PropertyID: "prp_2", Offset: 999, Limit: 999, ContractID: "ctr_1", GroupID: "grp_2",
+	}).Return(&papi.ListActivePropertyHostnamesResponse{Hostnames: papi.HostnamesResponseItems{
+		Items: []papi.HostnameItem{{CnameFrom: "api2.example.com", StagingEdgeHostnameID: "ehn_2", ProductionEdgeHostnameID: "ehn_2"}},
+	}}, nil).Once()
+
+	p.On("GetProperties", mock.Anything, papi.GetPropertiesRequest{ContractID: "ctr_1", GroupID: "grp_3"}).
+		Return(&papi.GetPropertiesResponse{Properties: papi.PropertiesItems{Items: []*papi.Property{
+			{PropertyID: "prp_3", PropertyName: "shop", LatestVersion: 4, ProductionVersion: ptr.To(4)},
+		}}}, nil).Once()
+	p.On("GetPropertyVersionHostnames", mock.Anything, papi.GetPropertyVersionHostnamesRequest{
+		PropertyID: "prp_3", PropertyVersion: 4, ContractID: "ctr_1", GroupID: "grp_3",
+	}).Return(&papi.GetPropertyVersionHostnamesResponse{Hostnames: papi.HostnameResponseItems{Items: []papi.Hostname{
+		{CnameFrom: "shop.example.com", EdgeHostnameID: "ehn_2"},
+	}}}, nil).Once()
+}
+
+func withUsages(ehn EdgeHostnameUsage, usages ...Usage) EdgeHostnameUsage {
+	ehn.Usages = usages
+	return ehn
+}
+
+func TestMap(t *testing.T) {
+	classic := func(version int, network Network, hostname string) Usage {
+		return Usage{PropertyID: "prp_1", PropertyName: "classic", PropertyVersion: version, Network: network, Hostname: hostname}
+	}
+	bucket := func(network Network, hostname string) Usage {
+		return Usage{PropertyID: "prp_2", PropertyName: "bucket", Network: network, Hostname: hostname}
+	}
+	shop := Usage{PropertyID: "prp_3", PropertyName: "shop", PropertyVersion: 4, Network: NetworkProduction, Hostname: "shop.example.com"}
+
+	tests := map[string]struct {
+		params    MapRequest
+		withHAPI  bool
+		init      func(*papi.Mock, *hapi.Mock)
+		expected  *UsageMap
+		orphaned  []EdgeHostnameUsage
+		withError []error
+	}{
+		"active versions": {
+			params:   mapRequest,
+			withHAPI: true,
+			init: func(p *papi.Mock, h *hapi.Mock) {
+				mockEdgeHostnames(p, h)
+				mockProperties(p, false)
+			},
+			expected: &UsageMap{EdgeHostnames: []EdgeHostnameUsage{
+				withUsages(wwwEHN, classic(2, NetworkStaging, "www.example.com"), classic(1, NetworkProduction, "www.example.com")),
+				withUsages(apiEHN, bucket(NetworkStaging, "api.example.com"), bucket(NetworkStaging, "api2.example.com"), bucket(NetworkProduction, "api2.example.com"), shop),
+				oldEHN,
+				{Domain: "other.example.com.edgekey.net", Usages: []Usage{classic(2, NetworkStaging, "other.example.com")}},
+			}},
+			orphaned: []EdgeHostnameUsage{oldEHN},
+		},
+		"latest versions without HAPI": {
+			params: MapRequest{ContractID: "ctr_1", GroupID: "grp_2", IncludeLatestVersions: true},
+			init: func(p *papi.Mock, _ *hapi.Mock) {
+				mockEdgeHostnames(p, nil)
+				mockProperties(p, true, oldLatestHostname)
+			},
+			expected: &UsageMap{EdgeHostnames: []EdgeHostnameUsage{
+				withUsages(wwwEHN, classic(2, NetworkStaging, "www.example.com"), classic(1, NetworkProduction, "www.example.com")),
+				withUsages(apiEHN, bucket(NetworkStaging, "api.example.com"), bucket(NetworkStaging, "api2.example.com"), bucket(NetworkProduction, "api2.example.com"), shop),
+				withUsages(oldEHN, classic(3, NetworkLatest, "old.example.com")),
+				{Domain: "other.example.com.edgekey.net", Usages: []Usage{classic(2, NetworkStaging, "other.example.com")}},
+			}},
+		},
+		"property hostnames error": {
+			params: mapRequest,
+			init: func(p *papi.Mock, _ *hapi.Mock) {
+				mockEdgeHostnames(p, nil)
+				mockGroups(p)
+				p.On("GetProperties", mock.Anything, mock.Anything).Return(&papi.GetPropertiesResponse{Properties: papi.PropertiesItems{Items: []*papi.Property{
+					{PropertyID: "prp_1", StagingVersion: ptr.To(1)},
+				}}}, nil).Once()
+				p.On("GetPropertyVersionHostnames", mock.Anything, mock.Anything).Return(nil, papi.ErrGetPropertyVersionHostnames).Once()
+			},
+			withError: []error{ErrMap, papi.ErrGetPropertyVersionHostnames},
+		},
+		"validation error": {
+			params:    MapRequest{ContractID: "ctr_1"},
+			init:      func(*papi.Mock, *hapi.Mock) {},
+			withError: []error{ErrStructValidation},
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			p, h := &papi.Mock{}, &hapi.Mock{}
+			test.init(p, h)
+			clients := Clients{PAPI: p}
+			if test.withHAPI {
+				clients.HAPI = h
+			}
+			result, err := Map(context.Background(), clients, test.params)
+			p.AssertExpectations(t)
+			h.AssertExpectations(t)
+			if test.withError != nil {
+				for _, e := range test.withError {
+					assert.True(t, errors.Is(err, e), "want: %s; got: %s", e, err)
+				}
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, test.expected, result)
+			assert.Equal(t, test.orphaned, result.Orphaned())
+		})
+	}
+}
+
+func TestDeleteEdgeHostname(t *testing.T) {
+	tests := map[string]struct {
+		edgeHostnameID int
+		init           func(*papi.Mock, *hapi.Mock)
+		withError      []error
+	}{
+		"orphaned edge hostname is deleted": {
+			edgeHostnameID: 3,
+			init: func(p *papi.Mock, h *hapi.Mock) {
+				mockProperties(p, true)
+				h.On("DeleteEdgeHostname", mock.Anything, hapi.DeleteEdgeHostnameRequest{
+					DNSZone: "edgesuite.net", RecordName: "old.example.com", Comments: "cleanup",
+				}).Return(&hapi.DeleteEdgeHostnameResponse{ChangeID: 10}, nil).Once()
+			},
+		},
+		"edge hostname in use": {
+			edgeHostnameID: 1,
+			init: func(p *papi.Mock, _ *hapi.Mock) {
+				mockProperties(p, true)
+			},
+			withError: []error{ErrDeleteEdgeHostname, ErrEdgeHostnameInUse},
+		},
+		"edge hostname in use by the latest version only": {
+			edgeHostnameID: 3,
+			init: func(p *papi.Mock, _ *hapi.Mock) {
+				mockProperties(p, true, oldLatestHostname)
+			},
+			withError: []error{ErrDeleteEdgeHostname, ErrEdgeHostnameInUse},
+		},
+		"edge hostname not found": {
+			edgeHostnameID: 9,
+			init: func(p *papi.Mock, _ *hapi.Mock) {
+				mockProperties(p, true)
+			},
+			withError: []error{ErrDeleteEdgeHostname, ErrEdgeHostnameNotFound},
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			p, h := &papi.Mock{}, &hapi.Mock{}
+			mockEdgeHostnames(p, h)
+			test.init(p, h)
+			result, err := DeleteEdgeHostname(context.Background(), Clients{PAPI: p, HAPI: h}, DeleteRequest{
+				MapRequest:     mapRequest,
+				EdgeHostnameID: test.edgeHostnameID,
+				Comments:       "cleanup",
+			})
+			p.AssertExpectations(t)
+			h.AssertExpectations(t)
+			if test.withError != nil {
+				for _, e := range test.withError {
+					assert.True(t, errors.Is(err, e), "want: %s; got: %s", e, err)
+				}
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, 10, result.ChangeID)
+		})
+	}
+}
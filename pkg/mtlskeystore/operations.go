@@ -0,0 +1,43 @@
+package mtlskeystore
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+
+	"github.com/akamai/AkamaiOPEN-edgegrid-golang/v11/pkg/operation"
+)
+
+// ClientCertificateVersionOperation returns an Operation tracking the deployment of a client certificate version.
+// It succeeds once the version is deployed and fails when the version is pending deletion or fails validation.
+// A poll returns ErrVersionNotFound when the version is not listed.
+func ClientCertificateVersionOperation(client MTLSKeystore, certificateID, version int64) *operation.Operation[*ClientCertificateVersion] {
+	return operation.New(strconv.FormatInt(version, 10), func(ctx context.Context) (operation.Status[*ClientCertificateVersion], error) {
+		versions, err := client.ListClientCertificateVersions(ctx, ListClientCertificateVersionsRequest{CertificateID: certificateID})
+		if err != nil {
+			return operation.Status[*ClientCertificateVersion]{}, err
+		}
+		var found *ClientCertificateVersion
+		for i := range versions.Versions {
+			if versions.Versions[i].Version == version {
+				found = &versions.Versions[i]
+			}
+		}
+		if found == nil {
+			return operation.Status[*ClientCertificateVersion]{}, fmt.Errorf("%w: version %d", ErrVersionNotFound, version)
+		}
+
+		status := operation.Status[*ClientCertificateVersion]{State: operation.StatePending, Value: found}
+		switch {
+		case CertificateVersionStatus(found.Status) == CertificateVersionStatusDeployed:
+			status.State = operation.StateSucceeded
+		case CertificateVersionStatus(found.Status) == CertificateVersionStatusDeletePending:
+			status.State = operation.StateFailed
+			status.Message = fmt.Sprintf("version %d is pending deletion", version)
+		case len(found.Validation.Errors) > 0:
+			status.State = operation.StateFailed
+			status.Message = fmt.Sprintf("version %d: %s", version, found.Validation.Errors[0].Message)
+		}
+		return status, nil
+	})
+}
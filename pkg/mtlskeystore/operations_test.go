@@ -0,0 +1,68 @@
+package mtlskeystore
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/akamai/AkamaiOPEN-edgegrid-golang/v11/pkg/operation"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestClientCertificateVersionOperation(t *testing.T) {
+	invalid := ClientCertificateVersion{Version: 2, Status: string(CertificateVersionStatusAwaitingSigned)}
+	invalid.Validation.Errors = []ValidationDetail{{Message: "certificate does not match the CSR"}}
+
+	tests := map[string]struct {
+		version  ClientCertificateVersion
+		expected operation.State
+		message  string
+	}{
+		"pending": {
+			version:  ClientCertificateVersion{Version: 2, Status: string(CertificateVersionStatusDeploymentPending)},
+			expected: operation.StatePending,
+		},
+		"deployed": {
+			version:  ClientCertificateVersion{Version: 2, Status: string(CertificateVersionStatusDeployed)},
+			expected: operation.StateSucceeded,
+		},
+		"pending deletion": {
+			version:  ClientCertificateVersion{Version: 2, Status: string(CertificateVersionStatusDeletePending)},
+			expected: operation.StateFailed,
+			message:  "version 2 is pending deletion",
+		},
+		"validation errors": {
+			version:  invalid,
+			expected: operation.StateFailed,
+			message:  "version 2: certificate does not match the CSR",
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			m := &Mock{}
+			m.On("ListClientCertificateVersions", mock.Anything, ListClientCertificateVersionsRequest{CertificateID: 10}).
+				Return(&ListClientCertificateVersionsResponse{Versions: []ClientCertificateVersion{test.version}}, nil).Once()
+			op := ClientCertificateVersionOperation(m, 10, 2)
+			status, err := op.Poll(context.Background())
+			m.AssertExpectations(t)
+			require.NoError(t, err)
+			assert.Equal(t, "2", op.ID)
+			assert.Equal(t, test.expected, status.State)
+			assert.Equal(t, test.message, status.Message)
+			assert.Equal(t, int64(2), status.Value.Version)
+		})
+	}
+
+	t.Run("version not found", func(t *testing.T) {
+		m := &Mock{}
+		m.On("ListClientCertificateVersions", mock.Anything, ListClientCertificateVersionsRequest{CertificateID: 10}).
+			Return(&ListClientCertificateVersionsResponse{}, nil).Once()
+		_, err := ClientCertificateVersionOperation(m, 10, 2).Poll(context.Background())
+		m.AssertExpectations(t)
+		assert.True(t, errors.Is(err, ErrVersionNotFound))
+		assert.False(t, errors.Is(err, ErrVersionNotDeployed))
+	})
+}
@@ -0,0 +1,287 @@
+package mtlskeystore
+
+import (
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"time"
+
+	"github.com/akamai/AkamaiOPEN-edgegrid-golang/v11/pkg/edgegriderr"
+	"github.com/akamai/AkamaiOPEN-edgegrid-golang/v11/pkg/operation"
+	validation "github.com/go-ozzo/ozzo-validation/v4"
+)
+
+type (
+	// CSRSigner signs the certificate signing requests of THIRD_PARTY client certificate versions.
+	CSRSigner interface {
+		// SignCSR signs the CSR and returns the signed certificate with its trust chain.
+		SignCSR(ctx context.Context, csr CSRBlock) (*SignedClientCertificate, error)
+	}
+
+	// SignedClientCertificate is a certificate returned by a CSRSigner.
+	SignedClientCertificate struct {
+		// Certificate is the signed client certificate in PEM format.
+		Certificate string
+
+		// TrustChain is the trust chain of the certificate in PEM format. Optional.
+		TrustChain string
+	}
+
+	// LocalCASigner is a CSRSigner which signs certificates with a local CA. It is meant for tests and
+	// non-production setups; production certificates are usually signed by an external CA.
+	LocalCASigner struct {
+		// Certificate is the CA certificate.
+		Certificate *x509.Certificate
+
+		// Key is the private key of the CA certificate.
+		Key crypto.Signer
+
+		// Validity is the validity period of signed certificates. It defaults to DefaultLocalCAValidity.
+		Validity time.Duration
+
+		// TrustChain is the trust chain returned with signed certificates in PEM format. It defaults to the CA certificate.
+		TrustChain string
+	}
+
+	// RotateClientCertificateRequest is used to rotate a client certificate with RotateClientCertificate.
+	RotateClientCertificateRequest struct {
+		// CertificateID is a unique identifier representing the client certificate.
+		CertificateID int64
+
+		// Signer signs the new version of a THIRD_PARTY client certificate. Required for THIRD_PARTY client certificates.
+		Signer CSRSigner
+
+		// AcknowledgeAllWarnings specifies whether to ignore all warnings during the signed certificate's upload.
+		AcknowledgeAllWarnings *bool
+
+		// DeleteOldVersion deletes the previously current version once the new version is deployed,
+		// unless properties still reference it.
+		DeleteOldVersion bool
+
+		// PollInterval is the interval between checks of the new version's status. It defaults to DefaultRotationPollInterval.
+		PollInterval time.Duration
+	}
+
+	// RotateClientCertificateResult is the result of RotateClientCertificate.
+	RotateClientCertificateResult struct {
+		// NewVersion is the deployed new version.
+		NewVersion ClientCertificateVersion
+
+		// OldVersion is the version which was current before the rotation. It is nil when the certificate had no deployed version.
+		OldVersion *ClientCertificateVersion
+
+		// OldVersionProperties are the properties which still reference the old version after the new version is deployed.
+		OldVersionProperties []AssociatedProperty
+
+		// OldVersionDeleted is set when the old version was deleted.
+		OldVersionDeleted bool
+
+		// DeleteMessage is the message returned when the old version was deleted.
+		DeleteMessage string
+	}
+)
+
+const (
+	// DefaultRotationPollInterval is the default interval between checks of the new version's status.
+	DefaultRotationPollInterval = 30 * time.Second
+
+	// DefaultLocalCAValidity is the default validity period of certificates signed by LocalCASigner.
+	DefaultLocalCAValidity = 365 * 24 * time.Hour
+
+	versionAliasCurrent = "CURRENT"
+)
+
+var (
+	// ErrRotateClientCertificate represents error when rotating a client certificate fails.
+	ErrRotateClientCertificate = errors.New("rotating client certificate")
+
+	// ErrSignerRequired represents error when a THIRD_PARTY client certificate is rotated without a signer.
+	ErrSignerRequired = errors.New("signer is required for THIRD_PARTY client certificates")
+
+	// ErrVersionNotDeployed represents error when the new client certificate version cannot be deployed.
+	ErrVersionNotDeployed = errors.New("client certificate version was not deployed")
+
+	// ErrVersionNotFound represents error when a client certificate version is missing from the list of versions,
+	// for example because it was deleted.
+	ErrVersionNotFound = errors.New("client certificate version not found")
+
+	// ErrSignCSR represents error when signing a certificate signing request fails.
+	ErrSignCSR = errors.New("signing certificate signing request")
+)
+
+// Validate validates a RotateClientCertificateRequest.
+func (r RotateClientCertificateRequest) Validate() error {
+	return edgegriderr.ParseValidationErrors(validation.Errors{
+		"CertificateID": validateCertificateID(r.CertificateID),
+		"PollInterval":  validation.Validate(r.PollInterval, validation.Min(time.Duration(0))),
+	})
+}
+
+// RotateClientCertificate creates a new version of a client certificate and waits until it is deployed.
+// New versions of THIRD_PARTY client certificates are signed with the given signer and uploaded.
+//
+// Once the new version is deployed, the properties which still reference the previously current version are reported.
+// With DeleteOldVersion set, the old version is deleted only when no property references it.
+func RotateClientCertificate(ctx context.Context, client MTLSKeystore, params RotateClientCertificateRequest) (*RotateClientCertificateResult, error) {
+	if err := params.Validate(); err != nil {
+		return nil, fmt.Errorf("%w: validation failed: %s", ErrRotateClientCertificate, err)
+	}
+	if params.PollInterval == 0 {
+		params.PollInterval = DefaultRotationPollInterval
+	}
+
+	certificate, err := client.GetClientCertificate(ctx, GetClientCertificateRequest{CertificateID: params.CertificateID})
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrRotateClientCertificate, err)
+	}
+	thirdParty := Signer(certificate.Signer) == SignerThirdParty
+	if thirdParty && params.Signer == nil {
+		return nil, fmt.Errorf("%w: %w", ErrRotateClientCertificate, ErrSignerRequired)
+	}
+
+	versions, err := client.ListClientCertificateVersions(ctx, ListClientCertificateVersionsRequest{CertificateID: params.CertificateID})
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrRotateClientCertificate, err)
+	}
+	result := &RotateClientCertificateResult{OldVersion: currentVersion(versions.Versions)}
+
+	rotated, err := client.RotateClientCertificateVersion(ctx, RotateClientCertificateVersionRequest{CertificateID: params.CertificateID})
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrRotateClientCertificate, err)
+	}
+
+	if thirdParty && CertificateVersionStatus(rotated.Status) == CertificateVersionStatusAwaitingSigned {
+		if rotated.CSRBlock == nil {
+			return nil, fmt.Errorf("%w: version %d has no CSR", ErrRotateClientCertificate, rotated.Version)
+		}
+		signed, err := params.Signer.SignCSR(ctx, *rotated.CSRBlock)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %w: %w", ErrRotateClientCertificate, ErrSignCSR, err)
+		}
+		body := UploadSignedClientCertificateRequestBody{Certificate: signed.Certificate}
+		if signed.TrustChain != "" {
+			body.TrustChain = &signed.TrustChain
+		}
+		if err := client.UploadSignedClientCertificate(ctx, UploadSignedClientCertificateRequest{
+			CertificateID:          params.CertificateID,
+			Version:                rotated.Version,
+			AcknowledgeAllWarnings: params.AcknowledgeAllWarnings,
+			Body:                   body,
+		}); err != nil {
+			return nil, fmt.Errorf("%w: %w", ErrRotateClientCertificate, err)
+		}
+	}
+
+	newVersion, err := waitForDeployedVersion(ctx, client, params, rotated.Version)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrRotateClientCertificate, err)
+	}
+	result.NewVersion = *newVersion
+	if result.OldVersion == nil {
+		return result, nil
+	}
+
+	versions, err = client.ListClientCertificateVersions(ctx, ListClientCertificateVersionsRequest{
+		CertificateID:               params.CertificateID,
+		IncludeAssociatedProperties: true,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrRotateClientCertificate, err)
+	}
+	for _, version := range versions.Versions {
+		if version.Version == result.OldVersion.Version {
+			result.OldVersionProperties = version.AssociatedProperties
+		}
+	}
+	if !params.DeleteOldVersion || len(result.OldVersionProperties) > 0 {
+		return result, nil
+	}
+
+	deleted, err := client.DeleteClientCertificateVersion(ctx, DeleteClientCertificateVersionRequest{
+		CertificateID: params.CertificateID,
+		Version:       result.OldVersion.Version,
+	})
+	if err != nil {
+		return result, fmt.Errorf("%w: %w", ErrRotateClientCertificate, err)
+	}
+	result.OldVersionDeleted = true
+	result.DeleteMessage = deleted.Message
+	return result, nil
+}
+
+// SignCSR signs the CSR with the local CA.
+func (s LocalCASigner) SignCSR(_ context.Context, csrBlock CSRBlock) (*SignedClientCertificate, error) {
+	block, _ := pem.Decode([]byte(csrBlock.CSR))
+	if block == nil || block.Type != "CERTIFICATE REQUEST" {
+		return nil, fmt.Errorf("%w: no PEM encoded certificate request found", ErrSignCSR)
+	}
+	csr, err := x509.ParseCertificateRequest(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrSignCSR, err)
+	}
+	if err := csr.CheckSignature(); err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrSignCSR, err)
+	}
+	serial, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrSignCSR, err)
+	}
+	validity := s.Validity
+	if validity == 0 {
+		validity = DefaultLocalCAValidity
+	}
+	now := time.Now()
+	template := &x509.Certificate{
+		SerialNumber: serial,
+		Subject:      csr.Subject,
+		DNSNames:     csr.DNSNames,
+		NotBefore:    now.Add(-time.Minute),
+		NotAfter:     now.Add(validity),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, s.Certificate, csr.PublicKey, s.Key)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrSignCSR, err)
+	}
+	trustChain := s.TrustChain
+	if trustChain == "" {
+		trustChain = string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: s.Certificate.Raw}))
+	}
+	return &SignedClientCertificate{
+		Certificate: string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})),
+		TrustChain:  trustChain,
+	}, nil
+}
+
+// currentVersion returns the version with the CURRENT alias or, when no version has it, the latest deployed version.
+func currentVersion(versions []ClientCertificateVersion) *ClientCertificateVersion {
+	var current *ClientCertificateVersion
+	for i, version := range versions {
+		if version.VersionAlias != nil && *version.VersionAlias == versionAliasCurrent {
+			return &versions[i]
+		}
+		if CertificateVersionStatus(version.Status) == CertificateVersionStatusDeployed && (current == nil || version.Version > current.Version) {
+			current = &versions[i]
+		}
+	}
+	return current
+}
+
+func waitForDeployedVersion(ctx context.Context, client MTLSKeystore, params RotateClientCertificateRequest, version int64) (*ClientCertificateVersion, error) {
+	op := ClientCertificateVersionOperation(client, params.CertificateID, version)
+	op.Backoff = operation.ConstantBackoff(params.PollInterval)
+	status, err := op.Wait(ctx)
+	if errors.Is(err, operation.ErrOperationFailed) {
+		return nil, fmt.Errorf("%w: %s", ErrVersionNotDeployed, status.Message)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return status.Value, nil
+}
@@ -0,0 +1,241 @@
+package mtlskeystore
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"errors"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/akamai/AkamaiOPEN-edgegrid-golang/v11/pkg/ptr"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestLocalCA(t *testing.T) LocalCASigner {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "Test CA"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(24 * time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, key.Public(), key)
+	require.NoError(t, err)
+	cert, err := x509.ParseCertificate(der)
+	require.NoError(t, err)
+	return LocalCASigner{Certificate: cert, Key: key}
+}
+
+func newTestCSR(t *testing.T) string {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	der, err := x509.CreateCertificateRequest(rand.Reader, &x509.CertificateRequest{Subject: pkix.Name{CommonName: "client"}}, key)
+	require.NoError(t, err)
+	return string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE REQUEST", Bytes: der}))
+}
+
+type failingSigner struct{}
+
+func (failingSigner) SignCSR(context.Context, CSRBlock) (*SignedClientCertificate, error) {
+	return nil, errors.New("CA unavailable")
+}
+
+func TestRotateClientCertificate(t *testing.T) {
+	ca := newTestLocalCA(t)
+	csr := CSRBlock{CSR: newTestCSR(t), KeyAlgorithm: "ECDSA"}
+	property := AssociatedProperty{AssetID: 1, GroupID: 2, PropertyName: "example", PropertyVersion: 3}
+	oldVersion := ClientCertificateVersion{Version: 1, VersionAlias: ptr.To("CURRENT"), Status: "DEPLOYED"}
+	listReq := ListClientCertificateVersionsRequest{CertificateID: 10}
+	listWithPropertiesReq := ListClientCertificateVersionsRequest{CertificateID: 10, IncludeAssociatedProperties: true}
+	versions := func(vs ...ClientCertificateVersion) *ListClientCertificateVersionsResponse {
+		return &ListClientCertificateVersionsResponse{Versions: vs}
+	}
+	withProperties := func(v ClientCertificateVersion, properties ...AssociatedProperty) ClientCertificateVersion {
+		v.AssociatedProperties = properties
+		return v
+	}
+	newVersion := func(status CertificateVersionStatus) ClientCertificateVersion {
+		return ClientCertificateVersion{Version: 2, Status: string(status)}
+	}
+	akamaiCertificate := &GetClientCertificateResponse{CertificateID: 10, Signer: string(SignerAkamai)}
+	thirdPartyCertificate := &GetClientCertificateResponse{CertificateID: 10, Signer: string(SignerThirdParty)}
+
+	tests := map[string]struct {
+		params    RotateClientCertificateRequest
+		init      func(*Mock)
+		expected  *RotateClientCertificateResult
+		withError func(*testing.T, error)
+	}{
+		"akamai signed, old version deleted": {
+			params: RotateClientCertificateRequest{CertificateID: 10, DeleteOldVersion: true, PollInterval: time.Millisecond},
+			init: func(m *Mock) {
+				m.On("GetClientCertificate", mock.Anything, GetClientCertificateRequest{CertificateID: 10}).Return(akamaiCertificate, nil).Once()
+				m.On("ListClientCertificateVersions", mock.Anything, listReq).Return(versions(oldVersion), nil).Once()
+				m.On("RotateClientCertificateVersion", mock.Anything, RotateClientCertificateVersionRequest{CertificateID: 10}).
+					Return(&RotateClientCertificateVersionResponse{Version: 2, Status: string(CertificateVersionStatusDeploymentPending)}, nil).Once()
+				m.On("ListClientCertificateVersions", mock.Anything, listReq).
+					Return(versions(newVersion(CertificateVersionStatusDeploymentPending), oldVersion), nil).Once()
+				m.On("ListClientCertificateVersions", mock.Anything, listReq).
+					Return(versions(newVersion(CertificateVersionStatusDeployed), oldVersion), nil).Once()
+				m.On("ListClientCertificateVersions", mock.Anything, listWithPropertiesReq).
+					Return(versions(newVersion(CertificateVersionStatusDeployed), oldVersion), nil).Once()
+				m.On("DeleteClientCertificateVersion", mock.Anything, DeleteClientCertificateVersionRequest{CertificateID: 10, Version: 1}).
+					Return(&DeleteClientCertificateVersionResponse{Message: "scheduled for deletion"}, nil).Once()
+			},
+			expected: &RotateClientCertificateResult{
+				NewVersion:        newVersion(CertificateVersionStatusDeployed),
+				OldVersion:        &oldVersion,
+				OldVersionDeleted: true,
+				DeleteMessage:     "scheduled for deletion",
+			},
+		},
+		"third party signed, old version still referenced": {
+			params: RotateClientCertificateRequest{CertificateID: 10, Signer: ca, DeleteOldVersion: true, AcknowledgeAllWarnings: ptr.To(true)},
+			init: func(m *Mock) {
+				m.On("GetClientCertificate", mock.Anything, GetClientCertificateRequest{CertificateID: 10}).Return(thirdPartyCertificate, nil).Once()
+				m.On("ListClientCertificateVersions", mock.Anything, listReq).Return(versions(oldVersion), nil).Once()
+				m.On("RotateClientCertificateVersion", mock.Anything, RotateClientCertificateVersionRequest{CertificateID: 10}).
+					Return(&RotateClientCertificateVersionResponse{Version: 2, Status: string(CertificateVersionStatusAwaitingSigned), CSRBlock: &csr}, nil).Once()
+				m.On("UploadSignedClientCertificate", mock.Anything, mock.MatchedBy(func(r UploadSignedClientCertificateRequest) bool {
+					block, _ := pem.Decode([]byte(r.Body.Certificate))
+					if block == nil || r.Body.TrustChain == nil {
+						return false
+					}
+					cert, err := x509.ParseCertificate(block.Bytes)
+					return err == nil && cert.CheckSignatureFrom(ca.Certificate) == nil && cert.Subject.CommonName == "client" &&
+						r.CertificateID == 10 && r.Version == 2 && *r.AcknowledgeAllWarnings
+				})).Return(nil).Once()
+				m.On("ListClientCertificateVersions", mock.Anything, listReq).
+					Return(versions(newVersion(CertificateVersionStatusDeployed), oldVersion), nil).Once()
+				m.On("ListClientCertificateVersions", mock.Anything, listWithPropertiesReq).
+					Return(versions(newVersion(CertificateVersionStatusDeployed), withProperties(oldVersion, property)), nil).Once()
+			},
+			expected: &RotateClientCertificateResult{
+				NewVersion:           newVersion(CertificateVersionStatusDeployed),
+				OldVersion:           &oldVersion,
+				OldVersionProperties: []AssociatedProperty{property},
+			},
+		},
+		"first version": {
+			params: RotateClientCertificateRequest{CertificateID: 10, DeleteOldVersion: true},
+			init: func(m *Mock) {
+				m.On("GetClientCertificate", mock.Anything, GetClientCertificateRequest{CertificateID: 10}).Return(akamaiCertificate, nil).Once()
+				m.On("ListClientCertificateVersions", mock.Anything, listReq).Return(versions(), nil).Once()
+				m.On("RotateClientCertificateVersion", mock.Anything, RotateClientCertificateVersionRequest{CertificateID: 10}).
+					Return(&RotateClientCertificateVersionResponse{Version: 2, Status: string(CertificateVersionStatusDeployed)}, nil).Once()
+				m.On("ListClientCertificateVersions", mock.Anything, listReq).
+					Return(versions(newVersion(CertificateVersionStatusDeployed)), nil).Once()
+			},
+			expected: &RotateClientCertificateResult{NewVersion: newVersion(CertificateVersionStatusDeployed)},
+		},
+		"third party without signer": {
+			params: RotateClientCertificateRequest{CertificateID: 10},
+			init: func(m *Mock) {
+				m.On("GetClientCertificate", mock.Anything, GetClientCertificateRequest{CertificateID: 10}).Return(thirdPartyCertificate, nil).Once()
+			},
+			withError: func(t *testing.T, err error) {
+				assert.True(t, errors.Is(err, ErrRotateClientCertificate))
+				assert.True(t, errors.Is(err, ErrSignerRequired))
+			},
+		},
+		"signing fails": {
+			params: RotateClientCertificateRequest{CertificateID: 10, Signer: failingSigner{}},
+			init: func(m *Mock) {
+				m.On("GetClientCertificate", mock.Anything, GetClientCertificateRequest{CertificateID: 10}).Return(thirdPartyCertificate, nil).Once()
+				m.On("ListClientCertificateVersions", mock.Anything, listReq).Return(versions(oldVersion), nil).Once()
+				m.On("RotateClientCertificateVersion", mock.Anything, RotateClientCertificateVersionRequest{CertificateID: 10}).
+					Return(&RotateClientCertificateVersionResponse{Version: 2, Status: string(CertificateVersionStatusAwaitingSigned), CSRBlock: &csr}, nil).Once()
+			},
+			withError: func(t *testing.T, err error) {
+				assert.True(t, errors.Is(err, ErrSignCSR))
+				assert.Contains(t, err.Error(), "CA unavailable")
+			},
+		},
+		"new version fails validation": {
+			params: RotateClientCertificateRequest{CertificateID: 10, Signer: ca},
+			init: func(m *Mock) {
+				m.On("GetClientCertificate", mock.Anything, GetClientCertificateRequest{CertificateID: 10}).Return(thirdPartyCertificate, nil).Once()
+				m.On("ListClientCertificateVersions", mock.Anything, listReq).Return(versions(oldVersion), nil).Once()
+				m.On("RotateClientCertificateVersion", mock.Anything, RotateClientCertificateVersionRequest{CertificateID: 10}).
+					Return(&RotateClientCertificateVersionResponse{Version: 2, Status: string(CertificateVersionStatusAwaitingSigned), CSRBlock: &csr}, nil).Once()
+				m.On("UploadSignedClientCertificate", mock.Anything, mock.Anything).Return(nil).Once()
+				failed := newVersion(CertificateVersionStatusAwaitingSigned)
+				failed.Validation.Errors = []ValidationDetail{{Message: "certificate does not match the CSR"}}
+				m.On("ListClientCertificateVersions", mock.Anything, listReq).Return(versions(failed, oldVersion), nil).Once()
+			},
+			withError: func(t *testing.T, err error) {
+				assert.True(t, errors.Is(err, ErrVersionNotDeployed))
+				assert.Contains(t, err.Error(), "certificate does not match the CSR")
+			},
+		},
+		"new version deleted": {
+			params: RotateClientCertificateRequest{CertificateID: 10},
+			init: func(m *Mock) {
+				m.On("GetClientCertificate", mock.Anything, GetClientCertificateRequest{CertificateID: 10}).Return(akamaiCertificate, nil).Once()
+				m.On("ListClientCertificateVersions", mock.Anything, listReq).Return(versions(oldVersion), nil).Once()
+				m.On("RotateClientCertificateVersion", mock.Anything, RotateClientCertificateVersionRequest{CertificateID: 10}).
+					Return(&RotateClientCertificateVersionResponse{Version: 2, Status: string(CertificateVersionStatusDeploymentPending)}, nil).Once()
+				m.On("ListClientCertificateVersions", mock.Anything, listReq).Return(versions(oldVersion), nil).Once()
+			},
+			withError: func(t *testing.T, err error) {
+				assert.True(t, errors.Is(err, ErrVersionNotFound))
+				assert.Contains(t, err.Error(), "version 2")
+			},
+		},
+		"validation error": {
+			params: RotateClientCertificateRequest{},
+			init:   func(*Mock) {},
+			withError: func(t *testing.T, err error) {
+				assert.True(t, errors.Is(err, ErrRotateClientCertificate))
+				assert.Contains(t, err.Error(), "CertificateID: cannot be blank")
+			},
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			m := &Mock{}
+			tc.init(m)
+			result, err := RotateClientCertificate(context.Background(), m, tc.params)
+			m.AssertExpectations(t)
+			if tc.withError != nil {
+				tc.withError(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tc.expected, result)
+		})
+	}
+}
+
+func TestLocalCASigner(t *testing.T) {
+	ca := newTestLocalCA(t)
+	ca.Validity = time.Hour
+
+	signed, err := ca.SignCSR(context.Background(), CSRBlock{CSR: newTestCSR(t)})
+	require.NoError(t, err)
+	block, _ := pem.Decode([]byte(signed.Certificate))
+	require.NotNil(t, block)
+	cert, err := x509.ParseCertificate(block.Bytes)
+	require.NoError(t, err)
+	assert.NoError(t, cert.CheckSignatureFrom(ca.Certificate))
+	assert.Equal(t, "client", cert.Subject.CommonName)
+	assert.Equal(t, []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth}, cert.ExtKeyUsage)
+	assert.WithinDuration(t, time.Now().Add(time.Hour), cert.NotAfter, time.Minute)
+	assert.Equal(t, string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: ca.Certificate.Raw})), signed.TrustChain)
+
+	_, err = ca.SignCSR(context.Background(), CSRBlock{CSR: "invalid"})
+	assert.True(t, errors.Is(err, ErrSignCSR))
+}
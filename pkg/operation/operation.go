@@ -0,0 +1,238 @@
+// Package operation provides a common way to track asynchronous operations of Akamai APIs, which accept a request
+// and process it in the background while its status is polled with a separate call.
+//
+// Packages return an Operation for each of their asynchronous APIs, mapping the API specific status to a State.
+// Callers either Poll the operation themselves or Wait until it reaches a terminal state.
+package operation
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+type (
+	// Operation tracks an asynchronous operation
+	Operation[T any] struct {
+		// ID identifies the operation in events and errors, e.g. the request or change ID
+		ID string
+
+		// PollFunc gets the current status of the operation
+		PollFunc PollFunc[T]
+
+		// Backoff returns the delay between polls. It defaults to DefaultBackoff
+		Backoff Backoff
+
+		// InitialDelay is the delay before the first poll of Wait, for APIs which ask to wait before the status is
+		// checked. Optional; by default the first poll is immediate
+		InitialDelay time.Duration
+
+		// OnProgress is called after each poll. Optional
+		OnProgress func(Event[T])
+
+		// IsRetryable reports whether a poll error is temporary, in which case polling continues. Optional;
+		// by default any poll error stops Wait
+		IsRetryable func(error) bool
+	}
+
+	// PollFunc gets the current status of an operation
+	PollFunc[T any] func(context.Context) (Status[T], error)
+
+	// Status is the status of an operation
+	Status[T any] struct {
+		// State is the state of the operation
+		State State
+
+		// Message describes the state, e.g. the reason of a failure
+		Message string
+
+		// Value is the status returned by the API
+		Value T
+	}
+
+	// Event reports a poll of an operation
+	Event[T any] struct {
+		// ID is the operation ID
+		ID string
+
+		// Attempt is the number of the poll, starting at 1
+		Attempt int
+
+		// Status is the polled status. It is empty when the poll failed
+		Status Status[T]
+
+		// Err is the error of a failed poll
+		Err error
+
+		// NextPoll is the delay until the next poll. It is zero when the operation reached a terminal state or Wait stops
+		NextPoll time.Duration
+	}
+
+	// State is a state of an operation
+	State string
+
+	// Backoff returns the delay before the next poll of an operation
+	Backoff interface {
+		// Delay returns the delay after the given poll attempt, starting at 1
+		Delay(attempt int) time.Duration
+	}
+
+	// ConstantBackoff is a Backoff with the same delay between all polls
+	ConstantBackoff time.Duration
+
+	// ExponentialBackoff is a Backoff which multiplies the delay after each poll
+	ExponentialBackoff struct {
+		// Initial is the delay after the first poll
+		Initial time.Duration
+
+		// Max is the maximum delay. Optional
+		Max time.Duration
+
+		// Multiplier multiplies the delay after each poll. It defaults to 2
+		Multiplier float64
+	}
+)
+
+const (
+	// StatePending represents an operation which is still processed
+	StatePending State = "PENDING"
+	// StateSucceeded represents an operation which finished successfully
+	StateSucceeded State = "SUCCEEDED"
+	// StateFailed represents an operation which failed
+	StateFailed State = "FAILED"
+	// StateAwaitingInput represents an operation which cannot proceed until the user acts on it
+	StateAwaitingInput State = "AWAITING_INPUT"
+)
+
+var (
+	// DefaultBackoff is the Backoff used by operations with no Backoff set
+	DefaultBackoff Backoff = ExponentialBackoff{Initial: 5 * time.Second, Max: 2 * time.Minute}
+
+	// ErrOperationFailed is returned by Wait when the operation reaches StateFailed
+	ErrOperationFailed = errors.New("operation failed")
+	// ErrAwaitingInput is returned by Wait when the operation reaches StateAwaitingInput
+	ErrAwaitingInput = errors.New("operation awaiting input")
+	// ErrUnknownState is returned when a poll returns a state not known to the package
+	ErrUnknownState = errors.New("unknown operation state")
+	// ErrPollFuncRequired is returned when an operation has no PollFunc
+	ErrPollFuncRequired = errors.New("operation poll function is required")
+)
+
+// New returns an Operation with the given ID and poll function
+func New[T any](id string, poll PollFunc[T]) *Operation[T] {
+	return &Operation[T]{ID: id, PollFunc: poll}
+}
+
+// Terminal reports whether no further progress can be made by waiting
+func (s State) Terminal() bool {
+	return s == StateSucceeded || s == StateFailed || s == StateAwaitingInput
+}
+
+// Validate validates a State
+func (s State) Validate() error {
+	switch s {
+	case StatePending, StateSucceeded, StateFailed, StateAwaitingInput:
+		return nil
+	}
+	return fmt.Errorf("%w: '%s'", ErrUnknownState, s)
+}
+
+// Delay returns the constant delay
+func (b ConstantBackoff) Delay(int) time.Duration {
+	return time.Duration(b)
+}
+
+// Delay returns the initial delay multiplied for each previous attempt, up to the maximum delay
+func (b ExponentialBackoff) Delay(attempt int) time.Duration {
+	multiplier := b.Multiplier
+	if multiplier <= 0 {
+		multiplier = 2
+	}
+	delay := float64(b.Initial)
+	for i := 1; i < attempt; i++ {
+		delay *= multiplier
+		if b.Max > 0 && delay >= float64(b.Max) {
+			return b.Max
+		}
+	}
+	return time.Duration(delay)
+}
+
+// Poll gets the current status of the operation once
+func (o *Operation[T]) Poll(ctx context.Context) (Status[T], error) {
+	if o.PollFunc == nil {
+		return Status[T]{}, fmt.Errorf("%s: %w", o.ID, ErrPollFuncRequired)
+	}
+	status, err := o.PollFunc(ctx)
+	if err != nil {
+		return Status[T]{}, err
+	}
+	if err := status.State.Validate(); err != nil {
+		return Status[T]{}, fmt.Errorf("%s: %w", o.ID, err)
+	}
+	return status, nil
+}
+
+// Wait polls the operation until it reaches a terminal state and returns its last status.
+// The first poll is made after InitialDelay.
+//
+// It returns ErrOperationFailed or ErrAwaitingInput along with the status when the operation does not succeed,
+// the poll error when it is not retryable, or the context error when the context is done first.
+func (o *Operation[T]) Wait(ctx context.Context) (Status[T], error) {
+	backoff := o.Backoff
+	if backoff == nil {
+		backoff = DefaultBackoff
+	}
+	if err := Sleep(ctx, o.InitialDelay); err != nil {
+		return Status[T]{}, err
+	}
+	for attempt := 1; ; attempt++ {
+		status, err := o.Poll(ctx)
+		event := Event[T]{ID: o.ID, Attempt: attempt, Status: status, Err: err}
+		retry := err == nil || (o.IsRetryable != nil && o.IsRetryable(err))
+		if retry && !status.State.Terminal() {
+			event.NextPoll = backoff.Delay(attempt)
+		}
+		if o.OnProgress != nil {
+			o.OnProgress(event)
+		}
+
+		switch {
+		case !retry:
+			return status, err
+		case status.State == StateSucceeded:
+			return status, nil
+		case status.State == StateFailed:
+			return status, o.terminalError(ErrOperationFailed, status)
+		case status.State == StateAwaitingInput:
+			return status, o.terminalError(ErrAwaitingInput, status)
+		}
+
+		if err := Sleep(ctx, event.NextPoll); err != nil {
+			return status, err
+		}
+	}
+}
+
+// Sleep waits for the given duration or until the context is done, in which case it returns the context error
+func Sleep(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return nil
+	}
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+func (o *Operation[T]) terminalError(err error, status Status[T]) error {
+	if status.Message == "" {
+		return fmt.Errorf("%w: %s", err, o.ID)
+	}
+	return fmt.Errorf("%w: %s: %s", err, o.ID, status.Message)
+}
@@ -0,0 +1,165 @@
+package operation
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var errTransient = errors.New("transient")
+
+func sequence(results ...any) PollFunc[int] {
+	i := 0
+	return func(context.Context) (Status[int], error) {
+		r := results[i]
+		i++
+		if err, ok := r.(error); ok {
+			return Status[int]{}, err
+		}
+		return r.(Status[int]), nil
+	}
+}
+
+func TestWait(t *testing.T) {
+	pending := Status[int]{State: StatePending, Value: 1}
+
+	tests := map[string]struct {
+		poll        PollFunc[int]
+		isRetryable func(error) bool
+		expected    Status[int]
+		attempts    []int
+		withError   []error
+	}{
+		"succeeded": {
+			poll:     sequence(pending, pending, Status[int]{State: StateSucceeded, Value: 2}),
+			expected: Status[int]{State: StateSucceeded, Value: 2},
+			attempts: []int{1, 2, 3},
+		},
+		"failed": {
+			poll:      sequence(pending, Status[int]{State: StateFailed, Message: "rejected", Value: 3}),
+			expected:  Status[int]{State: StateFailed, Message: "rejected", Value: 3},
+			attempts:  []int{1, 2},
+			withError: []error{ErrOperationFailed},
+		},
+		"awaiting input": {
+			poll:      sequence(Status[int]{State: StateAwaitingInput}),
+			expected:  Status[int]{State: StateAwaitingInput},
+			attempts:  []int{1},
+			withError: []error{ErrAwaitingInput},
+		},
+		"retryable poll error": {
+			poll:        sequence(errTransient, Status[int]{State: StateSucceeded}),
+			isRetryable: func(err error) bool { return errors.Is(err, errTransient) },
+			expected:    Status[int]{State: StateSucceeded},
+			attempts:    []int{1, 2},
+		},
+		"poll error": {
+			poll:      sequence(pending, errTransient),
+			attempts:  []int{1, 2},
+			withError: []error{errTransient},
+		},
+		"unknown state": {
+			poll:      sequence(Status[int]{State: "DONE"}),
+			attempts:  []int{1},
+			withError: []error{ErrUnknownState},
+		},
+		"no poll function": {
+			attempts:  []int{1},
+			withError: []error{ErrPollFuncRequired},
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			var attempts []int
+			op := New("op-1", test.poll)
+			op.Backoff = ConstantBackoff(time.Millisecond)
+			op.IsRetryable = test.isRetryable
+			op.OnProgress = func(e Event[int]) {
+				assert.Equal(t, "op-1", e.ID)
+				assert.Equal(t, e.Status.State.Terminal() || (e.Err != nil && test.isRetryable == nil), e.NextPoll == 0)
+				attempts = append(attempts, e.Attempt)
+			}
+			status, err := op.Wait(context.Background())
+			assert.Equal(t, test.attempts, attempts)
+			assert.Equal(t, test.expected, status)
+			if test.withError != nil {
+				for _, e := range test.withError {
+					assert.True(t, errors.Is(err, e), "want: %s; got: %s", e, err)
+				}
+				return
+			}
+			require.NoError(t, err)
+		})
+	}
+}
+
+func TestWaitContextDone(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	op := New("op-1", func(context.Context) (Status[int], error) {
+		cancel()
+		return Status[int]{State: StatePending}, nil
+	})
+	op.Backoff = ConstantBackoff(time.Hour)
+
+	status, err := op.Wait(ctx)
+	assert.True(t, errors.Is(err, context.Canceled))
+	assert.Equal(t, StatePending, status.State)
+}
+
+func TestWaitInitialDelay(t *testing.T) {
+	t.Run("first poll is delayed", func(t *testing.T) {
+		start := time.Now()
+		var firstPoll time.Duration
+		op := New("op-1", func(context.Context) (Status[int], error) {
+			firstPoll = time.Since(start)
+			return Status[int]{State: StateSucceeded}, nil
+		})
+		op.InitialDelay = 20 * time.Millisecond
+
+		_, err := op.Wait(context.Background())
+		require.NoError(t, err)
+		assert.GreaterOrEqual(t, firstPoll, 20*time.Millisecond)
+	})
+
+	t.Run("context done before first poll", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		op := New("op-1", func(context.Context) (Status[int], error) {
+			t.Fatal("unexpected poll")
+			return Status[int]{}, nil
+		})
+		op.InitialDelay = time.Hour
+
+		_, err := op.Wait(ctx)
+		assert.True(t, errors.Is(err, context.Canceled))
+	})
+}
+
+func TestExponentialBackoff(t *testing.T) {
+	tests := map[string]struct {
+		backoff  ExponentialBackoff
+		expected []time.Duration
+	}{
+		"default multiplier": {
+			backoff:  ExponentialBackoff{Initial: time.Second, Max: 5 * time.Second},
+			expected: []time.Duration{time.Second, 2 * time.Second, 4 * time.Second, 5 * time.Second, 5 * time.Second},
+		},
+		"custom multiplier without max": {
+			backoff:  ExponentialBackoff{Initial: time.Second, Multiplier: 1.5},
+			expected: []time.Duration{time.Second, 1500 * time.Millisecond, 2250 * time.Millisecond},
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			for i, expected := range test.expected {
+				assert.Equal(t, expected, test.backoff.Delay(i+1))
+			}
+		})
+	}
+}
@@ -0,0 +1,783 @@
+// Package securitypromotion clones a golden Application Security configuration, together with its
+// BotMan settings, into many accounts managed through account switch keys.
+package securitypromotion
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/akamai/AkamaiOPEN-edgegrid-golang/v11/pkg/appsec"
+	"github.com/akamai/AkamaiOPEN-edgegrid-golang/v11/pkg/botman"
+	"github.com/akamai/AkamaiOPEN-edgegrid-golang/v11/pkg/edgegrid"
+	"github.com/akamai/AkamaiOPEN-edgegrid-golang/v11/pkg/edgegriderr"
+	"github.com/akamai/AkamaiOPEN-edgegrid-golang/v11/pkg/session"
+	validation "github.com/go-ozzo/ozzo-validation/v4"
+)
+
+type (
+	// Clients groups the API clients acting on behalf of a single account.
+	// BotMan may be nil, in which case BotMan settings are neither exported nor promoted.
+	Clients struct {
+		APPSEC appsec.APPSEC
+		BotMan botman.BotMan
+	}
+
+	// ClientFactory returns clients acting on behalf of the account identified by the account switch key.
+	// Account switch keys available to the API client can be listed with iam.ListAccountSwitchKeys.
+	ClientFactory func(accountSwitchKey string) (*Clients, error)
+
+	// Target describes an account the golden configuration is promoted into.
+	Target struct {
+		// AccountSwitchKey identifies the target account. An empty key targets the account of the API client.
+		AccountSwitchKey string
+		// ConfigName is the name of the created configuration. It defaults to the name of the golden configuration.
+		ConfigName  string
+		Description string
+		ContractID  string
+		GroupID     int
+		// Hostnames maps hostnames of the golden configuration to hostnames of the target account.
+		Hostnames map[string]string
+		// NetworkListIDs maps network list IDs of the golden configuration to network list IDs of the target account.
+		NetworkListIDs map[string]string
+		// APIEndpointIDs maps API endpoint IDs of the golden configuration to API endpoint IDs of the target account.
+		// Every API endpoint referenced by an API match target of the golden configuration must be mapped.
+		APIEndpointIDs map[int]int
+	}
+
+	// PromoteRequest is used to call Promote.
+	PromoteRequest struct {
+		ConfigID int
+		Version  int
+		Targets  []Target
+		// Concurrency limits the number of targets promoted at the same time. It defaults to DefaultConcurrency.
+		Concurrency int
+	}
+
+	// Status is the outcome of promoting a configuration into a single target.
+	Status string
+
+	// TargetReport describes what was created in a single target account.
+	// When promotion fails half way, the report lists the objects created before the failure,
+	// so that they can be inspected or removed.
+	TargetReport struct {
+		AccountSwitchKey  string                     `json:"accountSwitchKey"`
+		Status            Status                     `json:"status"`
+		ConfigID          int                        `json:"configId,omitempty"`
+		Version           int                        `json:"version,omitempty"`
+		SecurityPolicyIDs map[string]string          `json:"securityPolicyIds,omitempty"`
+		CustomRuleIDs     map[int]int                `json:"customRuleIds,omitempty"`
+		RatePolicyIDs     map[int]int                `json:"ratePolicyIds,omitempty"`
+		MatchTargetIDs    map[int]int                `json:"matchTargetIds,omitempty"`
+		BotMan            *botman.ImportBundleResult `json:"botMan,omitempty"`
+		FailedStep        string                     `json:"failedStep,omitempty"`
+		Error             string                     `json:"error,omitempty"`
+		Err               error                      `json:"-"`
+	}
+
+	// Report is returned from a call to Promote. Targets are listed in the order of the request.
+	Report struct {
+		Targets []TargetReport `json:"targets"`
+	}
+)
+
+const (
+	// StatusSucceeded indicates that all objects were created in the target account.
+	StatusSucceeded Status = "SUCCEEDED"
+	// StatusFailed indicates that promotion stopped at TargetReport.FailedStep.
+	StatusFailed Status = "FAILED"
+
+	// DefaultConcurrency is the number of targets promoted at the same time when PromoteRequest.Concurrency is not set.
+	DefaultConcurrency = 4
+)
+
+const (
+	stepClients                 = "create clients"
+	stepSubstitute              = "substitute values"
+	stepCreateConfiguration     = "create configuration"
+	stepCreateSecurityPolicies  = "create security policies"
+	stepUpdatePolicySettings    = "update security policy settings"
+	stepCreateCustomRules       = "create custom rules"
+	stepUpdateCustomRuleActions = "update custom rule actions"
+	stepCreateRatePolicies      = "create rate policies"
+	stepUpdateRatePolicyActions = "update rate policy actions"
+	stepCreateMatchTargets      = "create match targets"
+	stepUpdateBotSettings       = "update bot management settings"
+	stepImportBotMan            = "import botman settings"
+)
+
+var (
+	// ErrStructValidation is returned when given struct validation failed.
+	ErrStructValidation = errors.New("struct validation")
+
+	// ErrExport is returned when the golden configuration cannot be exported.
+	ErrExport = errors.New("golden configuration export")
+
+	// ErrPromotion is returned from TargetReport.Err when promoting into a target fails.
+	ErrPromotion = errors.New("promotion")
+)
+
+// Validate validates a Target.
+func (t Target) Validate() error {
+	return validation.Errors{
+		"ContractID": validation.Validate(t.ContractID, validation.Required),
+		"GroupID":    validation.Validate(t.GroupID, validation.Required),
+	}.Filter()
+}
+
+// Validate validates a PromoteRequest.
+func (v PromoteRequest) Validate() error {
+	return edgegriderr.ParseValidationErrors(validation.Errors{
+		"ConfigID":    validation.Validate(v.ConfigID, validation.Required),
+		"Version":     validation.Validate(v.Version, validation.Required),
+		"Targets":     validation.Validate(v.Targets, validation.Required),
+		"Concurrency": validation.Validate(v.Concurrency, validation.Min(0)),
+	})
+}
+
+// Failed returns reports of the targets which were not promoted successfully.
+func (r Report) Failed() []TargetReport {
+	var failed []TargetReport
+	for _, target := range r.Targets {
+		if target.Status != StatusSucceeded {
+			failed = append(failed, target)
+		}
+	}
+	return failed
+}
+
+// SessionClientFactory returns a ClientFactory creating a new session for every account switch key.
+// The config is copied and its AccountKey replaced, so the signer adds the key to each request.
+func SessionClientFactory(config edgegrid.Config, opts ...session.Option) ClientFactory {
+	return func(accountSwitchKey string) (*Clients, error) {
+		signer := config
+		signer.AccountKey = accountSwitchKey
+		sess, err := session.New(append([]session.Option{session.WithSigner(&signer)}, opts...)...)
+		if err != nil {
+			return nil, err
+		}
+		return &Clients{
+			APPSEC: appsec.Client(sess),
+			BotMan: botman.Client(sess),
+		}, nil
+	}
+}
+
+// Promote exports the golden configuration version using the source clients and recreates its security policies,
+// custom rules, rate policies, website match targets and BotMan settings in a new configuration of every target.
+//
+// Each security policy gets the protections, WAF mode, attack group and rule actions, penalty box, slow POST,
+// IP/Geo firewall, reputation profile actions and API request constraints action of the golden policy.
+// Actions of individual API endpoints are not copied, as endpoint IDs belong to the golden account.
+//
+// Hostnames and network list IDs are substituted per target in every exported string value which equals a key of
+// Target.Hostnames or Target.NetworkListIDs. Contract and group IDs of the target are used to create the configuration.
+// Targets are promoted concurrently. A failure in one target does not stop the others; it is recorded in the report.
+// An error is returned only when the request is invalid or the golden configuration cannot be exported.
+func Promote(ctx context.Context, source Clients, factory ClientFactory, params PromoteRequest) (*Report, error) {
+	if err := params.Validate(); err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrStructValidation, err.Error())
+	}
+	for i, target := range params.Targets {
+		if err := target.Validate(); err != nil {
+			return nil, fmt.Errorf("%w: target %d: %s", ErrStructValidation, i, err.Error())
+		}
+	}
+
+	golden, err := source.APPSEC.GetExportConfiguration(ctx, appsec.GetExportConfigurationRequest{
+		ConfigID: params.ConfigID,
+		Version:  params.Version,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrExport, err)
+	}
+
+	wafModes := make(map[string]string, len(golden.SecurityPolicies))
+	for _, policy := range golden.SecurityPolicies {
+		mode, err := source.APPSEC.GetWAFMode(ctx, appsec.GetWAFModeRequest{
+			ConfigID: params.ConfigID,
+			Version:  params.Version,
+			PolicyID: policy.ID,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("%w: WAF mode of policy %s: %w", ErrExport, policy.ID, err)
+		}
+		wafModes[policy.ID] = mode.Mode
+	}
+
+	var bundle *botman.Bundle
+	if source.BotMan != nil {
+		policyIDs := make([]string, 0, len(golden.SecurityPolicies))
+		for _, policy := range golden.SecurityPolicies {
+			policyIDs = append(policyIDs, policy.ID)
+		}
+		bundle, err = botman.ExportBundle(ctx, source.BotMan, botman.ExportBundleRequest{
+			ConfigID:          int64(params.ConfigID),
+			Version:           int64(params.Version),
+			SecurityPolicyIDs: policyIDs,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("%w: %w", ErrExport, err)
+		}
+	}
+
+	concurrency := params.Concurrency
+	if concurrency == 0 {
+		concurrency = DefaultConcurrency
+	}
+
+	report := Report{Targets: make([]TargetReport, len(params.Targets))}
+	sem := make(chan struct{}, concurrency)
+	var wg sync.WaitGroup
+	for i, target := range params.Targets {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(i int, target Target) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			report.Targets[i] = promoteTarget(ctx, factory, golden, wafModes, bundle, target)
+		}(i, target)
+	}
+	wg.Wait()
+
+	return &report, nil
+}
+
+type promotion struct {
+	ctx      context.Context
+	clients  *Clients
+	golden   *appsec.GetExportConfigurationResponse
+	wafModes map[string]string
+	bundle   *botman.Bundle
+	target   Target
+	report   *TargetReport
+}
+
+func promoteTarget(ctx context.Context, factory ClientFactory, golden *appsec.GetExportConfigurationResponse, wafModes map[string]string,
+	bundle *botman.Bundle, target Target) TargetReport {
+	report := TargetReport{AccountSwitchKey: target.AccountSwitchKey}
+
+	fail := func(step string, err error) TargetReport {
+		report.Status = StatusFailed
+		report.FailedStep = step
+		report.Err = fmt.Errorf("%w: account %q: %s: %w", ErrPromotion, target.AccountSwitchKey, step, err)
+		report.Error = report.Err.Error()
+		return report
+	}
+
+	clients, err := factory(target.AccountSwitchKey)
+	if err != nil {
+		return fail(stepClients, err)
+	}
+
+	replacements := make(map[string]string, len(target.Hostnames)+len(target.NetworkListIDs))
+	for from, to := range target.Hostnames {
+		replacements[from] = to
+	}
+	for from, to := range target.NetworkListIDs {
+		replacements[from] = to
+	}
+
+	p := promotion{ctx: ctx, clients: clients, wafModes: wafModes, target: target, report: &report}
+	if p.golden, err = substitute(golden, replacements); err != nil {
+		return fail(stepSubstitute, err)
+	}
+	if bundle != nil && clients.BotMan != nil {
+		if p.bundle, err = substitute(bundle, replacements); err != nil {
+			return fail(stepSubstitute, err)
+		}
+	}
+
+	steps := []struct {
+		name string
+		run  func() error
+	}{
+		{stepCreateConfiguration, p.createConfiguration},
+		{stepCreateSecurityPolicies, p.createSecurityPolicies},
+		{stepUpdatePolicySettings, p.updatePolicySettings},
+		{stepCreateCustomRules, p.createCustomRules},
+		{stepUpdateCustomRuleActions, p.updateCustomRuleActions},
+		{stepCreateRatePolicies, p.createRatePolicies},
+		{stepUpdateRatePolicyActions, p.updateRatePolicyActions},
+		{stepCreateMatchTargets, p.createMatchTargets},
+		{stepUpdateBotSettings, p.updateBotManagementSettings},
+		{stepImportBotMan, p.importBotMan},
+	}
+	for _, step := range steps {
+		if err := step.run(); err != nil {
+			return fail(step.name, err)
+		}
+	}
+
+	report.Status = StatusSucceeded
+	return report
+}
+
+func (p *promotion) createConfiguration() error {
+	name := p.target.ConfigName
+	if name == "" {
+		name = p.golden.ConfigName
+	}
+	created, err := p.clients.APPSEC.CreateConfiguration(p.ctx, appsec.CreateConfigurationRequest{
+		Name:        name,
+		Description: p.target.Description,
+		ContractID:  p.target.ContractID,
+		GroupID:     p.target.GroupID,
+		Hostnames:   p.golden.SelectedHosts,
+	})
+	if err != nil {
+		return err
+	}
+	p.report.ConfigID = created.ConfigID
+	p.report.Version = created.Version
+	return nil
+}
+
+func (p *promotion) createSecurityPolicies() error {
+	for _, policy := range p.golden.SecurityPolicies {
+		created, err := p.clients.APPSEC.CreateSecurityPolicy(p.ctx, appsec.CreateSecurityPolicyRequest{
+			ConfigID:        p.report.ConfigID,
+			Version:         p.report.Version,
+			PolicyName:      policy.Name,
+			PolicyPrefix:    policyPrefix(policy.ID),
+			DefaultSettings: true,
+		})
+		if err != nil {
+			return fmt.Errorf("policy %s: %w", policy.ID, err)
+		}
+		if p.report.SecurityPolicyIDs == nil {
+			p.report.SecurityPolicyIDs = make(map[string]string)
+		}
+		p.report.SecurityPolicyIDs[policy.ID] = created.PolicyID
+	}
+	return nil
+}
+
+func (p *promotion) updatePolicySettings() error {
+	for i, policy := range p.golden.SecurityPolicies {
+		if err := p.updatePolicy(i); err != nil {
+			return fmt.Errorf("policy %s: %w", policy.ID, err)
+		}
+	}
+	return nil
+}
+
+// updatePolicy applies the settings of the i-th golden security policy to the policy created from it.
+// The golden configuration has already been substituted, so network lists and hostnames match the target.
+func (p *promotion) updatePolicy(i int) error {
+	policy := p.golden.SecurityPolicies[i]
+	configID, version, policyID := p.report.ConfigID, p.report.Version, p.report.SecurityPolicyIDs[policy.ID]
+
+	controls := policy.SecurityControls
+	if _, err := p.clients.APPSEC.UpdatePolicyProtections(p.ctx, appsec.UpdatePolicyProtectionsRequest{
+		ConfigID:                      configID,
+		Version:                       version,
+		PolicyID:                      policyID,
+		ApplyAPIConstraints:           controls.ApplyAPIConstraints,
+		ApplyApplicationLayerControls: controls.ApplyApplicationLayerControls,
+		ApplyBotmanControls:           controls.ApplyBotmanControls,
+		ApplyNetworkLayerControls:     controls.ApplyNetworkLayerControls,
+		ApplyRateControls:             controls.ApplyRateControls,
+		ApplyReputationControls:       controls.ApplyReputationControls,
+		ApplySlowPostControls:         controls.ApplySlowPostControls,
+		ApplyMalwareControls:          controls.ApplyMalwareControls,
+	}); err != nil {
+		return fmt.Errorf("protections: %w", err)
+	}
+
+	if mode := p.wafModes[policy.ID]; mode != "" {
+		if _, err := p.clients.APPSEC.UpdateWAFMode(p.ctx, appsec.UpdateWAFModeRequest{
+			ConfigID: configID,
+			Version:  version,
+			PolicyID: policyID,
+			Mode:     mode,
+		}); err != nil {
+			return fmt.Errorf("WAF mode: %w", err)
+		}
+	}
+
+	for _, action := range policy.WebApplicationFirewall.AttackGroupActions {
+		var conditionException json.RawMessage
+		if action.AdvancedExceptionsList != nil || action.Exception != nil {
+			payload, err := json.Marshal(appsec.AttackGroupConditionException{
+				AdvancedExceptionsList: action.AdvancedExceptionsList,
+				Exception:              action.Exception,
+			})
+			if err != nil {
+				return fmt.Errorf("attack group %s: %w", action.Group, err)
+			}
+			conditionException = payload
+		}
+		if _, err := p.clients.APPSEC.UpdateAttackGroup(p.ctx, appsec.UpdateAttackGroupRequest{
+			ConfigID:       configID,
+			Version:        version,
+			PolicyID:       policyID,
+			Group:          action.Group,
+			Action:         action.Action,
+			JsonPayloadRaw: conditionException,
+		}); err != nil {
+			return fmt.Errorf("attack group %s: %w", action.Group, err)
+		}
+	}
+
+	for _, action := range policy.WebApplicationFirewall.RuleActions {
+		var conditionException json.RawMessage
+		if action.Conditions != nil || action.Exception != nil || action.AdvancedExceptionsList != nil {
+			payload, err := json.Marshal(appsec.RuleConditionException{
+				Conditions:             action.Conditions,
+				Exception:              action.Exception,
+				AdvancedExceptionsList: action.AdvancedExceptionsList,
+			})
+			if err != nil {
+				return fmt.Errorf("rule %d: %w", action.ID, err)
+			}
+			conditionException = payload
+		}
+		if _, err := p.clients.APPSEC.UpdateRule(p.ctx, appsec.UpdateRuleRequest{
+			ConfigID:       configID,
+			Version:        version,
+			PolicyID:       policyID,
+			RuleID:         action.ID,
+			Action:         action.Action,
+			JsonPayloadRaw: conditionException,
+		}); err != nil {
+			return fmt.Errorf("rule %d: %w", action.ID, err)
+		}
+	}
+
+	if policy.PenaltyBox != nil {
+		if _, err := p.clients.APPSEC.UpdatePenaltyBox(p.ctx, appsec.UpdatePenaltyBoxRequest{
+			ConfigID:             configID,
+			Version:              version,
+			PolicyID:             policyID,
+			Action:               policy.PenaltyBox.Action,
+			PenaltyBoxProtection: policy.PenaltyBox.PenaltyBoxProtection,
+		}); err != nil {
+			return fmt.Errorf("penalty box: %w", err)
+		}
+	}
+
+	if policy.SlowPost != nil {
+		request := appsec.UpdateSlowPostProtectionSettingRequest{
+			ConfigID: configID,
+			Version:  version,
+			PolicyID: policyID,
+			Action:   policy.SlowPost.Action,
+		}
+		if threshold := policy.SlowPost.SlowRateThreshold; threshold != nil {
+			request.SlowRateThreshold.Rate = threshold.Rate
+			request.SlowRateThreshold.Period = threshold.Period
+		}
+		if threshold := policy.SlowPost.DurationThreshold; threshold != nil {
+			request.DurationThreshold.Timeout = threshold.Timeout
+		}
+		if _, err := p.clients.APPSEC.UpdateSlowPostProtectionSetting(p.ctx, request); err != nil {
+			return fmt.Errorf("slow POST: %w", err)
+		}
+	}
+
+	if firewall := policy.IPGeoFirewall; firewall != nil {
+		if _, err := p.clients.APPSEC.UpdateIPGeo(p.ctx, appsec.UpdateIPGeoRequest{
+			ConfigID:           configID,
+			Version:            version,
+			PolicyID:           policyID,
+			Block:              firewall.Block,
+			GeoControls:        firewall.GeoControls,
+			IPControls:         firewall.IPControls,
+			ASNControls:        firewall.ASNControls,
+			UkraineGeoControls: firewall.UkraineGeoControls,
+		}); err != nil {
+			return fmt.Errorf("IP/Geo firewall: %w", err)
+		}
+	}
+
+	if actions := policy.ClientReputation.ReputationProfileActions; actions != nil {
+		for _, action := range *actions {
+			if _, err := p.clients.APPSEC.UpdateReputationProfileAction(p.ctx, appsec.UpdateReputationProfileActionRequest{
+				ConfigID:            configID,
+				Version:             version,
+				PolicyID:            policyID,
+				ReputationProfileID: action.ID,
+				Action:              action.Action,
+			}); err != nil {
+				return fmt.Errorf("reputation profile %d: %w", action.ID, err)
+			}
+		}
+	}
+
+	if constraints := policy.APIRequestConstraints; constraints != nil && constraints.Action != "" {
+		if _, err := p.clients.APPSEC.UpdateApiRequestConstraints(p.ctx, appsec.UpdateApiRequestConstraintsRequest{
+			ConfigID: configID,
+			Version:  version,
+			PolicyID: policyID,
+			Action:   constraints.Action,
+		}); err != nil {
+			return fmt.Errorf("API request constraints: %w", err)
+		}
+	}
+
+	return nil
+}
+
+func (p *promotion) createCustomRules() error {
+	for _, rule := range p.golden.CustomRules {
+		payload, err := marshalForCreate(rule)
+		if err != nil {
+			return fmt.Errorf("custom rule %d: %w", rule.ID, err)
+		}
+		created, err := p.clients.APPSEC.CreateCustomRule(p.ctx, appsec.CreateCustomRuleRequest{
+			ConfigID:       p.report.ConfigID,
+			Version:        p.report.Version,
+			JsonPayloadRaw: payload,
+		})
+		if err != nil {
+			return fmt.Errorf("custom rule %d: %w", rule.ID, err)
+		}
+		if p.report.CustomRuleIDs == nil {
+			p.report.CustomRuleIDs = make(map[int]int)
+		}
+		p.report.CustomRuleIDs[rule.ID] = created.ID
+	}
+	return nil
+}
+
+func (p *promotion) updateCustomRuleActions() error {
+	for _, policy := range p.golden.SecurityPolicies {
+		for _, action := range policy.CustomRuleActions {
+			ruleID, ok := p.report.CustomRuleIDs[action.ID]
+			if !ok {
+				return fmt.Errorf("policy %s references unknown custom rule %d", policy.ID, action.ID)
+			}
+			if _, err := p.clients.APPSEC.UpdateCustomRuleAction(p.ctx, appsec.UpdateCustomRuleActionRequest{
+				ConfigID: p.report.ConfigID,
+				Version:  p.report.Version,
+				PolicyID: p.report.SecurityPolicyIDs[policy.ID],
+				RuleID:   ruleID,
+				Action:   action.Action,
+			}); err != nil {
+				return fmt.Errorf("policy %s, custom rule %d: %w", policy.ID, action.ID, err)
+			}
+		}
+	}
+	return nil
+}
+
+func (p *promotion) createRatePolicies() error {
+	for _, ratePolicy := range p.golden.RatePolicies {
+		payload, err := marshalForCreate(ratePolicy)
+		if err != nil {
+			return fmt.Errorf("rate policy %d: %w", ratePolicy.ID, err)
+		}
+		created, err := p.clients.APPSEC.CreateRatePolicy(p.ctx, appsec.CreateRatePolicyRequest{
+			ConfigID:       p.report.ConfigID,
+			ConfigVersion:  p.report.Version,
+			JsonPayloadRaw: payload,
+		})
+		if err != nil {
+			return fmt.Errorf("rate policy %d: %w", ratePolicy.ID, err)
+		}
+		if p.report.RatePolicyIDs == nil {
+			p.report.RatePolicyIDs = make(map[int]int)
+		}
+		p.report.RatePolicyIDs[ratePolicy.ID] = created.ID
+	}
+	return nil
+}
+
+func (p *promotion) updateRatePolicyActions() error {
+	for _, policy := range p.golden.SecurityPolicies {
+		if policy.RatePolicyActions == nil {
+			continue
+		}
+		for _, action := range *policy.RatePolicyActions {
+			ratePolicyID, ok := p.report.RatePolicyIDs[action.ID]
+			if !ok {
+				return fmt.Errorf("policy %s references unknown rate policy %d", policy.ID, action.ID)
+			}
+			if _, err := p.clients.APPSEC.UpdateRatePolicyAction(p.ctx, appsec.UpdateRatePolicyActionRequest{
+				ConfigID:     p.report.ConfigID,
+				Version:      p.report.Version,
+				PolicyID:     p.report.SecurityPolicyIDs[policy.ID],
+				RatePolicyID: ratePolicyID,
+				Ipv4Action:   action.Ipv4Action,
+				Ipv6Action:   action.Ipv6Action,
+			}); err != nil {
+				return fmt.Errorf("policy %s, rate policy %d: %w", policy.ID, action.ID, err)
+			}
+		}
+	}
+	return nil
+}
+
+func (p *promotion) createMatchTargets() error {
+	for _, target := range p.golden.MatchTargets.WebsiteTargets {
+		policyID, ok := p.report.SecurityPolicyIDs[target.SecurityPolicy.PolicyID]
+		if !ok {
+			return fmt.Errorf("match target %d references unknown policy %s", target.ID, target.SecurityPolicy.PolicyID)
+		}
+		target.SecurityPolicy.PolicyID = policyID
+		payload, err := marshalForCreate(target)
+		if err != nil {
+			return fmt.Errorf("match target %d: %w", target.ID, err)
+		}
+		created, err := p.clients.APPSEC.CreateMatchTarget(p.ctx, appsec.CreateMatchTargetRequest{
+			Type:           target.Type,
+			ConfigID:       p.report.ConfigID,
+			ConfigVersion:  p.report.Version,
+			JsonPayloadRaw: payload,
+		})
+		if err != nil {
+			return fmt.Errorf("match target %d: %w", target.ID, err)
+		}
+		p.recordMatchTarget(target.ID, created.TargetID)
+	}
+	for _, target := range p.golden.MatchTargets.APITargets {
+		policyID, ok := p.report.SecurityPolicyIDs[target.SecurityPolicy.PolicyID]
+		if !ok {
+			return fmt.Errorf("match target %d references unknown policy %s", target.TargetID, target.SecurityPolicy.PolicyID)
+		}
+		target.SecurityPolicy.PolicyID = policyID
+		target.Apis = append(target.Apis[:0:0], target.Apis...)
+		for i, api := range target.Apis {
+			endpointID, ok := p.target.APIEndpointIDs[api.ID]
+			if !ok {
+				return fmt.Errorf("match target %d references API endpoint %d which is not mapped in APIEndpointIDs", target.TargetID, api.ID)
+			}
+			target.Apis[i].ID = endpointID
+			target.Apis[i].Name = ""
+		}
+		payload, err := marshalForCreate(target)
+		if err != nil {
+			return fmt.Errorf("match target %d: %w", target.TargetID, err)
+		}
+		created, err := p.clients.APPSEC.CreateMatchTarget(p.ctx, appsec.CreateMatchTargetRequest{
+			Type:           target.Type,
+			ConfigID:       p.report.ConfigID,
+			ConfigVersion:  p.report.Version,
+			JsonPayloadRaw: payload,
+		})
+		if err != nil {
+			return fmt.Errorf("match target %d: %w", target.TargetID, err)
+		}
+		p.recordMatchTarget(target.TargetID, created.TargetID)
+	}
+	return nil
+}
+
+func (p *promotion) recordMatchTarget(goldenID, createdID int) {
+	if p.report.MatchTargetIDs == nil {
+		p.report.MatchTargetIDs = make(map[int]int)
+	}
+	p.report.MatchTargetIDs[goldenID] = createdID
+}
+
+func (p *promotion) updateBotManagementSettings() error {
+	if p.bundle == nil {
+		return nil
+	}
+	for _, policy := range p.golden.SecurityPolicies {
+		if policy.BotManagement == nil || policy.BotManagement.BotManagementSettings == nil {
+			continue
+		}
+		payload, err := json.Marshal(policy.BotManagement.BotManagementSettings)
+		if err != nil {
+			return fmt.Errorf("policy %s: %w", policy.ID, err)
+		}
+		if _, err := p.clients.BotMan.UpdateBotManagementSetting(p.ctx, botman.UpdateBotManagementSettingRequest{
+			ConfigID:         int64(p.report.ConfigID),
+			Version:          int64(p.report.Version),
+			SecurityPolicyID: p.report.SecurityPolicyIDs[policy.ID],
+			JsonPayload:      payload,
+		}); err != nil {
+			return fmt.Errorf("policy %s: %w", policy.ID, err)
+		}
+	}
+	return nil
+}
+
+func (p *promotion) importBotMan() error {
+	if p.bundle == nil {
+		return nil
+	}
+	result, err := botman.ImportBundle(p.ctx, p.clients.BotMan, botman.ImportBundleRequest{
+		ConfigID:          int64(p.report.ConfigID),
+		Version:           int64(p.report.Version),
+		Bundle:            *p.bundle,
+		SecurityPolicyIDs: p.report.SecurityPolicyIDs,
+	})
+	p.report.BotMan = result
+	return err
+}
+
+// policyPrefix returns the four character prefix of a security policy ID such as "WEB1_12345".
+func policyPrefix(policyID string) string {
+	prefix, _, _ := strings.Cut(policyID, "_")
+	return prefix
+}
+
+// readOnlyMembers are the members of exported objects which are assigned by the API and are not part of
+// the create requests.
+var readOnlyMembers = []string{"id", "targetId", "configId", "configVersion", "sequence", "effectiveSecurityControls",
+	"validations", "used", "createDate", "createdBy", "updateDate", "updatedBy"}
+
+// marshalForCreate marshals an exported object and drops its read-only members, so that it can be sent in a create request.
+func marshalForCreate(object interface{}) (json.RawMessage, error) {
+	data, err := json.Marshal(object)
+	if err != nil {
+		return nil, err
+	}
+	var members map[string]interface{}
+	if err := json.Unmarshal(data, &members); err != nil {
+		return nil, err
+	}
+	for _, member := range readOnlyMembers {
+		delete(members, member)
+	}
+	return json.Marshal(members)
+}
+
+// substitute returns a copy of in with every string value found in replacements replaced.
+// Object keys are left untouched.
+func substitute[T any](in *T, replacements map[string]string) (*T, error) {
+	data, err := json.Marshal(in)
+	if err != nil {
+		return nil, err
+	}
+	var tree interface{}
+	if err := json.Unmarshal(data, &tree); err != nil {
+		return nil, err
+	}
+	if data, err = json.Marshal(replaceStrings(tree, replacements)); err != nil {
+		return nil, err
+	}
+	var out T
+	if err := json.Unmarshal(data, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+func replaceStrings(value interface{}, replacements map[string]string) interface{} {
+	switch v := value.(type) {
+	case string:
+		if replacement, ok := replacements[v]; ok {
+			return replacement
+		}
+		return v
+	case []interface{}:
+		for i := range v {
+			v[i] = replaceStrings(v[i], replacements)
+		}
+		return v
+	case map[string]interface{}:
+		for key, member := range v {
+			v[key] = replaceStrings(member, replacements)
+		}
+		return v
+	default:
+		return v
+	}
+}
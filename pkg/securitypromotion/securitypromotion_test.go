@@ -0,0 +1,418 @@
+package securitypromotion
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"reflect"
+	"sync/atomic"
+	"testing"
+
+	"github.com/akamai/AkamaiOPEN-edgegrid-golang/v11/pkg/appsec"
+	"github.com/akamai/AkamaiOPEN-edgegrid-golang/v11/pkg/botman"
+	"github.com/akamai/AkamaiOPEN-edgegrid-golang/v11/pkg/edgegrid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func loadGoldenConfig(t *testing.T) *appsec.GetExportConfigurationResponse {
+	data, err := os.ReadFile("testdata/TestPromote/GoldenConfig.json")
+	require.NoError(t, err)
+	var config appsec.GetExportConfigurationResponse
+	require.NoError(t, json.Unmarshal(data, &config))
+	return &config
+}
+
+// jsonEqual reports whether both documents hold the same JSON value.
+func jsonEqual(expected string, actual []byte) bool {
+	var a, b interface{}
+	if err := json.Unmarshal([]byte(expected), &a); err != nil {
+		return false
+	}
+	if err := json.Unmarshal(actual, &b); err != nil {
+		return false
+	}
+	return reflect.DeepEqual(a, b)
+}
+
+// jsonMember returns a member of the JSON object.
+func jsonMember(data []byte, member string) interface{} {
+	var object map[string]interface{}
+	if err := json.Unmarshal(data, &object); err != nil {
+		return nil
+	}
+	return object[member]
+}
+
+func mockTargetAPPSEC(m *appsec.Mock, configID int, policyID string, hostname, networkListID string, apiEndpointID int) {
+	m.On("CreateConfiguration", mock.Anything, appsec.CreateConfigurationRequest{
+		Name:       "Golden",
+		ContractID: "C-1",
+		GroupID:    100,
+		Hostnames:  []string{hostname, "api.example.com"},
+	}).Return(&appsec.CreateConfigurationResponse{ConfigID: configID, Version: 1}, nil).Once()
+	m.On("CreateSecurityPolicy", mock.Anything, appsec.CreateSecurityPolicyRequest{
+		ConfigID:        configID,
+		Version:         1,
+		PolicyName:      "Web",
+		PolicyPrefix:    "WEB1",
+		DefaultSettings: true,
+	}).Return(&appsec.CreateSecurityPolicyResponse{PolicyID: policyID}, nil).Once()
+	m.On("UpdatePolicyProtections", mock.Anything, appsec.UpdatePolicyProtectionsRequest{
+		ConfigID:                      configID,
+		Version:                       1,
+		PolicyID:                      policyID,
+		ApplyApplicationLayerControls: true,
+		ApplyNetworkLayerControls:     true,
+		ApplyRateControls:             true,
+		ApplySlowPostControls:         true,
+	}).Return(&appsec.PolicyProtectionsResponse{}, nil).Once()
+	m.On("UpdateWAFMode", mock.Anything, appsec.UpdateWAFModeRequest{ConfigID: configID, Version: 1, PolicyID: policyID, Mode: "ASE_MANUAL"}).
+		Return(&appsec.UpdateWAFModeResponse{}, nil).Once()
+	m.On("UpdateAttackGroup", mock.Anything, appsec.UpdateAttackGroupRequest{ConfigID: configID, Version: 1, PolicyID: policyID, Group: "SQL", Action: "deny"}).
+		Return(&appsec.UpdateAttackGroupResponse{}, nil).Once()
+	m.On("UpdateRule", mock.Anything, mock.MatchedBy(func(req appsec.UpdateRuleRequest) bool {
+		return req.ConfigID == configID && req.Version == 1 && req.PolicyID == policyID && req.RuleID == 950002 && req.Action == "alert" &&
+			jsonEqual(fmt.Sprintf(`{"conditions":[{"type":"hostMatch","positiveMatch":true,"hosts":[%q]}]}`, hostname), req.JsonPayloadRaw)
+	})).Return(&appsec.UpdateRuleResponse{}, nil).Once()
+	m.On("UpdatePenaltyBox", mock.Anything, appsec.UpdatePenaltyBoxRequest{ConfigID: configID, Version: 1, PolicyID: policyID, Action: "deny", PenaltyBoxProtection: true}).
+		Return(&appsec.UpdatePenaltyBoxResponse{}, nil).Once()
+	slowPost := appsec.UpdateSlowPostProtectionSettingRequest{ConfigID: configID, Version: 1, PolicyID: policyID, Action: "alert"}
+	slowPost.SlowRateThreshold.Rate = 10
+	slowPost.SlowRateThreshold.Period = 60
+	slowPost.DurationThreshold.Timeout = 15
+	m.On("UpdateSlowPostProtectionSetting", mock.Anything, slowPost).Return(&appsec.UpdateSlowPostProtectionSettingResponse{}, nil).Once()
+	m.On("UpdateIPGeo", mock.Anything, appsec.UpdateIPGeoRequest{
+		ConfigID:   configID,
+		Version:    1,
+		PolicyID:   policyID,
+		Block:      "blockSpecificIPGeo",
+		IPControls: &appsec.IPGeoIPControls{BlockedIPNetworkLists: &appsec.IPGeoNetworkLists{NetworkList: []string{networkListID}}},
+	}).Return(&appsec.UpdateIPGeoResponse{}, nil).Once()
+	m.On("UpdateReputationProfileAction", mock.Anything, appsec.UpdateReputationProfileActionRequest{
+		ConfigID:            configID,
+		Version:             1,
+		PolicyID:            policyID,
+		ReputationProfileID: 2001,
+		Action:              "alert",
+	}).Return(&appsec.UpdateReputationProfileActionResponse{}, nil).Once()
+	m.On("UpdateApiRequestConstraints", mock.Anything, appsec.UpdateApiRequestConstraintsRequest{ConfigID: configID, Version: 1, PolicyID: policyID, Action: "alert"}).
+		Return(&appsec.UpdateApiRequestConstraintsResponse{}, nil).Once()
+	m.On("CreateCustomRule", mock.Anything, mock.MatchedBy(func(req appsec.CreateCustomRuleRequest) bool {
+		return req.ConfigID == configID && req.Version == 1 && jsonEqual(fmt.Sprintf(`{"name":"Block admin","operation":"AND","conditions":[
+{"type":"hostMatch","positiveMatch":true,"value":[%q]},{"type":"ipMatch","positiveMatch":true,"value":[%q]}]}`, hostname, networkListID), req.JsonPayloadRaw)
+	})).Return(&appsec.CreateCustomRuleResponse{ID: configID + 1}, nil).Once()
+	m.On("UpdateCustomRuleAction", mock.Anything, appsec.UpdateCustomRuleActionRequest{
+		ConfigID: configID,
+		Version:  1,
+		PolicyID: policyID,
+		RuleID:   configID + 1,
+		Action:   "deny",
+	}).Return(&appsec.UpdateCustomRuleActionResponse{}, nil).Once()
+	m.On("CreateRatePolicy", mock.Anything, mock.MatchedBy(func(req appsec.CreateRatePolicyRequest) bool {
+		return req.ConfigID == configID && req.ConfigVersion == 1 && jsonEqual(`{"name":"Origin errors","averageThreshold":5,
+"burstThreshold":8,"clientIdentifiers":["ip"],"counterType":"per_edge","matchType":"path","pathMatchType":"Custom",
+"pathUriPositiveMatch":true,"penaltyBoxDuration":"TEN_MINUTES","requestType":"ClientRequest","sameActionOnIpv6":true,
+"type":"WAF","useXForwardForHeaders":false,"hostnames":["api.example.com"]}`, req.JsonPayloadRaw)
+	})).Return(&appsec.CreateRatePolicyResponse{ID: configID + 2}, nil).Once()
+	m.On("UpdateRatePolicyAction", mock.Anything, appsec.UpdateRatePolicyActionRequest{
+		ConfigID:     configID,
+		Version:      1,
+		PolicyID:     policyID,
+		RatePolicyID: configID + 2,
+		Ipv4Action:   "alert",
+		Ipv6Action:   "alert",
+	}).Return(&appsec.UpdateRatePolicyActionResponse{}, nil).Once()
+	m.On("CreateMatchTarget", mock.Anything, mock.MatchedBy(func(req appsec.CreateMatchTargetRequest) bool {
+		return req.Type == "website" && req.ConfigID == configID && req.ConfigVersion == 1 && jsonEqual(fmt.Sprintf(`{"type":"website",
+"defaultFile":"NO_MATCH","filePaths":["/*"],"hostnames":[%q,"api.example.com"],"isNegativeFileExtensionMatch":false,
+"isNegativePathMatch":false,"bypassNetworkLists":[{"id":%q,"name":"Block list"}],"securityPolicy":{"policyId":%q}}`,
+			hostname, networkListID, policyID), req.JsonPayloadRaw)
+	})).Return(&appsec.CreateMatchTargetResponse{TargetID: configID + 3}, nil).Once()
+	m.On("CreateMatchTarget", mock.Anything, mock.MatchedBy(func(req appsec.CreateMatchTargetRequest) bool {
+		return req.Type == "api" && req.ConfigID == configID && req.ConfigVersion == 1 && jsonEqual(fmt.Sprintf(`{"type":"api",
+"apis":[{"id":%d}],"bypassNetworkLists":[{"id":%q,"name":"Block list"}],"securityPolicy":{"policyId":%q}}`,
+			apiEndpointID, networkListID, policyID), req.JsonPayloadRaw)
+	})).Return(&appsec.CreateMatchTargetResponse{TargetID: configID + 4}, nil).Once()
+}
+
+func TestPromote(t *testing.T) {
+	targets := []Target{
+		{
+			AccountSwitchKey: "1-ABC",
+			ContractID:       "C-1",
+			GroupID:          100,
+			Hostnames:        map[string]string{"www.example.com": "www.customer-a.com"},
+			NetworkListIDs:   map[string]string{"12345_BLOCKLIST": "111_BLOCK"},
+			APIEndpointIDs:   map[int]int{7001: 8001},
+		},
+		{
+			AccountSwitchKey: "1-DEF",
+			ContractID:       "C-1",
+			GroupID:          100,
+			Hostnames:        map[string]string{"www.example.com": "www.customer-b.com"},
+			NetworkListIDs:   map[string]string{"12345_BLOCKLIST": "222_BLOCK"},
+			APIEndpointIDs:   map[int]int{7001: 9001},
+		},
+	}
+
+	tests := map[string]struct {
+		params         PromoteRequest
+		init           func(source *appsec.Mock, accounts map[string]*appsec.Mock)
+		expectedReport *Report
+		withError      error
+	}{
+		"all targets promoted": {
+			params: PromoteRequest{ConfigID: 43253, Version: 7, Targets: targets, Concurrency: 2},
+			init: func(_ *appsec.Mock, accounts map[string]*appsec.Mock) {
+				mockTargetAPPSEC(accounts["1-ABC"], 1000, "WEB1_2000", "www.customer-a.com", "111_BLOCK", 8001)
+				mockTargetAPPSEC(accounts["1-DEF"], 3000, "WEB1_4000", "www.customer-b.com", "222_BLOCK", 9001)
+			},
+			expectedReport: &Report{Targets: []TargetReport{
+				{
+					AccountSwitchKey:  "1-ABC",
+					Status:            StatusSucceeded,
+					ConfigID:          1000,
+					Version:           1,
+					SecurityPolicyIDs: map[string]string{"WEB1_1001": "WEB1_2000"},
+					CustomRuleIDs:     map[int]int{60001: 1001},
+					RatePolicyIDs:     map[int]int{501: 1002},
+					MatchTargetIDs:    map[int]int{3001: 1003, 3002: 1004},
+				},
+				{
+					AccountSwitchKey:  "1-DEF",
+					Status:            StatusSucceeded,
+					ConfigID:          3000,
+					Version:           1,
+					SecurityPolicyIDs: map[string]string{"WEB1_1001": "WEB1_4000"},
+					CustomRuleIDs:     map[int]int{60001: 3001},
+					RatePolicyIDs:     map[int]int{501: 3002},
+					MatchTargetIDs:    map[int]int{3001: 3003, 3002: 3004},
+				},
+			}},
+		},
+		"one target fails": {
+			params: PromoteRequest{ConfigID: 43253, Version: 7, Targets: targets},
+			init: func(_ *appsec.Mock, accounts map[string]*appsec.Mock) {
+				mockTargetAPPSEC(accounts["1-ABC"], 1000, "WEB1_2000", "www.customer-a.com", "111_BLOCK", 8001)
+				accounts["1-DEF"].On("CreateConfiguration", mock.Anything, mock.Anything).
+					Return(&appsec.CreateConfigurationResponse{ConfigID: 3000, Version: 1}, nil).Once()
+				accounts["1-DEF"].On("CreateSecurityPolicy", mock.Anything, mock.Anything).
+					Return(nil, errors.New("policy prefix already in use")).Once()
+			},
+			expectedReport: &Report{Targets: []TargetReport{
+				{
+					AccountSwitchKey:  "1-ABC",
+					Status:            StatusSucceeded,
+					ConfigID:          1000,
+					Version:           1,
+					SecurityPolicyIDs: map[string]string{"WEB1_1001": "WEB1_2000"},
+					CustomRuleIDs:     map[int]int{60001: 1001},
+					RatePolicyIDs:     map[int]int{501: 1002},
+					MatchTargetIDs:    map[int]int{3001: 1003, 3002: 1004},
+				},
+				{
+					AccountSwitchKey: "1-DEF",
+					Status:           StatusFailed,
+					ConfigID:         3000,
+					Version:          1,
+					FailedStep:       stepCreateSecurityPolicies,
+					Error:            `promotion: account "1-DEF": create security policies: policy WEB1_1001: policy prefix already in use`,
+				},
+			}},
+		},
+		"API endpoint not mapped": {
+			params: PromoteRequest{ConfigID: 43253, Version: 7, Targets: []Target{
+				{
+					AccountSwitchKey: "1-ABC",
+					ContractID:       "C-1",
+					GroupID:          100,
+					Hostnames:        map[string]string{"www.example.com": "www.customer-a.com"},
+					NetworkListIDs:   map[string]string{"12345_BLOCKLIST": "111_BLOCK"},
+				},
+			}},
+			init: func(_ *appsec.Mock, accounts map[string]*appsec.Mock) {
+				m := accounts["1-ABC"]
+				mockTargetAPPSEC(m, 1000, "WEB1_2000", "www.customer-a.com", "111_BLOCK", 8001)
+				m.ExpectedCalls = m.ExpectedCalls[:len(m.ExpectedCalls)-1]
+			},
+			expectedReport: &Report{Targets: []TargetReport{
+				{
+					AccountSwitchKey:  "1-ABC",
+					Status:            StatusFailed,
+					ConfigID:          1000,
+					Version:           1,
+					SecurityPolicyIDs: map[string]string{"WEB1_1001": "WEB1_2000"},
+					CustomRuleIDs:     map[int]int{60001: 1001},
+					RatePolicyIDs:     map[int]int{501: 1002},
+					MatchTargetIDs:    map[int]int{3001: 1003},
+					FailedStep:        stepCreateMatchTargets,
+					Error:             `promotion: account "1-ABC": create match targets: match target 3002 references API endpoint 7001 which is not mapped in APIEndpointIDs`,
+				},
+			}},
+		},
+		"WAF mode export fails": {
+			params: PromoteRequest{ConfigID: 43253, Version: 7, Targets: targets},
+			init: func(source *appsec.Mock, _ map[string]*appsec.Mock) {
+				source.ExpectedCalls = source.ExpectedCalls[:1]
+				source.On("GetWAFMode", mock.Anything, mock.Anything).Return(nil, errors.New("oops")).Once()
+			},
+			withError: ErrExport,
+		},
+		"export fails": {
+			params: PromoteRequest{ConfigID: 43253, Version: 7, Targets: targets},
+			init: func(source *appsec.Mock, _ map[string]*appsec.Mock) {
+				source.ExpectedCalls = nil
+				source.On("GetExportConfiguration", mock.Anything, mock.Anything).Return(nil, errors.New("oops")).Once()
+			},
+			withError: ErrExport,
+		},
+		"validation error": {
+			params:    PromoteRequest{ConfigID: 43253, Version: 7},
+			withError: ErrStructValidation,
+		},
+		"target validation error": {
+			params:    PromoteRequest{ConfigID: 43253, Version: 7, Targets: []Target{{AccountSwitchKey: "1-ABC"}}},
+			withError: ErrStructValidation,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			source := &appsec.Mock{}
+			source.On("GetExportConfiguration", mock.Anything, appsec.GetExportConfigurationRequest{ConfigID: 43253, Version: 7}).
+				Return(loadGoldenConfig(t), nil).Maybe()
+			source.On("GetWAFMode", mock.Anything, appsec.GetWAFModeRequest{ConfigID: 43253, Version: 7, PolicyID: "WEB1_1001"}).
+				Return(&appsec.GetWAFModeResponse{Mode: "ASE_MANUAL"}, nil).Maybe()
+			accounts := map[string]*appsec.Mock{"1-ABC": {}, "1-DEF": {}}
+			if test.init != nil {
+				test.init(source, accounts)
+			}
+			factory := func(accountSwitchKey string) (*Clients, error) {
+				return &Clients{APPSEC: accounts[accountSwitchKey]}, nil
+			}
+
+			report, err := Promote(context.Background(), Clients{APPSEC: source}, factory, test.params)
+			if test.withError != nil {
+				assert.True(t, errors.Is(err, test.withError), "want: %s; got: %s", test.withError, err)
+				return
+			}
+			require.NoError(t, err)
+			for i := range report.Targets {
+				if report.Targets[i].Status == StatusFailed {
+					assert.True(t, errors.Is(report.Targets[i].Err, ErrPromotion))
+				}
+				report.Targets[i].Err = nil
+			}
+			assert.Equal(t, test.expectedReport, report)
+			source.AssertExpectations(t)
+			for _, m := range accounts {
+				m.AssertExpectations(t)
+			}
+		})
+	}
+}
+
+func TestPromote_BotMan(t *testing.T) {
+	ctx := context.Background()
+	source := &appsec.Mock{}
+	source.On("GetExportConfiguration", mock.Anything, mock.Anything).Return(loadGoldenConfig(t), nil).Once()
+	source.On("GetWAFMode", mock.Anything, mock.Anything).Return(&appsec.GetWAFModeResponse{Mode: "ASE_MANUAL"}, nil).Once()
+
+	sourceBotMan := &botman.Mock{}
+	sourceBotMan.On("GetCustomBotCategoryList", ctx, mock.Anything).Return(&botman.GetCustomBotCategoryListResponse{}, nil).Once()
+	sourceBotMan.On("GetCustomBotCategorySequence", ctx, mock.Anything).Return(&botman.CustomBotCategorySequenceResponse{}, nil).Once()
+	sourceBotMan.On("GetCustomDefinedBotList", ctx, mock.Anything).Return(&botman.GetCustomDefinedBotListResponse{}, nil).Once()
+	sourceBotMan.On("GetCustomClientList", ctx, mock.Anything).Return(&botman.GetCustomClientListResponse{
+		CustomClients: []map[string]interface{}{
+			{"customClientId": "cc1", "customClientName": "Mobile app", "hostnames": []interface{}{"www.example.com"}},
+		},
+	}, nil).Once()
+	sourceBotMan.On("GetCustomClientSequence", ctx, mock.Anything).Return(&botman.CustomClientSequenceResponse{Sequence: []string{"cc1"}}, nil).Once()
+	sourceBotMan.On("GetCustomCode", ctx, mock.Anything).Return(map[string]interface{}(nil), nil).Once()
+	sourceBotMan.On("GetChallengeInjectionRules", ctx, mock.Anything).Return(map[string]interface{}(nil), nil).Once()
+	sourceBotMan.On("GetContentProtectionRuleList", ctx, mock.Anything).Return(&botman.GetContentProtectionRuleListResponse{}, nil).Once()
+	sourceBotMan.On("GetContentProtectionRuleSequence", ctx, mock.Anything).Return(&botman.GetContentProtectionRuleSequenceResponse{}, nil).Once()
+	sourceBotMan.On("GetJavascriptInjection", ctx, mock.Anything).Return(map[string]interface{}(nil), nil).Once()
+
+	target := &appsec.Mock{}
+	mockTargetAPPSEC(target, 1000, "WEB1_2000", "www.customer-a.com", "111_BLOCK", 8001)
+	targetBotMan := &botman.Mock{}
+	targetBotMan.On("UpdateBotManagementSetting", ctx, botman.UpdateBotManagementSettingRequest{
+		ConfigID:         1000,
+		Version:          1,
+		SecurityPolicyID: "WEB1_2000",
+		JsonPayload:      json.RawMessage(`{"enableBotManagement":true}`),
+	}).Return(map[string]interface{}{}, nil).Once()
+	targetBotMan.On("CreateCustomClient", ctx, botman.CreateCustomClientRequest{
+		ConfigID:    1000,
+		Version:     1,
+		JsonPayload: json.RawMessage(`{"customClientName":"Mobile app","hostnames":["www.customer-a.com"]}`),
+	}).Return(map[string]interface{}{"customClientId": "cc9"}, nil).Once()
+	targetBotMan.On("UpdateCustomClientSequence", ctx, botman.UpdateCustomClientSequenceRequest{
+		ConfigID: 1000,
+		Version:  1,
+		Sequence: []string{"cc9"},
+	}).Return(&botman.CustomClientSequenceResponse{}, nil).Once()
+
+	var calls atomic.Int32
+	factory := func(accountSwitchKey string) (*Clients, error) {
+		calls.Add(1)
+		assert.Equal(t, "1-ABC", accountSwitchKey)
+		return &Clients{APPSEC: target, BotMan: targetBotMan}, nil
+	}
+
+	report, err := Promote(ctx, Clients{APPSEC: source, BotMan: sourceBotMan}, factory, PromoteRequest{
+		ConfigID: 43253,
+		Version:  7,
+		Targets: []Target{{
+			AccountSwitchKey: "1-ABC",
+			ContractID:       "C-1",
+			GroupID:          100,
+			Hostnames:        map[string]string{"www.example.com": "www.customer-a.com"},
+			NetworkListIDs:   map[string]string{"12345_BLOCKLIST": "111_BLOCK"},
+			APIEndpointIDs:   map[int]int{7001: 8001},
+		}},
+	})
+	require.NoError(t, err)
+	require.Len(t, report.Targets, 1)
+	assert.Equal(t, StatusSucceeded, report.Targets[0].Status)
+	assert.Equal(t, map[string]string{"cc1": "cc9"}, report.Targets[0].BotMan.CustomClientIDs)
+	assert.Empty(t, report.Failed())
+	assert.Equal(t, int32(1), calls.Load())
+	source.AssertExpectations(t)
+	sourceBotMan.AssertExpectations(t)
+	target.AssertExpectations(t)
+	targetBotMan.AssertExpectations(t)
+}
+
+func TestSessionClientFactory(t *testing.T) {
+	factory := SessionClientFactory(edgegrid.Config{Host: "akab-host.luna.akamaiapis.net", AccountKey: "golden"})
+
+	clients, err := factory("1-ABC")
+	require.NoError(t, err)
+	assert.NotNil(t, clients.APPSEC)
+	assert.NotNil(t, clients.BotMan)
+}
+
+func TestSubstitute(t *testing.T) {
+	in := &botman.Bundle{
+		CustomClients: []botman.CustomClientDetails{
+			{CustomClientID: "www.example.com", Hostnames: []string{"www.example.com", "other.example.com"}},
+		},
+		CustomCode: map[string]interface{}{"www.example.com": "keep key"},
+	}
+
+	out, err := substitute(in, map[string]string{"www.example.com": "www.customer.com"})
+	require.NoError(t, err)
+	assert.Equal(t, &botman.Bundle{
+		CustomClients: []botman.CustomClientDetails{
+			{CustomClientID: "www.customer.com", Hostnames: []string{"www.customer.com", "other.example.com"}},
+		},
+		CustomCode: map[string]interface{}{"www.example.com": "keep key"},
+	}, out)
+	assert.Equal(t, "www.example.com", in.CustomClients[0].CustomClientID, "input must not be modified")
+}